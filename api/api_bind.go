@@ -156,6 +156,7 @@ func forceStop(c echo.Context) error {
 	if !haskey {
 		return c.JSON(http.StatusForbidden, nil)
 	}
+	audit(c, "system.force_stop", "", nil, nil)
 	defer func() {
 		// Same with main.go `cleanUpCreate()` 由于无法导入 main.go 中的函数，所以这里直接复制过来了
 		logger := myDice.Logger
@@ -261,7 +262,9 @@ func checkSecurity(c echo.Context) error {
 
 func doSignIn(c echo.Context) error {
 	v := struct {
+		Username string `json:"username"` // 为空时为主人登录
 		Password string `json:"password"`
+		TOTPCode string `json:"totpCode"` // 两步验证码或恢复码
 	}{}
 
 	err := c.Bind(&v)
@@ -270,11 +273,21 @@ func doSignIn(c echo.Context) error {
 	}
 
 	generateToken := func() error {
+		// 密码正确后再检查两步验证，未填验证码时提示前端补充
+		if enabled, _ := myDice.Parent.TOTPEnabled(v.Username); enabled {
+			if v.TOTPCode == "" {
+				return c.JSON(http.StatusOK, map[string]interface{}{"needTotp": true})
+			}
+			if err := myDice.Parent.TOTPVerify(v.Username, v.TOTPCode); err != nil {
+				return c.JSON(400, map[string]interface{}{"needTotp": true, "err": err.Error()})
+			}
+		}
+
 		now := time.Now().Unix()
 		head := hex.EncodeToString(Int64ToBytes(now))
 		token := dice.RandStringBytesMaskImprSrcSB2(64) + ":" + head
 
-		myDice.Parent.AccessTokens[token] = true
+		myDice.Parent.AccessTokenAdd(token, v.Username)
 		myDice.LastUpdatedTime = time.Now().Unix()
 		myDice.Parent.Save()
 		return c.JSON(http.StatusOK, map[string]string{
@@ -282,6 +295,13 @@ func doSignIn(c echo.Context) error {
 		})
 	}
 
+	if v.Username != "" {
+		if myDice.Parent.WebUIUserCheck(v.Username, v.Password) != nil {
+			return c.JSON(400, nil)
+		}
+		return generateToken()
+	}
+
 	if myDice.Parent.UIPasswordHash == "" {
 		return generateToken()
 	}
@@ -364,6 +384,7 @@ func DiceExec(c echo.Context) error {
 		GroupID:   groupID,
 		GroupName: groupName,
 	}
+	audit(c, "dice.exec", messageType, nil, v.Message)
 	myDice.ImSession.Execute(myDice.UIEndpoint, msg, false)
 	return c.JSON(200, "ok")
 }
@@ -561,172 +582,212 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	prefix := "/sd-api"
 
 	e.GET(prefix+"/preInfo", preInfo)
-	e.GET(prefix+"/baseInfo", baseInfo)
-	e.GET(prefix+"/hello", hello2)
-	e.GET(prefix+"/log/fetchAndClear", logFetchAndClear)
-	e.GET(prefix+"/im_connections/list", ImConnections)
-	e.GET(prefix+"/im_connections/get", ImConnectionsGet)
-
-	e.GET(prefix+"/im_connections/qq/get_versions", ImConnectionsGetQQVersions)
-	e.POST(prefix+"/im_connections/qrcode", ImConnectionsQrcodeGet)
-	e.POST(prefix+"/im_connections/sms_code_get", ImConnectionsSmsCodeGet)
-	e.POST(prefix+"/im_connections/sms_code_set", ImConnectionsSmsCodeSet)
-	e.POST(prefix+"/im_connections/gocq_captcha_set", ImConnectionsCaptchaSet)
+	e.GET(prefix+"/baseInfo", baseInfo, need(dice.PermBase))
+	e.GET(prefix+"/hello", hello2, need(dice.PermBase))
+	e.GET(prefix+"/log/fetchAndClear", logFetchAndClear, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/list", ImConnections, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/get", ImConnectionsGet, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/capabilities", ImConnectionsCapabilities, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/history", ImConnectionsHistory, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/outbox/metrics", outboxMetrics, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/outbox/config", outboxConfigGet, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/outbox/config/set", outboxConfigSet, need(dice.PermConfig))
+
+	e.GET(prefix+"/im_connections/qq/get_versions", ImConnectionsGetQQVersions, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/qrcode", ImConnectionsQrcodeGet, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/sms_code_get", ImConnectionsSmsCodeGet, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/sms_code_set", ImConnectionsSmsCodeSet, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/gocq_captcha_set", ImConnectionsCaptchaSet, need(dice.PermConfig))
 
 	// 这些都是与QQ/OneBot直接相关
-	e.POST(prefix+"/im_connections/add", ImConnectionsAddBuiltinGocq) // 逐步弃用此链接
-	e.POST(prefix+"/im_connections/addGocq", ImConnectionsAddBuiltinGocq)
-	e.POST(prefix+"/im_connections/addOnebot11ReverseWs", ImConnectionsAddReverseWs)
-	e.POST(prefix+"/im_connections/addGocqSeparate", ImConnectionsAddGocqSeparate)
-	e.POST(prefix+"/im_connections/addWalleQ", ImConnectionsAddWalleQ)
-	e.POST(prefix+"/im_connections/addLagrange", ImConnectionsAddBuiltinLagrange)
+	e.POST(prefix+"/im_connections/add", ImConnectionsAddBuiltinGocq, need(dice.PermConfig)) // 逐步弃用此链接
+	e.POST(prefix+"/im_connections/addGocq", ImConnectionsAddBuiltinGocq, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addOnebot11ReverseWs", ImConnectionsAddReverseWs, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addGocqSeparate", ImConnectionsAddGocqSeparate, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addWalleQ", ImConnectionsAddWalleQ, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addLagrange", ImConnectionsAddBuiltinLagrange, need(dice.PermConfig))
 	// e.POST(prefix+"/im_connections/addLagrangeGo", ImConnectionsAddLagrangeGO)
-	e.POST(prefix+"/im_connections/addRed", ImConnectionsAddRed)
-	e.POST(prefix+"/im_connections/addOfficialQQ", ImConnectionsAddOfficialQQ)
-
-	e.POST(prefix+"/im_connections/addDiscord", ImConnectionsAddDiscord)
-	e.POST(prefix+"/im_connections/addKook", ImConnectionsAddKook)
-	e.POST(prefix+"/im_connections/addTelegram", ImConnectionsAddTelegram)
-	e.POST(prefix+"/im_connections/addMinecraft", ImConnectionsAddMinecraft)
-	e.POST(prefix+"/im_connections/addDodo", ImConnectionsAddDodo)
-	e.POST(prefix+"/im_connections/addDingtalk", ImConnectionsAddDingTalk)
-	e.POST(prefix+"/im_connections/addSlack", ImConnectionsAddSlack)
-	e.POST(prefix+"/im_connections/addSealChat", ImConnectionsAddSealChat)
-	e.POST(prefix+"/im_connections/addSatori", ImConnectionsAddSatori)
-
-	e.POST(prefix+"/im_connections/del", ImConnectionsDel)
-	e.POST(prefix+"/im_connections/set_enable", ImConnectionsSetEnable)
-	e.POST(prefix+"/im_connections/set_data", ImConnectionsSetData)
-	e.POST(prefix+"/im_connections/set_sign_server", ImConnectionsRWSignServerUrl)
-	e.POST(prefix+"/im_connections/gocqhttpRelogin", ImConnectionsGocqhttpRelogin)
-	e.POST(prefix+"/im_connections/walleQRelogin", ImConnectionsWalleQRelogin)
-	e.GET(prefix+"/im_connections/gocq_config_download.zip", ImConnectionsGocqConfigDownload)
-
-	e.GET(prefix+"/configs/customText", customText)
-	e.POST(prefix+"/configs/customText/save", customTextSave)
-	e.POST(prefix+"/configs/customText/preview-refresh", customTextPreviewRefresh)
-
-	e.GET(prefix+"/configs/custom_reply", customReplyGet)
-	e.POST(prefix+"/configs/custom_reply/save", customReplySave)
-	e.GET(prefix+"/configs/custom_reply/file_list", customReplyFileList)
-	e.POST(prefix+"/configs/custom_reply/file_new", customReplyFileNew)
-	e.POST(prefix+"/configs/custom_reply/file_delete", customReplyFileDelete)
-	e.GET(prefix+"/configs/custom_reply/file_download", customReplyFileDownload)
-	e.POST(prefix+"/configs/custom_reply/file_upload", customReplyFileUpload)
-	e.GET(prefix+"/configs/custom_reply/debug_mode", customReplyDebugModeGet)
-	e.POST(prefix+"/configs/custom_reply/debug_mode", customReplyDebugModeSet)
-
-	e.GET(prefix+"/dice/config/get", DiceConfig)
-	e.POST(prefix+"/dice/config/set", DiceConfigSet)
-	e.GET(prefix+"/dice/config/advanced/get", DiceAdvancedConfigGet)
-	e.POST(prefix+"/dice/config/advanced/set", DiceAdvancedConfigSet)
-	e.POST(prefix+"/dice/config/mail_test", DiceMailTest)
-	e.POST(prefix+"/dice/exec", DiceExec)
-	e.GET(prefix+"/dice/recentMessage", DiceRecentMessage)
-	e.GET(prefix+"/dice/cmdList", DiceAllCommand)
-	e.POST(prefix+"/dice/upload_to_upgrade", DiceNewVersionUpload)
+	e.POST(prefix+"/im_connections/addRed", ImConnectionsAddRed, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addOfficialQQ", ImConnectionsAddOfficialQQ, need(dice.PermConfig))
+
+	e.POST(prefix+"/im_connections/addDiscord", ImConnectionsAddDiscord, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addKook", ImConnectionsAddKook, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addTelegram", ImConnectionsAddTelegram, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addMinecraft", ImConnectionsAddMinecraft, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addDodo", ImConnectionsAddDodo, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addDingtalk", ImConnectionsAddDingTalk, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addSlack", ImConnectionsAddSlack, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addMatrix", ImConnectionsAddMatrix, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addIRC", ImConnectionsAddIRC, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addFeishu", ImConnectionsAddFeishu, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addHTTP", ImConnectionsAddHTTP, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addSealChat", ImConnectionsAddSealChat, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/addSatori", ImConnectionsAddSatori, need(dice.PermConfig))
+
+	e.POST(prefix+"/im_connections/del", ImConnectionsDel, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/set_enable", ImConnectionsSetEnable, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/set_data", ImConnectionsSetData, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/set_sign_server", ImConnectionsRWSignServerUrl, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/gocqhttpRelogin", ImConnectionsGocqhttpRelogin, need(dice.PermConfig))
+	e.POST(prefix+"/im_connections/walleQRelogin", ImConnectionsWalleQRelogin, need(dice.PermConfig))
+	e.GET(prefix+"/im_connections/gocq_config_download.zip", ImConnectionsGocqConfigDownload, need(dice.PermConfig))
+
+	e.GET(prefix+"/configs/customText", customText, need(dice.PermConfig))
+	e.POST(prefix+"/configs/customText/save", customTextSave, need(dice.PermConfig))
+	e.POST(prefix+"/configs/customText/preview-refresh", customTextPreviewRefresh, need(dice.PermConfig))
+
+	e.GET(prefix+"/configs/custom_reply", customReplyGet, need(dice.PermConfig))
+	e.POST(prefix+"/configs/custom_reply/save", customReplySave, need(dice.PermConfig))
+	e.GET(prefix+"/configs/custom_reply/file_list", customReplyFileList, need(dice.PermConfig))
+	e.POST(prefix+"/configs/custom_reply/file_new", customReplyFileNew, need(dice.PermConfig))
+	e.POST(prefix+"/configs/custom_reply/file_delete", customReplyFileDelete, need(dice.PermConfig))
+	e.GET(prefix+"/configs/custom_reply/file_download", customReplyFileDownload, need(dice.PermConfig))
+	e.POST(prefix+"/configs/custom_reply/file_upload", customReplyFileUpload, need(dice.PermConfig))
+	e.GET(prefix+"/configs/custom_reply/debug_mode", customReplyDebugModeGet, need(dice.PermConfig))
+	e.POST(prefix+"/configs/custom_reply/debug_mode", customReplyDebugModeSet, need(dice.PermConfig))
+
+	e.GET(prefix+"/dice/config/get", DiceConfig, need(dice.PermConfig))
+	e.POST(prefix+"/dice/config/set", DiceConfigSet, need(dice.PermConfig))
+	e.GET(prefix+"/dice/config/advanced/get", DiceAdvancedConfigGet, need(dice.PermConfig))
+	e.POST(prefix+"/dice/config/advanced/set", DiceAdvancedConfigSet, need(dice.PermConfig))
+	e.POST(prefix+"/dice/config/mail_test", DiceMailTest, need(dice.PermConfig))
+	e.POST(prefix+"/dice/exec", DiceExec, need(dice.PermScript))
+	e.GET(prefix+"/dice/recentMessage", DiceRecentMessage, need(dice.PermScript))
+	e.GET(prefix+"/dice/cmdList", DiceAllCommand, need(dice.PermBase))
+	e.POST(prefix+"/dice/upload_to_upgrade", DiceNewVersionUpload, need(dice.PermSystem))
+	e.GET(prefix+"/dice/fairroll/list", fairRollSessionList, need(dice.PermDataRead))
+	e.GET(prefix+"/dice/fairroll/verify", fairRollVerify, need(dice.PermDataRead))
+	e.GET(prefix+"/dice/stat", diceStatGet, need(dice.PermDataRead))
+	e.GET(prefix+"/dice/bridge/list", bridgeList, need(dice.PermDataRead))
+	e.POST(prefix+"/dice/bridge/set", bridgeSet, need(dice.PermConfig))
+	e.POST(prefix+"/dice/bridge/delete", bridgeDelete, need(dice.PermConfig))
+	e.GET(prefix+"/dice/identity/list", identityList, need(dice.PermDataRead))
+	e.POST(prefix+"/dice/identity/unlink", identityUnlink, need(dice.PermConfig))
+	e.GET(prefix+"/dice/identity/log/page", identityLogPage, need(dice.PermDataRead))
+	e.GET(prefix+"/dice/webhook/list", webhookList, need(dice.PermConfig))
+	e.POST(prefix+"/dice/webhook/upsert", webhookUpsert, need(dice.PermConfig))
+	e.POST(prefix+"/dice/webhook/delete", webhookDelete, need(dice.PermConfig))
+	e.POST(prefix+"/dice/webhook/test", webhookTest, need(dice.PermConfig))
+	e.GET(prefix+"/dice/webhook/dead_letter/page", webhookDeadLetterPage, need(dice.PermConfig))
+	e.POST(prefix+"/dice/webhook/dead_letter/retry", webhookDeadLetterRetry, need(dice.PermConfig))
+	e.POST(prefix+"/dice/webhook/dead_letter/delete", webhookDeadLetterDelete, need(dice.PermConfig))
 
 	e.POST(prefix+"/signin", doSignIn)
 	e.GET(prefix+"/signin/salt", doSignInGetSalt)
-	e.GET(prefix+"/checkSecurity", checkSecurity)
-
-	e.GET(prefix+"/backup/list", backupGetList)
-	e.POST(prefix+"/backup/do_backup", backupExec)
-	e.GET(prefix+"/backup/config_get", backupConfigGet)
-	e.POST(prefix+"/backup/config_set", backupConfigSave)
-	e.GET(prefix+"/backup/download", backupDownload)
-	e.POST(prefix+"/backup/delete", backupDelete)
-	e.POST(prefix+"/backup/batch_delete", backupBatchDelete)
-
-	e.GET(prefix+"/group/list", groupList)
-	e.POST(prefix+"/group/set_one", groupSetOne)
-	e.POST(prefix+"/group/quit_one", groupQuit)
-
-	e.GET(prefix+"/banconfig/list", banMapList)
-	e.GET(prefix+"/banconfig/get", banConfigGet)
-	e.POST(prefix+"/banconfig/set", banConfigSet)
+	e.GET(prefix+"/checkSecurity", checkSecurity, need(dice.PermBase))
+	e.GET(prefix+"/webui/user/me", webUIUserMe)
+	e.GET(prefix+"/webui/user/list", webUIUserList, need(dice.PermUser))
+	e.POST(prefix+"/webui/user/upsert", webUIUserUpsert, need(dice.PermUser))
+	e.POST(prefix+"/webui/user/delete", webUIUserDelete, need(dice.PermUser))
+	e.POST(prefix+"/webui/user/reset_2fa", totpResetUser, need(dice.PermUser))
+	e.GET(prefix+"/webui/2fa/status", totpStatus)
+	e.POST(prefix+"/webui/2fa/setup", totpSetup)
+	e.POST(prefix+"/webui/2fa/enable", totpEnable)
+	e.POST(prefix+"/webui/2fa/disable", totpDisable)
+	e.GET(prefix+"/webui/token/list", apiTokenList)
+	e.POST(prefix+"/webui/token/create", apiTokenCreate)
+	e.POST(prefix+"/webui/token/revoke", apiTokenRevoke)
+	e.GET(prefix+"/webui/token/log/page", apiTokenLogPage)
+
+	e.GET(prefix+"/audit/page", auditPage, need(dice.PermAudit))
+
+	e.GET(prefix+"/backup/list", backupGetList, need(dice.PermBackup))
+	e.POST(prefix+"/backup/do_backup", backupExec, need(dice.PermBackup))
+	e.GET(prefix+"/backup/config_get", backupConfigGet, need(dice.PermBackup))
+	e.POST(prefix+"/backup/config_set", backupConfigSave, need(dice.PermBackup))
+	e.GET(prefix+"/backup/download", backupDownload, need(dice.PermBackup))
+	e.POST(prefix+"/backup/delete", backupDelete, need(dice.PermBackup))
+	e.POST(prefix+"/backup/batch_delete", backupBatchDelete, need(dice.PermBackup))
+
+	e.GET(prefix+"/group/list", groupList, need(dice.PermDataRead))
+	e.POST(prefix+"/group/set_one", groupSetOne, need(dice.PermConfig))
+	e.POST(prefix+"/group/quit_one", groupQuit, need(dice.PermConfig))
+
+	e.GET(prefix+"/banconfig/list", banMapList, need(dice.PermBan))
+	e.GET(prefix+"/banconfig/get", banConfigGet, need(dice.PermBan))
+	e.POST(prefix+"/banconfig/set", banConfigSet, need(dice.PermBan))
 	// e.GET(prefix+"/banconfig/map_get", banMapGet)
-	e.POST(prefix+"/banconfig/map_delete_one", banMapDeleteOne)
-	e.POST(prefix+"/banconfig/map_add_one", banMapAddOne)
+	e.POST(prefix+"/banconfig/map_delete_one", banMapDeleteOne, need(dice.PermBan))
+	e.POST(prefix+"/banconfig/map_add_one", banMapAddOne, need(dice.PermBan))
 	// e.POST(prefix+"/banconfig/map_set", banMapSet)
-	e.GET(prefix+"/banconfig/export", banExport)
-	e.POST(prefix+"/banconfig/import", banImport)
-
-	e.GET(prefix+"/deck/list", deckList)
-	e.POST(prefix+"/deck/reload", deckReload)
-	e.POST(prefix+"/deck/upload", deckUpload)
-	e.POST(prefix+"/deck/enable", deckEnable)
-	e.POST(prefix+"/deck/delete", deckDelete)
-	e.POST(prefix+"/deck/check_update", deckCheckUpdate)
-	e.POST(prefix+"/deck/update", deckUpdate)
-
-	e.POST(prefix+"/dice/upgrade", upgrade)
-
-	e.POST(prefix+"/force_stop", forceStop)
-
-	e.POST(prefix+"/js/reload", jsReload)
-	e.POST(prefix+"/js/execute", jsExec)
-	e.POST(prefix+"/js/upload", jsUpload)
-	e.GET(prefix+"/js/list", jsList)
-	e.POST(prefix+"/js/delete", jsDelete)
-	e.GET(prefix+"/js/get_record", jsGetRecord)
-	e.POST(prefix+"/js/shutdown", jsShutdown)
-	e.GET(prefix+"/js/status", jsStatus)
-	e.POST(prefix+"/js/enable", jsEnable)
-	e.POST(prefix+"/js/disable", jsDisable)
-	e.POST(prefix+"/js/check_update", jsCheckUpdate)
-	e.POST(prefix+"/js/update", jsUpdate)
-	e.GET(prefix+"/js/get_configs", handleGetConfigs)
-	e.POST(prefix+"/js/set_configs", handleSetConfigs)
-	e.POST(prefix+"/js/delete_unused_config", handleDeleteUnusedConfig)
-	e.POST(prefix+"/js/reset_config", handleResetConfig)
-
-	e.GET(prefix+"/helpdoc/status", helpDocStatus)
-	e.GET(prefix+"/helpdoc/tree", helpDocTree)
-	e.POST(prefix+"/helpdoc/reload", helpDocReload)
-	e.POST(prefix+"/helpdoc/upload", helpDocUpload)
-	e.POST(prefix+"/helpdoc/delete", helpDocDelete)
-	e.POST(prefix+"/helpdoc/textitem/get_page", helpGetTextItemPage)
-	e.GET(prefix+"/helpdoc/config", helpGetConfig)
-	e.POST(prefix+"/helpdoc/config", helpSetConfig)
-
-	e.GET(prefix+"/story/info", storyGetInfo)
-	e.GET(prefix+"/story/logs", storyGetLogs)
-	e.GET(prefix+"/story/logs/page", storyGetLogPage)
-	e.GET(prefix+"/story/items", storyGetItems)
-	e.GET(prefix+"/story/items/page", storyGetItemPage)
-	e.DELETE(prefix+"/story/log", storyDelLog)
-	e.POST(prefix+"/story/uploadLog", storyUploadLog)
-	e.GET(prefix+"/story/backup/list", storyGetLogBackupList)
-	e.GET(prefix+"/story/backup/download", storyDownloadLogBackup)
-	e.POST(prefix+"/story/backup/batch_delete", storyBatchDeleteLogBackup)
-
-	e.POST(prefix+"/tool/onebot", onebotTool)
+	e.GET(prefix+"/banconfig/export", banExport, need(dice.PermBan))
+	e.POST(prefix+"/banconfig/import", banImport, need(dice.PermBan))
+
+	e.GET(prefix+"/deck/list", deckList, need(dice.PermConfig))
+	e.POST(prefix+"/deck/reload", deckReload, need(dice.PermConfig))
+	e.POST(prefix+"/deck/upload", deckUpload, need(dice.PermConfig))
+	e.POST(prefix+"/deck/enable", deckEnable, need(dice.PermConfig))
+	e.POST(prefix+"/deck/delete", deckDelete, need(dice.PermConfig))
+	e.POST(prefix+"/deck/check_update", deckCheckUpdate, need(dice.PermConfig))
+	e.POST(prefix+"/deck/update", deckUpdate, need(dice.PermConfig))
+
+	e.POST(prefix+"/dice/upgrade", upgrade, need(dice.PermSystem))
+
+	e.POST(prefix+"/force_stop", forceStop) // 安卓启动器调用，使用 FSTOP_KEY 校验
+
+	e.POST(prefix+"/js/reload", jsReload, need(dice.PermScript))
+	e.POST(prefix+"/js/execute", jsExec, need(dice.PermScript))
+	e.POST(prefix+"/js/upload", jsUpload, need(dice.PermScript))
+	e.GET(prefix+"/js/list", jsList, need(dice.PermScript))
+	e.POST(prefix+"/js/delete", jsDelete, need(dice.PermScript))
+	e.GET(prefix+"/js/get_record", jsGetRecord, need(dice.PermScript))
+	e.POST(prefix+"/js/shutdown", jsShutdown, need(dice.PermScript))
+	e.GET(prefix+"/js/status", jsStatus, need(dice.PermScript))
+	e.POST(prefix+"/js/enable", jsEnable, need(dice.PermScript))
+	e.POST(prefix+"/js/disable", jsDisable, need(dice.PermScript))
+	e.POST(prefix+"/js/check_update", jsCheckUpdate, need(dice.PermScript))
+	e.POST(prefix+"/js/update", jsUpdate, need(dice.PermScript))
+	e.GET(prefix+"/js/get_configs", handleGetConfigs, need(dice.PermScript))
+	e.POST(prefix+"/js/set_configs", handleSetConfigs, need(dice.PermScript))
+	e.POST(prefix+"/js/delete_unused_config", handleDeleteUnusedConfig, need(dice.PermScript))
+	e.POST(prefix+"/js/reset_config", handleResetConfig, need(dice.PermScript))
+
+	e.GET(prefix+"/helpdoc/status", helpDocStatus, need(dice.PermConfig))
+	e.GET(prefix+"/helpdoc/tree", helpDocTree, need(dice.PermConfig))
+	e.POST(prefix+"/helpdoc/reload", helpDocReload, need(dice.PermConfig))
+	e.POST(prefix+"/helpdoc/upload", helpDocUpload, need(dice.PermConfig))
+	e.POST(prefix+"/helpdoc/delete", helpDocDelete, need(dice.PermConfig))
+	e.POST(prefix+"/helpdoc/textitem/get_page", helpGetTextItemPage, need(dice.PermConfig))
+	e.GET(prefix+"/helpdoc/config", helpGetConfig, need(dice.PermConfig))
+	e.POST(prefix+"/helpdoc/config", helpSetConfig, need(dice.PermConfig))
+
+	e.GET(prefix+"/story/info", storyGetInfo, need(dice.PermLogRead))
+	e.GET(prefix+"/story/logs", storyGetLogs, need(dice.PermLogRead))
+	e.GET(prefix+"/story/logs/page", storyGetLogPage, need(dice.PermLogRead))
+	e.GET(prefix+"/story/items", storyGetItems, need(dice.PermLogRead))
+	e.GET(prefix+"/story/items/page", storyGetItemPage, need(dice.PermLogRead))
+	e.DELETE(prefix+"/story/log", storyDelLog, need(dice.PermLogWrite))
+	e.POST(prefix+"/story/uploadLog", storyUploadLog, need(dice.PermLogWrite))
+	e.GET(prefix+"/story/backup/list", storyGetLogBackupList, need(dice.PermLogRead))
+	e.GET(prefix+"/story/backup/download", storyDownloadLogBackup, need(dice.PermLogRead))
+	e.POST(prefix+"/story/backup/batch_delete", storyBatchDeleteLogBackup, need(dice.PermLogWrite))
+
+	e.POST(prefix+"/tool/onebot", onebotTool, need(dice.PermConfig))
 	e.GET(prefix+"/utils/ga/:uid", getGithubAvatar)
 	e.GET(prefix+"/utils/news", getNews)
-	e.POST(prefix+"/utils/check_news", checkNews)
+	e.POST(prefix+"/utils/check_news", checkNews, need(dice.PermBase))
 	e.GET(prefix+"/utils/get_token", getToken)
 	e.POST(prefix+"/utils/check_cron_expr", checkCronExpr)
 	e.GET(prefix+"/utils/check_network_health", checkNetworkHealth)
 
-	e.POST(prefix+"/censor/restart", censorRestart)
-	e.POST(prefix+"/censor/stop", censorStop)
-	e.GET(prefix+"/censor/status", censorGetStatus)
-	e.GET(prefix+"/censor/config", censorGetConfig)
-	e.POST(prefix+"/censor/config", censorSetConfig)
-	e.GET(prefix+"/censor/words", censorGetWords)
-	e.GET(prefix+"/censor/files", censorGetWordFiles)
-	e.POST(prefix+"/censor/files/upload", censorUploadWordFiles)
-	e.DELETE(prefix+"/censor/files", censorDeleteWordFiles)
-	e.GET(prefix+"/censor/files/template/toml", censorGetTomlFileTemplate)
-	e.GET(prefix+"/censor/files/template/txt", censorGetTxtFileTemplate)
-	e.GET(prefix+"/censor/logs/page", censorGetLogPage)
-
-	e.GET(prefix+"/resource/page", resourceGetList)
-	e.GET(prefix+"/resource/download", resourceDownload)
-	e.POST(prefix+"/resource", resourceUpload)
-	e.DELETE(prefix+"/resource", resourceDelete)
-	e.GET(prefix+"/resource/data", resourceGetData)
+	e.POST(prefix+"/censor/restart", censorRestart, need(dice.PermCensor))
+	e.POST(prefix+"/censor/stop", censorStop, need(dice.PermCensor))
+	e.GET(prefix+"/censor/status", censorGetStatus, need(dice.PermCensor))
+	e.GET(prefix+"/censor/config", censorGetConfig, need(dice.PermCensor))
+	e.POST(prefix+"/censor/config", censorSetConfig, need(dice.PermCensor))
+	e.GET(prefix+"/censor/words", censorGetWords, need(dice.PermCensor))
+	e.GET(prefix+"/censor/files", censorGetWordFiles, need(dice.PermCensor))
+	e.POST(prefix+"/censor/files/upload", censorUploadWordFiles, need(dice.PermCensor))
+	e.DELETE(prefix+"/censor/files", censorDeleteWordFiles, need(dice.PermCensor))
+	e.GET(prefix+"/censor/files/template/toml", censorGetTomlFileTemplate, need(dice.PermCensor))
+	e.GET(prefix+"/censor/files/template/txt", censorGetTxtFileTemplate, need(dice.PermCensor))
+	e.GET(prefix+"/censor/logs/page", censorGetLogPage, need(dice.PermCensor))
+
+	e.GET(prefix+"/resource/page", resourceGetList, need(dice.PermConfig))
+	e.GET(prefix+"/resource/download", resourceDownload, need(dice.PermConfig))
+	e.POST(prefix+"/resource", resourceUpload, need(dice.PermConfig))
+	e.DELETE(prefix+"/resource", resourceDelete, need(dice.PermConfig))
+	e.GET(prefix+"/resource/data", resourceGetData, need(dice.PermConfig))
 
 	e.GET(prefix+"/verify/generate_code", verifyGenerateCode)
 }
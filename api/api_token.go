@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+// tokenOwnerFilter 主人可以管理所有人的令牌，其他人只能管理自己的
+func tokenOwnerFilter(a *authInfo) *string {
+	if a.has(dice.PermUser) {
+		return nil
+	}
+	return &a.Username
+}
+
+func apiTokenList(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	items, err := model.APITokenList(myDice.DBData, tokenOwnerFilter(a))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":        items,
+		"scopes":      dice.WebUIRolePermissions[a.Role],
+		"permissions": dice.WebUIPermissionList,
+	})
+}
+
+func apiTokenCreate(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		Name      string                 `json:"name"`
+		Scopes    []dice.WebUIPermission `json:"scopes"`
+		ExpiresIn int64                  `json:"expiresIn"` // 有效期(秒)，0为永不过期
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.ExpiresIn < 0 {
+		return Error(&c, "有效期无效", Response{})
+	}
+	token, item, err := myDice.APITokenNew(a.Username, v.Name, v.Scopes, time.Duration(v.ExpiresIn)*time.Second)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	audit(c, "token.create", item.ID, nil, item)
+	return Success(&c, Response{"token": token, "item": item})
+}
+
+func apiTokenRevoke(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		ID string `json:"id"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	done, err := model.APITokenRevoke(myDice.DBData, v.ID, tokenOwnerFilter(a))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if !done {
+		return Error(&c, "令牌不存在", Response{})
+	}
+	audit(c, "token.revoke", v.ID, nil, nil)
+	return Success(&c, Response{})
+}
+
+func apiTokenLogPage(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	v := model.QueryAPITokenLog{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+	v.Owner = tokenOwnerFilter(a)
+	total, page, err := model.APITokenLogGetPage(myDice.DBData, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+// audit 记录当前请求执行的管理操作
+func audit(c echo.Context, action string, target string, before, after interface{}) {
+	item := &model.AuditLog{Source: dice.AuditSourceUI, IP: c.RealIP(), Action: action, Target: target}
+	if a, ok := getAuth(c); ok {
+		name := a.Username
+		if name == "" {
+			name = "主人"
+		}
+		if a.Token != nil {
+			item.Source = dice.AuditSourceToken
+			item.Actor = a.Token.ID
+			item.ActorName = name + "/" + a.Token.Name
+		} else {
+			item.Actor = a.Username
+			if item.Actor == "" {
+				item.Actor = "owner"
+			}
+			item.ActorName = name
+		}
+	}
+	myDice.AuditAdd(item, before, after)
+}
+
+func auditPage(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	v := model.QueryAuditLog{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+	total, page, err := model.AuditLogGetPage(myDice.DBData, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
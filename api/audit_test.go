@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/robfig/cron/v3"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+func TestDiceExecAudit(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	_ = os.MkdirAll("data/decks", 0o755)
+
+	dm = &dice.DiceManager{Cron: cron.New(), AccessTokens: map[string]bool{"owner-token": true}}
+	myDice = &dice.Dice{BaseConfig: dice.DiceConfig{Name: "default"}, Parent: dm}
+	dm.Dice = []*dice.Dice{myDice}
+	myDice.Init()
+	myDice.UIEndpoint = &dice.EndPointInfo{EndPointInfoBase: dice.EndPointInfoBase{ID: "1", Platform: "UI", UserID: "UI:1000"}}
+	myDice.UIEndpoint.Adapter = &dice.PlatformAdapterHTTP{Session: myDice.ImSession, EndPoint: myDice.UIEndpoint}
+	myDice.UIEndpoint.Session = myDice.ImSession
+
+	e := echo.New()
+	e.POST("/dice/exec", DiceExec, need(dice.PermScript))
+	req := httptest.NewRequest(http.MethodPost, "/dice/exec", strings.NewReader(url.Values{"message": {".r d20"}}.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set("token", "owner-token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	total, items, err := model.AuditLogGetPage(myDice.DBData, model.QueryAuditLog{PageNum: 1, PageSize: 10, Action: "dice.exec"})
+	if err != nil || total != 1 {
+		t.Fatalf("dice.exec should be audited: %v %d", err, total)
+	}
+	if items[0].Actor != "owner" || items[0].Target != "private" || items[0].After != ".r d20" {
+		t.Fatalf("unexpected item %+v", items[0])
+	}
+}
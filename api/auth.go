@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+// authInfo 请求的登录信息
+type authInfo struct {
+	Username string
+	Role     dice.WebUIRole
+	Token    *model.APITokenModel   // 使用个人访问令牌时不为nil
+	Scopes   []dice.WebUIPermission // 令牌可用的权限
+}
+
+func (a *authInfo) has(p dice.WebUIPermission) bool {
+	if a.Token == nil {
+		return dice.WebUIRoleHas(a.Role, p)
+	}
+	for _, i := range a.Scopes {
+		if i == p {
+			return true
+		}
+	}
+	return false
+}
+
+func requestToken(c echo.Context) string {
+	token := c.Request().Header.Get("token")
+	if token == "" {
+		token = c.QueryParam("token")
+	}
+	return token
+}
+
+// getAuth 解析请求的登录信息，每个请求只解析一次。使用个人访问令牌的请求会在响应后记入访问日志
+func getAuth(c echo.Context) (*authInfo, bool) {
+	if v := c.Get("auth"); v != nil {
+		a := v.(*authInfo)
+		return a, a.Role != ""
+	}
+	a := &authInfo{}
+	c.Set("auth", a)
+
+	token := requestToken(c)
+	if !strings.HasPrefix(token, dice.APITokenPrefix) {
+		a.Username, a.Role, _ = myDice.Parent.AccessTokenRole(token)
+		return a, a.Role != ""
+	}
+
+	item, scopes, err := myDice.APITokenCheck(token, c.RealIP())
+	if item != nil {
+		c.Response().After(func() {
+			_ = model.APITokenLogAdd(myDice.DBData, &model.APITokenLog{
+				TokenID: item.ID,
+				Method:  c.Request().Method,
+				Path:    c.Request().URL.Path,
+				IP:      c.RealIP(),
+				Status:  c.Response().Status,
+			})
+		})
+	}
+	if err != nil {
+		return a, false
+	}
+	a.Username, a.Token, a.Scopes = item.Owner, item, scopes
+	a.Role, _ = myDice.Parent.WebUIUserRole(item.Owner)
+	return a, a.Role != ""
+}
+
+// sessionAuth 只接受登录得到的token，用于令牌管理、两步验证等不允许令牌自身操作的接口
+func sessionAuth(c echo.Context) (*authInfo, bool) {
+	a, ok := getAuth(c)
+	return a, ok && a.Token == nil
+}
+
+// hasPerm 当前登录用户是否拥有权限
+func hasPerm(c echo.Context, p dice.WebUIPermission) bool {
+	a, ok := getAuth(c)
+	return ok && a.has(p)
+}
+
+// need 路由的权限要求，未登录与权限不足的请求都不会进入处理函数
+func need(p dice.WebUIPermission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			a, ok := getAuth(c)
+			if !ok {
+				return c.JSON(http.StatusForbidden, nil)
+			}
+			if !a.has(p) {
+				return c.JSON(http.StatusForbidden, Response{"result": false, "err": "权限不足"})
+			}
+			return next(c)
+		}
+	}
+}
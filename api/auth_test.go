@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+)
+
+func TestNeedRejectsAnonymous(t *testing.T) {
+	dm = &dice.DiceManager{AccessTokens: map[string]bool{"owner-token": true}}
+	myDice = &dice.Dice{Parent: dm}
+	if err := dm.WebUIUserSet("gm1", dice.WebUIRoleGM, "hash", false); err != nil {
+		t.Fatal(err)
+	}
+	dm.AccessTokenAdd("gm-token", "gm1")
+
+	called := 0
+	e := echo.New()
+	e.POST("/js/execute", func(c echo.Context) error {
+		called++
+		return c.NoContent(http.StatusOK)
+	}, need(dice.PermScript))
+
+	for _, tc := range []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusForbidden},
+		{"bad-token", http.StatusForbidden},
+		{"gm-token", http.StatusForbidden},
+		{"owner-token", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/js/execute", nil)
+		if tc.token != "" {
+			req.Header.Set("token", tc.token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("token %q: got %d, want %d", tc.token, rec.Code, tc.status)
+		}
+	}
+	if called != 1 {
+		t.Fatalf("handler called %d times", called)
+	}
+}
@@ -42,6 +42,7 @@ func banConfigSet(c echo.Context) error {
 		v.ThresholdBan = 200
 	}
 
+	before := *myDice.BanList
 	myDice.BanList.BanBehaviorRefuseReply = v.BanBehaviorRefuseReply
 	myDice.BanList.BanBehaviorRefuseInvite = v.BanBehaviorRefuseInvite
 	myDice.BanList.BanBehaviorQuitLastPlace = v.BanBehaviorQuitLastPlace
@@ -56,6 +57,7 @@ func banConfigSet(c echo.Context) error {
 
 	myDice.BanList.JointScorePercentOfGroup = v.JointScorePercentOfGroup
 	myDice.BanList.JointScorePercentOfInviter = v.JointScorePercentOfInviter
+	audit(c, "ban.config", "", before, *myDice.BanList)
 	myDice.MarkModified()
 
 	return c.JSON(http.StatusOK, myDice.BanList)
@@ -70,16 +72,24 @@ func banMapList(c echo.Context) error {
 }
 
 func banMapDeleteOne(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
 	v := dice.BanListInfoItem{}
 	err := c.Bind(&v)
 	if err != nil {
 		return c.String(430, err.Error())
 	}
+	before, _ := myDice.BanList.GetByID(v.ID)
 	myDice.BanList.DeleteByID(myDice, v.ID)
+	audit(c, "ban.delete", v.ID, before, nil)
 	return c.JSON(http.StatusOK, nil)
 }
 
 func banMapAddOne(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
 	if dm.JustForTest {
 		return c.JSON(200, map[string]interface{}{
 			"testMode": true,
@@ -114,6 +124,8 @@ func banMapAddOne(c echo.Context) error {
 	if v.Rank == dice.BanRankTrusted {
 		myDice.BanList.SetTrustByID(v.ID, "海豹后台", "骰主后台设置")
 	}
+	after, _ := myDice.BanList.GetByID(v.ID)
+	audit(c, "ban.add", v.ID, nil, after)
 
 	return c.JSON(http.StatusOK, nil)
 }
@@ -189,6 +201,7 @@ func banImport(c echo.Context) error {
 		return Error(&c, err.Error(), Response{})
 	}
 
+	ids := make([]string, 0, len(lst))
 	now := time.Now()
 	for _, item := range lst {
 		item.UpdatedAt = now.Unix()
@@ -200,8 +213,10 @@ func banImport(c echo.Context) error {
 		}
 		item.Reasons = newReasons
 		myDice.BanList.Map.Store(item.ID, item)
+		ids = append(ids, item.ID)
 	}
 	myDice.BanList.SaveChanged(myDice)
+	audit(c, "ban.import", file.Filename, nil, ids)
 
 	return Success(&c, Response{})
 }
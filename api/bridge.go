@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func bridgeList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	return Success(&c, Response{"data": myDice.BridgeList()})
+}
+
+func bridgeSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		ID        string `json:"id"`
+		Enable    bool   `json:"enable"`
+		RelayChat bool   `json:"relayChat"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if !myDice.BridgeSet(v.ID, v.Enable, v.RelayChat) {
+		return Error(&c, "找不到该桥接", Response{})
+	}
+	myDice.Save(false)
+	return Success(&c, Response{})
+}
+
+func bridgeDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		ID string `json:"id"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if !myDice.BridgeDelete(v.ID) {
+		return Error(&c, "找不到该桥接", Response{})
+	}
+	myDice.Save(false)
+	return Success(&c, Response{})
+}
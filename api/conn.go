@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
 	"sort"
 	"strconv"
@@ -21,6 +22,55 @@ func ImConnections(c echo.Context) error {
 	return c.JSON(http.StatusOK, myDice.ImSession.EndPoints)
 }
 
+// ImConnectionsCapabilities 各帐号的平台功能支持情况
+func ImConnectionsCapabilities(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	type item struct {
+		ID           string                          `json:"id"`
+		Platform     string                          `json:"platform"`
+		Nickname     string                          `json:"nickname"`
+		UserID       string                          `json:"userId"`
+		Capabilities map[dice.AdapterCapability]bool `json:"capabilities"`
+	}
+	items := []item{}
+	for _, ep := range myDice.ImSession.EndPoints {
+		items = append(items, item{
+			ID:           ep.ID,
+			Platform:     ep.Platform,
+			Nickname:     ep.Nickname,
+			UserID:       ep.UserID,
+			Capabilities: ep.Capabilities(),
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"capabilities": dice.AdapterCapabilityList,
+		"items":        items,
+	})
+}
+
+// ImConnectionsHistory 帐号的连接历史，旧的在前
+func ImConnectionsHistory(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	id := c.QueryParam("id")
+	for _, ep := range myDice.ImSession.EndPoints {
+		if ep.ID == id {
+			return c.JSON(http.StatusOK, map[string]interface{}{
+				"id":      ep.ID,
+				"state":   ep.State,
+				"attempt": dice.GetConnSupervisor(ep).Attempt(),
+				"items":   ep.ConnHistory(),
+			})
+		}
+	}
+	return c.JSON(http.StatusNotFound, nil)
+}
+
 func ImConnectionsGet(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
@@ -198,6 +248,8 @@ func ImConnectionsDel(c echo.Context) error {
 			if i.ID == v.ID {
 				// 禁用该endpoint防止出问题
 				i.SetEnable(myDice, false)
+				// 适配器都停下后再移除连接守护，以免停用时又重新创建
+				defer dice.RemoveConnSupervisor(i)
 				// 待删除的EPInfo落库，保留其统计数据
 				i.StatsDump(myDice)
 				// TODO: 注意 这个好像很不科学
@@ -249,6 +301,22 @@ func ImConnectionsDel(c echo.Context) error {
 					i.Adapter.SetEnable(false)
 					myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints[:index], myDice.ImSession.EndPoints[index+1:]...)
 					return c.JSON(http.StatusOK, i)
+				case "MATRIX":
+					i.Adapter.SetEnable(false)
+					myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints[:index], myDice.ImSession.EndPoints[index+1:]...)
+					return c.JSON(http.StatusOK, i)
+				case "IRC":
+					i.Adapter.SetEnable(false)
+					myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints[:index], myDice.ImSession.EndPoints[index+1:]...)
+					return c.JSON(http.StatusOK, i)
+				case "FEISHU":
+					i.Adapter.SetEnable(false)
+					myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints[:index], myDice.ImSession.EndPoints[index+1:]...)
+					return c.JSON(http.StatusOK, i)
+				case "HTTP":
+					i.Adapter.SetEnable(false)
+					myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints[:index], myDice.ImSession.EndPoints[index+1:]...)
+					return c.JSON(http.StatusOK, i)
 				}
 			}
 		}
@@ -719,6 +787,136 @@ func ImConnectionsAddSlack(c echo.Context) error {
 	return c.String(430, "")
 }
 
+func ImConnectionsAddMatrix(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"testMode": true,
+		})
+	}
+
+	v := struct {
+		HomeServer  string `json:"homeServer"`
+		UserID      string `json:"userId"`
+		Password    string `json:"password"`
+		AccessToken string `json:"accessToken"`
+	}{}
+	err := c.Bind(&v)
+	if err == nil {
+		if v.HomeServer == "" || (v.Password == "" && v.AccessToken == "") {
+			return c.String(430, "")
+		}
+		conn := dice.NewMatrixConnItem(v.HomeServer, v.UserID, v.Password, v.AccessToken)
+		pa := conn.Adapter.(*dice.PlatformAdapterMatrix)
+		pa.Session = myDice.ImSession
+		myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints, conn)
+		myDice.LastUpdatedTime = time.Now().Unix()
+		myDice.Save(false)
+		go dice.ServeMatrix(myDice, conn)
+		return c.JSON(http.StatusOK, conn)
+	}
+	return c.String(430, "")
+}
+
+func ImConnectionsAddIRC(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"testMode": true,
+		})
+	}
+
+	v := dice.AddIRCEcho{}
+	err := c.Bind(&v)
+	if err == nil {
+		if v.Server == "" || v.Nick == "" {
+			return c.String(430, "")
+		}
+		if _, _, errSplit := net.SplitHostPort(v.Server); errSplit != nil {
+			if v.UseTLS {
+				v.Server = net.JoinHostPort(v.Server, "6697")
+			} else {
+				v.Server = net.JoinHostPort(v.Server, "6667")
+			}
+		}
+		conn := dice.NewIRCConnItem(v)
+		pa := conn.Adapter.(*dice.PlatformAdapterIRC)
+		pa.Session = myDice.ImSession
+		myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints, conn)
+		myDice.LastUpdatedTime = time.Now().Unix()
+		myDice.Save(false)
+		go dice.ServeIRC(myDice, conn)
+		return c.JSON(http.StatusOK, conn)
+	}
+	return c.String(430, "")
+}
+
+func ImConnectionsAddFeishu(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"testMode": true,
+		})
+	}
+
+	v := dice.AddFeishuEcho{}
+	err := c.Bind(&v)
+	if err == nil {
+		if v.AppID == "" || v.AppSecret == "" || (v.Mode == "webhook" && v.WebhookAddr == "") {
+			return c.String(430, "")
+		}
+		conn := dice.NewFeishuConnItem(v)
+		pa := conn.Adapter.(*dice.PlatformAdapterFeishu)
+		if v.Mode == "webhook" && !pa.WebhookSecured() {
+			return c.String(430, "")
+		}
+		pa.Session = myDice.ImSession
+		myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints, conn)
+		myDice.LastUpdatedTime = time.Now().Unix()
+		myDice.Save(false)
+		go dice.ServeFeishu(myDice, conn)
+		return c.JSON(http.StatusOK, conn)
+	}
+	return c.String(430, "")
+}
+
+func ImConnectionsAddHTTP(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"testMode": true,
+		})
+	}
+
+	v := dice.AddHTTPEcho{}
+	err := c.Bind(&v)
+	if err == nil {
+		if v.ListenAddr == "" || (v.ReplyMode == "callback" && v.CallbackURL == "") {
+			return c.String(430, "")
+		}
+		if v.AccessToken == "" && !dice.HTTPListenIsLoopback(v.ListenAddr) {
+			return c.String(430, "")
+		}
+		conn := dice.NewHTTPConnItem(v)
+		pa := conn.Adapter.(*dice.PlatformAdapterHTTP)
+		pa.Session = myDice.ImSession
+		myDice.ImSession.EndPoints = append(myDice.ImSession.EndPoints, conn)
+		myDice.LastUpdatedTime = time.Now().Unix()
+		myDice.Save(false)
+		go dice.ServeHTTPAdapter(myDice, conn)
+		return c.JSON(http.StatusOK, conn)
+	}
+	return c.String(430, "")
+}
+
 func ImConnectionsAddBuiltinGocq(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
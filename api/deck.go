@@ -82,6 +82,7 @@ func deckUpload(c echo.Context) error {
 	if _, err = io.Copy(dst, src); err != nil {
 		return err
 	}
+	audit(c, "deck.upload", file.Filename, nil, nil)
 
 	return c.JSON(http.StatusOK, nil)
 }
@@ -124,8 +125,10 @@ func deckDelete(c echo.Context) error {
 
 	if err == nil {
 		if v.Index >= 0 && v.Index < len(myDice.DeckList) {
-			dice.DeckDelete(myDice, myDice.DeckList[v.Index])
+			deck := myDice.DeckList[v.Index]
+			dice.DeckDelete(myDice, deck)
 			myDice.MarkModified()
+			audit(c, "deck.delete", deck.Filename, nil, nil)
 		}
 	}
 
@@ -68,6 +68,7 @@ func DiceNewVersionUpload(c echo.Context) error {
 	f2.Close()
 
 	myDice.Logger.Info("新版本骰子上传成功")
+	audit(c, "system.upgrade", file.Filename, nil, nil)
 
 	_ = Success(&c, Response{"result": true})
 
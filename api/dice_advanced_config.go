@@ -18,6 +18,7 @@ func DiceAdvancedConfigSet(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, nil)
 	}
 
+	before := myDice.AdvancedConfig
 	advancedConfig := myDice.AdvancedConfig
 	err := c.Bind(&advancedConfig)
 	if err != nil {
@@ -25,6 +26,7 @@ func DiceAdvancedConfigSet(c echo.Context) error {
 	}
 
 	myDice.AdvancedConfig = advancedConfig
+	audit(c, "advancedConfig.set", "", before, advancedConfig)
 
 	// 统一标记为修改
 	myDice.MarkModified()
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -71,6 +72,14 @@ type DiceConfigInfo struct {
 	PersonalReplenishBurst int64  `json:"personalBurst"`         // 个人自定义上限
 	GroupReplenishRate     string `json:"groupReplenishRate"`    // 群组自定义速率
 	GroupReplenishBurst    int64  `json:"groupBurst"`            // 群组自定义上限
+
+	DiceStatEnable bool `json:"diceStatEnable"` // 记录骰点分布
+
+	ReconnectMaxRetries int   `json:"reconnectMaxRetries"` // 断线重连次数上限，0为不限
+	ReconnectMaxDelay   int64 `json:"reconnectMaxDelay"`   // 重连最长等待(秒)
+
+	TextImageThreshold int `json:"textImageThreshold"` // 长回复转图片的字数，0为关闭
+	AuditRetentionDays int `json:"auditRetentionDays"` // 操作记录保留天数，-1为永久保留
 }
 
 func DiceConfig(c echo.Context) error {
@@ -78,6 +87,11 @@ func DiceConfig(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, nil)
 	}
 
+	myDice.UnlockCodeUpdate(false)
+	return c.JSON(http.StatusOK, diceConfigInfo())
+}
+
+func diceConfigInfo() DiceConfigInfo {
 	password := ""
 	if myDice.Parent.UIPasswordHash != "" {
 		password = "------"
@@ -87,7 +101,6 @@ func DiceConfig(c echo.Context) error {
 	if limit == 0 {
 		limit = 100
 	}
-	myDice.UnlockCodeUpdate(false)
 
 	cocRule := strconv.FormatInt(myDice.DefaultCocRuleIndex, 10)
 	if myDice.DefaultCocRuleIndex == 11 {
@@ -112,7 +125,7 @@ func DiceConfig(c echo.Context) error {
 		}
 	}
 
-	info := DiceConfigInfo{
+	return DiceConfigInfo{
 		CommandPrefix:           myDice.CommandPrefix,
 		DiceMasters:             myDice.DiceMasters,
 		NoticeIds:               myDice.NoticeIDs,
@@ -169,8 +182,38 @@ func DiceConfig(c echo.Context) error {
 		GroupReplenishRate:      myDice.GroupReplenishRateStr,
 		GroupReplenishBurst:     myDice.GroupBurst,
 		IgnoreUnaddressedBotCmd: myDice.IgnoreUnaddressedBotCmd,
+		DiceStatEnable:          myDice.DiceStatEnable,
+		ReconnectMaxRetries:     myDice.ReconnectMaxRetries,
+		ReconnectMaxDelay:       myDice.ReconnectMaxDelay,
+		TextImageThreshold:      myDice.TextImageThreshold,
+		AuditRetentionDays:      myDice.AuditRetentionDays,
+	}
+}
+
+// diceConfigSnapshot 用于比对修改前后的设置，密码项只记录是否改动
+func diceConfigSnapshot() map[string]interface{} {
+	m := map[string]interface{}{}
+	data, _ := json.Marshal(diceConfigInfo())
+	_ = json.Unmarshal(data, &m)
+	m["uiPassword"] = myDice.Parent.UIPasswordHash
+	m["mailPassword"] = myDice.MailPassword
+	return m
+}
+
+// diceConfigDiff 取出本次提交的项中实际变化的部分
+func diceConfigDiff(keys map[string]interface{}, before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	b, a := map[string]interface{}{}, map[string]interface{}{}
+	for k := range keys {
+		if reflect.DeepEqual(before[k], after[k]) {
+			continue
+		}
+		if k == "uiPassword" || k == "mailPassword" {
+			b[k], a[k] = "******", "******"
+			continue
+		}
+		b[k], a[k] = before[k], after[k]
 	}
-	return c.JSON(http.StatusOK, info)
+	return b, a
 }
 
 func DiceConfigSet(c echo.Context) error {
@@ -196,6 +239,7 @@ func DiceConfigSet(c echo.Context) error {
 		fmt.Println(err)
 		return c.JSON(http.StatusOK, nil)
 	}
+	snapshot := diceConfigSnapshot()
 	if val, ok := jsonMap["commandPrefix"]; ok {
 		myDice.CommandPrefix = stringConvert(val)
 	}
@@ -398,7 +442,8 @@ func DiceConfigSet(c echo.Context) error {
 	}
 
 	if val, ok := jsonMap["uiPassword"]; ok {
-		if !dm.JustForTest {
+		// 主人密码只有主人能改
+		if !dm.JustForTest && hasPerm(c, dice.PermUser) {
 			myDice.Parent.UIPasswordHash = val.(string)
 		}
 	}
@@ -437,6 +482,34 @@ func DiceConfigSet(c echo.Context) error {
 		myDice.LogSizeNoticeEnable = val.(bool)
 	}
 
+	if val, ok := jsonMap["diceStatEnable"]; ok {
+		myDice.DiceStatEnable = val.(bool)
+	}
+
+	if val, ok := jsonMap["reconnectMaxRetries"]; ok {
+		if v, ok := val.(float64); ok && v >= 0 {
+			myDice.ReconnectMaxRetries = int(v)
+		}
+	}
+
+	if val, ok := jsonMap["reconnectMaxDelay"]; ok {
+		if v, ok := val.(float64); ok && v >= 0 {
+			myDice.ReconnectMaxDelay = int64(v)
+		}
+	}
+
+	if val, ok := jsonMap["textImageThreshold"]; ok {
+		if v, ok := val.(float64); ok && v >= 0 {
+			myDice.TextImageThreshold = int(v)
+		}
+	}
+
+	if val, ok := jsonMap["auditRetentionDays"]; ok {
+		if v, ok := val.(float64); ok && (v == -1 || v >= 1) {
+			myDice.AuditRetentionDays = int(v)
+		}
+	}
+
 	if val, ok := jsonMap["logSizeNoticeCount"]; ok {
 		count, ok := val.(float64)
 		if ok {
@@ -525,6 +598,10 @@ func DiceConfigSet(c echo.Context) error {
 		}
 	}
 
+	if before, after := diceConfigDiff(jsonMap, snapshot, diceConfigSnapshot()); len(after) > 0 {
+		audit(c, "config.set", "", before, after)
+	}
+
 	// 统一标记为修改
 	myDice.MarkModified()
 	myDice.Parent.Save()
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+func diceStatGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	q := model.QueryDiceStat{}
+	if err := c.Bind(&q); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+
+	if err := myDice.DiceStat.Flush(myDice.DBData); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	results, err := dice.DiceStatAnalyze(myDice.DBData, &q)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"enable": myDice.DiceStatEnable,
+		"data":   results,
+	})
+}
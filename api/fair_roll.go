@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+func fairRollSessionList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	groupID := c.QueryParam("groupId")
+	if groupID == "" {
+		return Error(&c, "缺少groupId", Response{})
+	}
+	sessions, err := model.FairRollSessionList(myDice.DBData, groupID, 50)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	for _, s := range sessions {
+		// 未公开的种子不能外泄，否则承诺失去意义
+		if s.RevealedAt == 0 {
+			s.Seed = ""
+		}
+	}
+	return Success(&c, Response{
+		"data": sessions,
+	})
+}
+
+func fairRollVerify(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	id, err := strconv.ParseInt(c.QueryParam("id"), 10, 64)
+	if err != nil {
+		return Error(&c, "会话编号无效", Response{})
+	}
+	ret, err := dice.FairRollVerify(myDice.DBData, id)
+	if err != nil {
+		if errors.Is(err, model.ErrFairRollSessionNotFound) {
+			return Error(&c, "找不到该会话", Response{})
+		}
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data": ret,
+	})
+}
@@ -61,6 +61,7 @@ func groupSetOne(c echo.Context) error {
 	if err == nil {
 		_, exists := myDice.ImSession.ServiceAtNew.Load(v.GroupID)
 		if exists {
+			audit(c, "group.set", v.GroupID, nil, map[string]interface{}{"active": v.Active})
 			for _, ep := range myDice.ImSession.EndPoints {
 				// if ep.UserId == v.DiceId {
 				ctx := &dice.MsgContext{Dice: myDice, EndPoint: ep, Session: myDice.ImSession}
@@ -128,6 +129,7 @@ func groupQuit(c echo.Context) error {
 		group.UpdatedAtTime = time.Now().Unix()
 
 		ep.Adapter.QuitGroup(ctx, v.GroupID)
+		audit(c, "group.quit", v.GroupID, nil, map[string]interface{}{"diceId": v.DiceID, "silence": v.Silence, "extraText": v.ExtraText})
 		return c.String(http.StatusOK, "")
 	}
 	return c.String(430, "")
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice/model"
+)
+
+func identityList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	items, err := model.PlatformMappingList(myDice.DBData)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	type identity struct {
+		ID    string                       `json:"id"`
+		Items []model.PlatformMappingModel `json:"items"`
+	}
+	lst := []*identity{}
+	for _, i := range items {
+		if len(lst) == 0 || lst[len(lst)-1].ID != i.Id {
+			lst = append(lst, &identity{ID: i.Id})
+		}
+		cur := lst[len(lst)-1]
+		cur.Items = append(cur.Items, i)
+	}
+	return Success(&c, Response{"data": lst})
+}
+
+func identityUnlink(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		IMUserID string `json:"IMUserID"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.AttrsManager.Unlink(v.IMUserID, ""); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
+
+func identityLogPage(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	v := model.QueryPlatformMappingLog{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+	total, page, err := model.PlatformMappingLogGetPage(myDice.DBData, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
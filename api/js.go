@@ -16,10 +16,8 @@ import (
 )
 
 func jsExec(c echo.Context) error {
-	if dm.JustForTest {
-		return c.JSON(200, map[string]interface{}{
-			"testMode": true,
-		})
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
 	}
 	if dm.JustForTest {
 		return c.JSON(200, map[string]interface{}{
@@ -43,6 +41,7 @@ func jsExec(c echo.Context) error {
 	}
 
 	source := "(function(exports, require, module) {" + v.Value + "\n})()"
+	audit(c, "js.execute", "", nil, v.Value)
 
 	waitRun := make(chan int, 1)
 
@@ -124,7 +123,9 @@ func jsDelete(c echo.Context) error {
 
 	if err == nil {
 		if v.Index >= 0 && v.Index < len(myDice.JsScriptList) {
-			dice.JsDelete(myDice, myDice.JsScriptList[v.Index])
+			js := myDice.JsScriptList[v.Index]
+			dice.JsDelete(myDice, js)
+			audit(c, "js.delete", js.Name, nil, nil)
 		}
 	}
 
@@ -192,6 +193,7 @@ func jsUpload(c echo.Context) error {
 	if _, err = io.Copy(dst, src); err != nil {
 		return err
 	}
+	audit(c, "js.upload", file.Filename, nil, nil)
 
 	return c.JSON(http.StatusOK, nil)
 }
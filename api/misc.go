@@ -29,6 +29,7 @@ func upgrade(c echo.Context) error {
 		})
 	}
 
+	audit(c, "system.upgrade", "", dm.AppVersionCode, nil)
 	dm.UpdateCheckRequestChan <- 1
 	time.Sleep(3 * time.Second) // 等待1s，应该能够取得新版本了。如果获取失败也不至于卡住
 
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/utils"
+)
+
+func outboxMetrics(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	return Success(&c, Response{"data": dice.OutboxMetricsAll(myDice)})
+}
+
+func outboxConfigGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	platforms := myDice.OutboxPlatformRates
+	if platforms == nil {
+		platforms = map[string]*dice.OutboxRateConfig{}
+	}
+	return Success(&c, Response{
+		"data":      myDice.OutboxRate.Normalize(),
+		"platforms": platforms,
+		"default":   dice.DefaultOutboxRate,
+	})
+}
+
+func outboxConfigSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		Data      dice.OutboxRateConfig             `json:"data"`
+		Platforms map[string]*dice.OutboxRateConfig `json:"platforms"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	check := func(cfg *dice.OutboxRateConfig) bool {
+		for _, s := range []string{cfg.GlobalRate, cfg.TargetRate} {
+			if s == "" {
+				continue
+			}
+			if r, err := utils.ParseRate(s); err != nil || r <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	if !check(&v.Data) {
+		return Error(&c, "速率格式不正确", Response{})
+	}
+	platforms := map[string]*dice.OutboxRateConfig{}
+	for k, cfg := range v.Platforms {
+		if k == "" || cfg == nil {
+			continue
+		}
+		if !check(cfg) {
+			return Error(&c, "平台 "+k+" 的速率格式不正确", Response{})
+		}
+		platforms[k] = cfg
+	}
+
+	myDice.OutboxRate = v.Data
+	myDice.OutboxPlatformRates = platforms
+	myDice.MarkModified()
+	myDice.Save(false)
+	return Success(&c, Response{})
+}
@@ -39,14 +39,8 @@ func Int64ToBytes(i int64) []byte {
 }
 
 func doAuth(c echo.Context) bool {
-	token := c.Request().Header.Get("token")
-	if token == "" {
-		token = c.QueryParam("token")
-	}
-	if myDice.Parent.AccessTokens[token] {
-		return true
-	}
-	return false
+	_, ok := getAuth(c)
+	return ok
 }
 
 func GetHexData(c echo.Context, method string, name string) (value []byte, finished bool) {
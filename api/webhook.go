@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+func webhookList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	lst := myDice.Webhook.List()
+	if lst == nil {
+		lst = []*dice.WebhookItem{}
+	}
+	return Success(&c, Response{
+		"data":   lst,
+		"events": dice.WebhookEventTypes,
+	})
+}
+
+func webhookUpsert(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v dice.WebhookItem
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if u, err := url.Parse(v.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Error(&c, "URL 不合法", Response{})
+	}
+
+	if err := myDice.Webhook.Upsert(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.MarkModified()
+	myDice.Save(false)
+	return Success(&c, Response{"data": v})
+}
+
+func webhookDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		ID string `json:"id"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Webhook.Delete(v.ID)
+	myDice.MarkModified()
+	myDice.Save(false)
+	return Success(&c, Response{})
+}
+
+func webhookTest(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		ID string `json:"id"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Webhook.Test(v.ID); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
+
+func webhookDeadLetterPage(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	v := model.QueryWebhookDeadLetter{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+	total, page, err := model.WebhookDeadLetterGetPage(myDice.DBData, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
+
+func webhookDeadLetterRetry(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Webhook.RetryDeadLetter(v.ID); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
+
+func webhookDeadLetterDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		ID     int64  `json:"id"`
+		HookID string `json:"hookId"`
+		All    bool   `json:"all"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	var err error
+	if v.All {
+		err = model.WebhookDeadLetterClear(myDice.DBData, v.HookID)
+	} else {
+		err = model.WebhookDeadLetterDelete(myDice.DBData, v.ID)
+	}
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/skip2/go-qrcode"
+)
+
+func totpStatus(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	enabled, left := myDice.Parent.TOTPEnabled(a.Username)
+	return Success(&c, Response{"enabled": enabled, "recoveryCodesLeft": left})
+}
+
+// totpSetup 生成密钥与二维码，扫码后用 totpEnable 确认
+func totpSetup(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	secret, uri, err := myDice.Parent.TOTPSetupBegin(a.Username)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"secret": secret,
+		"uri":    uri,
+		"qrcode": "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+func totpEnable(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		Code string `json:"code"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	codes, err := myDice.Parent.TOTPSetupConfirm(a.Username, v.Code)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	audit(c, "2fa.enable", a.Username, nil, nil)
+	return Success(&c, Response{"recoveryCodes": codes})
+}
+
+// totpDisable 关闭自己的两步验证，需要验证码或恢复码
+func totpDisable(c echo.Context) error {
+	a, ok := sessionAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		Code string `json:"code"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Parent.TOTPVerify(a.Username, v.Code); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Parent.TOTPReset(a.Username); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	audit(c, "2fa.disable", a.Username, nil, nil)
+	return Success(&c, Response{})
+}
+
+// totpResetUser 主人为丢失验证器的用户关闭两步验证
+func totpResetUser(c echo.Context) error {
+	if _, ok := sessionAuth(c); !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	var v struct {
+		Username string `json:"username"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.Username == "" {
+		return Error(&c, "主人的两步验证请使用 --reset-2fa 启动参数重置", Response{})
+	}
+	if err := myDice.Parent.TOTPReset(v.Username); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	audit(c, "2fa.reset", v.Username, nil, nil)
+	return Success(&c, Response{})
+}
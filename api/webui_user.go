@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/model"
+)
+
+// webUIUserMe 当前登录的用户与权限，供UI隐藏无权限的页面
+func webUIUserMe(c echo.Context) error {
+	a, ok := getAuth(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+	perms := dice.WebUIRolePermissions[a.Role]
+	if a.Token != nil {
+		perms = a.Scopes
+	}
+	return Success(&c, Response{
+		"username":    a.Username,
+		"role":        a.Role,
+		"permissions": perms,
+	})
+}
+
+// webUIUserGet 用户信息的副本，用于操作记录
+func webUIUserGet(name string) *dice.WebUIUser {
+	for _, u := range myDice.Parent.WebUIUserList() {
+		if u.Username == name {
+			return &u
+		}
+	}
+	return nil
+}
+
+func webUIUserList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	return Success(&c, Response{
+		"data":        myDice.Parent.WebUIUserList(),
+		"roles":       dice.WebUIRolePermissions,
+		"permissions": dice.WebUIPermissionList,
+	})
+}
+
+func webUIUserUpsert(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		Username string         `json:"username"`
+		Role     dice.WebUIRole `json:"role"`
+		Password string         `json:"password"` // 与登录相同，为前端加盐哈希后的值；为空时不修改
+		Disabled bool           `json:"disabled"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.Role == dice.WebUIRoleOwner {
+		return Error(&c, "主人只能有一个，请使用其他角色", Response{})
+	}
+	before := webUIUserGet(v.Username)
+	if err := myDice.Parent.WebUIUserSet(v.Username, v.Role, v.Password, v.Disabled); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	after := map[string]interface{}{"user": webUIUserGet(v.Username), "passwordChanged": v.Password != ""}
+	audit(c, "user.upsert", v.Username, before, after)
+	return Success(&c, Response{})
+}
+
+func webUIUserDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{})
+	}
+	var v struct {
+		Username string `json:"username"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	before := webUIUserGet(v.Username)
+	if err := myDice.Parent.WebUIUserDelete(v.Username); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	_ = model.APITokenRevokeByOwner(myDice.DBData, v.Username)
+	myDice.Parent.Save()
+	audit(c, "user.delete", v.Username, before, nil)
+	return Success(&c, Response{})
+}
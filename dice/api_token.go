@@ -0,0 +1,114 @@
+package dice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"sealdice-core/dice/model"
+	"sealdice-core/utils"
+)
+
+// 个人访问令牌：供脚本调用API使用，权限、有效期可选，数据库中只存哈希
+
+const APITokenPrefix = "sdpat_"
+
+var (
+	ErrAPITokenInvalid = errors.New("令牌无效或已吊销")
+	ErrAPITokenExpired = errors.New("令牌已过期")
+	ErrAPITokenNoScope = errors.New("至少需要一项权限")
+	ErrAPITokenScope   = errors.New("不能授予自己没有的权限")
+)
+
+func apiTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// WebUIUserRole 用户当前的角色，空用户名为主人；用户不存在或已停用时 ok 为 false
+func (dm *DiceManager) WebUIUserRole(name string) (WebUIRole, bool) {
+	if name == "" {
+		return WebUIRoleOwner, true
+	}
+	dm.webUIUserLock.RLock()
+	defer dm.webUIUserLock.RUnlock()
+	u := dm.webUIUserFind(name)
+	if u == nil || u.Disabled {
+		return "", false
+	}
+	return u.Role, true
+}
+
+// APITokenNew 为用户创建令牌，返回令牌原文(只在创建时可见)。ttl 为0表示永不过期
+func (d *Dice) APITokenNew(owner string, name string, scopes []WebUIPermission, ttl time.Duration) (string, *model.APITokenModel, error) {
+	role, ok := d.Parent.WebUIUserRole(owner)
+	if !ok {
+		return "", nil, ErrWebUIUserNotFound
+	}
+	var lst []string
+	seen := map[WebUIPermission]bool{}
+	for _, s := range scopes {
+		// 令牌不能用于管理用户
+		if !WebUIRoleHas(role, s) || s == PermUser {
+			return "", nil, ErrAPITokenScope
+		}
+		if !seen[s] {
+			seen[s] = true
+			lst = append(lst, string(s))
+		}
+	}
+	if len(lst) == 0 {
+		return "", nil, ErrAPITokenNoScope
+	}
+
+	now := time.Now()
+	token := APITokenPrefix + utils.NewID() + utils.NewID()
+	item := &model.APITokenModel{
+		ID:        utils.NewID(),
+		Name:      name,
+		TokenHash: apiTokenHash(token),
+		Owner:     owner,
+		Scopes:    strings.Join(lst, ","),
+		CreatedAt: now.Unix(),
+	}
+	if ttl > 0 {
+		item.ExpiresAt = now.Add(ttl).Unix()
+	}
+	if err := model.APITokenAdd(d.DBData, item); err != nil {
+		return "", nil, err
+	}
+	return token, item, nil
+}
+
+// APITokenCheck 校验令牌并记录使用时间，返回可用的权限：令牌权限与创建者当前权限的交集。
+// 令牌存在但不可用时，返回的令牌记录不为nil，以便记录访问日志
+func (d *Dice) APITokenCheck(token string, ip string) (*model.APITokenModel, []WebUIPermission, error) {
+	item, err := model.APITokenGetByHash(d.DBData, apiTokenHash(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if item == nil {
+		return nil, nil, ErrAPITokenInvalid
+	}
+	if item.Revoked {
+		return item, nil, ErrAPITokenInvalid
+	}
+	if item.ExpiresAt != 0 && time.Now().Unix() > item.ExpiresAt {
+		return item, nil, ErrAPITokenExpired
+	}
+	role, ok := d.Parent.WebUIUserRole(item.Owner)
+	if !ok {
+		return item, nil, ErrAPITokenInvalid
+	}
+
+	var perms []WebUIPermission
+	for _, s := range strings.Split(item.Scopes, ",") {
+		if p := WebUIPermission(s); WebUIRoleHas(role, p) && p != PermUser {
+			perms = append(perms, p)
+		}
+	}
+	_ = model.APITokenTouch(d.DBData, item.ID, ip)
+	return item, perms, nil
+}
@@ -0,0 +1,54 @@
+package dice
+
+import (
+	"testing"
+	"time"
+
+	"sealdice-core/dice/model"
+)
+
+func TestAPIToken(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm := &DiceManager{AccessTokens: map[string]bool{}}
+	d := &Dice{DBData: dataDB, Parent: dm}
+	if err = dm.WebUIUserSet("gm1", WebUIRoleGM, "hash", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = d.APITokenNew("gm1", "x", []WebUIPermission{PermScript}, 0); err != ErrAPITokenScope {
+		t.Fatalf("scope beyond role: %v", err)
+	}
+	token, item, err := d.APITokenNew("gm1", "logs", []WebUIPermission{PermLogRead, PermLogRead}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Scopes != string(PermLogRead) || item.ExpiresAt == 0 {
+		t.Fatalf("unexpected token %+v", item)
+	}
+
+	got, perms, err := d.APITokenCheck(token, "127.0.0.1")
+	if err != nil || got.ID != item.ID || len(perms) != 1 {
+		t.Fatalf("check failed: %v %v", err, perms)
+	}
+	if _, _, err = d.APITokenCheck(token+"x", ""); err != ErrAPITokenInvalid {
+		t.Fatal("wrong token should be rejected")
+	}
+
+	// 用户停用后令牌随之失效
+	_ = dm.WebUIUserSet("gm1", WebUIRoleGM, "", true)
+	if _, _, err = d.APITokenCheck(token, ""); err != ErrAPITokenInvalid {
+		t.Fatal("token of disabled user should be rejected")
+	}
+	_ = dm.WebUIUserSet("gm1", WebUIRoleGM, "", false)
+
+	owner := "gm1"
+	if ok, _ := model.APITokenRevoke(dataDB, item.ID, &owner); !ok {
+		t.Fatal("revoke failed")
+	}
+	if _, _, err = d.APITokenCheck(token, ""); err != ErrAPITokenInvalid {
+		t.Fatal("revoked token should be rejected")
+	}
+}
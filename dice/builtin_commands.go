@@ -2,6 +2,7 @@ package dice
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -18,6 +19,8 @@ import (
 	"github.com/juliangruber/go-intersect"
 	cp "github.com/otiai10/copy"
 	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/model"
 )
 
 /** 这几条指令不能移除 */
@@ -76,6 +79,8 @@ func (d *Dice) registerCoreCommands() {
 					reason = "骰主指令"
 				}
 				d.BanList.AddScoreBase(uid, d.BanList.ThresholdBan, "骰主指令", reason, ctx)
+				after, _ := d.BanList.GetByID(uid)
+				ctx.Audit("ban.add", uid, nil, after)
 				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 加入黑名单，原因: %s", uid, reason))
 			case "rm", "del":
 				uid = getID()
@@ -90,8 +95,10 @@ func (d *Dice) registerCoreCommands() {
 				}
 
 				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 移出%s列表", uid, BanRankText[item.Rank]))
+				before := *item
 				item.Score = 0
 				item.Rank = BanRankNormal
+				ctx.Audit("ban.delete", uid, before, nil)
 			case "trust":
 				uid = cmdArgs.GetArgN(2)
 				if !strings.Contains(uid, ":") {
@@ -100,6 +107,8 @@ func (d *Dice) registerCoreCommands() {
 				}
 
 				d.BanList.SetTrustByID(uid, "骰主指令", "骰主指令")
+				after, _ := d.BanList.GetByID(uid)
+				ctx.Audit("ban.trust", uid, nil, after)
 				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 加入信任列表", uid))
 			case "list", "show":
 				// ban/warn/trust
@@ -573,6 +582,11 @@ func (d *Dice) registerCoreCommands() {
 						return CmdExecuteResult{Matched: true, Solved: true}
 					}
 
+					if !ctx.EndPoint.Can(CapQuitGroup) {
+						ReplyToSender(ctx, msg, capabilityUnsupportedText(ctx, CapQuitGroup))
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:骰子退群预告"))
 
 					userName := ctx.Dice.Parent.TryGetUserName(msg.Sender.UserID)
@@ -586,6 +600,8 @@ func (d *Dice) registerCoreCommands() {
 					ctx.Group.DiceIDExistsMap.Delete(ctx.EndPoint.UserID)
 					ctx.Group.UpdatedAtTime = time.Now().Unix()
 					ctx.EndPoint.Adapter.QuitGroup(ctx, msg.GroupID)
+					ctx.Session.OnGroupLeft(ctx.EndPoint, msg.GroupID, msg.Sender.UserID, false)
+					ctx.Audit("group.quit", msg.GroupID, nil, nil)
 
 					return CmdExecuteResult{Matched: true, Solved: true}
 				} else if cmdArgs.IsArgEqual(1, "save") {
@@ -876,8 +892,10 @@ func (d *Dice) registerCoreCommands() {
 				// 特殊解锁指令
 				code := cmdArgs.GetArgN(2)
 				if ctx.Dice.UnlockCodeVerify(code) {
+					before := append([]string(nil), ctx.Dice.DiceMasters...)
 					ctx.Dice.MasterRefresh()
 					ctx.Dice.MasterAdd(ctx.Player.UserID)
+					ctx.Audit("master.unlock", ctx.Player.UserID, before, ctx.Dice.DiceMasters)
 
 					ctx.Dice.UnlockCodeUpdate(true) // 强制刷新解锁码
 					ReplyToSender(ctx, msg, "你已成为Master")
@@ -895,6 +913,7 @@ func (d *Dice) registerCoreCommands() {
 			switch subCmd {
 			case "add":
 				var count int
+				before := append([]string(nil), ctx.Dice.DiceMasters...)
 				for _, uid := range readIDList(ctx, msg, cmdArgs) {
 					if uid != ctx.EndPoint.UserID {
 						ctx.Dice.MasterAdd(uid)
@@ -902,15 +921,22 @@ func (d *Dice) registerCoreCommands() {
 					}
 				}
 				ctx.Dice.Save(false)
+				if count > 0 {
+					ctx.Audit("master.add", "", before, ctx.Dice.DiceMasters)
+				}
 				ReplyToSender(ctx, msg, fmt.Sprintf("海豹将新增%d位master", count))
 			case "del", "rm":
 				var count int
+				before := append([]string(nil), ctx.Dice.DiceMasters...)
 				for _, uid := range readIDList(ctx, msg, cmdArgs) {
 					if ctx.Dice.MasterRemove(uid) {
 						count++
 					}
 				}
 				ctx.Dice.Save(false)
+				if count > 0 {
+					ctx.Audit("master.delete", "", before, ctx.Dice.DiceMasters)
+				}
 				ReplyToSender(ctx, msg, fmt.Sprintf("海豹移除了%d名master", count))
 			case "relogin":
 				var kw *Kwarg
@@ -1116,6 +1142,11 @@ func (d *Dice) registerCoreCommands() {
 					return CmdExecuteResult{Matched: true, Solved: true}
 				}
 
+				if !ctx.EndPoint.Can(CapQuitGroup) {
+					ReplyToSender(ctx, msg, capabilityUnsupportedText(ctx, CapQuitGroup))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+
 				// 既然是骰主自己操作，就不通知了
 				// 除非有多骰主……
 				ReplyToSender(ctx, msg, fmt.Sprintf("收到指令，将在5秒后退出群组%s", gp.GroupID))
@@ -1141,6 +1172,8 @@ func (d *Dice) registerCoreCommands() {
 				gp.DiceIDExistsMap.Delete(mctx.EndPoint.UserID)
 				gp.UpdatedAtTime = time.Now().Unix()
 				mctx.EndPoint.Adapter.QuitGroup(mctx, gp.GroupID)
+				ctx.Session.OnGroupLeft(mctx.EndPoint, gp.GroupID, msg.Sender.UserID, false)
+				ctx.Audit("group.quit", gp.GroupID, nil, map[string]string{"reason": wherefore})
 
 				return CmdExecuteResult{Matched: true, Solved: true}
 			case "jsclear":
@@ -1408,7 +1441,7 @@ func (d *Dice) registerCoreCommands() {
 				return CmdExecuteResult{Matched: true, Solved: true}
 			}
 
-			ReplyToSender(ctx, msg, text)
+			ReplyToSender(ctx, msg, text+buttonRepeat(ctx, cmdArgs, "重骰"))
 			return CmdExecuteResult{Matched: true, Solved: true}
 		},
 	}
@@ -1435,6 +1468,295 @@ func (d *Dice) registerCoreCommands() {
 	d.CmdMap["rxh"] = cmdRollX
 	d.CmdMap["rhx"] = cmdRollX
 
+	helpProb := "" +
+		".prob <表达式> // 计算表达式结果的分布，如 .prob 4d6k3\n" +
+		".prob <表达式> vs <目标> // 计算达成目标的概率，目标可为数值或技能名，如 .prob d20+5 vs 15\n" +
+		".prob <表达式> --explode[=次数] // 骰子掷出最大值时追加投掷，默认最多追加3次，也可写作 3d6!\n" +
+		"coc7规则下计算不高于目标的概率，其余规则计算不低于目标的概率，也可写作 vs <=50、vs >=15"
+	cmdProb := &CmdItemInfo{
+		Name:      "prob",
+		ShortHelp: helpProb,
+		Help:      "概率计算:\n" + helpProb,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if val := cmdArgs.GetArgN(1); val == "" || val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			var explode int64
+			if kw := cmdArgs.GetKwarg("explode"); kw != nil {
+				explode = probDefaultExplode
+				if kw.ValueExists {
+					explode, _ = strconv.ParseInt(kw.Value, 10, 64)
+				}
+				if explode < 0 || explode > ctx.Dice.MaxExecuteTime {
+					ReplyToSender(ctx, msg, fmt.Sprintf("爆炸骰追加次数应在0到%d之间", ctx.Dice.MaxExecuteTime))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+			}
+
+			ctx.SystemTemplate = ctx.Group.GetCharTemplate(ctx.Dice)
+			expr := cmdArgs.CleanArgs
+			var targetText, targetOp string
+			if m := probTargetRe.FindStringSubmatchIndex(expr); m != nil {
+				targetOp = expr[m[2]:m[3]]
+				targetText = strings.TrimSpace(expr[m[4]:m[5]])
+				expr = strings.TrimSpace(expr[:m[0]])
+			}
+
+			ret, err := ProbCalculate(ctx, expr, explode)
+			if err != nil {
+				ReplyToSender(ctx, msg, "无法计算: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			dist := ret.Dist
+			var method string
+			switch ret.Method {
+			case "exact":
+				method = "精确计算"
+			default:
+				method = fmt.Sprintf("模拟%d次", ret.Trials)
+			}
+			text := fmt.Sprintf("<%s>的概率计算: %s (%s)\n", ctx.Player.Name, ret.Matched, method)
+			if explode > 0 {
+				text += fmt.Sprintf("爆炸骰: 最多追加%d次\n", explode)
+			}
+			text += fmt.Sprintf("范围: %d ~ %d 期望: %.2f 标准差: %.2f\n", dist.Min, dist.Max(), dist.Mean(), dist.StdDev())
+			var percentiles []string
+			for _, q := range []int{10, 25, 50, 75, 90} {
+				percentiles = append(percentiles, fmt.Sprintf("%d%%:%d", q, dist.Percentile(float64(q)/100)))
+			}
+			text += "分位数: " + strings.Join(percentiles, " ")
+
+			if targetText != "" {
+				r, _, err := DiceExprEvalBase(ctx, targetText, RollExtraFlags{
+					DefaultDiceSideNum: getDefaultDicePoints(ctx),
+					DisableBlock:       true,
+					V2Only:             true,
+				})
+				if err != nil || r.TypeId != ds.VMTypeInt {
+					ReplyToSender(ctx, msg, "无法解析目标值: "+targetText)
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				target := int64(r.MustReadInt())
+				if targetOp == "" {
+					targetOp = ">="
+					if ctx.Group.System == "coc7" {
+						targetOp = "<="
+					}
+				}
+				var chance float64
+				switch targetOp {
+				case "<=":
+					chance = dist.ChanceLE(target)
+				case "<":
+					chance = dist.ChanceLE(target - 1)
+				case ">":
+					chance = dist.ChanceGE(target + 1)
+				default:
+					chance = dist.ChanceGE(target)
+				}
+				text += fmt.Sprintf("\n结果%s%d(%s)的概率: %.2f%%", targetOp, target, r.GetMatched(), chance*100)
+			}
+
+			ReplyToSender(ctx, msg, text)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["prob"] = cmdProb
+
+	helpFairRoll := "" +
+		".fairroll on // 开启公平骰，公布种子承诺，此后群内掷骰均由该种子推导\n" +
+		".fairroll off // 关闭公平骰\n" +
+		".fairroll status // 查看当前会话的种子承诺\n" +
+		".fairroll reveal // 立即公开当前种子并开始新会话，.log end 时也会自动公开\n" +
+		".fairroll list // 查看本群最近的会话\n" +
+		".fairroll verify <会话编号> // 用已公开的种子复算该会话的全部掷骰"
+	cmdFairRoll := &CmdItemInfo{
+		Name:      "fairroll",
+		ShortHelp: helpFairRoll,
+		Help:      "公平骰(承诺-公开):\n" + helpFairRoll,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if ctx.IsPrivate {
+				ReplyToSender(ctx, msg, "公平骰只能在群内使用")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			db := ctx.Dice.DBData
+			group := ctx.Group
+
+			switch strings.ToLower(cmdArgs.GetArgN(1)) {
+			case "on":
+				if ctx.PrivilegeLevel < 40 {
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者"))
+					break
+				}
+				group.FairRollOn = true
+				group.UpdatedAtTime = time.Now().Unix()
+				session, err := model.FairRollSessionGetActive(db, group.GroupID)
+				if errors.Is(err, model.ErrFairRollSessionNotFound) {
+					session, err = fairRollNewSession(db, group.GroupID)
+				}
+				if err != nil {
+					ReplyToSender(ctx, msg, "公平骰会话创建失败: "+err.Error())
+					break
+				}
+				ReplyToSender(ctx, msg, "已开启公平骰\n"+fairRollCommitmentText(session))
+			case "off":
+				if ctx.PrivilegeLevel < 40 {
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者"))
+					break
+				}
+				group.FairRollOn = false
+				group.UpdatedAtTime = time.Now().Unix()
+				ReplyToSender(ctx, msg, "已关闭公平骰，当前会话的种子仍可通过 .fairroll reveal 公开")
+			case "status", "":
+				text := "公平骰: 关闭"
+				if group.FairRollOn {
+					text = "公平骰: 开启"
+				}
+				if session, err := model.FairRollSessionGetActive(db, group.GroupID); err == nil {
+					text += fmt.Sprintf("\n当前会话#%d 已使用%d个nonce\n种子承诺(sha256): %s", session.ID, session.NextNonce, session.Commitment)
+				}
+				ReplyToSender(ctx, msg, text)
+			case "reveal":
+				text, err := fairRollReveal(ctx)
+				if err != nil {
+					ReplyToSender(ctx, msg, "没有可以公开的会话")
+					break
+				}
+				ReplyToSender(ctx, msg, text)
+			case "list":
+				sessions, _ := model.FairRollSessionList(db, group.GroupID, 10)
+				if len(sessions) == 0 {
+					ReplyToSender(ctx, msg, "本群还没有公平骰会话")
+					break
+				}
+				text := "本群最近的公平骰会话:"
+				for _, s := range sessions {
+					state := "未公开"
+					if s.RevealedAt != 0 {
+						state = "已公开 " + fairRollTimeText(s.RevealedAt)
+					}
+					text += fmt.Sprintf("\n#%d 开始于%s %s", s.ID, fairRollTimeText(s.CreatedAt), state)
+				}
+				ReplyToSender(ctx, msg, text)
+			case "verify":
+				id, err := strconv.ParseInt(cmdArgs.GetArgN(2), 10, 64)
+				if err != nil {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				ret, err := FairRollVerify(db, id)
+				if err != nil {
+					if errors.Is(err, model.ErrFairRollSessionNotFound) {
+						ReplyToSender(ctx, msg, "找不到该会话")
+					} else {
+						ReplyToSender(ctx, msg, "无法复核: "+err.Error())
+					}
+					break
+				}
+				ReplyToSender(ctx, msg, fairRollVerifyText(ret))
+			default:
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["fairroll"] = cmdFairRoll
+
+	helpDiceStat := "" +
+		".dicestat on/off // 开启/关闭骰点统计\n" +
+		".dicestat [d<面数>] // 对全部记录进行卡方检验与游程检验\n" +
+		".dicestat here [d<面数>] // 只检验本群的记录\n" +
+		".dicestat group <群ID> [d<面数>] // 只检验指定群的记录，如 QQ-Group:12345\n" +
+		".dicestat platform <平台> [d<面数>] // 只检验指定平台的记录，如 QQ\n" +
+		".dicestat clr // 清空统计数据"
+	cmdDiceStat := &CmdItemInfo{
+		Name:      "dicestat",
+		ShortHelp: helpDiceStat,
+		Help:      "骰点统计(仅骰主可用):\n" + helpDiceStat,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if ctx.PrivilegeLevel < 100 {
+				ReplyToSender(ctx, msg, "你不具备Master权限")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			q := &model.QueryDiceStat{}
+			args := cmdArgs.Args
+			if len(args) > 0 {
+				switch strings.ToLower(args[0]) {
+				case "help":
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				case "on", "off":
+					ctx.Dice.DiceStatEnable = strings.EqualFold(args[0], "on")
+					ctx.Dice.MarkModified()
+					if ctx.Dice.DiceStatEnable {
+						ReplyToSender(ctx, msg, "已开启骰点统计，此后的掷骰结果将被记录")
+					} else {
+						ReplyToSender(ctx, msg, "已关闭骰点统计，已有数据仍然保留")
+					}
+					return CmdExecuteResult{Matched: true, Solved: true}
+				case "clr", "clear":
+					_ = ctx.Dice.DiceStat.Flush(ctx.Dice.DBData)
+					if err := model.DiceStatClear(ctx.Dice.DBData); err != nil {
+						ReplyToSender(ctx, msg, "清空失败: "+err.Error())
+					} else {
+						ReplyToSender(ctx, msg, "骰点统计数据已清空")
+					}
+					return CmdExecuteResult{Matched: true, Solved: true}
+				case "here":
+					if ctx.IsPrivate {
+						ReplyToSender(ctx, msg, "私聊中无法使用here")
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+					q.GroupID = ctx.Group.GroupID
+					args = args[1:]
+				case "group", "platform":
+					if len(args) < 2 {
+						return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+					}
+					if strings.EqualFold(args[0], "group") {
+						q.GroupID = args[1]
+					} else {
+						q.Platform = args[1]
+					}
+					args = args[2:]
+				}
+			}
+			if len(args) > 0 {
+				sides, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(args[0]), "d"), 10, 64)
+				if err != nil || sides < 2 {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				q.Sides = sides
+			}
+
+			_ = ctx.Dice.DiceStat.Flush(ctx.Dice.DBData)
+			results, err := DiceStatAnalyze(ctx.Dice.DBData, q)
+			if err != nil {
+				ReplyToSender(ctx, msg, "统计失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			text := "骰点统计"
+			if !ctx.Dice.DiceStatEnable {
+				text += "(当前未开启记录)"
+			}
+			if len(results) == 0 {
+				ReplyToSender(ctx, msg, text+": 暂无数据")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			for i, r := range results {
+				if i >= 6 {
+					text += fmt.Sprintf("\n……另有%d种骰子，可指定面数查看", len(results)-i)
+					break
+				}
+				text += "\n" + diceStatResultText(r)
+			}
+			ReplyToSender(ctx, msg, text)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["dicestat"] = cmdDiceStat
+
 	helpExt := ".ext // 查看扩展列表"
 	cmdExt := &CmdItemInfo{
 		Name:      "ext",
@@ -1638,10 +1960,221 @@ func (d *Dice) registerCoreCommands() {
 		},
 	}
 
+	helpLink := ".link // 生成关联码(需私聊)，10分钟内有效\n" +
+		".link <关联码> // 在另一个帐号上发送，将其关联到生成关联码的帐号\n" +
+		".link list // 查看当前帐号关联的帐号\n" +
+		".link del // 解除当前帐号的关联\n" +
+		".link del <帐号ID> // 解除指定帐号的关联(仅骰主可用)"
+	cmdLink := &CmdItemInfo{
+		Name:      "link",
+		ShortHelp: helpLink,
+		Help: "跨平台帐号关联:\n" + helpLink +
+			"\n关联后各帐号共用人物卡、信任与拉黑状态。关联时两边已有的数据会合并，同一群内都有数据时以生成关联码的帐号为准",
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			am := ctx.Dice.AttrsManager
+			uid := ctx.Player.UserID
+			switch val := cmdArgs.GetArgN(1); strings.ToLower(val) {
+			case "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "":
+				if !ctx.IsPrivate {
+					ReplyToSender(ctx, msg, "为防止关联码被他人使用，请私聊骰子生成")
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				code := am.LinkCodeNew(uid)
+				ReplyToSender(ctx, msg, fmt.Sprintf("关联码: %s\n请在10分钟内用要关联的另一个帐号向骰子发送 .link %s\n请勿将关联码告诉他人", code, code))
+			case "list":
+				ids := am.LinkedIDs(uid)
+				if len(ids) == 0 {
+					ReplyToSender(ctx, msg, "当前帐号没有关联其他帐号")
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("当前帐号的统一ID为 %s，已关联:\n%s", am.UIDConvert(uid), strings.Join(ids, "\n")))
+			case "del", "rm", "unlink":
+				target := uid
+				if id := cmdArgs.GetArgN(2); id != "" && id != uid {
+					if ctx.PrivilegeLevel < 100 {
+						ReplyToSender(ctx, msg, "你不具备Master权限")
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+					target = id
+				}
+				if err := am.Unlink(target, uid); err != nil {
+					ReplyToSender(ctx, msg, "解除关联失败: "+err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("已解除 %s 的关联，原有人物卡等数据留给其余关联帐号使用", target))
+			default:
+				id, err := am.LinkConfirm(val, uid)
+				if err != nil {
+					ReplyToSender(ctx, msg, "关联失败: "+err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("关联成功，统一ID为 %s，已关联:\n%s", id, strings.Join(am.LinkedIDs(uid), "\n")))
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["link"] = cmdLink
+
+	helpBridge := ".bridge // 查看当前群的桥接\n" +
+		".bridge new // 以当前群为主群新建桥接，并生成加入码\n" +
+		".bridge code // 重新生成加入码，10分钟内有效\n" +
+		".bridge join <加入码> // 当前群加入桥接\n" +
+		".bridge leave // 当前群退出桥接\n" +
+		".bridge chat on/off // 是否转发普通聊天，关闭后只转发指令与骰子回复"
+	cmdBridge := &CmdItemInfo{
+		Name:      "bridge",
+		ShortHelp: helpBridge,
+		Help: "跨平台群桥接:\n" + helpBridge +
+			"\n桥接的群之间互相转发消息，共用规则、扩展与日志，日志和先攻列表等记在主群下",
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if ctx.IsPrivate {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			d := ctx.Dice
+			val := strings.ToLower(cmdArgs.GetArgN(1))
+			if val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if val != "" && ctx.PrivilegeLevel < 40 {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			switch val {
+			case "":
+				b := d.BridgeByGroup(ctx.Group.GroupID)
+				if b == nil {
+					ReplyToSender(ctx, msg, "当前群不在任何桥接中")
+					break
+				}
+				text := "当前桥接的群:"
+				for i, m := range b.Members {
+					text += fmt.Sprintf("\n%d. %s(%s)", i+1, m.GroupName, m.GroupID)
+					if i == 0 {
+						text += " [主群]"
+					}
+				}
+				if !b.RelayChat {
+					text += "\n普通聊天不转发"
+				}
+				ReplyToSender(ctx, msg, text)
+			case "new", "code":
+				var b *GroupBridge
+				var err error
+				if val == "new" {
+					b, err = d.BridgeNew(ctx)
+				} else if b = d.BridgeByGroup(ctx.Group.GroupID); b == nil {
+					err = ErrBridgeNotJoined
+				}
+				if err != nil {
+					ReplyToSender(ctx, msg, err.Error())
+					break
+				}
+				code := d.BridgeCodeNew(b)
+				ReplyToSender(ctx, msg, fmt.Sprintf("加入码: %s\n请在10分钟内于要桥接的群中发送 .bridge join %s", code, code))
+			case "join":
+				b, err := d.BridgeJoin(ctx, cmdArgs.GetArgN(2))
+				if err != nil {
+					ReplyToSender(ctx, msg, "加入失败: "+err.Error())
+					break
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("已加入桥接，当前共%d个群，规则、扩展与日志以主群 %s 为准", len(b.Members), b.Members[0].GroupID))
+				if main, ok := ctx.Session.ServiceAtNew.Load(b.Members[0].GroupID); ok {
+					// 加入时从主群同步一次状态
+					d.bridgeSyncState(main)
+				}
+			case "leave":
+				if err := d.BridgeLeave(ctx.Group.GroupID); err != nil {
+					ReplyToSender(ctx, msg, err.Error())
+					break
+				}
+				ReplyToSender(ctx, msg, "当前群已退出桥接")
+			case "chat":
+				b := d.BridgeByGroup(ctx.Group.GroupID)
+				if b == nil {
+					ReplyToSender(ctx, msg, "当前群不在任何桥接中")
+					break
+				}
+				switch v := strings.ToLower(cmdArgs.GetArgN(2)); v {
+				case "on", "off":
+					d.BridgeSet(b.ID, b.Enable, v == "on")
+					ReplyToSender(ctx, msg, "普通聊天转发: "+v)
+				default:
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+			default:
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["bridge"] = cmdBridge
+
+	helpTextImg := ".textimg // 查看当前群的长文本转图片设置\n" +
+		".textimg <字数> // 回复超过该字数时转为图片发送\n" +
+		".textimg off // 当前群不转图片\n" +
+		".textimg default // 跟随全局设置"
+	cmdTextImg := &CmdItemInfo{
+		Name:      "textimg",
+		ShortHelp: helpTextImg,
+		Help:      "长文本转图片:\n" + helpTextImg + "\n需要平台支持发送图片，含图片等富文本的回复不转换",
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if ctx.IsPrivate {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			val := strings.ToLower(cmdArgs.GetArgN(1))
+			if val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if val != "" && ctx.PrivilegeLevel < 40 {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			switch val {
+			case "":
+			case "off":
+				ctx.Group.TextImageThreshold = -1
+			case "default":
+				ctx.Group.TextImageThreshold = 0
+			default:
+				n, err := strconv.Atoi(val)
+				if err != nil || n <= 0 {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				ctx.Group.TextImageThreshold = n
+			}
+			if val != "" {
+				ctx.Group.UpdatedAtTime = time.Now().Unix()
+			}
+
+			var text string
+			if n := ctx.TextImageThreshold(); n > 0 {
+				text = fmt.Sprintf("长文本转图片: 超过%d字时转换", n)
+			} else {
+				text = "长文本转图片: 关闭"
+			}
+			if ctx.Group.TextImageThreshold == 0 {
+				text += "(跟随全局设置)"
+			}
+			if !ctx.EndPoint.Can(CapImage) {
+				text += "\n注意: 当前平台不支持发送图片，设置不会生效"
+			}
+			ReplyToSender(ctx, msg, text)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["textimg"] = cmdTextImg
+
 	helpSet := ".set info// 查看当前面数设置\n" +
 		".set dnd/coc // 设置群内骰子面数为20/100，并自动开启对应扩展 \n" +
 		".set <面数> // 设置群内骰子面数\n" +
-		".set clr // 清除群内骰子面数设置"
+		".set clr // 清除群内骰子面数设置\n" +
+		".set rng [<随机源>] [<种子>] // 查看/切换群内随机源，仅管理员可用，种子会私聊发送"
 	cmdSet := &CmdItemInfo{
 		Name:      "set",
 		ShortHelp: helpSet,
@@ -1655,7 +2188,8 @@ func (d *Dice) registerCoreCommands() {
 				text += fmt.Sprintf(".set %s // %s\n", strings.Join(tmpl.SetConfig.Keys, "/"), textHelp)
 				return true
 			})
-			text += `.set clr // 清除群内骰子面数设置`
+			text += ".set clr // 清除群内骰子面数设置\n"
+			text += `.set rng [<随机源>] [<种子>] // 查看/切换群内随机源，仅管理员可用，种子会私聊发送`
 			if isShort {
 				return text
 			}
@@ -1749,6 +2283,17 @@ func (d *Dice) registerCoreCommands() {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:设定默认骰子面数_重置"))
 				case "help":
 					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				case "rng":
+					// 随机源影响全群的掷骰，仅限管理员与骰主
+					if ctx.PrivilegeLevel < 40 {
+						ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者"))
+						break
+					}
+					text, secret := setRandSource(ctx, cmdArgs.GetArgN(2), cmdArgs.GetArgN(3))
+					ReplyToSender(ctx, msg, text)
+					if secret != "" {
+						ReplyPerson(ctx, msg, secret)
+					}
 				case "info":
 					ReplyToSender(ctx, msg, DiceFormat(ctx, `个人骰子面数: {$t个人骰子面数}\n`+
 						`群组骰子面数: {$t群组骰子面数}\n当前骰子面数: {$t当前骰子面数}`))
@@ -1761,6 +2306,44 @@ func (d *Dice) registerCoreCommands() {
 	}
 	d.CmdMap["set"] = cmdSet
 
+	helpFace := ".face <点数1> <点数2> ... // 物理骰模式下，按提示的顺序录入投出的点数\n" +
+		".face cancel // 放弃等待录入点数的指令"
+	cmdFace := &CmdItemInfo{
+		Name:      "face",
+		ShortHelp: helpFace,
+		Help:      "录入物理骰点数:\n" + helpFace,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if len(cmdArgs.Args) == 0 {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if cmdArgs.IsArgEqual(1, "cancel") {
+				if _, ok := physicalDicePendingMap.LoadAndDelete(physicalDiceKey(msg)); ok {
+					ReplyToSender(ctx, msg, "已放弃等待录入点数的指令")
+				} else {
+					ReplyToSender(ctx, msg, "当前没有等待录入点数的指令")
+				}
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			var faces []int64
+			for _, arg := range cmdArgs.Args {
+				for _, s := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == '，' }) {
+					v, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						ReplyToSender(ctx, msg, fmt.Sprintf("无法识别的点数: %s", s))
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+					faces = append(faces, v)
+				}
+			}
+			if text := physicalDiceSubmit(ctx, msg, faces); text != "" {
+				ReplyToSender(ctx, msg, text)
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+	d.CmdMap["face"] = cmdFace
+
 	helpCh := ".pc new <角色名> // 新建角色并绑卡\n" +
 		".pc tag [<角色名> | <角色序号>] // 当前群绑卡/解除绑卡(不填角色名)\n" +
 		".pc untagAll [<角色名> | <角色序号>] // 全部群解绑(不填即当前卡)\n" +
@@ -579,6 +579,14 @@ func AtParse(cmd string, prefix string) (string, []*AtInfo) {
 		re = regexp.MustCompile(`<@(.+?)>`)
 	case "SEALCHAT":
 		re = regexp.MustCompile(`<@(\S+?)>`)
+	case "MATRIX":
+		re = regexp.MustCompile(`<(@[^<>\s:]+:[^<>\s]+)>`)
+	case "IRC":
+		re = regexp.MustCompile(`<@([^<>\s]+)>`)
+	case "FEISHU":
+		re = regexp.MustCompile(`<at user_id="([^"]+)">[^<]*</at>`)
+	case "HTTP":
+		re = regexp.MustCompile(`\[CQ:at,qq=([^,\]]+)(?:,name=(?:.*?))?\]`)
 	}
 
 	m := re.FindAllStringSubmatch(cmd, -1)
@@ -599,7 +607,7 @@ func AtBuild(uid string) string {
 	if uid == "" {
 		return ""
 	}
-	re := regexp.MustCompile("(QQ|DISCORD|KOOK|TG|DODO).*?:(.*)")
+	re := regexp.MustCompile("(QQ|DISCORD|KOOK|TG|DODO|MATRIX|IRC|FEISHU|HTTP).*?:(.*)")
 	m := re.FindStringSubmatch(uid)
 	var text string
 	if len(m) == 3 {
@@ -905,6 +905,9 @@ func setupBaseTextTemplate(d *Dice) {
 			"提示_无权限": {
 				{"你没有权限这样做", 1},
 			},
+			"提示_平台不支持": {
+				{"当前平台不支持{$t功能}", 1},
+			},
 			"提示_无权限_非master/管理/邀请者": {
 				{"你不是管理员、邀请者或master", 1},
 			},
@@ -1629,6 +1632,10 @@ func setupBaseTextTemplate(d *Dice) {
 			"提示_无权限": {
 				SubType: "通用",
 			},
+			"提示_平台不支持": {
+				SubType: "通用",
+				Vars:    []string{"$t功能"},
+			},
 			"提示_无权限_非master/管理/邀请者": {
 				SubType: "通用",
 			},
@@ -2117,6 +2124,22 @@ func (d *Dice) loads() {
 			d.QuitInactiveBatchWait = 30
 		}
 
+		d.DiceStatEnable = dNew.DiceStatEnable
+		d.ReconnectMaxRetries = dNew.ReconnectMaxRetries
+		d.ReconnectMaxDelay = dNew.ReconnectMaxDelay
+		d.TextImageThreshold = dNew.TextImageThreshold
+		d.AuditRetentionDays = dNew.AuditRetentionDays
+		if d.AuditRetentionDays == 0 {
+			d.AuditRetentionDays = auditRetentionDefault
+		}
+		d.OutboxRate = dNew.OutboxRate
+		d.OutboxPlatformRates = dNew.OutboxPlatformRates
+		d.Webhooks = dNew.Webhooks
+		d.bridgeLock.Lock()
+		d.GroupBridges = dNew.GroupBridges
+		d.bridgeMain.Store(nil)
+		d.bridgeLock.Unlock()
+
 		d.EnableCensor = dNew.EnableCensor
 		d.CensorMode = dNew.CensorMode
 		d.CensorThresholds = dNew.CensorThresholds
@@ -2582,7 +2605,13 @@ func (d *Dice) ApplyExtDefaultSettings() {
 
 func (d *Dice) Save(isAuto bool) {
 	if d.LastUpdatedTime != 0 {
+		if d.Webhook != nil {
+			d.Webhook.lock.RLock()
+		}
 		a, err1 := yaml.Marshal(d)
+		if d.Webhook != nil {
+			d.Webhook.lock.RUnlock()
+		}
 		advancedData, err2 := yaml.Marshal(d.AdvancedConfig)
 
 		if err1 == nil && err2 == nil {
@@ -2637,6 +2666,11 @@ func (d *Dice) Save(isAuto bool) {
 	// 同步全部属性数据：个人角色卡、群内角色卡、群数据、个人全局数据
 	d.AttrsManager.CheckForSave()
 
+	// 骰点统计落库
+	if err := d.DiceStat.Flush(d.DBData); err != nil {
+		d.Logger.Errorf("骰点统计保存失败: %v", err)
+	}
+
 	// 保存黑名单数据
 	// TODO: 增加更新时间检测
 	// model.BanMapSet(d.DBData, d.BanList.MapToJSON())
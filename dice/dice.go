@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja_nodejs/eventloop"
@@ -251,6 +252,27 @@ type Dice struct {
 
 	AttrsManager *AttrsManager `json:"-" yaml:"-"`
 
+	DiceStatEnable bool              `json:"diceStatEnable" yaml:"diceStatEnable"` // 记录骰点分布，用于公平性检验
+	DiceStat       *DiceStatRecorder `json:"-" yaml:"-"`
+
+	ReconnectMaxRetries int   `json:"reconnectMaxRetries" yaml:"reconnectMaxRetries"` // 断线连续重连次数上限，0为不限
+	ReconnectMaxDelay   int64 `json:"reconnectMaxDelay" yaml:"reconnectMaxDelay"`     // 重连最长等待(秒)，0为默认5分钟
+
+	TextImageThreshold int `json:"textImageThreshold" yaml:"textImageThreshold"` // 回复超过此字数时转为图片发送，0为关闭
+
+	AuditRetentionDays int          `json:"auditRetentionDays" yaml:"auditRetentionDays"` // 操作记录保留天数，-1为永久保留
+	auditCleanTime     atomic.Int64 // 上次清理过期操作记录的时间(unix秒)
+
+	OutboxRate          OutboxRateConfig             `json:"-" yaml:"outboxRate"`          // 发送队列限速
+	OutboxPlatformRates map[string]*OutboxRateConfig `json:"-" yaml:"outboxPlatformRates"` // 按平台单独设置的发送限速
+
+	Webhooks []*WebhookItem  `json:"-" yaml:"webhooks"` // 对外推送事件的订阅
+	Webhook  *WebhookManager `json:"-" yaml:"-"`
+
+	GroupBridges []*GroupBridge `json:"-" yaml:"groupBridges"` // 跨平台群桥接
+	bridgeLock   sync.RWMutex
+	bridgeMain   atomic.Pointer[map[string]string] // 群 -> 主群 的缓存，桥接变化时清空
+
 	AdvancedConfig AdvancedConfig `json:"-" yaml:"-"`
 
 	ContainerMode bool `yaml:"-" json:"-"` // 容器模式：禁用内置适配器，不允许使用内置Lagrange和旧的内置Gocq
@@ -333,6 +355,9 @@ func (d *Dice) Init() {
 	d.BanList = &BanListInfo{Parent: d}
 	d.BanList.Init()
 
+	d.DiceStat = &DiceStatRecorder{}
+	d.Webhook = NewWebhookManager(d)
+
 	initVerify()
 
 	d.CommandCompatibleMode = true
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -14,9 +16,16 @@ import (
 )
 
 type AttrsManager struct {
+	parent *Dice
 	db     *sqlx.DB
 	logger *zap.SugaredLogger
 	m      SyncMap[string, *AttributesItem]
+
+	// 跨平台关联，见 dice_identity.go
+	uidCache  SyncMap[string, string]   // 平台用户ID -> 虚拟ID，未关联时为空字符串
+	linkCache SyncMap[string, []string] // 虚拟ID -> 关联的平台用户ID
+	linkCodes SyncMap[string, *identityLinkCode]
+	linkLock  sync.Mutex
 }
 
 // LoadByCtx 获取当前角色，如有绑定，则获取绑定的角色，若无绑定，获取群内默认卡
@@ -54,6 +63,9 @@ func (am *AttrsManager) Load(groupId string, userId string) (*AttributesItem, er
 
 func (am *AttrsManager) UIDConvert(userId string) string {
 	// 如果存在一个虚拟id，那么返回虚拟id，不存在原样返回
+	if id := am.uniformID(userId); id != "" {
+		return id
+	}
 	return userId
 }
 
@@ -99,6 +111,10 @@ func (am *AttrsManager) CharDelete(id string) error {
 // 3. 群属性(id为群id)
 // 4. 用户全局属性
 func (am *AttrsManager) LoadById(id string) (*AttributesItem, error) {
+	if am.parent != nil && strings.Contains(id, "-Group:") {
+		// 桥接的群共用主群的群属性。角色卡与用户属性的id不含群前缀，不必查询
+		id = am.parent.BridgeMainGroupID(id)
+	}
 	// 1. 如果当前有缓存，那么从缓存中返回。
 	// 但是。。如果有人把这个对象一直持有呢？
 	i, exists := am.m.Load(id)
@@ -147,6 +163,7 @@ func (am *AttrsManager) LoadById(id string) (*AttributesItem, error) {
 }
 
 func (am *AttrsManager) Init(d *Dice) {
+	am.parent = d
 	am.db = d.DBData
 	am.logger = d.Logger
 	go func() {
@@ -181,6 +198,9 @@ func (am *AttrsManager) CheckForSave() (int, int) {
 		if !value.IsSaved {
 			saved += 1
 			value.SaveToDB(db, tx)
+			if am.parent != nil {
+				webhookEmitSheet(am.parent, value)
+			}
 		}
 		times += 1
 		return true
@@ -234,6 +254,7 @@ func (am *AttrsManager) CharGetBindingId(groupId string, userId string) (string,
 }
 
 func (am *AttrsManager) CharIdGetByName(userId string, name string) (string, error) {
+	userId = am.UIDConvert(userId)
 	return model.AttrsGetIdByUidAndName(am.db, userId, name)
 }
 
@@ -279,9 +300,13 @@ type AttributesItem struct {
 	SheetType        string
 }
 
+func (i *AttributesItem) toJSON() ([]byte, error) {
+	return ds.NewDictVal(i.valueMap).V().ToJSON()
+}
+
 func (i *AttributesItem) SaveToDB(db *sqlx.DB, tx *sql.Tx) {
 	// 使用事务写入
-	rawData, err := ds.NewDictVal(i.valueMap).V().ToJSON()
+	rawData, err := i.toJSON()
 	if err != nil {
 		return
 	}
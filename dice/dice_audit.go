@@ -0,0 +1,79 @@
+package dice
+
+import (
+	"encoding/json"
+	"time"
+
+	"sealdice-core/dice/model"
+)
+
+// 操作审计：记录通过UI/API以及聊天中的管理操作
+
+const (
+	AuditSourceUI    = "ui"
+	AuditSourceToken = "token"
+	AuditSourceIM    = "im"
+
+	auditRetentionDefault = 180 // 天
+)
+
+func auditJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// AuditAdd 写入一条操作记录，before/after 为字符串时原样保存，其余转为JSON
+func (d *Dice) AuditAdd(item *model.AuditLog, before, after interface{}) {
+	if d.DBData == nil {
+		return
+	}
+	item.CreatedAt = time.Now().Unix()
+	item.Before = auditJSON(before)
+	item.After = auditJSON(after)
+	if err := model.AuditLogAdd(d.DBData, item); err != nil && d.Logger != nil {
+		d.Logger.Errorf("写入操作记录失败: %v", err)
+	}
+
+	// 顺带清理过期记录，每天至多一次
+	now := time.Now().Unix()
+	last := d.auditCleanTime.Load()
+	if now-last > 24*60*60 && d.auditCleanTime.CompareAndSwap(last, now) {
+		go d.auditClean()
+	}
+}
+
+func (d *Dice) auditClean() {
+	days := d.AuditRetentionDays
+	if days == 0 {
+		days = auditRetentionDefault
+	}
+	if days < 0 {
+		return
+	}
+	n, err := model.AuditLogClean(d.DBData, time.Now().AddDate(0, 0, -days).Unix())
+	if err == nil && n > 0 && d.Logger != nil {
+		d.Logger.Infof("已清理%d条过期的操作记录", n)
+	}
+}
+
+// Audit 记录聊天中由当前用户执行的管理操作
+func (ctx *MsgContext) Audit(action string, target string, before, after interface{}) {
+	if ctx.Dice == nil {
+		return
+	}
+	item := &model.AuditLog{Source: AuditSourceIM, Action: action, Target: target}
+	if ctx.Player != nil {
+		item.Actor = ctx.Player.UserID
+		item.ActorName = ctx.Player.Name
+	}
+	ctx.Dice.AuditAdd(item, before, after)
+}
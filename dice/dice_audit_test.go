@@ -0,0 +1,43 @@
+package dice
+
+import (
+	"testing"
+	"time"
+
+	"sealdice-core/dice/model"
+)
+
+func TestAuditLog(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBData: dataDB}
+	d.auditCleanTime.Store(time.Now().Unix())
+
+	d.AuditAdd(&model.AuditLog{Source: AuditSourceUI, Actor: "owner", Action: "ban.add", Target: "QQ:1"}, nil, map[string]int{"rank": 30})
+	d.AuditAdd(&model.AuditLog{Source: AuditSourceUI, Actor: "admin1", Action: "config.set"}, map[string]int{"a": 1}, map[string]int{"a": 2})
+	ctx := &MsgContext{Dice: d, Player: &GroupPlayerInfo{UserID: "QQ:2", Name: "骰主"}}
+	ctx.Audit("ban.delete", "QQ:1", "x", nil)
+
+	total, items, err := model.AuditLogGetPage(dataDB, model.QueryAuditLog{PageNum: 1, PageSize: 10, Action: "ban"})
+	if err != nil || total != 2 || len(items) != 2 {
+		t.Fatalf("filter by action: %v %d", err, total)
+	}
+	if items[0].Source != AuditSourceIM || items[0].ActorName != "骰主" || items[0].Before != "x" {
+		t.Fatalf("unexpected item %+v", items[0])
+	}
+	total, items, _ = model.AuditLogGetPage(dataDB, model.QueryAuditLog{PageNum: 1, PageSize: 10, Actor: "admin1"})
+	if total != 1 || items[0].After != `{"a":2}` {
+		t.Fatalf("filter by actor: %+v", items)
+	}
+
+	// 只增不改
+	if _, err = dataDB.Exec(`update audit_log set actor = 'x'`); err == nil {
+		t.Fatal("update should be rejected")
+	}
+	n, err := model.AuditLogClean(dataDB, time.Now().Add(time.Hour).Unix())
+	if err != nil || n != 3 {
+		t.Fatalf("clean: %v %d", err, n)
+	}
+}
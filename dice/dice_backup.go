@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/alexmullins/zip"
+	"gopkg.in/yaml.v3"
 
 	"sealdice-core/dice/model"
 	"sealdice-core/utils"
@@ -122,9 +123,10 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 		return err == nil && stat.IsDir()
 	}
 
-	backup := func(d *Dice, fn string) {
-		data, err := os.ReadFile(fn)
-		if err != nil && !strings.Contains(fn, "session.token") {
+	backupData := func(d *Dice, fn string, data []byte) {
+		h := &zip.FileHeader{Name: fn, Method: zip.Deflate, Flags: 0x800}
+		fileWriter, err := writer.CreateHeader(h)
+		if err != nil {
 			if d != nil {
 				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
 			} else {
@@ -132,10 +134,12 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 			}
 			return
 		}
+		_, _ = fileWriter.Write(data)
+	}
 
-		h := &zip.FileHeader{Name: fn, Method: zip.Deflate, Flags: 0x800}
-		fileWriter, err := writer.CreateHeader(h)
-		if err != nil {
+	backup := func(d *Dice, fn string) {
+		data, err := os.ReadFile(fn)
+		if err != nil && !strings.Contains(fn, "session.token") {
 			if d != nil {
 				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
 			} else {
@@ -143,7 +147,7 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 			}
 			return
 		}
-		_, _ = fileWriter.Write(data)
+		backupData(d, fn, data)
 	}
 
 	backupDir := func(path string, info fs.FileInfo, _ error) error {
@@ -153,7 +157,14 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 		return nil
 	}
 
-	backup(nil, "data/dice.yaml")
+	// dice.yaml 中的登录凭据不进入备份，否则能下载备份的管理员可借此登录为主人
+	if data, err := os.ReadFile("data/dice.yaml"); err != nil {
+		logger.Errorf("备份文件失败: %s, 原因: %s", "data/dice.yaml", err.Error())
+	} else if data, err = backupStripCredentials(data); err != nil {
+		logger.Errorf("备份文件失败: %s, 原因: %s", "data/dice.yaml", err.Error())
+	} else {
+		backupData(nil, "data/dice.yaml", data)
+	}
 
 	if sel&BackupSelectionDecks != 0 {
 		cfgGlb.Decks = true
@@ -326,6 +337,24 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 	return fzip.Name(), nil
 }
 
+// backupStripCredentials 去掉 dice.yaml 中的密码、访问令牌与两步验证密钥。
+// 从这样的备份恢复后，需要重新设置 WebUI 密码
+func backupStripCredentials(data []byte) ([]byte, error) {
+	var dc DiceConfigs
+	if err := yaml.Unmarshal(data, &dc); err != nil {
+		return nil, err
+	}
+	dc.UIPasswordHash = ""
+	dc.AccessTokens = nil
+	dc.AccessTokenUsers = nil
+	dc.UITOTP = nil
+	for _, u := range dc.WebUIUsers {
+		u.PasswordHash = ""
+		u.TOTP = nil
+	}
+	return yaml.Marshal(dc)
+}
+
 func (dm *DiceManager) BackupAuto() error {
 	_, err := dm.Backup(dm.AutoBackupSelection, true)
 	return err
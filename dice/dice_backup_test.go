@@ -0,0 +1,68 @@
+package dice
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmullins/zip"
+
+	"sealdice-core/utils"
+)
+
+func TestBackupStripsCredentials(t *testing.T) {
+	d := newTestDiceFull(t)
+	dm := d.Parent
+	dm.AccessTokens = map[string]bool{"owner-session-token": true, "admin-session-token": true}
+	dm.AccessTokenUsers = map[string]string{"admin-session-token": "alice"}
+	dm.UIPasswordHash = "owner-password-hash"
+	if err := dm.WebUIUserSet("alice", WebUIRoleAdmin, "alice-password", false); err != nil {
+		t.Fatal(err)
+	}
+	secret, _, err := dm.TOTPSetupBegin("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if _, err = dm.TOTPSetupConfirm("", code); err != nil {
+		t.Fatal(err)
+	}
+	dm.Save()
+
+	// 管理员通过 WebUI 发起的备份与自动备份走同一路径
+	fn, err := dm.Backup(BackupSelectionBasic, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	var cfg string
+	for _, f := range r.File {
+		if f.Name != "data/dice.yaml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		cfg = string(data)
+	}
+	if !strings.Contains(cfg, "alice") {
+		t.Fatalf("dice.yaml missing from backup: %q", cfg)
+	}
+	for _, secretText := range []string{"owner-session-token", "admin-session-token", "owner-password-hash", secret, "passwordHash: $2"} {
+		if strings.Contains(cfg, secretText) {
+			t.Errorf("backup contains credential %q", secretText)
+		}
+	}
+	if strings.Contains(cfg, "uiTotp") || strings.Contains(cfg, "totp:") {
+		t.Error("backup contains TOTP config")
+	}
+}
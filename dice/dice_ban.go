@@ -297,6 +297,7 @@ func (i *BanListInfo) NoticeCheck(uid string, place string, oldRank BanRankType,
 						"")
 					time.Sleep(1 * time.Second)
 					ctx.EndPoint.Adapter.QuitGroup(ctx, place)
+					ctx.Session.OnGroupLeft(ctx.EndPoint, place, "", false)
 				}
 			}
 		}
@@ -360,6 +361,7 @@ func (i *BanListInfo) AddScoreByCensor(uid string, score int64, place string, le
 	}
 }
 
+// GetByID 返回帐号自身的记录，修改记录时使用
 func (i *BanListInfo) GetByID(uid string) (*BanListInfoItem, bool) {
 	if uid == "" {
 		return nil, false
@@ -367,6 +369,34 @@ func (i *BanListInfo) GetByID(uid string) (*BanListInfoItem, bool) {
 	return i.Map.Load(uid)
 }
 
+// EffectiveRank 综合跨平台关联帐号后的拉黑/信任等级，拉黑优先，仅用于判定
+func (i *BanListInfo) EffectiveRank(uid string) BanRankType {
+	rank := BanRankNormal
+	if v, ok := i.GetByID(uid); ok {
+		rank = v.Rank
+	}
+	if rank == BanRankBanned || i.Parent == nil || i.Parent.AttrsManager == nil {
+		return rank
+	}
+	for _, id := range i.Parent.AttrsManager.LinkedIDs(uid) {
+		if id == uid {
+			continue
+		}
+		if other, ok := i.Map.Load(id); ok {
+			switch other.Rank {
+			case BanRankBanned:
+				return BanRankBanned
+			case BanRankTrusted:
+				if rank == BanRankNormal {
+					rank = BanRankTrusted
+				}
+			default: /* no-op */
+			}
+		}
+	}
+	return rank
+}
+
 func (i *BanListInfo) SetTrustByID(uid string, place string, reason string) {
 	v, ok := i.GetByID(uid)
 	if !ok {
@@ -409,6 +439,7 @@ func (i *BanListInfo) SaveChanged(d *Dice) {
 			if err == nil {
 				_ = model.BanItemSave(d.DBData, k, v.UpdatedAt, v.BanUpdatedAt, data)
 				v.UpdatedAt = 0
+				d.Webhook.Emit(WebhookEventBanChange, json.RawMessage(data))
 			}
 		}
 		return true
@@ -418,4 +449,5 @@ func (i *BanListInfo) SaveChanged(d *Dice) {
 func (i *BanListInfo) DeleteByID(d *Dice, id string) {
 	i.Map.Delete(id)
 	_ = model.BanItemDel(d.DBData, id)
+	d.Webhook.Emit(WebhookEventBanDelete, map[string]string{"id": id})
 }
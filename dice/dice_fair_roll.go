@@ -0,0 +1,532 @@
+package dice
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	ds "github.com/sealdice/dicescript"
+	rand2 "golang.org/x/exp/rand"
+
+	"sealdice-core/dice/model"
+)
+
+// 公平骰(commit-reveal)：
+// 会话开始时生成32字节种子，只公开其sha256作为承诺；
+// 每条指令取一个nonce，随机流由 sha256(种子 || nonce) 的前16字节作为PCG状态推导；
+// 会话结束(.log end)时公开种子，任何人都可以据此复算每一次掷骰。
+
+const (
+	fairRollSeedSize  = 32
+	fairRollDrawLimit = 1000000 // 计算随机流消耗次数时的上限
+)
+
+var errFairRollNotRevealed = errors.New("该会话的种子尚未公开")
+
+// fairRollState 一条指令执行期间的公平骰状态
+type fairRollState struct {
+	Session *model.FairRollSession
+	Nonce   int64
+	UserID  string
+	Command string
+
+	start   rand2.PCGSource  // 该nonce的初始状态
+	src     *rand2.PCGSource // 当前使用的随机流
+	tracing bool             // 正在记录一次求值，嵌套求值不单独记录
+	nested  bool             // 本次求值中发生过消耗随机数的嵌套求值
+	records []*model.FairRollRecord
+
+	oldV1Rand *rand2.PCGSource
+	oldVMRand *rand2.PCGSource
+}
+
+// fairRollEnv 复算一次求值所需的环境
+type fairRollEnv struct {
+	Vars map[string]json.RawMessage `json:"vars,omitempty"` // 0层读取到的变量的最终值
+
+	EnableDiceWoD         bool   `json:"wod,omitempty"`
+	EnableDiceCoC         bool   `json:"coc,omitempty"`
+	EnableDiceFate        bool   `json:"fate,omitempty"`
+	EnableDiceDoubleCross bool   `json:"dx,omitempty"`
+	DisableBitwiseOp      bool   `json:"disableBitwiseOp,omitempty"`
+	DisableStmts          bool   `json:"disableStmts,omitempty"`
+	DisableNDice          bool   `json:"disableNDice,omitempty"`
+	IgnoreDiv0            bool   `json:"ignoreDiv0,omitempty"`
+	DiceMinMode           bool   `json:"diceMinMode,omitempty"`
+	DiceMaxMode           bool   `json:"diceMaxMode,omitempty"`
+	DefaultDiceSideExpr   string `json:"defaultDiceSideExpr,omitempty"`
+	OpCountLimit          int64  `json:"opCountLimit,omitempty"`
+
+	Nested         bool `json:"nested,omitempty"`         // 含有消耗随机数的嵌套求值，无法单独复算
+	Unserializable bool `json:"unserializable,omitempty"` // 有变量无法序列化，无法复算
+
+	Die      bool `json:"die,omitempty"`      // 游戏规则扩展或v1表达式掷出的单颗骰子，Expr 为 d面数
+	Untraced bool `json:"untraced,omitempty"` // 指令中未经记录的随机数消耗，无法复算
+}
+
+// FairRollCommitment 计算种子的承诺值
+func FairRollCommitment(seed []byte) string {
+	h := sha256.Sum256(seed)
+	return hex.EncodeToString(h[:])
+}
+
+// FairRollDeriveSource 由种子和nonce推导随机流
+func FairRollDeriveSource(seed []byte, nonce int64) *rand2.PCGSource {
+	buf := make([]byte, len(seed)+8)
+	copy(buf, seed)
+	binary.BigEndian.PutUint64(buf[len(seed):], uint64(nonce))
+	h := sha256.Sum256(buf)
+	src := &rand2.PCGSource{}
+	_ = src.UnmarshalBinary(h[:16])
+	return src
+}
+
+// fairRollCountDraws 计算从from到to消耗了几个随机数
+func fairRollCountDraws(from, to rand2.PCGSource) (int64, bool) {
+	cur := from
+	for i := int64(0); i <= fairRollDrawLimit; i++ {
+		if cur == to {
+			return i, true
+		}
+		cur.Uint64()
+	}
+	return 0, false
+}
+
+func fairRollNewSession(db *sqlx.DB, groupID string) (*model.FairRollSession, error) {
+	seed := make([]byte, fairRollSeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return model.FairRollSessionCreate(db, groupID, FairRollCommitment(seed), hex.EncodeToString(seed))
+}
+
+// fairRollCommitmentText 会话开始时的承诺公告
+func fairRollCommitmentText(s *model.FairRollSession) string {
+	return fmt.Sprintf("公平骰会话#%d 已开始，种子承诺(sha256):\n%s\n种子将在 .log end 时公开", s.ID, s.Commitment)
+}
+
+// fairRollBegin 在指令执行前替换随机流，非公平骰模式返回nil
+func (ctx *MsgContext) fairRollBegin(msg *Message, cmdArgs *CmdArgs) *fairRollState {
+	if ctx.Group == nil || !ctx.Group.FairRollOn || ctx.Dice.DBData == nil || ctx.fairRoll != nil {
+		return nil
+	}
+	db := ctx.Dice.DBData
+	session, err := model.FairRollSessionGetActive(db, ctx.Group.GroupID)
+	if errors.Is(err, model.ErrFairRollSessionNotFound) {
+		session, err = fairRollNewSession(db, ctx.Group.GroupID)
+		if err == nil {
+			ReplyToSender(ctx, msg, fairRollCommitmentText(session))
+		}
+	}
+	if err != nil {
+		ctx.Dice.Logger.Errorf("公平骰会话获取失败: %v", err)
+		return nil
+	}
+	nonce, err := model.FairRollSessionNextNonce(db, session.ID)
+	if err != nil {
+		ctx.Dice.Logger.Errorf("公平骰nonce获取失败: %v", err)
+		return nil
+	}
+	seed, _ := hex.DecodeString(session.Seed)
+
+	st := &fairRollState{
+		Session: session,
+		Nonce:   nonce,
+		UserID:  ctx.Player.UserID,
+		Command: cmdArgs.RawText,
+		src:     FairRollDeriveSource(seed, nonce),
+	}
+	st.start = *st.src
+
+	ctx.CreateVmIfNotExists()
+	st.oldV1Rand = ctx._v1Rand
+	st.oldVMRand = ctx.vm.RandSrc
+	ctx._v1Rand = st.src
+	ctx.vm.RandSrc = st.src
+	ctx.fairRoll = st
+	return st
+}
+
+// fairRollEnd 指令执行完毕，保存记录并还原随机流
+func (ctx *MsgContext) fairRollEnd(st *fairRollState) {
+	if st == nil {
+		return
+	}
+	ctx.fairRoll = nil
+	ctx._v1Rand = st.oldV1Rand
+	if ctx.vm != nil {
+		ctx.vm.RandSrc = st.oldVMRand
+	}
+	// v1 的骰池等会直接使用随机流，这部分无法复算，单独记一条让复核时能看到
+	var traced int64
+	for _, r := range st.records {
+		traced += r.Draws
+	}
+	if total, ok := fairRollCountDraws(st.start, *st.src); !ok || total > traced {
+		envData, _ := json.Marshal(fairRollEnv{Untraced: true})
+		st.records = append(st.records, &model.FairRollRecord{
+			SessionID: st.Session.ID,
+			Nonce:     st.Nonce,
+			Draws:     total - traced,
+			UserID:    st.UserID,
+			Command:   st.Command,
+			Env:       string(envData),
+		})
+	}
+	for _, r := range st.records {
+		if err := model.FairRollRecordAppend(ctx.Dice.DBData, r); err != nil {
+			ctx.Dice.Logger.Errorf("公平骰记录保存失败: %v", err)
+		}
+	}
+}
+
+// fairRollPause 暂停公平骰，用于概率模拟等不应消耗随机流的场合，返回恢复函数
+func (ctx *MsgContext) fairRollPause() func() {
+	st := ctx.fairRoll
+	if st == nil {
+		return func() {}
+	}
+	ctx.fairRoll = nil
+	ctx._v1Rand = st.oldV1Rand
+	if ctx.vm != nil {
+		ctx.vm.RandSrc = st.oldVMRand
+	}
+	return func() {
+		ctx.fairRoll = st
+		ctx._v1Rand = st.src
+		if ctx.vm != nil {
+			ctx.vm.RandSrc = st.src
+		}
+	}
+}
+
+// fairRollTrace 执行表达式，公平骰模式下记录消耗了随机数的求值
+func (ctx *MsgContext) fairRollTrace(vm *ds.Context, expr string) error {
+	st := ctx.fairRoll
+	if st == nil || vm.RandSrc != st.src {
+		return vm.Run(expr)
+	}
+	before := *st.src
+	if st.tracing {
+		err := vm.Run(expr)
+		if *st.src != before {
+			st.nested = true
+		}
+		return err
+	}
+
+	vars := map[string]*ds.VMValue{}
+	orig := vm.Config.HookFuncValueLoadOverwrite
+	vm.Config.HookFuncValueLoadOverwrite = func(c *ds.Context, name string, curVal *ds.VMValue, doCompute func(curVal *ds.VMValue) *ds.VMValue, detail *ds.BufferSpan) *ds.VMValue {
+		var v *ds.VMValue
+		if orig != nil {
+			v = orig(c, name, curVal, doCompute, detail)
+		} else {
+			v = doCompute(curVal)
+		}
+		if v != nil && c.Depth() == 0 && c.UpCtx == nil {
+			vars[name] = v
+		}
+		return v
+	}
+	st.tracing = true
+	st.nested = false
+	err := vm.Run(expr)
+	st.tracing = false
+	vm.Config.HookFuncValueLoadOverwrite = orig
+
+	if *st.src == before {
+		return err
+	}
+
+	cfg := &vm.Config
+	env := fairRollEnv{
+		EnableDiceWoD:         cfg.EnableDiceWoD,
+		EnableDiceCoC:         cfg.EnableDiceCoC,
+		EnableDiceFate:        cfg.EnableDiceFate,
+		EnableDiceDoubleCross: cfg.EnableDiceDoubleCross,
+		DisableBitwiseOp:      cfg.DisableBitwiseOp,
+		DisableStmts:          cfg.DisableStmts,
+		DisableNDice:          cfg.DisableNDice,
+		IgnoreDiv0:            cfg.IgnoreDiv0,
+		DiceMinMode:           cfg.DiceMinMode,
+		DiceMaxMode:           cfg.DiceMaxMode,
+		DefaultDiceSideExpr:   cfg.DefaultDiceSideExpr,
+		OpCountLimit:          int64(cfg.OpCountLimit),
+		Nested:                st.nested,
+	}
+	if len(vars) > 0 {
+		env.Vars = map[string]json.RawMessage{}
+		for k, v := range vars {
+			data, errJSON := v.ToJSON()
+			if errJSON != nil {
+				env.Unserializable = true
+				continue
+			}
+			env.Vars[k] = data
+		}
+	}
+	envData, _ := json.Marshal(env)
+
+	offset, _ := fairRollCountDraws(st.start, before)
+	draws, _ := fairRollCountDraws(before, *st.src)
+	st.records = append(st.records, &model.FairRollRecord{
+		SessionID: st.Session.ID,
+		Nonce:     st.Nonce,
+		Offset:    offset,
+		Draws:     draws,
+		UserID:    st.UserID,
+		Command:   st.Command,
+		Expr:      expr,
+		Env:       string(envData),
+		Result:    fairRollResultText(vm, err),
+	})
+	return err
+}
+
+// fairRollTraceDie 用 roll 掷一颗骰子，公平骰模式下记录下来，复算时按同样的面数掷骰
+func (ctx *MsgContext) fairRollTraceDie(sides int64, roll func() int64) int64 {
+	st := ctx.fairRoll
+	if st == nil || ctx._v1Rand != st.src {
+		return roll()
+	}
+	before := *st.src
+	val := roll()
+	if *st.src == before {
+		return val
+	}
+	if st.tracing {
+		st.nested = true
+		return val
+	}
+
+	envData, _ := json.Marshal(fairRollEnv{Die: true})
+	offset, _ := fairRollCountDraws(st.start, before)
+	draws, _ := fairRollCountDraws(before, *st.src)
+	st.records = append(st.records, &model.FairRollRecord{
+		SessionID: st.Session.ID,
+		Nonce:     st.Nonce,
+		Offset:    offset,
+		Draws:     draws,
+		UserID:    st.UserID,
+		Command:   st.Command,
+		Expr:      fmt.Sprintf("d%d", sides),
+		Env:       string(envData),
+		Result:    strconv.FormatInt(val, 10),
+	})
+	return val
+}
+
+func fairRollResultText(vm *ds.Context, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if vm.Ret == nil {
+		return ""
+	}
+	return vm.Ret.ToString()
+}
+
+// FairRollReplay 用公开的种子复算一条记录，返回结果和消耗的随机数个数
+func FairRollReplay(seed []byte, r *model.FairRollRecord) (string, int64, error) {
+	var env fairRollEnv
+	if err := json.Unmarshal([]byte(r.Env), &env); err != nil {
+		return "", 0, err
+	}
+	vars := map[string]*ds.VMValue{}
+	for k, data := range env.Vars {
+		v, err := ds.VMValueFromJSON(data)
+		if err != nil {
+			return "", 0, err
+		}
+		vars[k] = v
+	}
+
+	src := FairRollDeriveSource(seed, r.Nonce)
+	for i := int64(0); i < r.Offset; i++ {
+		src.Uint64()
+	}
+	start := *src
+
+	if env.Die {
+		sides, err := strconv.ParseInt(strings.TrimPrefix(r.Expr, "d"), 10, 64)
+		if err != nil {
+			return "", 0, err
+		}
+		val := DiceRoll64x(src, sides)
+		draws, _ := fairRollCountDraws(start, *src)
+		return strconv.FormatInt(val, 10), draws, nil
+	}
+
+	vm := ds.NewVM()
+	vm.RandSrc = src
+	vm.Config.EnableDiceWoD = env.EnableDiceWoD
+	vm.Config.EnableDiceCoC = env.EnableDiceCoC
+	vm.Config.EnableDiceFate = env.EnableDiceFate
+	vm.Config.EnableDiceDoubleCross = env.EnableDiceDoubleCross
+	vm.Config.DisableBitwiseOp = env.DisableBitwiseOp
+	vm.Config.DisableStmts = env.DisableStmts
+	vm.Config.DisableNDice = env.DisableNDice
+	vm.Config.IgnoreDiv0 = env.IgnoreDiv0
+	vm.Config.DiceMinMode = env.DiceMinMode
+	vm.Config.DiceMaxMode = env.DiceMaxMode
+	vm.Config.DefaultDiceSideExpr = env.DefaultDiceSideExpr
+	vm.Config.OpCountLimit = ds.IntType(env.OpCountLimit)
+	vm.Config.HookFuncValueLoadOverwrite = func(c *ds.Context, name string, curVal *ds.VMValue, doCompute func(curVal *ds.VMValue) *ds.VMValue, detail *ds.BufferSpan) *ds.VMValue {
+		if c.Depth() == 0 && c.UpCtx == nil {
+			if v, ok := vars[name]; ok {
+				return v
+			}
+		}
+		return doCompute(curVal)
+	}
+
+	err := vm.Run(r.Expr)
+	draws, _ := fairRollCountDraws(start, *src)
+	return fairRollResultText(vm, err), draws, nil
+}
+
+// FairRollVerifyItem 单条记录的复核结果
+type FairRollVerifyItem struct {
+	Record   *model.FairRollRecord `json:"record"`
+	Replayed string                `json:"replayed"`
+	Draws    int64                 `json:"draws"`
+	OK       bool                  `json:"ok"`
+	Skipped  bool                  `json:"skipped"`
+	Reason   string                `json:"reason,omitempty"`
+}
+
+// FairRollVerifyResult 会话的复核结果
+type FairRollVerifyResult struct {
+	Session      *model.FairRollSession `json:"session"`
+	CommitmentOK bool                   `json:"commitmentOk"`
+	Items        []*FairRollVerifyItem  `json:"items"`
+	Passed       int                    `json:"passed"`
+	Failed       int                    `json:"failed"`
+	Skipped      int                    `json:"skipped"`
+}
+
+// FairRollVerify 复核一个已公开种子的会话中的全部掷骰
+func FairRollVerify(db *sqlx.DB, sessionID int64) (*FairRollVerifyResult, error) {
+	session, err := model.FairRollSessionGet(db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.RevealedAt == 0 {
+		return nil, errFairRollNotRevealed
+	}
+	seed, err := hex.DecodeString(session.Seed)
+	if err != nil {
+		return nil, err
+	}
+	records, err := model.FairRollRecordList(db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &FairRollVerifyResult{
+		Session:      session,
+		CommitmentOK: FairRollCommitment(seed) == session.Commitment,
+	}
+	for _, r := range records {
+		item := &FairRollVerifyItem{Record: r}
+		ret.Items = append(ret.Items, item)
+
+		var env fairRollEnv
+		_ = json.Unmarshal([]byte(r.Env), &env)
+		if env.Nested || env.Unserializable || env.Untraced {
+			item.Skipped = true
+			item.Reason = "求值依赖无法还原的上下文"
+			if env.Untraced {
+				item.Reason = "指令中有未记录的随机数消耗，无法复算"
+			}
+			ret.Skipped++
+			continue
+		}
+
+		item.Replayed, item.Draws, err = FairRollReplay(seed, r)
+		switch {
+		case err != nil:
+			item.Reason = err.Error()
+		case item.Replayed != r.Result:
+			item.Reason = "结果不一致"
+		case item.Draws != r.Draws:
+			item.Reason = "随机数消耗不一致"
+		default:
+			item.OK = true
+		}
+		if item.OK {
+			ret.Passed++
+		} else {
+			ret.Failed++
+		}
+	}
+	return ret, nil
+}
+
+// fairRollReveal 公开群内当前会话的种子，并开启下一个会话
+func fairRollReveal(ctx *MsgContext) (string, error) {
+	db := ctx.Dice.DBData
+	session, err := model.FairRollSessionGetActive(db, ctx.Group.GroupID)
+	if err != nil {
+		return "", err
+	}
+	if err = model.FairRollSessionReveal(db, session.ID); err != nil {
+		return "", err
+	}
+	records, _ := model.FairRollRecordList(db, session.ID)
+	text := fmt.Sprintf("公平骰会话#%d 已结束，公开种子:\n%s\n承诺(sha256): %s\n本轮共记录%d次掷骰，可使用 .fairroll verify %d 复核",
+		session.ID, session.Seed, session.Commitment, len(records), session.ID)
+
+	if ctx.Group.FairRollOn {
+		next, errNew := fairRollNewSession(db, ctx.Group.GroupID)
+		if errNew == nil {
+			text += "\n\n" + fairRollCommitmentText(next)
+		}
+	}
+	return text, nil
+}
+
+// fairRollVerifyText 复核结果的文本形式
+func fairRollVerifyText(ret *FairRollVerifyResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("公平骰会话#%d 复核结果:\n", ret.Session.ID))
+	if ret.CommitmentOK {
+		sb.WriteString("种子与承诺一致\n")
+	} else {
+		sb.WriteString("种子与承诺不一致！\n")
+	}
+	sb.WriteString(fmt.Sprintf("通过%d 失败%d 跳过%d", ret.Passed, ret.Failed, ret.Skipped))
+
+	shown := 0
+	for _, item := range ret.Items {
+		if item.OK || item.Skipped {
+			continue
+		}
+		if shown >= 10 {
+			sb.WriteString("\n……")
+			break
+		}
+		r := item.Record
+		sb.WriteString(fmt.Sprintf("\n#%d-%d %s: 记录%s 复算%s (%s)", r.Nonce, r.Offset, strings.Trim(r.Expr, "\x1e"), r.Result, item.Replayed, item.Reason))
+		shown++
+	}
+	return sb.String()
+}
+
+// fairRollTimeText 会话时间
+func fairRollTimeText(t int64) string {
+	if t == 0 {
+		return "-"
+	}
+	return time.Unix(t, 0).Format("2006-01-02 15:04")
+}
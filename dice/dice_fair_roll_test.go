@@ -0,0 +1,137 @@
+package dice
+
+import (
+	"strconv"
+	"testing"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/model"
+)
+
+func TestFairRollDeriveSource(t *testing.T) {
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	a := FairRollDeriveSource(seed, 1)
+	b := FairRollDeriveSource(seed, 1)
+	c := FairRollDeriveSource(seed, 2)
+	if *a != *b {
+		t.Fatal("相同种子与nonce应得到相同随机流")
+	}
+	if *a == *c {
+		t.Fatal("不同nonce应得到不同随机流")
+	}
+
+	start := *a
+	for i := 0; i < 5; i++ {
+		a.Uint64()
+	}
+	if n, ok := fairRollCountDraws(start, *a); !ok || n != 5 {
+		t.Errorf("随机数消耗计数错误: %d %v", n, ok)
+	}
+}
+
+func TestFairRollVerify(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBData: dataDB, AttrsManager: &AttrsManager{db: dataDB}}
+	group := &GroupInfo{GroupID: "QQ-Group:1", FairRollOn: true}
+	ctx := &MsgContext{
+		Dice:   d,
+		Group:  group,
+		Player: &GroupPlayerInfo{UserID: "QQ:2", ValueMapTemp: &ds.ValueMap{}},
+	}
+	session, err := fairRollNewSession(dataDB, group.GroupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, _ := d.AttrsManager.LoadByCtx(ctx)
+	attrs.Store("力量", ds.NewIntVal(3))
+
+	for _, text := range []string{"3d6+力量", "d20 撬门", "1+1"} {
+		st := ctx.fairRollBegin(nil, &CmdArgs{RawText: ".r " + text})
+		if st == nil {
+			t.Fatal("公平骰未生效")
+		}
+		if _, _, err := DiceExprEvalBase(ctx, text, RollExtraFlags{V2Only: true}); err != nil {
+			t.Fatal(err)
+		}
+		ctx.fairRollEnd(st)
+	}
+
+	records, _ := model.FairRollRecordList(dataDB, session.ID)
+	if len(records) != 2 {
+		t.Fatalf("应记录2次掷骰，实际%d次", len(records))
+	}
+
+	if _, err = FairRollVerify(dataDB, session.ID); err != errFairRollNotRevealed {
+		t.Errorf("公开前不应能复核: %v", err)
+	}
+	_ = model.FairRollSessionReveal(dataDB, session.ID)
+	ret, err := FairRollVerify(dataDB, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ret.CommitmentOK || ret.Passed != 2 || ret.Failed != 0 {
+		t.Errorf("复核结果错误: %+v", ret)
+	}
+
+	// 篡改结果后应无法通过
+	_, _ = dataDB.Exec(`UPDATE fair_roll_record SET result = '99' WHERE id = ?`, records[0].ID)
+	ret, _ = FairRollVerify(dataDB, session.ID)
+	if ret.Failed != 1 {
+		t.Errorf("篡改后的记录应复核失败: %+v", ret)
+	}
+}
+
+func TestFairRollTraceDice(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBData: dataDB, AttrsManager: &AttrsManager{db: dataDB}}
+	group := &GroupInfo{GroupID: "QQ-Group:1", FairRollOn: true}
+	ctx := &MsgContext{
+		Dice:   d,
+		Group:  group,
+		Player: &GroupPlayerInfo{UserID: "QQ:2", ValueMapTemp: &ds.ValueMap{}},
+	}
+	session, err := fairRollNewSession(dataDB, group.GroupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 游戏规则扩展经 diceRoll64 掷骰，每颗骰子都能复算
+	st := ctx.fairRollBegin(nil, &CmdArgs{RawText: ".roll 3"})
+	r, err := fitdRoll(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.fairRollEnd(st)
+
+	// 直接使用随机流的部分无法复算，记为跳过
+	st = ctx.fairRollBegin(nil, &CmdArgs{RawText: ".ww 5"})
+	ctx._v1Rand.Uint64()
+	ctx.fairRollEnd(st)
+
+	records, _ := model.FairRollRecordList(dataDB, session.ID)
+	if len(records) != 4 {
+		t.Fatalf("应记录3颗骰子和1条未记录的消耗，实际%d条", len(records))
+	}
+	for i, v := range r.Dice {
+		if records[i].Expr != "d6" || records[i].Result != strconv.FormatInt(v, 10) {
+			t.Errorf("record %d: %+v, want d6=%d", i, records[i], v)
+		}
+	}
+
+	_ = model.FairRollSessionReveal(dataDB, session.ID)
+	ret, err := FairRollVerify(dataDB, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Passed != 3 || ret.Skipped != 1 || ret.Failed != 0 {
+		t.Errorf("复核结果错误: passed=%d skipped=%d failed=%d", ret.Passed, ret.Skipped, ret.Failed)
+	}
+}
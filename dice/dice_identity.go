@@ -0,0 +1,166 @@
+package dice
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"sealdice-core/dice/model"
+	"sealdice-core/utils"
+)
+
+// 跨平台帐号关联：多个平台的用户ID映射到同一个虚拟ID(U:xxx)，
+// 人物卡、信任与拉黑状态按虚拟ID共享
+
+const (
+	UniformIDPrefix     = "U:"
+	identityLinkCodeTTL = 10 * time.Minute
+)
+
+var (
+	ErrIdentityCodeInvalid  = errors.New("关联码无效或已过期")
+	ErrIdentitySameAccount  = errors.New("不能关联到自己")
+	ErrIdentityLinked       = errors.New("这两个帐号已经关联")
+	ErrIdentityLinkedOthers = errors.New("当前帐号已关联到其他帐号，请先解除关联")
+	ErrIdentityNotLinked    = errors.New("帐号没有关联其他帐号")
+)
+
+type identityLinkCode struct {
+	userID  string
+	expires time.Time
+}
+
+// uniformID 平台用户ID对应的虚拟ID，未关联时返回空字符串
+func (am *AttrsManager) uniformID(userId string) string {
+	if userId == "" || am.db == nil || strings.HasPrefix(userId, UniformIDPrefix) {
+		return ""
+	}
+	if id, ok := am.uidCache.Load(userId); ok {
+		return id
+	}
+	id, err := model.PlatformMappingGet(am.db, userId)
+	if err != nil {
+		return ""
+	}
+	am.uidCache.Store(userId, id)
+	return id
+}
+
+// LinkedIDs 与该帐号关联的全部平台用户ID(含自身)，未关联时返回nil
+func (am *AttrsManager) LinkedIDs(userId string) []string {
+	uid := am.uniformID(userId)
+	if uid == "" {
+		return nil
+	}
+	if ids, ok := am.linkCache.Load(uid); ok {
+		return ids
+	}
+	items, err := model.PlatformMappingListByID(am.db, uid)
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(items))
+	for _, i := range items {
+		ids = append(ids, i.IMUserID)
+	}
+	am.linkCache.Store(uid, ids)
+	return ids
+}
+
+func (am *AttrsManager) resetIdentityCache() {
+	am.uidCache.Range(func(key string, _ string) bool {
+		am.uidCache.Delete(key)
+		return true
+	})
+	am.linkCache.Range(func(key string, _ []string) bool {
+		am.linkCache.Delete(key)
+		return true
+	})
+}
+
+// LinkCodeNew 为帐号生成一次性关联码，同一帐号之前生成的码作废
+func (am *AttrsManager) LinkCodeNew(userId string) string {
+	now := time.Now()
+	am.linkCodes.Range(func(code string, v *identityLinkCode) bool {
+		if v.userID == userId || now.After(v.expires) {
+			am.linkCodes.Delete(code)
+		}
+		return true
+	})
+	code := utils.RandStr(8)
+	am.linkCodes.Store(code, &identityLinkCode{userID: userId, expires: now.Add(identityLinkCodeTTL)})
+	return code
+}
+
+// LinkConfirm 使用关联码将 userId 关联到生成关联码的帐号，返回虚拟ID。
+// 关联时两个帐号名下的数据都转入虚拟ID，同一群内都有数据时以生成关联码的帐号为准
+func (am *AttrsManager) LinkConfirm(code string, userId string) (string, error) {
+	am.linkLock.Lock()
+	defer am.linkLock.Unlock()
+
+	c, ok := am.linkCodes.LoadAndDelete(strings.ToUpper(strings.TrimSpace(code)))
+	if !ok || time.Now().After(c.expires) {
+		return "", ErrIdentityCodeInvalid
+	}
+	if c.userID == userId {
+		return "", ErrIdentitySameAccount
+	}
+	uid := am.uniformID(c.userID)
+	if cur := am.uniformID(userId); cur != "" {
+		if cur == uid {
+			return "", ErrIdentityLinked
+		}
+		return "", ErrIdentityLinkedOthers
+	}
+
+	ids := []string{userId}
+	if uid == "" {
+		uid = UniformIDPrefix + utils.NewID()
+		ids = []string{c.userID, userId}
+	}
+
+	// 先把内存中的数据写入，再迁移
+	am.CheckForSave()
+	for _, id := range ids {
+		if err := model.PlatformMappingSet(am.db, uid, id); err != nil {
+			am.resetIdentityCache()
+			return "", err
+		}
+		if err := model.AttrsMigrateUser(am.db, id, uid); err != nil && am.logger != nil {
+			am.logger.Errorf("关联帐号 %s 时迁移数据失败: %v", id, err)
+		}
+		am.freeUserCache(id)
+		_ = model.PlatformMappingLogAdd(am.db, uid, id, "link", userId)
+	}
+	am.resetIdentityCache()
+	return uid, nil
+}
+
+// Unlink 解除帐号的关联，数据留在虚拟ID下由其余帐号继续使用。operator 为空表示由UI操作
+func (am *AttrsManager) Unlink(userId string, operator string) error {
+	am.linkLock.Lock()
+	defer am.linkLock.Unlock()
+
+	uid := am.uniformID(userId)
+	if uid == "" || len(am.LinkedIDs(userId)) <= 1 {
+		return ErrIdentityNotLinked
+	}
+	am.CheckForSave()
+	if err := model.PlatformMappingDelete(am.db, userId); err != nil {
+		return err
+	}
+	_ = model.PlatformMappingLogAdd(am.db, uid, userId, "unlink", operator)
+	am.freeUserCache(uid)
+	am.resetIdentityCache()
+	return nil
+}
+
+// freeUserCache 移除缓存中属于该用户的数据，避免迁移后旧数据被写回
+func (am *AttrsManager) freeUserCache(userId string) {
+	am.m.Range(func(key string, _ *AttributesItem) bool {
+		if key == userId || strings.HasSuffix(key, "-"+userId) {
+			am.m.Delete(key)
+		}
+		return true
+	})
+}
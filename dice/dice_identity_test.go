@@ -0,0 +1,128 @@
+package dice
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"sealdice-core/dice/model"
+)
+
+func TestIdentityLink(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBData: dataDB, Logger: zap.NewNop().Sugar()}
+	am := &AttrsManager{parent: d, db: dataDB, logger: d.Logger}
+	d.AttrsManager = am
+	d.BanList = &BanListInfo{Parent: d}
+	d.BanList.Init()
+
+	if _, err = am.CharNew("QQ:1", "阿卡", "coc7"); err != nil {
+		t.Fatal(err)
+	}
+	_ = model.AttrsPutById(dataDB, nil, "QQ-Group:9-QQ:1", []byte(`{}`), "", "")
+	_ = model.AttrsPutById(dataDB, nil, "QQ-Group:9-QQ:1-x", []byte(`{}`), "", "")
+
+	code := am.LinkCodeNew("QQ:1")
+	if _, err = am.LinkConfirm(code, "QQ:1"); err == nil {
+		t.Fatal("should not link to itself")
+	}
+	code = am.LinkCodeNew("QQ:1")
+	uid, err := am.LinkConfirm(code, "DISCORD:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = am.LinkConfirm(code, "DISCORD:2"); err != ErrIdentityCodeInvalid {
+		t.Fatal("code should be one-time")
+	}
+
+	if am.UIDConvert("QQ:1") != uid || am.UIDConvert("DISCORD:2") != uid {
+		t.Fatal("both accounts should resolve to the uniform id")
+	}
+	if lst, _ := am.GetCharacterList("DISCORD:2"); len(lst) != 1 || lst[0].Name != "阿卡" {
+		t.Fatalf("characters should be shared, got %v", lst)
+	}
+	if item, _ := model.AttrsGetById(dataDB, "QQ-Group:9-"+uid); !item.IsDataExists() {
+		t.Fatal("group data should be migrated")
+	}
+	if item, _ := model.AttrsGetById(dataDB, "QQ-Group:9-QQ:1-x"); !item.IsDataExists() {
+		t.Fatal("unrelated data should not be touched")
+	}
+	if g, u, ok := UnpackGroupUserId("QQ-Group:9-" + uid); !ok || g != "QQ-Group:9" || u != uid {
+		t.Fatalf("unexpected unpack result %s %s", g, u)
+	}
+
+	d.BanList.Map.Store("QQ:1", &BanListInfoItem{ID: "QQ:1", Rank: BanRankTrusted})
+	if d.BanList.EffectiveRank("DISCORD:2") != BanRankTrusted {
+		t.Fatal("trust should be shared")
+	}
+	if _, ok := d.BanList.GetByID("DISCORD:2"); ok {
+		t.Fatal("GetByID should only return the account's own entry")
+	}
+	d.BanList.Map.Store("DISCORD:2", &BanListInfoItem{ID: "DISCORD:2", Rank: BanRankBanned})
+	if d.BanList.EffectiveRank("QQ:1") != BanRankBanned {
+		t.Fatal("ban should take precedence over trust")
+	}
+
+	if err = am.Unlink("DISCORD:2", ""); err != nil {
+		t.Fatal(err)
+	}
+	if am.UIDConvert("DISCORD:2") != "DISCORD:2" || am.UIDConvert("QQ:1") != uid {
+		t.Fatal("unlink should only affect the given account")
+	}
+	if err = am.Unlink("QQ:1", ""); err != ErrIdentityNotLinked {
+		t.Fatal("the last account should keep the mapping")
+	}
+	_, logs, _ := model.PlatformMappingLogGetPage(dataDB, model.QueryPlatformMappingLog{PageNum: 1, PageSize: 10, ID: uid})
+	if len(logs) != 3 {
+		t.Fatalf("expected 3 audit records, got %d", len(logs))
+	}
+}
+
+func TestIdentityLinkBanCommands(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	d.MasterAdd(FormatDiceIDHTTP("vtt", "gm"))
+
+	send := func(text string) string { return chat.send("gm", "", text) }
+
+	a, b := FormatDiceIDHTTP("vtt", "a"), "QQ:2"
+	am := d.AttrsManager
+	if _, err := am.LinkConfirm(am.LinkCodeNew(b), a); err != nil {
+		t.Fatal(err)
+	}
+	banned := &BanListInfoItem{ID: b, Rank: BanRankBanned, Score: d.BanList.ThresholdBan}
+	d.BanList.Map.Store(b, banned)
+
+	// 对 A 的操作不能改动关联帐号 B 的记录
+	if text := send(".ban rm " + a); !strings.Contains(text, "找不到") {
+		t.Fatalf("A has no entry of its own: %q", text)
+	}
+	if banned.Rank != BanRankBanned {
+		t.Fatal(".ban rm A should not unban B")
+	}
+
+	send(".ban trust " + a)
+	if banned.Rank != BanRankBanned {
+		t.Fatal(".ban trust A should not touch B")
+	}
+	if v, ok := d.BanList.GetByID(a); !ok || v == banned || v.Rank != BanRankTrusted {
+		t.Fatal("A should get its own trusted entry")
+	}
+	if d.BanList.EffectiveRank(a) != BanRankBanned {
+		t.Fatal("ban of the linked account should still take precedence")
+	}
+
+	if text := send(".ban rm " + a); !strings.Contains(text, a) || !strings.Contains(text, "信任") {
+		t.Fatalf("unexpected reply: %q", text)
+	}
+	if v, _ := d.BanList.GetByID(a); v.Rank != BanRankNormal {
+		t.Fatal("A should be removed from the trust list")
+	}
+	if banned.Rank != BanRankBanned {
+		t.Fatal(".ban rm A should not unban B")
+	}
+}
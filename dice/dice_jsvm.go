@@ -32,6 +32,7 @@ import (
 	"gopkg.in/elazarl/goproxy.v1"
 	"gopkg.in/yaml.v3"
 
+	"sealdice-core/message"
 	"sealdice-core/static"
 	"sealdice-core/utils/crypto"
 )
@@ -488,6 +489,8 @@ func (d *Dice) JsInit() {
 		_ = seal.Set("format", DiceFormat)
 		_ = seal.Set("formatTmpl", DiceFormatTmpl)
 		_ = seal.Set("getCtxProxyFirst", GetCtxProxyFirst)
+		// 生成按钮，拼在回复文本中发出，不支持按钮的平台会显示为文字提示
+		_ = seal.Set("button", message.ButtonCQ)
 
 		// 1.2新增
 		_ = seal.Set("newMessage", func() *Message {
@@ -545,6 +548,7 @@ func (d *Dice) JsInit() {
 				},
 			}
 		})
+		// 帐号可通过 ep.can("memberKick") 或 ep.capabilities() 查询平台是否支持某项操作
 		_ = seal.Set("getEndPoints", func() []*EndPointInfo {
 			return d.ImSession.EndPoints
 		})
@@ -777,6 +781,8 @@ func (d *Dice) JsReload() {
 	d.JsInit()
 	_ = d.ConfigManager.Load()
 	d.JsLoadScripts()
+	// 指令可能有增减，同步到支持斜杠指令的平台
+	DiscordSlashCommandsResync(d)
 }
 
 // JsExtSettingVacuum 清理已被删除的脚本对应的插件配置
@@ -3,6 +3,7 @@ package dice
 import (
 	"fmt"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -45,6 +46,13 @@ type DiceManager struct { //nolint:revive
 	AccessTokens   map[string]bool
 	IsReady        bool
 
+	WebUIUsers       []*WebUIUser      // WebUI 用户，主人之外的登录帐号
+	AccessTokenUsers map[string]string // 登录token对应的用户名，不在其中的token属于主人
+	UITOTP           *TOTPConfig       // 主人的两步验证
+	webUIUserLock    sync.RWMutex
+	totpPending      map[string]*totpPending
+	totpFailures     map[string]*totpFailure
+
 	AutoBackupEnable    bool
 	AutoBackupTime      string
 	AutoBackupSelection BackupSelection
@@ -91,6 +99,10 @@ type DiceConfigs struct { //nolint:revive
 	UIPasswordHash string   `yaml:"uiPasswordHash"`
 	AccessTokens   []string `yaml:"accessTokens"`
 
+	WebUIUsers       []*WebUIUser      `yaml:"webUIUsers"`
+	AccessTokenUsers map[string]string `yaml:"accessTokenUsers"`
+	UITOTP           *TOTPConfig       `yaml:"uiTotp,omitempty"`
+
 	AutoBackupEnable    bool   `yaml:"autoBackupEnable"`
 	AutoBackupTime      string `yaml:"autoBackupTime"`
 	AutoBackupSelection uint64 `yaml:"autoBackupSelection"`
@@ -136,6 +148,7 @@ func (dm *DiceManager) LoadDice() {
 	dm.Cron.Start()
 
 	dm.AccessTokens = map[string]bool{}
+	dm.AccessTokenUsers = map[string]string{}
 	if dm.UIPasswordSalt == "" {
 		// 旧版本升级，或新用户
 		dm.UIPasswordSalt = RandStringBytesMaskImprSrcSB2(32)
@@ -185,6 +198,13 @@ func (dm *DiceManager) LoadDice() {
 	for _, i := range dc.AccessTokens {
 		dm.AccessTokens[i] = true
 	}
+	dm.WebUIUsers = dc.WebUIUsers
+	dm.UITOTP = dc.UITOTP
+	for token, name := range dc.AccessTokenUsers {
+		if dm.AccessTokens[token] {
+			dm.AccessTokenUsers[token] = name
+		}
+	}
 
 	for _, i := range dc.DiceConfigs {
 		newDice := new(Dice)
@@ -212,9 +232,14 @@ func (dm *DiceManager) Save() {
 	dc.ServiceName = dm.ServiceName
 	dc.ConfigVersion = 9914
 
+	dm.webUIUserLock.RLock()
 	for k := range dm.AccessTokens {
 		dc.AccessTokens = append(dc.AccessTokens, k)
 	}
+	dc.WebUIUsers = dm.WebUIUsers
+	dc.AccessTokenUsers = dm.AccessTokenUsers
+	dc.UITOTP = dm.UITOTP
+	dm.webUIUserLock.RUnlock()
 
 	for _, i := range dm.Dice {
 		dc.DiceConfigs = append(dc.DiceConfigs, i.BaseConfig)
@@ -0,0 +1,858 @@
+package dice
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// 概率计算，供 .prob 使用
+// 精确计算复用 v1 解析器(rollvm.go)生成的字节码，栈上每一项都是一个概率分布；
+// 分布过大时改为按同样的字节码抽样，遇到不支持的指令则用 v2 反复求值做蒙特卡洛模拟
+
+var (
+	errProbUnsupported  = errors.New("表达式包含无法精确计算的部分")
+	errProbTooExpensive = errors.New("精确计算开销过大")
+)
+
+const (
+	probExactBudget = 30000000 // 精确计算的算力上限
+	probMaxSpan     = 1000000  // 单个分布允许的最大取值跨度
+	probMCPerTimes  = 1000     // 蒙特卡洛次数为 MaxExecuteTime 的倍数
+	probMCTimeLimit = 3 * time.Second
+
+	probDefaultExplode = 3 // 爆炸骰默认最多追加的次数
+)
+
+// ProbDist 整数取值的概率分布，P[i] 为取值 Min+i*Step 的概率。
+// Step 仅在抽样结果跨度过大时大于1，此时每一项代表 [Min+i*Step, Min+(i+1)*Step) 内的取值
+type ProbDist struct {
+	Min  int64
+	Step int64
+	P    []float64
+}
+
+func probConst(v int64) *ProbDist {
+	return &ProbDist{Min: v, P: []float64{1}}
+}
+
+// probUniform 均匀分布 [lo, hi]，调用前应确保跨度不超过 probMaxSpan
+func probUniform(lo, hi int64) *ProbDist {
+	n := hi - lo + 1
+	p := make([]float64, n)
+	for i := range p {
+		p[i] = 1 / float64(n)
+	}
+	return &ProbDist{Min: lo, P: p}
+}
+
+func probFromMap(m map[int64]float64) (*ProbDist, error) {
+	if len(m) == 0 {
+		return probConst(0), nil
+	}
+	first := true
+	var lo, hi int64
+	for k := range m {
+		if first || k < lo {
+			lo = k
+		}
+		if first || k > hi {
+			hi = k
+		}
+		first = false
+	}
+	if hi-lo >= probMaxSpan {
+		return nil, errProbTooExpensive
+	}
+	d := &ProbDist{Min: lo, P: make([]float64, hi-lo+1)}
+	for k, v := range m {
+		d.P[k-lo] += v
+	}
+	return d, nil
+}
+
+// ProbDistFromSamples 由抽样结果构造近似分布
+func ProbDistFromSamples(samples []int64) *ProbDist {
+	m := map[int64]float64{}
+	for _, i := range samples {
+		m[i] += 1 / float64(len(samples))
+	}
+	d, err := probFromMap(m)
+	if err != nil {
+		// 跨度过大时按 Step 分桶，每桶以下界作为取值，统计量的误差不超过一个 Step
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		lo, hi := samples[0], samples[len(samples)-1]
+		step := (hi-lo)/probMaxSpan + 1
+		d = &ProbDist{Min: lo, Step: step, P: make([]float64, (hi-lo)/step+1)}
+		for _, i := range samples {
+			d.P[(i-lo)/step] += 1 / float64(len(samples))
+		}
+	}
+	return d
+}
+
+// value 第i项对应的取值
+func (d *ProbDist) value(i int) int64 {
+	if d.Step > 1 {
+		return d.Min + int64(i)*d.Step
+	}
+	return d.Min + int64(i)
+}
+
+func (d *ProbDist) Max() int64 {
+	return d.value(len(d.P) - 1)
+}
+
+func (d *ProbDist) IsConst() (int64, bool) {
+	if len(d.P) == 1 {
+		return d.Min, true
+	}
+	return 0, false
+}
+
+func (d *ProbDist) Each(f func(v int64, p float64)) {
+	for i, p := range d.P {
+		if p > 0 {
+			f(d.value(i), p)
+		}
+	}
+}
+
+func (d *ProbDist) Mean() float64 {
+	var sum float64
+	d.Each(func(v int64, p float64) {
+		sum += float64(v) * p
+	})
+	return sum
+}
+
+func (d *ProbDist) StdDev() float64 {
+	mean := d.Mean()
+	var sum float64
+	d.Each(func(v int64, p float64) {
+		sum += (float64(v) - mean) * (float64(v) - mean) * p
+	})
+	return math.Sqrt(sum)
+}
+
+// Percentile 返回累积概率首次达到q的取值
+func (d *ProbDist) Percentile(q float64) int64 {
+	var acc float64
+	for i, p := range d.P {
+		acc += p
+		if acc >= q-1e-12 {
+			return d.value(i)
+		}
+	}
+	return d.Max()
+}
+
+func (d *ProbDist) ChanceGE(t int64) float64 {
+	var sum float64
+	d.Each(func(v int64, p float64) {
+		if v >= t {
+			sum += p
+		}
+	})
+	return sum
+}
+
+func (d *ProbDist) ChanceLE(t int64) float64 {
+	var sum float64
+	d.Each(func(v int64, p float64) {
+		if v <= t {
+			sum += p
+		}
+	})
+	return sum
+}
+
+// trim 去掉两端概率为0的取值
+func (d *ProbDist) trim() *ProbDist {
+	lo, hi := 0, len(d.P)-1
+	for lo < hi && d.P[lo] == 0 {
+		lo++
+	}
+	for hi > lo && d.P[hi] == 0 {
+		hi--
+	}
+	return &ProbDist{Min: d.value(lo), Step: d.Step, P: d.P[lo : hi+1]}
+}
+
+type probEvaluator struct {
+	ctx          *MsgContext
+	DefaultSides int64
+	Explode      int64 // 爆炸骰最多追加的次数，0为不爆炸
+	Sample       bool  // 抽样模式，骰子直接取随机值
+	cost         int64
+}
+
+func (e *probEvaluator) charge(n int64) error {
+	if e.Sample {
+		return nil
+	}
+	e.cost += n
+	if e.cost > probExactBudget {
+		return errProbTooExpensive
+	}
+	return nil
+}
+
+func (e *probEvaluator) combine(a, b *ProbDist, f func(x, y int64) (int64, error)) (*ProbDist, error) {
+	if err := e.charge(int64(len(a.P)) * int64(len(b.P))); err != nil {
+		return nil, err
+	}
+	m := map[int64]float64{}
+	var err error
+	a.Each(func(x int64, px float64) {
+		b.Each(func(y int64, py float64) {
+			if err != nil {
+				return
+			}
+			var v int64
+			v, err = f(x, y)
+			m[v] += px * py
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return probFromMap(m)
+}
+
+// convolve 两个独立分布之和
+func (e *probEvaluator) convolve(a, b *ProbDist) (*ProbDist, error) {
+	if err := e.charge(int64(len(a.P)) * int64(len(b.P))); err != nil {
+		return nil, err
+	}
+	if int64(len(a.P)+len(b.P)) > probMaxSpan {
+		return nil, errProbTooExpensive
+	}
+	ret := &ProbDist{Min: a.Min + b.Min, P: make([]float64, len(a.P)+len(b.P)-1)}
+	for i, pa := range a.P {
+		if pa == 0 {
+			continue
+		}
+		for j, pb := range b.P {
+			ret.P[i+j] += pa * pb
+		}
+	}
+	return ret, nil
+}
+
+// singleDie 单颗骰子的分布，开启爆炸骰时掷出最大值会追加一次。
+// 面数过大时不分配分布，交由抽样计算
+func (e *probEvaluator) singleDie(sides int64) (*ProbDist, error) {
+	if sides >= probMaxSpan/(e.Explode+1) {
+		return nil, errProbTooExpensive
+	}
+	d := probUniform(1, sides)
+	if sides <= 1 {
+		return d, nil
+	}
+	for i := int64(0); i < e.Explode; i++ {
+		next := &ProbDist{Min: 1, P: make([]float64, sides+int64(len(d.P)))}
+		for v := int64(1); v < sides; v++ {
+			next.P[v-1] = 1 / float64(sides)
+		}
+		for j, p := range d.P {
+			next.P[sides+d.Min+int64(j)-1] += p / float64(sides)
+		}
+		d = next.trim()
+	}
+	return d, nil
+}
+
+func (e *probEvaluator) sampleDie(sides int64) int64 {
+	var sum int64
+	for i := int64(0); i <= e.Explode; i++ {
+		v := DiceRoll64x(e.ctx._v1Rand, sides)
+		sum += v
+		if v != sides || sides <= 1 {
+			break
+		}
+	}
+	return sum
+}
+
+// dice 计算 NdM 的分布，keep>0 时只取最高(keepHigh)或最低的keep颗
+func (e *probEvaluator) dice(n, sides, keep int64, keepHigh bool) (*ProbDist, error) {
+	if sides == 0 {
+		sides = e.DefaultSides
+	}
+	if sides <= 0 {
+		return nil, errors.New("骰子面数必须为正数")
+	}
+	if n <= 0 {
+		return probConst(0), nil
+	}
+	if n > 30000 {
+		return nil, errors.New("E5: 超出单指令允许算力，不予计算")
+	}
+	if keep <= 0 || keep > n {
+		keep = n
+	}
+
+	if e.Sample {
+		nums := make([]int64, n)
+		for i := range nums {
+			nums[i] = e.sampleDie(sides)
+		}
+		if keep < n {
+			sort.Slice(nums, func(i, j int) bool {
+				if keepHigh {
+					return nums[i] > nums[j]
+				}
+				return nums[i] < nums[j]
+			})
+		}
+		var sum int64
+		for _, i := range nums[:keep] {
+			sum += i
+		}
+		return probConst(sum), nil
+	}
+
+	die, err := e.singleDie(sides)
+	if err != nil {
+		return nil, err
+	}
+	if keep == n {
+		ret := probConst(0)
+		for i := int64(0); i < n; i++ {
+			ret, err = e.convolve(ret, die)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return ret, nil
+	}
+	return e.keepDice(die, n, keep, keepHigh)
+}
+
+// keepDice 按点数从高到低(或从低到高)依次决定有几颗骰子落在该点数上，
+// 状态为(已分配骰数, 已取点数和)，概率按多项式系数累加
+func (e *probEvaluator) keepDice(die *ProbDist, n, keep int64, keepHigh bool) (*ProbDist, error) {
+	var values []int64
+	var probs []float64
+	die.Each(func(v int64, p float64) {
+		values = append(values, v)
+		probs = append(probs, p)
+	})
+	if keepHigh {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+			probs[i], probs[j] = probs[j], probs[i]
+		}
+	}
+
+	span := keep*(die.Max()-die.Min) + 1
+	if err := e.charge(int64(len(values)) * n * n * span); err != nil {
+		return nil, err
+	}
+
+	// binom[i][j] = C(i, j)
+	binom := make([][]float64, n+1)
+	for i := range binom {
+		binom[i] = make([]float64, i+1)
+		binom[i][0], binom[i][i] = 1, 1
+		for j := 1; j < i; j++ {
+			binom[i][j] = binom[i-1][j-1] + binom[i-1][j]
+		}
+	}
+
+	// 状态: 已分配的骰子数与已取的点数和
+	type state struct {
+		a, sum int64
+	}
+	states := map[state]float64{{0, 0}: 1}
+	for idx, v := range values {
+		p := probs[idx]
+		next := map[state]float64{}
+		for st, sp := range states {
+			rem := n - st.a
+			pk := 1.0
+			for c := int64(0); c <= rem; c++ {
+				taken := st.a
+				if taken > keep {
+					taken = keep
+				}
+				add := c
+				if taken+add > keep {
+					add = keep - taken
+				}
+				next[state{st.a + c, st.sum + add*v}] += sp * binom[rem][c] * pk
+				pk *= p
+			}
+		}
+		states = next
+	}
+
+	m := map[int64]float64{}
+	for st, sp := range states {
+		if st.a == n {
+			m[st.sum] += sp
+		}
+	}
+	return probFromMap(m)
+}
+
+// cocBonusDice 奖励骰/惩罚骰的分布，规则与 v1 的 dice.bonus/dice.penalty 一致
+func (e *probEvaluator) cocBonusDice(n int64, isBonus bool) (*ProbDist, error) {
+	if n < 0 {
+		n = 0
+	}
+	if n > 30000 {
+		return nil, errors.New("E5: 超出单指令允许算力，不予计算")
+	}
+	calc := func(diceResult, lo, hi int64, has10 bool) int64 {
+		diceTens := diceResult / 10
+		diceUnits := diceResult % 10
+		if isBonus {
+			diceMin := diceTens
+			if lo < diceMin {
+				diceMin = lo
+			}
+			if diceUnits != 0 && has10 {
+				diceMin = 0
+			}
+			return diceMin*10 + diceUnits
+		}
+		diceMax := diceTens
+		if hi > diceMax {
+			diceMax = hi
+		}
+		if diceUnits == 0 && has10 {
+			diceMax = 10
+		}
+		return diceMax*10 + diceUnits
+	}
+
+	if e.Sample {
+		diceResult := DiceRoll64x(e.ctx._v1Rand, 100)
+		lo, hi, has10 := int64(10), int64(0), false
+		for i := int64(0); i < n; i++ {
+			v := DiceRoll64x(e.ctx._v1Rand, 10)
+			if v == 10 {
+				has10 = true
+				continue
+			}
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		return probConst(calc(diceResult, lo, hi, has10)), nil
+	}
+
+	if err := e.charge(n * 200 * 10); err != nil {
+		return nil, err
+	}
+	// 额外十位骰的状态: 非0十位的最小值(无则为10)、最大值(无则为0)、是否出现0
+	type state struct {
+		lo, hi int64
+		has10  bool
+	}
+	states := map[state]float64{{10, 0, false}: 1}
+	for i := int64(0); i < n; i++ {
+		next := map[state]float64{}
+		for st, p := range states {
+			for v := int64(1); v <= 10; v++ {
+				ns := st
+				if v == 10 {
+					ns.has10 = true
+				} else {
+					if v < ns.lo {
+						ns.lo = v
+					}
+					if v > ns.hi {
+						ns.hi = v
+					}
+				}
+				next[ns] += p / 10
+			}
+		}
+		states = next
+	}
+
+	m := map[int64]float64{}
+	for diceResult := int64(1); diceResult <= 100; diceResult++ {
+		for st, p := range states {
+			m[calc(diceResult, st.lo, st.hi, st.has10)] += p / 100
+		}
+	}
+	return probFromMap(m)
+}
+
+func (e *probEvaluator) fateDice() (*ProbDist, error) {
+	if e.Sample {
+		var sum int64
+		for i := 0; i < 4; i++ {
+			sum += DiceRoll64x(e.ctx._v1Rand, 3) - 2
+		}
+		return probConst(sum), nil
+	}
+	ret := probConst(0)
+	for i := 0; i < 4; i++ {
+		var err error
+		ret, err = e.convolve(ret, probUniform(-1, 1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (e *probEvaluator) loadVar(name string) (*ProbDist, error) {
+	name = strings.TrimLeft(name, "_")
+	if e.ctx == nil {
+		return nil, errProbUnsupported
+	}
+	r := e.ctx.Eval(name, nil)
+	if r.vm.Error != nil {
+		return nil, errProbUnsupported
+	}
+	if r.TypeId == ds.VMTypeNull {
+		return probConst(0), nil
+	}
+	v, ok := r.ReadInt()
+	if !ok {
+		return nil, errProbUnsupported
+	}
+	return probConst(int64(v)), nil
+}
+
+// Run 计算字节码的分布
+func (e *probEvaluator) Run(codes []ByteCode) (*ProbDist, error) {
+	var stack []*ProbDist
+	var keepReg *ProbDist
+	var keepHigh bool
+	var keepFlag int64
+
+	pop := func() *ProbDist {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+
+	for _, code := range codes {
+		switch code.T {
+		case TypeLeftValueMark, TypeHalt, TypeNop:
+			continue
+		case TypePushNumber:
+			stack = append(stack, probConst(code.Value))
+			continue
+		case TypeLoadVarname, TypeLoadVarnameForThis:
+			v, err := e.loadVar(code.ValueStr)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+			continue
+		}
+
+		if len(stack) == 0 {
+			return nil, errProbUnsupported
+		}
+
+		// 单目运算
+		switch code.T {
+		case TypePop:
+			pop()
+			continue
+		case TypeDiceSetK, TypeDiceSetQ:
+			keepReg = pop()
+			keepHigh = code.T == TypeDiceSetK
+			keepFlag = code.Value
+			continue
+		case TypeNegation:
+			a := pop()
+			ret := &ProbDist{Min: -a.Max(), P: make([]float64, len(a.P))}
+			for i, p := range a.P {
+				ret.P[len(a.P)-1-i] = p
+			}
+			stack = append(stack, ret)
+			continue
+		case TypeDiceUnary:
+			a := pop()
+			ret, err := e.mixture(a, probConst(1), func(x, _ int64) (*ProbDist, error) {
+				return e.dice(1, x, 0, true)
+			})
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, ret)
+			continue
+		case TypeDiceBonus, TypeDicePenalty:
+			a := pop()
+			ret, err := e.mixture(a, probConst(1), func(x, _ int64) (*ProbDist, error) {
+				return e.cocBonusDice(x, code.T == TypeDiceBonus)
+			})
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, ret)
+			continue
+		}
+
+		if len(stack) < 2 {
+			return nil, errProbUnsupported
+		}
+		b := pop()
+		a := pop()
+
+		var ret *ProbDist
+		var err error
+		boolToInt64 := func(val bool) int64 {
+			if val {
+				return 1
+			}
+			return 0
+		}
+
+		switch code.T {
+		case TypeAdd:
+			ret, err = e.convolve(a, b)
+		case TypeSubtract:
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) { return x - y, nil })
+		case TypeMultiply:
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) { return x * y, nil })
+		case TypeDivide, TypeModulus:
+			isDiv := code.T == TypeDivide
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) {
+				if y == 0 {
+					return 0, errors.New("E2:被除数为0")
+				}
+				if isDiv {
+					return x / y, nil
+				}
+				return x % y, nil
+			})
+		case TypeExponentiation:
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) {
+				return int64(math.Pow(float64(x), float64(y))), nil
+			})
+		case TypeCompLT, TypeCompLE, TypeCompEQ, TypeCompNE, TypeCompGE, TypeCompGT:
+			t := code.T
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) {
+				switch t {
+				case TypeCompLT:
+					return boolToInt64(x < y), nil
+				case TypeCompLE:
+					return boolToInt64(x <= y), nil
+				case TypeCompEQ:
+					return boolToInt64(x == y), nil
+				case TypeCompNE:
+					return boolToInt64(x != y), nil
+				case TypeCompGE:
+					return boolToInt64(x >= y), nil
+				default:
+					return boolToInt64(x > y), nil
+				}
+			})
+		case TypeBitwiseAnd:
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) { return x & y, nil })
+		case TypeBitwiseOr:
+			ret, err = e.combine(a, b, func(x, y int64) (int64, error) { return x | y, nil })
+		case TypeDiceFate:
+			ret, err = e.fateDice()
+		case TypeDice:
+			var keep int64
+			if keepReg != nil {
+				k, ok := keepReg.IsConst()
+				if !ok {
+					return nil, errProbUnsupported
+				}
+				keep = k
+			}
+			flag, high := keepFlag, keepHigh
+			ret, err = e.mixture(a, b, func(n, sides int64) (*ProbDist, error) {
+				if keepReg == nil {
+					return e.dice(n, sides, 0, true)
+				}
+				k := keep
+				if flag == 1 {
+					// dl/dh 语法，丢弃k颗
+					k = n - keep
+					if k <= 0 {
+						return probConst(0), nil
+					}
+				}
+				return e.dice(n, sides, k, high)
+			})
+			keepReg = nil
+		default:
+			return nil, errProbUnsupported
+		}
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, ret)
+	}
+
+	if len(stack) == 0 {
+		return nil, errProbUnsupported
+	}
+	return stack[len(stack)-1].trim(), nil
+}
+
+// mixture 参数本身是分布时(如 (1d4)d6)，按参数的每种取值加权合并
+func (e *probEvaluator) mixture(a, b *ProbDist, f func(x, y int64) (*ProbDist, error)) (*ProbDist, error) {
+	if x, ok := a.IsConst(); ok {
+		if y, ok := b.IsConst(); ok {
+			return f(x, y)
+		}
+	}
+	m := map[int64]float64{}
+	var err error
+	a.Each(func(x int64, px float64) {
+		b.Each(func(y int64, py float64) {
+			if err != nil {
+				return
+			}
+			var d *ProbDist
+			d, err = f(x, y)
+			if err != nil {
+				return
+			}
+			d.Each(func(v int64, p float64) {
+				m[v] += p * px * py
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return probFromMap(m)
+}
+
+// probTargetRe .prob 指令中的目标，如 vs 15、vs <=侦查
+var probTargetRe = regexp.MustCompile(`(?i)\s+(?:vs|对抗)\s*(<=|>=|<|>)?\s*(.+)$`)
+
+// probExplodeRe 爆炸骰写法，如 3d6!
+var probExplodeRe = regexp.MustCompile(`(?i)(d\d*)!([^=]|$)`)
+
+var probKeepSyntaxRe = regexp.MustCompile(`(?i)(\d)(kh|kl)(\d*)`)
+
+// probParse 使用 v1 解析器解析表达式，返回字节码与匹配的文本
+func (d *Dice) probParse(text string) ([]ByteCode, string, string, error) {
+	// v1 不认识 kh/kl 写法，转为等价的 k/q
+	expr := probKeepSyntaxRe.ReplaceAllStringFunc(text, func(s string) string {
+		m := probKeepSyntaxRe.FindStringSubmatch(s)
+		op := "k"
+		if strings.EqualFold(m[2], "kl") {
+			op = "q"
+		}
+		num := m[3]
+		if num == "" {
+			num = "1"
+		}
+		return m[1] + op + num
+	})
+
+	parser := d.rebuildParser(expr)
+	if err := parser.Parse(); err != nil {
+		return nil, "", "", err
+	}
+	parser.Execute()
+	if parser.Error != nil {
+		return nil, "", "", parser.Error
+	}
+
+	tks := parser.Tokens()
+	if len(tks) == 0 {
+		return nil, "", "", errors.New("无法解析表达式")
+	}
+	runeBuffer := []rune(expr)
+	lastToken := tks[len(tks)-1]
+	rest := strings.TrimSpace(string(runeBuffer[lastToken.end:]))
+	matched := strings.TrimSpace(strings.TrimSuffix(text, rest))
+	return parser.Code[:parser.Top], matched, rest, nil
+}
+
+type ProbResult struct {
+	Dist    *ProbDist
+	Matched string
+	Rest    string
+	Method  string // exact | sample | mc
+	Trials  int
+}
+
+// ProbCalculate 计算表达式的分布，explode 为爆炸骰追加次数上限
+func ProbCalculate(ctx *MsgContext, expr string, explode int64) (*ProbResult, error) {
+	// 模拟计算不应消耗公平骰的随机流，也不计入骰点统计
+	defer ctx.fairRollPause()()
+	defer ctx.randSourcePause()()
+	defer ctx.diceStatPause()()
+
+	if probExplodeRe.MatchString(expr) {
+		expr = probExplodeRe.ReplaceAllString(expr, "$1$2")
+		if explode == 0 {
+			explode = probDefaultExplode
+		}
+	}
+	trials := int(ctx.Dice.MaxExecuteTime) * probMCPerTimes
+	if trials <= 0 {
+		trials = 12 * probMCPerTimes
+	}
+	deadline := time.Now().Add(probMCTimeLimit)
+
+	codes, matched, rest, err := ctx.Dice.probParse(expr)
+	if err == nil && len(codes) > 0 {
+		e := &probEvaluator{ctx: ctx, DefaultSides: getDefaultDicePoints(ctx), Explode: explode}
+		dist, err := e.Run(codes)
+		if err == nil {
+			return &ProbResult{Dist: dist, Matched: matched, Rest: rest, Method: "exact"}, nil
+		}
+		if errors.Is(err, errProbTooExpensive) {
+			e.Sample = true
+			var samples []int64
+			for i := 0; i < trials && time.Now().Before(deadline); i++ {
+				d, err := e.Run(codes)
+				if err != nil {
+					return nil, err
+				}
+				samples = append(samples, d.Min)
+			}
+			return &ProbResult{Dist: ProbDistFromSamples(samples), Matched: matched, Rest: rest, Method: "sample", Trials: len(samples)}, nil
+		}
+		if !errors.Is(err, errProbUnsupported) {
+			return nil, err
+		}
+	}
+
+	if explode > 0 {
+		return nil, errors.New("该表达式无法使用爆炸骰")
+	}
+
+	// v1 无法处理的表达式，使用 v2 反复求值
+	var samples []int64
+	for i := 0; i < trials && time.Now().Before(deadline); i++ {
+		r, _, err := DiceExprEvalBase(ctx, expr, RollExtraFlags{
+			DefaultDiceSideNum: getDefaultDicePoints(ctx),
+			DisableBlock:       true,
+			V2Only:             true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if r.TypeId != ds.VMTypeInt {
+			return nil, errors.New("表达式的结果不是整数")
+		}
+		if i == 0 {
+			matched, rest = r.GetMatched(), r.GetRestInput()
+		}
+		samples = append(samples, int64(r.MustReadInt()))
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("无法解析表达式")
+	}
+	return &ProbResult{Dist: ProbDistFromSamples(samples), Matched: matched, Rest: rest, Method: "mc", Trials: len(samples)}, nil
+}
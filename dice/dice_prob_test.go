@@ -0,0 +1,168 @@
+package dice
+
+import (
+	"math"
+	"testing"
+)
+
+func probRunExpr(t *testing.T, expr string, explode int64) *ProbDist {
+	t.Helper()
+	d := &Dice{}
+	codes, _, _, err := d.probParse(expr)
+	if err != nil {
+		t.Fatalf("%s: 解析失败 %v", expr, err)
+	}
+	e := &probEvaluator{DefaultSides: 100, Explode: explode}
+	dist, err := e.Run(codes)
+	if err != nil {
+		t.Fatalf("%s: 计算失败 %v", expr, err)
+	}
+	var sum float64
+	dist.Each(func(_ int64, p float64) { sum += p })
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("%s: 概率之和为 %v", expr, sum)
+	}
+	return dist
+}
+
+func probAlmostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestProbBasicDice(t *testing.T) {
+	dist := probRunExpr(t, "3d6", 0)
+	if dist.Min != 3 || dist.Max() != 18 {
+		t.Errorf("3d6 范围错误: %d ~ %d", dist.Min, dist.Max())
+	}
+	if !probAlmostEqual(dist.Mean(), 10.5) {
+		t.Errorf("3d6 期望错误: %v", dist.Mean())
+	}
+	if !probAlmostEqual(dist.ChanceGE(15), 20.0/216) {
+		t.Errorf("3d6>=15 概率错误: %v", dist.ChanceGE(15))
+	}
+	if dist.Percentile(0.5) != 10 {
+		t.Errorf("3d6 中位数错误: %d", dist.Percentile(0.5))
+	}
+
+	dist = probRunExpr(t, "d", 0)
+	if dist.Min != 1 || dist.Max() != 100 {
+		t.Errorf("默认骰范围错误: %d ~ %d", dist.Min, dist.Max())
+	}
+
+	dist = probRunExpr(t, "2d6*2-1", 0)
+	if dist.Min != 3 || dist.Max() != 23 || !probAlmostEqual(dist.Mean(), 13) {
+		t.Errorf("2d6*2-1 错误: %d ~ %d, %v", dist.Min, dist.Max(), dist.Mean())
+	}
+
+	dist = probRunExpr(t, "(1d4)d6", 0)
+	if !probAlmostEqual(dist.Mean(), 2.5*3.5) {
+		t.Errorf("(1d4)d6 期望错误: %v", dist.Mean())
+	}
+}
+
+func TestProbKeepDice(t *testing.T) {
+	// 4d6取高3，期望为 15869/1296
+	dist := probRunExpr(t, "4d6k3", 0)
+	if !probAlmostEqual(dist.Mean(), 15869.0/1296) {
+		t.Errorf("4d6k3 期望错误: %v", dist.Mean())
+	}
+	dist = probRunExpr(t, "4d6dl1", 0)
+	if !probAlmostEqual(dist.Mean(), 15869.0/1296) {
+		t.Errorf("4d6dl1 期望错误: %v", dist.Mean())
+	}
+
+	dist = probRunExpr(t, "2d20kh", 0)
+	if !probAlmostEqual(dist.ChanceGE(20), 1-(19.0/20)*(19.0/20)) {
+		t.Errorf("2d20kh 大成功概率错误: %v", dist.ChanceGE(20))
+	}
+	dist = probRunExpr(t, "2d20kl", 0)
+	if !probAlmostEqual(dist.ChanceGE(20), 1.0/400) {
+		t.Errorf("2d20kl 大成功概率错误: %v", dist.ChanceGE(20))
+	}
+}
+
+func TestProbExplodeAndBonus(t *testing.T) {
+	// 1d6 最多爆炸一次: 3.5 + 3.5/6
+	dist := probRunExpr(t, "1d6", 1)
+	if dist.Max() != 12 || !probAlmostEqual(dist.Mean(), 3.5+3.5/6) {
+		t.Errorf("1d6爆炸 错误: %d, %v", dist.Max(), dist.Mean())
+	}
+
+	dist = probRunExpr(t, "b1", 0)
+	if dist.Min != 1 || dist.Max() != 100 || dist.Mean() >= 50.5 {
+		t.Errorf("b1 错误: %d ~ %d, %v", dist.Min, dist.Max(), dist.Mean())
+	}
+	bonus := dist.ChanceLE(50)
+
+	dist = probRunExpr(t, "p1", 0)
+	if dist.Mean() <= 50.5 {
+		t.Errorf("p1 期望错误: %v", dist.Mean())
+	}
+	if dist.ChanceLE(50) >= bonus {
+		t.Errorf("p1 成功率应低于 b1: %v %v", dist.ChanceLE(50), bonus)
+	}
+
+	dist = probRunExpr(t, "f", 0)
+	if dist.Min != -4 || dist.Max() != 4 || !probAlmostEqual(dist.ChanceGE(4), 1.0/81) {
+		t.Errorf("4dF 错误: %d ~ %d, %v", dist.Min, dist.Max(), dist.ChanceGE(4))
+	}
+}
+
+func TestProbTooExpensive(t *testing.T) {
+	d := &Dice{}
+	codes, _, _, err := d.probParse("500d100k250")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &probEvaluator{DefaultSides: 100}
+	if _, err := e.Run(codes); err != errProbTooExpensive {
+		t.Errorf("应当超出精确计算开销: %v", err)
+	}
+
+	// 面数过大时不分配分布，直接交给抽样
+	codes, _, _, err = d.probParse("1d2000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Run(codes); err != errProbTooExpensive {
+		t.Errorf("大面数骰子应当超出精确计算开销: %v", err)
+	}
+}
+
+func TestProbDistFromSamplesWideSpan(t *testing.T) {
+	samples := []int64{0, 2500000, 5000000, 7500000, 10000000}
+	dist := ProbDistFromSamples(samples)
+	if dist.Step <= 1 {
+		t.Fatalf("wide samples should be bucketed, step=%d", dist.Step)
+	}
+	if dist.Min != 0 || dist.Max() < 10000000-dist.Step || dist.Max() > 10000000 {
+		t.Errorf("range %d ~ %d", dist.Min, dist.Max())
+	}
+	if math.Abs(dist.Mean()-5000000) > float64(dist.Step) {
+		t.Errorf("mean %v", dist.Mean())
+	}
+	if v := dist.Percentile(0.5); v < 5000000-dist.Step || v > 5000000 {
+		t.Errorf("median %d", v)
+	}
+	if !probAlmostEqual(dist.ChanceGE(7500000-dist.Step+1), 0.4) {
+		t.Errorf("chance %v", dist.ChanceGE(7500000-dist.Step+1))
+	}
+}
+
+func TestProbCalculateHugeDie(t *testing.T) {
+	ctx := newTestCtx(t)
+	ret, err := ProbCalculate(ctx, "1d10000000", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Method == "exact" {
+		t.Fatal("a huge die should fall back to sampling")
+	}
+	dist := ret.Dist
+	if mean := dist.Mean(); mean < 4500000 || mean > 5500000 {
+		t.Errorf("mean %v, want about 5e6", mean)
+	}
+	if dist.Max() < 9000000 || dist.Max() > 10000000 {
+		t.Errorf("max %d, want about 1e7", dist.Max())
+	}
+}
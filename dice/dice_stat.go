@@ -0,0 +1,368 @@
+package dice
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/model"
+)
+
+// 骰点统计：开启后记录每次掷骰的点数，按面数、群、平台分桶，
+// 定期落库，并可对记录做卡方检验(均匀性)和游程检验(独立性)。
+
+const (
+	diceStatMaxSides     = 1000 // 面数过大的骰子无法做有效的卡方检验，不记录
+	diceStatSignificance = 0.01 // 判定为异常的显著性水平
+)
+
+type diceStatKey struct {
+	Sides    int64
+	GroupID  string
+	Platform string
+}
+
+type diceStatBucket struct {
+	Faces map[int64]int64
+	Runs  model.DiceStatRunsDelta
+}
+
+// DiceStatRecorder 骰点统计的内存缓冲
+type DiceStatRecorder struct {
+	lock    sync.Mutex
+	pending map[diceStatKey]*diceStatBucket
+}
+
+// Record 记录一个点数
+func (r *DiceStatRecorder) Record(ctx *MsgContext, sides, face int64) {
+	if r == nil || sides < 2 || sides > diceStatMaxSides || face < 1 || face > sides {
+		return
+	}
+	key := diceStatKey{Sides: sides}
+	if ctx != nil {
+		if ctx.Group != nil && !ctx.IsPrivate {
+			key.GroupID = ctx.Group.GroupID
+		}
+		if ctx.EndPoint != nil {
+			key.Platform = ctx.EndPoint.Platform
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.pending == nil {
+		r.pending = map[diceStatKey]*diceStatBucket{}
+	}
+	b := r.pending[key]
+	if b == nil {
+		b = &diceStatBucket{Faces: map[int64]int64{}}
+		r.pending[key] = b
+	}
+	b.Faces[face]++
+
+	// 以中位数为界，奇数面的中间值不参与游程检验
+	var sign int64
+	switch {
+	case face*2 > sides+1:
+		sign = 1
+		b.Runs.NHigh++
+	case face*2 < sides+1:
+		sign = -1
+		b.Runs.NLow++
+	default:
+		return
+	}
+	if b.Runs.FirstSign == 0 {
+		b.Runs.FirstSign = sign
+	}
+	if sign != b.Runs.LastSign {
+		b.Runs.Runs++
+	}
+	b.Runs.LastSign = sign
+}
+
+// Flush 将缓冲写入数据库
+func (r *DiceStatRecorder) Flush(db *sqlx.DB) error {
+	if r == nil || db == nil {
+		return nil
+	}
+	r.lock.Lock()
+	pending := r.pending
+	r.pending = nil
+	r.lock.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	for k, b := range pending {
+		for face, count := range b.Faces {
+			if err = model.DiceStatFaceAdd(tx, k.Sides, face, k.GroupID, k.Platform, count); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		if b.Runs.Runs > 0 {
+			if err = model.DiceStatRunsAdd(tx, k.Sides, k.GroupID, k.Platform, &b.Runs); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// diceStatPause 暂停骰点统计，返回恢复函数
+func (ctx *MsgContext) diceStatPause() func() {
+	old := ctx.statPaused
+	ctx.statPaused = true
+	return func() {
+		ctx.statPaused = old
+	}
+}
+
+// diceRoll64 掷一个骰子，会计入骰点统计。物理骰模式下取玩家录入的点数，
+// 点数不足时返回 errPhysicalDiceWaiting，调用方应就此中止指令，录入后会重新执行
+func (ctx *MsgContext) diceRoll64(sides int64) (int64, error) {
+	if st := ctx.randSource; st != nil && st.physical != nil && !st.physical.probing && sides >= 1 {
+		if faces, ok := st.physical.take(1, sides); ok {
+			return faces[0], nil
+		}
+		return 0, errPhysicalDiceWaiting
+	}
+	val := ctx.fairRollTraceDie(sides, func() int64 {
+		return DiceRoll64x(ctx._v1Rand, sides)
+	})
+	if ctx.Dice != nil && ctx.Dice.DiceStatEnable && !ctx.statPaused {
+		ctx.Dice.DiceStat.Record(ctx, sides, val)
+	}
+	return val, nil
+}
+
+var (
+	diceStatSidesRe = regexp.MustCompile(`^\d*[dD](\d+)`)
+	diceStatNoSide  = regexp.MustCompile(`^\d*[dD]([^\d(]|$)`)
+	diceStatClampRe = regexp.MustCompile(`(?i)min|max`)
+	diceStatNumRe   = regexp.MustCompile(`\d+`)
+)
+
+// diceStatCollect 从dicescript的计算过程中取出普通骰子的点数
+func (ctx *MsgContext) diceStatCollect(vm *ds.Context, expr string) {
+	if ctx.Dice == nil || !ctx.Dice.DiceStatEnable || ctx.statPaused || vm.Error != nil {
+		return
+	}
+	if vm.Config.DiceMinMode || vm.Config.DiceMaxMode {
+		return
+	}
+	for _, span := range vm.DetailSpans {
+		if span.Tag != "dice" || span.Begin < 0 || int(span.End) > len(expr) || span.Begin >= span.End {
+			continue
+		}
+		src := expr[span.Begin:span.End]
+		if diceStatClampRe.MatchString(src) {
+			continue
+		}
+		var sides int64
+		if m := diceStatSidesRe.FindStringSubmatch(src); m != nil {
+			sides, _ = strconv.ParseInt(m[1], 10, 64)
+		} else if diceStatNoSide.MatchString(src) {
+			sides, _ = strconv.ParseInt(vm.Config.DefaultDiceSideExpr, 10, 64)
+		}
+		if sides < 2 {
+			continue
+		}
+
+		var faces []int64
+		valid := true
+		for _, s := range diceStatNumRe.FindAllString(span.Text, -1) {
+			v, _ := strconv.ParseInt(s, 10, 64)
+			if v < 1 || v > sides {
+				valid = false
+				break
+			}
+			faces = append(faces, v)
+		}
+		if !valid {
+			continue
+		}
+		for _, v := range faces {
+			ctx.Dice.DiceStat.Record(ctx, sides, v)
+		}
+	}
+}
+
+// DiceStatResult 一种骰子的检验结果
+type DiceStatResult struct {
+	Sides  int64   `json:"sides"`
+	Total  int64   `json:"total"`
+	Counts []int64 `json:"counts"` // 下标0对应点数1
+	Mean   float64 `json:"mean"`
+
+	ChiSquare   float64 `json:"chiSquare"`
+	ChiDF       int64   `json:"chiDf"`
+	ChiP        float64 `json:"chiP"`
+	ChiReliable bool    `json:"chiReliable"` // 每个点数的期望次数不少于5时结论可靠
+
+	Runs     int64   `json:"runs"`
+	RunsZ    float64 `json:"runsZ"`
+	RunsP    float64 `json:"runsP"`
+	RunsUsed bool    `json:"runsUsed"`
+
+	Suspicious bool `json:"suspicious"`
+}
+
+// DiceStatAnalyze 汇总符合条件的记录并检验
+func DiceStatAnalyze(db *sqlx.DB, q *model.QueryDiceStat) ([]*DiceStatResult, error) {
+	faces, err := model.DiceStatFaceList(db, q)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := model.DiceStatRunsList(db, q)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[int64]*DiceStatResult{}
+	get := func(sides int64) *DiceStatResult {
+		r := results[sides]
+		if r == nil {
+			r = &DiceStatResult{Sides: sides, Counts: make([]int64, sides)}
+			results[sides] = r
+		}
+		return r
+	}
+	for _, f := range faces {
+		if f.Face < 1 || f.Face > f.Sides {
+			continue
+		}
+		r := get(f.Sides)
+		r.Counts[f.Face-1] += f.Count
+		r.Total += f.Count
+	}
+
+	// 各分桶的游程分别计算期望与方差后合并
+	runsMean := map[int64]float64{}
+	runsVar := map[int64]float64{}
+	for _, item := range runs {
+		n1, n2 := float64(item.NHigh), float64(item.NLow)
+		n := n1 + n2
+		if n1 == 0 || n2 == 0 || n < 2 {
+			continue
+		}
+		r := get(item.Sides)
+		r.Runs += item.Runs
+		r.RunsUsed = true
+		runsMean[item.Sides] += 2*n1*n2/n + 1
+		runsVar[item.Sides] += 2 * n1 * n2 * (2*n1*n2 - n) / (n * n * (n - 1))
+	}
+
+	var ret []*DiceStatResult
+	for sides, r := range results {
+		if r.Total > 0 {
+			var sum float64
+			expected := float64(r.Total) / float64(sides)
+			for i, c := range r.Counts {
+				sum += float64(int64(i+1) * c)
+				d := float64(c) - expected
+				r.ChiSquare += d * d / expected
+			}
+			r.Mean = sum / float64(r.Total)
+			r.ChiDF = sides - 1
+			r.ChiP = chiSquarePValue(r.ChiSquare, float64(r.ChiDF))
+			r.ChiReliable = expected >= 5
+		} else {
+			r.ChiP = 1
+		}
+
+		r.RunsP = 1
+		if r.RunsUsed && runsVar[sides] > 0 {
+			r.RunsZ = (float64(r.Runs) - runsMean[sides]) / math.Sqrt(runsVar[sides])
+			r.RunsP = math.Erfc(math.Abs(r.RunsZ) / math.Sqrt2)
+		}
+		r.Suspicious = (r.ChiReliable && r.ChiP < diceStatSignificance) || (r.RunsUsed && r.RunsP < diceStatSignificance)
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Total > ret[j].Total
+	})
+	return ret, nil
+}
+
+// chiSquarePValue 卡方分布的上侧概率，即正则化上不完全伽马函数 Q(df/2, x/2)
+func chiSquarePValue(x, df float64) float64 {
+	if x <= 0 || df <= 0 {
+		return 1
+	}
+	return gammaQ(df/2, x/2)
+}
+
+func gammaQ(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	if x < a+1 {
+		// 级数展开求P，再取补
+		sum := 1 / a
+		del := sum
+		for n := 1; n < 1000; n++ {
+			del *= x / (a + float64(n))
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(-x+a*math.Log(x)-lg)
+	}
+
+	// 连分式求Q (Lentz算法)
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+// diceStatResultText 检验结果的文本形式
+func diceStatResultText(r *DiceStatResult) string {
+	text := fmt.Sprintf("d%d: %d次 均值%.2f(理论%.1f)", r.Sides, r.Total, r.Mean, float64(r.Sides+1)/2)
+	if r.ChiReliable {
+		text += fmt.Sprintf("\n  卡方%.1f(自由度%d) p=%.3f", r.ChiSquare, r.ChiDF, r.ChiP)
+	} else {
+		text += "\n  样本不足，卡方检验不可靠"
+	}
+	if r.RunsUsed {
+		text += fmt.Sprintf(" 游程Z=%.2f p=%.3f", r.RunsZ, r.RunsP)
+	}
+	if r.Suspicious {
+		text += "\n  结论: 偏离随机的迹象显著，建议继续观察"
+	} else {
+		text += "\n  结论: 未见异常"
+	}
+	return text
+}
@@ -0,0 +1,119 @@
+package dice
+
+import (
+	"math"
+	"testing"
+
+	ds "github.com/sealdice/dicescript"
+	rand2 "golang.org/x/exp/rand"
+
+	"sealdice-core/dice/model"
+)
+
+func TestChiSquarePValue(t *testing.T) {
+	for _, c := range []struct{ x, df, p float64 }{
+		{3.841, 1, 0.05},
+		{18.307, 10, 0.05},
+		{30.144, 19, 0.05},
+		{9.342, 10, 0.5},
+	} {
+		if p := chiSquarePValue(c.x, c.df); math.Abs(p-c.p) > 1e-3 {
+			t.Errorf("chi2=%v df=%v: p=%v, 应为%v", c.x, c.df, p, c.p)
+		}
+	}
+}
+
+func TestDiceStatRecord(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBData: dataDB, AttrsManager: &AttrsManager{db: dataDB}, DiceStatEnable: true, DiceStat: &DiceStatRecorder{}}
+	src := &rand2.PCGSource{}
+	src.Seed(42)
+	ctx := &MsgContext{
+		Dice:    d,
+		Group:   &GroupInfo{GroupID: "QQ-Group:1"},
+		Player:  &GroupPlayerInfo{UserID: "QQ:2", ValueMapTemp: &ds.ValueMap{}},
+		_v1Rand: src,
+	}
+
+	// 游程需要跨越多次落库接续
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 200; i++ {
+			ctx.diceRoll64(6)
+			if _, _, err := DiceExprEvalBase(ctx, "2d6+1d20", RollExtraFlags{V2Only: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := d.DiceStat.Flush(dataDB); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := DiceStatAnalyze(dataDB, &model.QueryDiceStat{GroupID: "QQ-Group:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := map[int64]int64{}
+	for _, r := range results {
+		totals[r.Sides] = r.Total
+		if r.Suspicious {
+			t.Errorf("d%d 不应判定为异常: %+v", r.Sides, r)
+		}
+	}
+	if totals[6] != 1800 || totals[20] != 600 {
+		t.Errorf("计数错误: %v", totals)
+	}
+
+	results, _ = DiceStatAnalyze(dataDB, &model.QueryDiceStat{GroupID: "QQ-Group:2"})
+	if len(results) != 0 {
+		t.Errorf("其他群不应有数据: %v", results)
+	}
+}
+
+func TestDiceStatSkipsProb(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBData: dataDB, AttrsManager: &AttrsManager{db: dataDB}, DiceStatEnable: true, DiceStat: &DiceStatRecorder{}, MaxExecuteTime: 1}
+	src := &rand2.PCGSource{}
+	src.Seed(42)
+	ctx := &MsgContext{
+		Dice:    d,
+		Group:   &GroupInfo{GroupID: "QQ-Group:1"},
+		Player:  &GroupPlayerInfo{UserID: "QQ:2", ValueMapTemp: &ds.ValueMap{}},
+		_v1Rand: src,
+	}
+
+	// 精确计算、抽样与蒙特卡洛三种方式都不应计入统计
+	methods := map[string]bool{}
+	for _, expr := range []string{"2d6", "500d100k250", "d20 + (d6 > 3 ? 5 : 0)"} {
+		r, err := ProbCalculate(ctx, expr, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		methods[r.Method] = true
+	}
+	if !methods["mc"] {
+		t.Fatalf("should cover the monte carlo path: %v", methods)
+	}
+	if err := d.DiceStat.Flush(dataDB); err != nil {
+		t.Fatal(err)
+	}
+	results, err := DiceStatAnalyze(dataDB, &model.QueryDiceStat{GroupID: "QQ-Group:1"})
+	if err != nil || len(results) != 0 {
+		t.Fatalf(".prob should not be recorded: %v %v", err, results)
+	}
+
+	// 之后的正常掷骰照常统计
+	if _, _, err := DiceExprEvalBase(ctx, "1d20", RollExtraFlags{V2Only: true}); err != nil {
+		t.Fatal(err)
+	}
+	_ = d.DiceStat.Flush(dataDB)
+	results, _ = DiceStatAnalyze(dataDB, &model.QueryDiceStat{GroupID: "QQ-Group:1"})
+	if len(results) != 1 || results[0].Total != 1 {
+		t.Fatalf("normal rolls should be recorded: %+v", results)
+	}
+}
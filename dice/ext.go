@@ -21,6 +21,9 @@ func (d *Dice) RegisterBuiltinExt() {
 	RegisterBuiltinExtDnd5e(d)
 	RegisterBuiltinStory(d)
 	RegisterBuiltinExtExp(d)
+	RegisterBuiltinExtBlades(d)
+	RegisterBuiltinExtPf2e(d)
+	RegisterBuiltinExtFate(d)
 
 	d.RegisterBuiltinSystemTemplate()
 }
@@ -28,6 +31,9 @@ func (d *Dice) RegisterBuiltinExt() {
 func (d *Dice) RegisterBuiltinSystemTemplate() {
 	d.GameSystemTemplateAdd(getCoc7CharTemplate())
 	d.GameSystemTemplateAdd(_dnd5eTmpl)
+	d.GameSystemTemplateAdd(_bladesTmpl)
+	d.GameSystemTemplateAdd(getPf2eCharTemplate())
+	d.GameSystemTemplateAdd(_fateTmpl)
 }
 
 // RegisterExtension 注册扩展
@@ -0,0 +1,659 @@
+package dice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// FitD 即 Forged in the Dark 系列规则，以暗夜刀锋(Blades in the Dark)为代表
+
+var fitdPositions = map[string]string{
+	"受控": "受控", "controlled": "受控",
+	"冒险": "冒险", "risky": "冒险",
+	"绝境": "绝境", "desperate": "绝境",
+}
+
+var fitdEffects = map[string]string{
+	"有限": "有限", "limited": "有限",
+	"标准": "标准", "standard": "标准",
+	"显著": "显著", "great": "显著",
+}
+
+// 大成功时效果提升一级
+var fitdEffectUpgrade = map[string]string{
+	"有限": "标准",
+	"标准": "显著",
+	"显著": "显著(额外收益)",
+}
+
+var fitdConsequences = map[string][2]string{
+	// 处境: [部分成功, 失败]
+	"受控": {
+		"你做到了，但要付出些许代价：效果降低、小麻烦，或是需要承受压力。",
+		"你没能成功。可以退而改用其他方法，或者转入冒险处境再试一次。",
+	},
+	"冒险": {
+		"你做到了，但伴随着后果：受到伤害、惹上麻烦、效果降低或处境恶化。",
+		"事情变糟了：你受到伤害、惹上麻烦，或是失去这个机会。",
+	},
+	"绝境": {
+		"你做到了，但要承受严重的后果。",
+		"最糟糕的情况发生了：严重伤害、大麻烦，或是彻底失去机会。",
+	},
+}
+
+type fitdRollResult struct {
+	Pool   int64   // 骰池大小，0d时实际投掷两颗骰子
+	Dice   []int64 // 各骰子结果
+	Result int64   // 采用的骰值
+	Crit   bool    // 多于一个6即为大成功
+}
+
+// fitdRoll 投掷一个FitD骰池。骰池为Nd6取最高，0d时投2d6取最低且不会大成功。
+// 物理骰点数不足时仍会掷完整个骰池，以便一次提示玩家投掷全部骰子
+func fitdRoll(ctx *MsgContext, pool int64) (*fitdRollResult, error) {
+	if pool < 0 {
+		pool = 0
+	}
+	n := pool
+	if n == 0 {
+		n = 2
+	}
+	dice := make([]int64, 0, n)
+	var rollErr error
+	for i := int64(0); i < n; i++ {
+		v, err := ctx.diceRoll64(6)
+		if err != nil {
+			rollErr = err
+		}
+		dice = append(dice, v)
+	}
+	if rollErr != nil {
+		return nil, rollErr
+	}
+	ret := &fitdRollResult{Pool: pool, Dice: dice}
+	ret.Result, ret.Crit = fitdEvalDice(dice, pool == 0)
+	return ret, nil
+}
+
+func fitdEvalDice(dice []int64, zeroPool bool) (int64, bool) {
+	if len(dice) == 0 {
+		return 0, false
+	}
+	result := dice[0]
+	sixes := 0
+	for _, i := range dice {
+		if i == 6 {
+			sixes++
+		}
+		if zeroPool {
+			if i < result {
+				result = i
+			}
+		} else if i > result {
+			result = i
+		}
+	}
+	return result, !zeroPool && sixes >= 2
+}
+
+func (r *fitdRollResult) DiceText() string {
+	var items []string
+	for _, i := range r.Dice {
+		items = append(items, strconv.FormatInt(i, 10))
+	}
+	if r.Pool == 0 {
+		return fmt.Sprintf("0d(2d6取低)=[%s]→%d", strings.Join(items, ","), r.Result)
+	}
+	return fmt.Sprintf("%dd6=[%s]→%d", r.Pool, strings.Join(items, ","), r.Result)
+}
+
+// Outcome 结果等级: 3大成功 2完全成功 1部分成功 0失败
+func (r *fitdRollResult) Outcome() int {
+	switch {
+	case r.Crit:
+		return 3
+	case r.Result == 6:
+		return 2
+	case r.Result >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SuccessRank 对应 coc 的 $tSuccessRank，部分成功也算成功
+func (r *fitdRollResult) SuccessRank() int64 {
+	if o := r.Outcome(); o > 0 {
+		return int64(o)
+	}
+	return -1
+}
+
+// fitdEvalPool 解析骰池表达式，如 "潜行"、"2"、"格斗+1"，返回骰池大小和剩余文本
+func fitdEvalPool(ctx *MsgContext, tmpl *GameSystemTemplate, text string) (int64, string, error) {
+	mctx := tmpl.evalCtx(ctx)
+	mctx.Eval(tmpl.PreloadCode, nil)
+	r := mctx.Eval(text, nil)
+	if r.vm.Error != nil {
+		return 0, "", r.vm.Error
+	}
+	n, ok := r.ReadInt()
+	if !ok {
+		return 0, "", fmt.Errorf("骰池必须为整数: %s", r.ToString())
+	}
+	return int64(n), strings.TrimSpace(r.vm.RestInput), nil
+}
+
+type FitDClock struct {
+	Name     string
+	Segments int64
+	Filled   int64
+}
+
+func (c *FitDClock) Render() string {
+	filled := c.Filled
+	if filled > c.Segments {
+		filled = c.Segments
+	}
+	bar := strings.Repeat("■", int(filled)) + strings.Repeat("□", int(c.Segments-filled))
+	text := fmt.Sprintf("%s [%s] %d/%d", c.Name, bar, c.Filled, c.Segments)
+	if c.IsFull() {
+		text += " (已完成)"
+	}
+	return text
+}
+
+func (c *FitDClock) IsFull() bool {
+	return c.Filled >= c.Segments
+}
+
+type FitDClockList []*FitDClock
+
+var fitdClockLocks sync.Map // 群号 -> *sync.Mutex
+
+// fitdClockLockFor 群的进度钟锁，读取、修改、写回期间都要持有，否则并发推进会丢失更新
+func fitdClockLockFor(groupID string) *sync.Mutex {
+	v, _ := fitdClockLocks.LoadOrStore(groupID, &sync.Mutex{})
+	return v.(*sync.Mutex)
+}
+
+// LoadByCurGroup 从群属性中加载进度钟，调用方需持有 fitdClockLockFor 取得的锁直到 SaveToGroup
+func (lst FitDClockList) LoadByCurGroup(ctx *MsgContext) FitDClockList {
+	am := ctx.Dice.AttrsManager
+	attrs, _ := am.LoadById(ctx.Group.GroupID)
+
+	clocks := attrs.Load("fitdClocks")
+	if clocks == nil || clocks.TypeId != ds.VMTypeArray {
+		clocks = ds.NewArrayVal()
+		attrs.Store("fitdClocks", clocks)
+	}
+
+	ret := FitDClockList{}
+	for _, i := range clocks.MustReadArray().List {
+		if i.TypeId != ds.VMTypeDict {
+			continue
+		}
+		dd := i.MustReadDictData()
+		readInt := func(key string) int64 {
+			v, ok := dd.Dict.Load(key)
+			if !ok {
+				return 0
+			}
+			n, _ := v.ReadInt()
+			return int64(n)
+		}
+		name, _ := dd.Dict.Load("name")
+		if name == nil {
+			continue
+		}
+		ret = append(ret, &FitDClock{
+			Name:     name.ToString(),
+			Segments: readInt("segments"),
+			Filled:   readInt("filled"),
+		})
+	}
+	return ret
+}
+
+// SaveToGroup 写入群属性中
+func (lst FitDClockList) SaveToGroup(ctx *MsgContext) {
+	am := ctx.Dice.AttrsManager
+	attrs, _ := am.LoadById(ctx.Group.GroupID)
+	clocks := ds.NewArrayVal()
+
+	ad := clocks.MustReadArray()
+	for _, i := range lst {
+		v := ds.NewDictValWithArrayMust(
+			ds.NewStrVal("name"), ds.NewStrVal(i.Name),
+			ds.NewStrVal("segments"), ds.NewIntVal(ds.IntType(i.Segments)),
+			ds.NewStrVal("filled"), ds.NewIntVal(ds.IntType(i.Filled)),
+		)
+		ad.List = append(ad.List, v.V())
+	}
+
+	attrs.Store("fitdClocks", clocks)
+}
+
+func (lst FitDClockList) Get(name string) *FitDClock {
+	for _, i := range lst {
+		if strings.EqualFold(i.Name, name) {
+			return i
+		}
+	}
+	return nil
+}
+
+func (lst FitDClockList) Remove(name string) (FitDClockList, bool) {
+	for index, i := range lst {
+		if strings.EqualFold(i.Name, name) {
+			return append(lst[:index], lst[index+1:]...), true
+		}
+	}
+	return lst, false
+}
+
+func RegisterBuiltinExtBlades(self *Dice) {
+	getTmpl := func(ctx *MsgContext) *GameSystemTemplate {
+		tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
+		if tmpl.Name != "blades" {
+			if tmpl2, _ := ctx.Dice.GameSystemMap.Load("blades"); tmpl2 != nil {
+				tmpl = tmpl2
+			}
+		}
+		return tmpl
+	}
+
+	setCommandInfo := func(mctx *MsgContext, cmd string, r *fitdRollResult, extra map[string]interface{}) {
+		item := map[string]interface{}{
+			"pool":   r.Pool,
+			"dice":   r.Dice,
+			"result": r.Result,
+			"crit":   r.Crit,
+		}
+		for k, v := range extra {
+			item[k] = v
+		}
+		mctx.CommandInfo = map[string]interface{}{
+			"cmd":    cmd,
+			"rule":   "blades",
+			"pcName": mctx.Player.Name,
+			"items":  []interface{}{item},
+		}
+	}
+
+	appendCommandInfo := func(mctx *MsgContext, cmdArgs *CmdArgs, text string) string {
+		if kw := cmdArgs.GetKwarg("ci"); kw != nil {
+			info, err := json.Marshal(mctx.CommandInfo)
+			if err == nil {
+				text += "\n" + string(info)
+			} else {
+				text += "\n" + "指令信息无法序列化"
+			}
+		}
+		return text
+	}
+
+	helpBr := "" +
+		".br <骰池> [处境] [效果] [原因] // 行动检定，骰池可为数字或行动名，如 .br 潜行 冒险 标准 溜进宅邸\n" +
+		".br 0 // 0d骰池，投2d6取低\n" +
+		"处境: 受控/冒险/绝境，默认冒险；效果: 有限/标准/显著，默认标准\n" +
+		".br <骰池> @某人 // 对某人做检定"
+
+	cmdBr := &CmdItemInfo{
+		Name:          "br",
+		ShortHelp:     helpBr,
+		Help:          "FitD 行动检定(多个6为大成功，最高为6完全成功，4/5部分成功，1-3失败):\n" + helpBr,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			if val := cmdArgs.GetArgN(1); val == "" || val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			position := "冒险"
+			effect := "标准"
+			var rest []string
+			for _, i := range cmdArgs.Args {
+				if v, ok := fitdPositions[strings.ToLower(i)]; ok {
+					position = v
+					continue
+				}
+				if v, ok := fitdEffects[strings.ToLower(i)]; ok {
+					effect = v
+					continue
+				}
+				rest = append(rest, i)
+			}
+
+			tmpl := getTmpl(mctx)
+			poolText := strings.Join(rest, " ")
+			if poolText == "" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			pool, reason, err := fitdEvalPool(mctx, tmpl, poolText)
+			if err != nil {
+				ReplyToSender(ctx, msg, "无法解析骰池: "+poolText)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if reason == "" {
+				reason = poolText
+			}
+
+			r, err := fitdRoll(mctx, pool)
+			if err != nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			var outcomeText string
+			switch r.Outcome() {
+			case 3:
+				outcomeText = fmt.Sprintf("大成功！你以更大的效果达成了目标，效果提升为%s。", fitdEffectUpgrade[effect])
+			case 2:
+				outcomeText = "完全成功！你完全达成了目标。"
+			case 1:
+				outcomeText = "部分成功。" + fitdConsequences[position][0]
+			default:
+				outcomeText = "失败。" + fitdConsequences[position][1]
+			}
+
+			text := fmt.Sprintf("<%s>的行动检定「%s」\n%s\n处境:%s 效果:%s\n%s",
+				mctx.Player.Name, reason, r.DiceText(), position, effect, outcomeText)
+			if position == "绝境" {
+				text += "\n(绝境行动，为对应属性标记1点经验)"
+			}
+
+			VarSetValueInt64(mctx, "$tSuccessRank", r.SuccessRank())
+			setCommandInfo(mctx, "br", r, map[string]interface{}{
+				"reason":   reason,
+				"position": position,
+				"effect":   effect,
+				"outcome":  r.Outcome(),
+			})
+			ReplyToSender(mctx, msg, appendCommandInfo(mctx, cmdArgs, text))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpFortune := "" +
+		".fortune <骰池> [原因] // 命运检定，用于判断不受角色控制的事件，如 .fortune 2 敌对帮派的反应\n" +
+		".fortune 0 // 0d骰池，投2d6取低"
+
+	cmdFortune := &CmdItemInfo{
+		Name:      "fortune",
+		ShortHelp: helpFortune,
+		Help:      "FitD 命运检定:\n" + helpFortune,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if val := cmdArgs.GetArgN(1); val == "" || val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			tmpl := getTmpl(ctx)
+			pool, reason, err := fitdEvalPool(ctx, tmpl, cmdArgs.CleanArgs)
+			if err != nil {
+				ReplyToSender(ctx, msg, "无法解析骰池: "+cmdArgs.CleanArgs)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			r, err := fitdRoll(ctx, pool)
+			if err != nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			var outcomeText string
+			switch r.Outcome() {
+			case 3:
+				outcomeText = "极佳：结果远超预期。"
+			case 2:
+				outcomeText = "良好：全额的效果或有利的结果。"
+			case 1:
+				outcomeText = "一般：部分的效果或喜忧参半的结果。"
+			default:
+				outcomeText = "不佳：效果甚微或不利的结果。"
+			}
+
+			text := "命运检定"
+			if reason != "" {
+				text += "「" + reason + "」"
+			}
+			text += fmt.Sprintf("\n%s\n%s", r.DiceText(), outcomeText)
+
+			VarSetValueInt64(ctx, "$tSuccessRank", r.SuccessRank())
+			setCommandInfo(ctx, "fortune", r, map[string]interface{}{
+				"reason":  reason,
+				"outcome": r.Outcome(),
+			})
+			ReplyToSender(ctx, msg, appendCommandInfo(ctx, cmdArgs, text))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpResist := "" +
+		".resist <属性|骰池> [原因] // 抵抗检定，消耗6减最高骰值的压力，大成功则回复1点压力，如 .resist 身手 躲开刀锋\n" +
+		".resist <属性|骰池> @某人 // 对某人做抵抗检定"
+
+	cmdResist := &CmdItemInfo{
+		Name:          "resist",
+		ShortHelp:     helpResist,
+		Help:          "FitD 抵抗检定:\n" + helpResist,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			if val := cmdArgs.GetArgN(1); val == "" || val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			tmpl := getTmpl(mctx)
+			pool, reason, err := fitdEvalPool(mctx, tmpl, cmdArgs.CleanArgs)
+			if err != nil {
+				ReplyToSender(ctx, msg, "无法解析骰池: "+cmdArgs.CleanArgs)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if reason == "" {
+				reason = cmdArgs.CleanArgs
+			}
+
+			r, err := fitdRoll(mctx, pool)
+			if err != nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			cost := 6 - r.Result
+			if r.Crit {
+				cost = -1
+			}
+
+			attrs, err := mctx.Dice.AttrsManager.LoadByCtx(mctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			stress := tmpl.GetRealValueInt(mctx, "压力")
+			stressMax := tmpl.GetRealValueInt(mctx, "压力上限")
+			trauma := tmpl.GetRealValueInt(mctx, "创伤")
+			traumaMax := tmpl.GetRealValueInt(mctx, "创伤上限")
+
+			newStress := stress + cost
+			if newStress < 0 {
+				newStress = 0
+			}
+
+			text := fmt.Sprintf("<%s>的抵抗检定「%s」\n%s\n", mctx.Player.Name, reason, r.DiceText())
+			if r.Crit {
+				text += "大成功！你不仅抵抗了后果，还回复了1点压力。"
+			} else {
+				text += fmt.Sprintf("后果被减轻或避免，承受%d点压力。", cost)
+			}
+			text += fmt.Sprintf("\n压力: %d → %d", stress, newStress)
+
+			if stressMax > 0 && newStress > stressMax {
+				trauma++
+				newStress = 0
+				attrs.Store("创伤", ds.NewIntVal(ds.IntType(trauma)))
+				text += fmt.Sprintf("\n压力超过上限！你陷入了创伤，压力清空，创伤: %d/%d", trauma, traumaMax)
+				if traumaMax > 0 && trauma >= traumaMax {
+					text += "\n创伤已满，角色将从冒险生涯中退场。"
+				}
+			}
+			attrs.Store("压力", ds.NewIntVal(ds.IntType(newStress)))
+
+			setCommandInfo(mctx, "resist", r, map[string]interface{}{
+				"reason":    reason,
+				"stressOld": stress,
+				"stressNew": newStress,
+				"trauma":    trauma,
+			})
+			if mctx.Player.AutoSetNameTemplate != "" {
+				_, _ = SetPlayerGroupCardByTemplate(mctx, mctx.Player.AutoSetNameTemplate)
+			}
+			ReplyToSender(mctx, msg, appendCommandInfo(mctx, cmdArgs, text))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpClock := "" +
+		".clock // 展示本群全部进度钟\n" +
+		".clock show <名称> // 展示指定进度钟\n" +
+		".clock add <名称> <格数> // 新建进度钟，格数通常为4/6/8，如 .clock add 警戒 6\n" +
+		".clock tick <名称> [格数] // 推进进度钟，默认1格，负数为回退\n" +
+		".clock del <名称> // 删除进度钟\n" +
+		".clock clr // 清空本群进度钟"
+
+	cmdClock := &CmdItemInfo{
+		Name:      "clock",
+		ShortHelp: helpClock,
+		Help:      "FitD 进度钟:\n" + helpClock,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			cmdArgs.ChopPrefixToArgsWith("add", "tick", "show", "del", "rm", "clr")
+			lock := fitdClockLockFor(ctx.Group.GroupID)
+			lock.Lock()
+			defer lock.Unlock()
+			clocks := (FitDClockList{}).LoadByCurGroup(ctx)
+
+			showAll := func() string {
+				if len(clocks) == 0 {
+					return "本群当前没有进度钟"
+				}
+				var lines []string
+				for _, i := range clocks {
+					lines = append(lines, i.Render())
+				}
+				return "进度钟列表:\n" + strings.Join(lines, "\n")
+			}
+
+			name := cmdArgs.GetArgN(2)
+			switch cmdArgs.GetArgN(1) {
+			case "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "", "show":
+				if name == "" {
+					ReplyToSender(ctx, msg, showAll())
+					break
+				}
+				c := clocks.Get(name)
+				if c == nil {
+					ReplyToSender(ctx, msg, "未找到进度钟: "+name)
+					break
+				}
+				ReplyToSender(ctx, msg, c.Render())
+			case "add":
+				segments, err := strconv.ParseInt(cmdArgs.GetArgN(3), 10, 64)
+				if name == "" || err != nil {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				if segments < 2 || segments > 12 {
+					ReplyToSender(ctx, msg, "进度钟的格数应在2到12之间")
+					break
+				}
+				if clocks.Get(name) != nil {
+					ReplyToSender(ctx, msg, "已存在同名进度钟: "+name)
+					break
+				}
+				c := &FitDClock{Name: name, Segments: segments}
+				clocks = append(clocks, c)
+				clocks.SaveToGroup(ctx)
+				ReplyToSender(ctx, msg, "新建进度钟: "+c.Render())
+			case "tick":
+				if name == "" {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				c := clocks.Get(name)
+				if c == nil {
+					ReplyToSender(ctx, msg, "未找到进度钟: "+name)
+					break
+				}
+				n := int64(1)
+				if v := cmdArgs.GetArgN(3); v != "" {
+					var err error
+					n, err = strconv.ParseInt(v, 10, 64)
+					if err != nil {
+						return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+					}
+				}
+				wasFull := c.IsFull()
+				c.Filled += n
+				if c.Filled < 0 {
+					c.Filled = 0
+				}
+				if c.Filled > c.Segments {
+					c.Filled = c.Segments
+				}
+				clocks.SaveToGroup(ctx)
+				text := c.Render()
+				if c.IsFull() && !wasFull {
+					text += "\n进度钟已走满！"
+				}
+				ReplyToSender(ctx, msg, text)
+			case "del", "rm":
+				var ok bool
+				clocks, ok = clocks.Remove(name)
+				if !ok {
+					ReplyToSender(ctx, msg, "未找到进度钟: "+name)
+					break
+				}
+				clocks.SaveToGroup(ctx)
+				ReplyToSender(ctx, msg, "已删除进度钟: "+name)
+			case "clr":
+				(FitDClockList{}).SaveToGroup(ctx)
+				ReplyToSender(ctx, msg, "已清空本群进度钟")
+			default:
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	cmdSt := getCmdStBase(CmdStOverrideInfo{})
+
+	theExt := &ExtInfo{
+		Name:       "blades", // 扩展的名称，需要用于开启和关闭指令中，写简短点
+		Aliases:    []string{"fitd"},
+		Version:    "1.0.0",
+		Brief:      "提供暗夜刀锋(FitD)规则TRPG支持",
+		Author:     "海豹核心",
+		AutoActive: false, // 是否自动开启
+		Official:   true,
+		ConflictWith: []string{
+			"coc7",
+			"dnd5e",
+		},
+		OnCommandReceived: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+		},
+		GetDescText: GetExtensionDesc,
+		CmdMap: CmdMapCls{
+			"br":      cmdBr,
+			"fortune": cmdFortune,
+			"resist":  cmdResist,
+			"clock":   cmdClock,
+			"st":      cmdSt,
+		},
+	}
+
+	self.RegisterExtension(theExt)
+}
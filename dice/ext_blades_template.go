@@ -0,0 +1,81 @@
+package dice
+
+var _bladesTmpl = &GameSystemTemplate{
+	Name:        "blades",
+	FullName:    "暗夜刀锋(Forged in the Dark)",
+	Authors:     []string{"海豹核心"},
+	Version:     "1.0.0",
+	UpdatedTime: "20241019",
+	TemplateVer: "1.0",
+
+	SetConfig: SetConfig{
+		DiceSidesExpr: "6",
+		DiceSides:     6,
+		Keys:          []string{"blades", "bitd", "fitd"},
+		EnableTip:     "已切换至6面骰，并自动开启blades扩展",
+		RelatedExt:    []string{"blades"},
+	},
+
+	NameTemplate: map[string]NameTemplateItem{
+		"blades": {
+			Template: "{$t玩家_RAW} 压力{压力}/{压力上限} 创伤{创伤}",
+			HelpText: "自动设置暗夜刀锋名片",
+		},
+	},
+
+	AttrConfig: AttrConfig{
+		Top: []string{
+			"洞察", "身手", "决心",
+			"压力", "创伤",
+			"狩猎", "研习", "勘察", "修补",
+			"巧技", "潜行", "格斗", "破坏",
+			"通灵", "指挥", "交际", "说服",
+		},
+		SortBy:  "Name",
+		Ignores: []string{"压力上限", "创伤上限"},
+		ShowAs: map[string]string{
+			"压力": "{压力}/{压力上限}",
+			"创伤": "{创伤}/{创伤上限}",
+		},
+		ItemsPerLine: 4,
+	},
+
+	Defaults: map[string]int64{
+		"压力":   0,
+		"压力上限": 9,
+		"创伤":   0,
+		"创伤上限": 4,
+	},
+	DefaultsComputed: map[string]string{
+		// 属性等级为其下有点数的行动数量
+		"洞察": "(狩猎>0) + (研习>0) + (勘察>0) + (修补>0)",
+		"身手": "(巧技>0) + (潜行>0) + (格斗>0) + (破坏>0)",
+		"决心": "(通灵>0) + (指挥>0) + (交际>0) + (说服>0)",
+	},
+
+	Alias: map[string][]string{
+		"洞察": {"insight"},
+		"身手": {"prowess"},
+		"决心": {"resolve"},
+
+		"压力":   {"stress"},
+		"压力上限": {"stressmax", "maxstress"},
+		"创伤":   {"trauma"},
+		"创伤上限": {"traumamax", "maxtrauma"},
+
+		"狩猎": {"hunt"},
+		"研习": {"study", "研究"},
+		"勘察": {"survey", "调查"},
+		"修补": {"tinker"},
+
+		"巧技": {"finesse", "巧手"},
+		"潜行": {"prowl"},
+		"格斗": {"skirmish", "搏斗"},
+		"破坏": {"wreck"},
+
+		"通灵": {"attune"},
+		"指挥": {"command", "命令"},
+		"交际": {"consort"},
+		"说服": {"sway"},
+	},
+}
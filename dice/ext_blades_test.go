@@ -0,0 +1,134 @@
+package dice
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+func newTestCtx(t *testing.T) *MsgContext {
+	d := newTestDiceFull(t)
+	return &MsgContext{
+		Dice:   d,
+		Group:  &GroupInfo{GroupID: "UI-Group:1"},
+		Player: &GroupPlayerInfo{UserID: "UI:1", Name: "tester", ValueMapTemp: &ds.ValueMap{}},
+	}
+}
+
+func TestFitdEvalPoolKeepsCtx(t *testing.T) {
+	ctx := newTestCtx(t)
+	blades, _ := ctx.Dice.GameSystemMap.Load("blades")
+	coc, _ := ctx.Dice.GameSystemMap.Load("coc7")
+	ctx.SystemTemplate = coc
+	ctx.CreateVmIfNotExists()
+	vm := ctx.vm
+
+	pool, rest, err := fitdEvalPool(ctx, blades, "2+1 溜进宅邸")
+	if err != nil || pool != 3 || rest != "溜进宅邸" {
+		t.Fatalf("pool=%d rest=%q err=%v", pool, rest, err)
+	}
+	if ctx.SystemTemplate != coc || ctx.vm != vm {
+		t.Fatal("fitdEvalPool should not change the caller's template or vm")
+	}
+	if v := blades.GetRealValueInt(ctx, "压力上限"); v <= 0 {
+		t.Fatalf("压力上限=%d", v)
+	}
+	if ctx.SystemTemplate != coc {
+		t.Fatal("GetRealValueInt should not change the caller's template")
+	}
+}
+
+// newFixedDiceCtx 以物理骰模式按顺序给出点数，用于固定检定结果
+func newFixedDiceCtx(faces ...int64) *MsgContext {
+	return &MsgContext{randSource: &randSourceState{physical: &physicalDiceState{Faces: faces}}}
+}
+
+func TestFitdRoll(t *testing.T) {
+	cases := []struct {
+		pool    int64
+		faces   []int64
+		result  int64
+		crit    bool
+		outcome int
+		rank    int64
+	}{
+		{3, []int64{2, 5, 3}, 5, false, 1, 1},
+		{2, []int64{6, 1}, 6, false, 2, 2},
+		{3, []int64{6, 2, 6}, 6, true, 3, 3},
+		{1, []int64{3}, 3, false, 0, -1},
+		// 0d投2d6取低，两个6也不算大成功
+		{0, []int64{5, 2}, 2, false, 0, -1},
+		{0, []int64{6, 6}, 6, false, 2, 2},
+		{-1, []int64{4, 5}, 4, false, 1, 1},
+	}
+	for _, c := range cases {
+		r, err := fitdRoll(newFixedDiceCtx(c.faces...), c.pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Dice) != len(c.faces) {
+			t.Errorf("pool %d: dice %v, want %v", c.pool, r.Dice, c.faces)
+			continue
+		}
+		if r.Result != c.result || r.Crit != c.crit || r.Outcome() != c.outcome || r.SuccessRank() != c.rank {
+			t.Errorf("pool %d %v: result=%d crit=%v outcome=%d rank=%d", c.pool, c.faces, r.Result, r.Crit, r.Outcome(), r.SuccessRank())
+		}
+	}
+
+	// 点数不足时中止，但整个骰池都计入需要投掷的数量
+	ctx := newFixedDiceCtx(4)
+	if _, err := fitdRoll(ctx, 3); err != errPhysicalDiceWaiting {
+		t.Fatalf("short of faces should stop the roll, got %v", err)
+	}
+	if pd := ctx.randSource.physical; !pd.Waiting || pd.count != 3 {
+		t.Fatalf("unexpected state %+v", pd)
+	}
+}
+
+func TestFitdClockConcurrentTick(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	send := func(text string) string { return chat.send("gm", "owner", text) }
+
+	send(".ext blades on")
+	send(".clock add 警戒 12")
+	send(".clock tick 警戒 6")
+	// 推进与回退各6次，顺序任意都不会越界，最终应回到6格
+	for round := 0; round < 20; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < 12; i++ {
+			n := "1"
+			if i%2 == 1 {
+				n = "-1"
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				send(".clock tick 警戒 " + n)
+			}()
+		}
+		wg.Wait()
+		if text := send(".clock show 警戒"); !strings.Contains(text, "6/12") {
+			t.Fatalf("并发推进丢失了更新: %q", text)
+		}
+	}
+}
+
+func TestFitdResistWaitsForPhysicalDice(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	send := func(text string) string { return chat.send("gm", "owner", text) }
+
+	send(".ext blades on")
+	send(".st 压力2")
+	send(".set rng physical")
+	if text := send(".resist 2 躲开刀锋"); !strings.Contains(text, "共2个点数") || strings.Contains(text, "压力:") {
+		t.Fatalf("resist should wait for 2d6 without a result: %q", text)
+	}
+	send(".set rng default")
+	if text := send(".st show 压力"); !strings.Contains(text, "压力:2") {
+		t.Fatalf("压力 should not change while waiting: %q", text)
+	}
+}
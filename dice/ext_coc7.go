@@ -270,6 +270,7 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 		".ra p2 <属性表达式> // 多个奖励骰或惩罚骰\n" +
 		".ra 3#p <属性表达式> // 多重检定\n" +
 		".ra <属性表达式> @某人 // 对某人做检定(使用他的属性)\n" +
+		".ra spendluck // 花费幸运使自己上一次失败的检定成功\n" +
 		".rch/rah // 暗中检定，和检定指令用法相同"
 
 	cmdRc := &CmdItemInfo{
@@ -289,6 +290,12 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
 			}
 
+			if cmdArgs.IsArgEqual(1, "spendluck") {
+				ctx.DelegateText = ""
+				cocSpendLuck(ctx, msg)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
 			mctx := GetCtxProxyFirst(ctx, cmdArgs)
 			mctx.DelegateText = ctx.DelegateText
 			mctx.SystemTemplate = mctx.Group.GetCharTemplate(ctx.Dice)
@@ -420,6 +427,8 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 				VarSetValueInt64(mctx, "$tD100", outcome)
 				VarSetValueInt64(mctx, "$t判定值", checkVal)
 				VarSetValueInt64(mctx, "$tSuccessRank", int64(successRank))
+				// 成功等级不含难度要求，是否通过以回复中的判定结果为准
+				mctx.aliasCheckResult(cocCheckPassed(successRank, difficultyRequire))
 
 				var suffix string
 				var suffixFull string
@@ -450,12 +459,13 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 
 				// 指令信息标记
 				infoItem := map[string]interface{}{
-					"expr1":    expr1Text,
-					"expr2":    expr2Text,
-					"outcome":  outcome,
-					"attrVal":  attrVal,
-					"checkVal": checkVal,
-					"rank":     successRank,
+					"expr1":      expr1Text,
+					"expr2":      expr2Text,
+					"outcome":    outcome,
+					"attrVal":    attrVal,
+					"checkVal":   checkVal,
+					"rank":       successRank,
+					"difficulty": difficultyRequire,
 				}
 				commandInfoItems = append(commandInfoItems, infoItem)
 
@@ -469,7 +479,7 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 				return nil
 			}
 
-			var text string
+			var text, buttons string
 			if cmdArgs.SpecialExecuteTimes > 1 {
 				VarSetValueInt64(mctx, "$t次数", int64(cmdArgs.SpecialExecuteTimes))
 				if cmdArgs.SpecialExecuteTimes > int(ctx.Dice.MaxExecuteTime) {
@@ -496,6 +506,27 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 				VarSetValueStr(mctx, "$t原因", reason)
 				VarSetValueStr(mctx, "$t结果文本", DiceFormatTmpl(mctx, "COC:检定_单项结果文本"))
 				text = DiceFormatTmpl(mctx, "COC:检定")
+
+				// 代骰时按钮会作用到自己身上，因此只给自己的检定加按钮
+				if mctx.Player.UserID == ctx.Player.UserID && len(commandInfoItems) == 1 {
+					item := commandInfoItems[0].(map[string]interface{})
+					rank, difficulty := item["rank"].(int), item["difficulty"].(int)
+					buttons = buttonCocCheck(cmdArgs, item["expr2"].(string), rank, difficulty,
+						item["outcome"].(int64), item["checkVal"].(int64), tmpl.GetRealValueInt(mctx, "幸运"))
+					if rank != -2 && !cocCheckPassed(rank, difficulty) && cmdArgs.Command != "rah" && cmdArgs.Command != "rch" {
+						cocLuckPendingMap.Store(physicalDiceKey(msg), &cocLuckPending{
+							Expr1:      item["expr1"].(string),
+							Expr2:      item["expr2"].(string),
+							Reason:     reason,
+							Outcome:    item["outcome"].(int64),
+							AttrVal:    item["attrVal"].(int64),
+							CheckVal:   item["checkVal"].(int64),
+							Difficulty: difficulty,
+							CocRule:    cocRule,
+							Time:       time.Now().Unix(),
+						})
+					}
+				}
 			}
 
 			isHide := cmdArgs.Command == "rah" || cmdArgs.Command == "rch"
@@ -535,7 +566,7 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 					ReplyPerson(mctx, msg, DiceFormatTmpl(mctx, "COC:检定_暗中_私聊_前缀")+text)
 				}
 			} else {
-				ReplyToSender(mctx, msg, text)
+				ReplyToSender(mctx, msg, text+buttons)
 			}
 			return CmdExecuteResult{Matched: true, Solved: true}
 		},
@@ -1587,6 +1618,81 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 	self.RegisterExtension(theExt)
 }
 
+// cocLuckPending 最近一次可以花费幸运的失败检定，按群和用户记录
+type cocLuckPending struct {
+	Expr1      string
+	Expr2      string
+	Reason     string
+	Outcome    int64
+	AttrVal    int64
+	CheckVal   int64
+	Difficulty int
+	CocRule    int
+	Time       int64
+}
+
+const cocLuckPendingTimeout = 10 * 60
+
+var cocLuckPendingMap = new(SyncMap[string, *cocLuckPending])
+
+// cocSpendLuck 花费幸运使最近一次失败的检定成功：扣除差值，出目降为判定值后重新判定成功等级
+func cocSpendLuck(ctx *MsgContext, msg *Message) {
+	key := physicalDiceKey(msg)
+	p, ok := cocLuckPendingMap.LoadAndDelete(key)
+	if !ok || time.Now().Unix()-p.Time > cocLuckPendingTimeout {
+		ReplyToSender(ctx, msg, "当前没有可以花费幸运的检定")
+		return
+	}
+	lower := strings.ToLower(p.Expr2)
+	if strings.Contains(lower, "幸运") || strings.Contains(lower, "理智") || strings.Contains(lower, "san") {
+		ReplyToSender(ctx, msg, "幸运与理智检定不能花费幸运")
+		return
+	}
+
+	ctx.SystemTemplate = ctx.Group.GetCharTemplate(ctx.Dice)
+	tmpl := cardRuleCheck(ctx, msg)
+	if tmpl == nil {
+		return
+	}
+	if p.Difficulty >= 4 {
+		ReplyToSender(ctx, msg, "要求大成功的检定不能花费幸运")
+		return
+	}
+	luck := tmpl.GetRealValueInt(ctx, "幸运")
+	need := p.Outcome - p.CheckVal
+	if need <= 0 || luck < need {
+		ReplyToSender(ctx, msg, fmt.Sprintf("幸运不足，需要%d点，当前为%d", need, luck))
+		return
+	}
+	name := ctx.Player.GetValueNameByAlias("幸运", tmpl.Alias)
+	VarSetValueInt64(ctx, name, luck-need)
+
+	outcome := p.CheckVal
+	successRank, _ := ResultCheck(ctx, p.CocRule, outcome, p.AttrVal, p.Difficulty)
+	VarSetValueInt64(ctx, "$tD100", outcome)
+	VarSetValueInt64(ctx, "$t判定值", p.CheckVal)
+	VarSetValueInt64(ctx, "$tSuccessRank", int64(successRank))
+	suffixFull := GetResultTextWithRequire(ctx, successRank, p.Difficulty, false)
+	VarSetValueStr(ctx, "$t判定结果", suffixFull)
+	VarSetValueStr(ctx, "$t判定结果_详细", suffixFull)
+	VarSetValueStr(ctx, "$t判定结果_简短", GetResultTextWithRequire(ctx, successRank, p.Difficulty, true))
+	VarSetValueStr(ctx, "$t检定表达式文本", p.Expr1)
+	VarSetValueStr(ctx, "$t属性表达式文本", p.Expr2)
+	VarSetValueStr(ctx, "$t检定计算过程", "")
+	VarSetValueStr(ctx, "$t计算过程", "")
+	VarSetValueStr(ctx, "$t原因", p.Reason)
+	SetTempVars(ctx, ctx.Player.Name)
+	VarSetValueStr(ctx, "$t结果文本", DiceFormatTmpl(ctx, "COC:检定_单项结果文本"))
+
+	text := fmt.Sprintf("<%s>花费%d点幸运(%d→%d)，出目%d→%d\n", ctx.Player.Name, need, luck, luck-need, p.Outcome, outcome)
+	ReplyToSender(ctx, msg, text+DiceFormatTmpl(ctx, "COC:检定"))
+}
+
+// cocCheckPassed 检定是否通过，与回复中的判定结果一致：成功等级需达到难度要求
+func cocCheckPassed(successRank, difficultyRequire int) bool {
+	return successRank > 0 && successRank >= difficultyRequire
+}
+
 func GetResultTextWithRequire(ctx *MsgContext, successRank int, difficultyRequire int, userShortVersion bool) string {
 	if difficultyRequire > 1 {
 		isSuccess := successRank >= difficultyRequire
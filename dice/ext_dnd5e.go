@@ -400,6 +400,7 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 		".rc <表达式> // .rc 力量+3\n" +
 		".rc 优势 <表达式> // .rc 优势 力量+4\n" +
 		".rc 劣势 <表达式> [<原因>] // .rc 劣势 力量+4 推一下试试\n" +
+		".rc <表达式> --dmg=<伤害> // 攻击检定，附带掷伤害的按钮，如 .rc 力量+2 长剑攻击 --dmg=1d8+2\n" +
 		".rc <表达式> @某人 // 对某人做检定"
 
 	cmdRc := &CmdItemInfo{
@@ -470,6 +471,9 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 						text += "\n" + "指令信息无法序列化"
 					}
 				}
+				if kw := cmdArgs.GetKwarg("dmg"); kw != nil && kw.Value != "" {
+					text += buttonCommand(cmdArgs, "伤害", "r "+kw.Value+" 伤害")
+				}
 				ReplyToSender(mctx, msg, text)
 			}
 
@@ -1034,11 +1038,13 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 				}
 
 				if d20 == 20 {
+					VarSetValueInt64(mctx, "$tSuccessRank", 2)
 					deathSavingStable(mctx)
 					VarSetValueInt64(mctx, "hp", 1)
 					suffix := DiceFormatTmpl(mctx, "DND:死亡豁免_D20_附加语")
 					ReplyToSender(mctx, msg, fmt.Sprintf(`%s的死亡豁免检定: %s=%d %s`, getPlayerNameTempFunc(mctx), exprToShow, d20, suffix))
 				} else if d20 == 1 {
+					VarSetValueInt64(mctx, "$tSuccessRank", -2)
 					suffix := DiceFormatTmpl(mctx, "DND:死亡豁免_D1_附加语")
 					text := fmt.Sprintf(`%s的死亡豁免检定: %s=%d %s`, getPlayerNameTempFunc(mctx), exprToShow, d20, suffix)
 					a, b := deathSaving(mctx, 0, 2)
@@ -1049,6 +1055,7 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 					text += fmt.Sprintf("\n当前情况: 成功%d 失败%d", a, b)
 					ReplyToSender(mctx, msg, text)
 				} else if d20 >= 10 {
+					VarSetValueInt64(mctx, "$tSuccessRank", 1)
 					suffix := DiceFormatTmpl(mctx, "DND:死亡豁免_成功_附加语")
 					text := fmt.Sprintf(`%s的死亡豁免检定: %s=%d %s`, getPlayerNameTempFunc(mctx), exprToShow, d20, suffix)
 					a, b := deathSaving(mctx, 1, 0)
@@ -1059,6 +1066,7 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 					text += fmt.Sprintf("\n当前情况: 成功%d 失败%d", a, b)
 					ReplyToSender(mctx, msg, text)
 				} else {
+					VarSetValueInt64(mctx, "$tSuccessRank", -1)
 					suffix := DiceFormatTmpl(mctx, "DND:死亡豁免_失败_附加语")
 					text := fmt.Sprintf(`%s的死亡豁免检定: %s=%d %s`, getPlayerNameTempFunc(mctx), exprToShow, d20, suffix)
 					a, b := deathSaving(mctx, 0, 1)
@@ -0,0 +1,937 @@
+package dice
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// 命运核心(Fate Core)，使用4dF配合形容词阶梯
+
+var fateLadder = map[int64]string{
+	-2: "糟糕(Terrible)",
+	-1: "很差(Poor)",
+	0:  "平庸(Mediocre)",
+	1:  "一般(Average)",
+	2:  "尚可(Fair)",
+	3:  "良好(Good)",
+	4:  "出色(Great)",
+	5:  "极佳(Superb)",
+	6:  "惊人(Fantastic)",
+	7:  "史诗(Epic)",
+	8:  "传奇(Legendary)",
+}
+
+var fateLadderAlias = map[string]int64{
+	"糟糕": -2, "terrible": -2,
+	"很差": -1, "poor": -1,
+	"平庸": 0, "mediocre": 0,
+	"一般": 1, "average": 1,
+	"尚可": 2, "fair": 2,
+	"良好": 3, "good": 3,
+	"出色": 4, "great": 4,
+	"极佳": 5, "superb": 5,
+	"惊人": 6, "fantastic": 6,
+	"史诗": 7, "epic": 7,
+	"传奇": 8, "legendary": 8,
+}
+
+// 后果等级及其吸收的伤害
+var fateConsequences = []struct {
+	Name  string
+	Shift int64
+}{
+	{"轻度", 2},
+	{"中度", 4},
+	{"重度", 6},
+}
+
+var fateConsequenceAlias = map[string]string{
+	"轻度": "轻度", "mild": "轻度",
+	"中度": "中度", "moderate": "中度",
+	"重度": "重度", "severe": "重度",
+}
+
+var fateStressTrackAlias = map[string]string{
+	"体能": "体能", "physical": "体能", "物理": "体能",
+	"心智": "心智", "mental": "心智", "精神": "心智",
+}
+
+var fateOppositionRe = regexp.MustCompile(`(?i)(?:^|\s)(?:vs|对抗|难度)\s*[:：=]?\s*([+-]?\d+|\S+)`)
+
+func fateLadderName(v int64) string {
+	if name, ok := fateLadder[v]; ok {
+		return name
+	}
+	if v > 8 {
+		return fmt.Sprintf("%s+%d", fateLadder[8], v-8)
+	}
+	return fmt.Sprintf("%s%d", fateLadder[-2], v+2)
+}
+
+// fateParseLadder 解析数值或阶梯形容词
+func fateParseLadder(s string) (int64, bool) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, true
+	}
+	v, ok := fateLadderAlias[strings.ToLower(s)]
+	return v, ok
+}
+
+func fateFormatShift(v int64) string {
+	if v >= 0 {
+		return "+" + strconv.FormatInt(v, 10)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+type fateRollResult struct {
+	Dice          []int64 // 各命运骰结果，-1/0/1
+	Modifier      int64   // 技能或修正值
+	ModText       string  // 修正的来源，如 "运动"
+	Bonus         int64   // 援引特征带来的加值
+	Invoked       []string
+	Reason        string
+	Opposition    int64
+	HasOpposition bool
+}
+
+func fateRollDice(ctx *MsgContext) ([]int64, error) {
+	dice := make([]int64, 4)
+	var rollErr error
+	for i := range dice {
+		v, err := ctx.diceRoll64(3)
+		if err != nil {
+			rollErr = err
+		}
+		dice[i] = v - 2
+	}
+	return dice, rollErr
+}
+
+func (r *fateRollResult) DiceSum() int64 {
+	var sum int64
+	for _, i := range r.Dice {
+		sum += i
+	}
+	return sum
+}
+
+func (r *fateRollResult) Total() int64 {
+	return r.DiceSum() + r.Modifier + r.Bonus
+}
+
+func (r *fateRollResult) DetailText() string {
+	var faces []string
+	for _, i := range r.Dice {
+		switch {
+		case i > 0:
+			faces = append(faces, "+")
+		case i < 0:
+			faces = append(faces, "-")
+		default:
+			faces = append(faces, "0")
+		}
+	}
+	text := fmt.Sprintf("4dF=[%s]%s", strings.Join(faces, " "), fateFormatShift(r.DiceSum()))
+	if r.ModText != "" {
+		text += fmt.Sprintf(" %s(%s)", r.ModText, fateFormatShift(r.Modifier))
+	} else if r.Modifier != 0 {
+		text += " " + fateFormatShift(r.Modifier)
+	}
+	if r.Bonus != 0 {
+		text += fmt.Sprintf(" 援引%s", fateFormatShift(r.Bonus))
+	}
+	return fmt.Sprintf("%s=%s %s", text, fateFormatShift(r.Total()), fateLadderName(r.Total()))
+}
+
+// OutcomeText 对抗结果，按超出的级数(shift)判定
+func (r *fateRollResult) OutcomeText() string {
+	if !r.HasOpposition {
+		return ""
+	}
+	shifts := r.Total() - r.Opposition
+	var text string
+	switch {
+	case shifts < 0:
+		text = "失败"
+	case shifts == 0:
+		text = "平手"
+	case shifts >= 3:
+		text = "漂亮地成功"
+	default:
+		text = "成功"
+	}
+	return fmt.Sprintf("对抗 %s %s，差值%s: %s",
+		fateFormatShift(r.Opposition), fateLadderName(r.Opposition), fateFormatShift(shifts), text)
+}
+
+// SuccessRank 对应 coc 的 $tSuccessRank，平手不算成功，没有对抗值时无从判断
+func (r *fateRollResult) SuccessRank() (int64, bool) {
+	if !r.HasOpposition {
+		return 0, false
+	}
+	shifts := r.Total() - r.Opposition
+	switch {
+	case shifts < 0:
+		return -1, true
+	case shifts == 0:
+		return 0, true
+	case shifts >= 3:
+		return 2, true
+	}
+	return 1, true
+}
+
+func (r *fateRollResult) ToVMValue() *ds.VMValue {
+	dice := ds.NewArrayVal()
+	ad := dice.MustReadArray()
+	for _, i := range r.Dice {
+		ad.List = append(ad.List, ds.NewIntVal(ds.IntType(i)))
+	}
+	invoked := ds.NewArrayVal()
+	ai := invoked.MustReadArray()
+	for _, i := range r.Invoked {
+		ai.List = append(ai.List, ds.NewStrVal(i))
+	}
+	hasOpposition := int64(0)
+	if r.HasOpposition {
+		hasOpposition = 1
+	}
+	return ds.NewDictValWithArrayMust(
+		ds.NewStrVal("dice"), dice,
+		ds.NewStrVal("mod"), ds.NewIntVal(ds.IntType(r.Modifier)),
+		ds.NewStrVal("modText"), ds.NewStrVal(r.ModText),
+		ds.NewStrVal("bonus"), ds.NewIntVal(ds.IntType(r.Bonus)),
+		ds.NewStrVal("invoked"), invoked,
+		ds.NewStrVal("reason"), ds.NewStrVal(r.Reason),
+		ds.NewStrVal("vs"), ds.NewIntVal(ds.IntType(r.Opposition)),
+		ds.NewStrVal("hasVs"), ds.NewIntVal(ds.IntType(hasOpposition)),
+	).V()
+}
+
+func fateRollResultFromVMValue(v *ds.VMValue) *fateRollResult {
+	if v == nil || v.TypeId != ds.VMTypeDict {
+		return nil
+	}
+	dd := v.MustReadDictData()
+	readInt := func(key string) int64 {
+		v, ok := dd.Dict.Load(key)
+		if !ok {
+			return 0
+		}
+		n, _ := v.ReadInt()
+		return int64(n)
+	}
+	readStr := func(key string) string {
+		v, ok := dd.Dict.Load(key)
+		if !ok {
+			return ""
+		}
+		return v.ToString()
+	}
+	readList := func(key string) []*ds.VMValue {
+		v, ok := dd.Dict.Load(key)
+		if !ok || v.TypeId != ds.VMTypeArray {
+			return nil
+		}
+		return v.MustReadArray().List
+	}
+
+	r := &fateRollResult{
+		Modifier:      readInt("mod"),
+		ModText:       readStr("modText"),
+		Bonus:         readInt("bonus"),
+		Reason:        readStr("reason"),
+		Opposition:    readInt("vs"),
+		HasOpposition: readInt("hasVs") != 0,
+	}
+	for _, i := range readList("dice") {
+		n, _ := i.ReadInt()
+		r.Dice = append(r.Dice, int64(n))
+	}
+	for _, i := range readList("invoked") {
+		r.Invoked = append(r.Invoked, i.ToString())
+	}
+	if len(r.Dice) != 4 {
+		return nil
+	}
+	return r
+}
+
+// fateLoadStrList 读取字符串数组，用于特征列表
+func fateLoadStrList(attrs *AttributesItem, key string) []string {
+	var ret []string
+	v := attrs.Load(key)
+	if v == nil || v.TypeId != ds.VMTypeArray {
+		return ret
+	}
+	for _, i := range v.MustReadArray().List {
+		ret = append(ret, i.ToString())
+	}
+	return ret
+}
+
+func fateStoreStrList(attrs *AttributesItem, key string, lst []string) {
+	v := ds.NewArrayVal()
+	ad := v.MustReadArray()
+	for _, i := range lst {
+		ad.List = append(ad.List, ds.NewStrVal(i))
+	}
+	attrs.Store(key, v)
+}
+
+func fateFindStr(lst []string, s string) int {
+	for index, i := range lst {
+		if strings.EqualFold(i, s) {
+			return index
+		}
+	}
+	return -1
+}
+
+// fateJoinArgs 拼接第from个(从0开始)之后的参数
+func fateJoinArgs(cmdArgs *CmdArgs, from int) string {
+	if from >= len(cmdArgs.Args) {
+		return ""
+	}
+	return strings.TrimSpace(strings.Join(cmdArgs.Args[from:], " "))
+}
+
+var fateSceneLock sync.Mutex
+
+// fateSceneAspects 场景特征存放于群属性中
+func fateSceneAspects(ctx *MsgContext) (*AttributesItem, []string) {
+	attrs, _ := ctx.Dice.AttrsManager.LoadById(ctx.Group.GroupID)
+	return attrs, fateLoadStrList(attrs, "fateSceneAspects")
+}
+
+func RegisterBuiltinExtFate(self *Dice) {
+	getTmpl := func(ctx *MsgContext) *GameSystemTemplate {
+		tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
+		if tmpl.Name != "fate" {
+			if tmpl2, _ := ctx.Dice.GameSystemMap.Load("fate"); tmpl2 != nil {
+				tmpl = tmpl2
+			}
+		}
+		return tmpl
+	}
+
+	setCommandInfo := func(mctx *MsgContext, cmd string, r *fateRollResult) {
+		mctx.CommandInfo = map[string]interface{}{
+			"cmd":    cmd,
+			"rule":   "fate",
+			"pcName": mctx.Player.Name,
+			"items": []interface{}{
+				map[string]interface{}{
+					"dice":     r.Dice,
+					"modifier": r.Modifier,
+					"bonus":    r.Bonus,
+					"invoked":  r.Invoked,
+					"total":    r.Total(),
+					"ladder":   fateLadderName(r.Total()),
+					"reason":   r.Reason,
+				},
+			},
+		}
+	}
+
+	appendCommandInfo := func(mctx *MsgContext, cmdArgs *CmdArgs, text string) string {
+		if kw := cmdArgs.GetKwarg("ci"); kw != nil {
+			info, err := json.Marshal(mctx.CommandInfo)
+			if err == nil {
+				text += "\n" + string(info)
+			} else {
+				text += "\n" + "指令信息无法序列化"
+			}
+		}
+		return text
+	}
+
+	updateNameCard := func(mctx *MsgContext) {
+		if mctx.Player.AutoSetNameTemplate != "" {
+			_, _ = SetPlayerGroupCardByTemplate(mctx, mctx.Player.AutoSetNameTemplate)
+		}
+	}
+
+	rollText := func(mctx *MsgContext, r *fateRollResult) string {
+		text := fmt.Sprintf("<%s>的命运检定", mctx.Player.Name)
+		if r.Reason != "" {
+			text += "「" + r.Reason + "」"
+		}
+		text += "\n" + r.DetailText()
+		if outcome := r.OutcomeText(); outcome != "" {
+			text += "\n" + outcome
+		}
+		return text
+	}
+
+	helpFate := "" +
+		".fate [技能|修正] [vs <对抗值>] [原因] // 投掷4dF，如 .fate 运动+1 vs 尚可 翻越围墙\n" +
+		".fate ladder // 查看形容词阶梯\n" +
+		".fate <技能> @某人 // 对某人做检定\n" +
+		"对抗值可以是数字或阶梯形容词，投掷后可用 .invoke 援引特征"
+
+	cmdFate := &CmdItemInfo{
+		Name:          "fate",
+		ShortHelp:     helpFate,
+		Help:          "命运核心检定:\n" + helpFate,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			switch cmdArgs.GetArgN(1) {
+			case "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "ladder", "阶梯":
+				var lines []string
+				for i := int64(8); i >= -2; i-- {
+					lines = append(lines, fmt.Sprintf("%s %s", fateFormatShift(i), fateLadder[i]))
+				}
+				ReplyToSender(ctx, msg, "形容词阶梯:\n"+strings.Join(lines, "\n"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			r := &fateRollResult{}
+			text := cmdArgs.CleanArgs
+			if m := fateOppositionRe.FindStringSubmatchIndex(text); m != nil {
+				v, ok := fateParseLadder(text[m[2]:m[3]])
+				if !ok {
+					ReplyToSender(ctx, msg, "无法识别的对抗值: "+text[m[2]:m[3]])
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				r.Opposition, r.HasOpposition = v, true
+				text = strings.TrimSpace(text[:m[0]] + " " + text[m[1]:])
+			}
+
+			if text != "" {
+				tmpl := getTmpl(mctx)
+				tctx := tmpl.evalCtx(mctx)
+				tctx.Eval(tmpl.PreloadCode, nil)
+				ret := tctx.Eval(text, nil)
+				if ret.vm.Error == nil {
+					n, ok := ret.ReadInt()
+					if !ok {
+						ReplyToSender(ctx, msg, "修正值必须为整数: "+ret.ToString())
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+					r.Modifier = int64(n)
+					r.ModText = strings.TrimSpace(ret.vm.Matched)
+					r.Reason = strings.TrimSpace(ret.vm.RestInput)
+					if _, err := strconv.ParseInt(r.ModText, 10, 64); err == nil {
+						r.ModText = ""
+					}
+				} else {
+					r.Reason = text
+				}
+			}
+
+			rolled, err := fateRollDice(mctx)
+			if err != nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			r.Dice = rolled
+			attrs, err := mctx.Dice.AttrsManager.LoadByCtx(mctx)
+			if err == nil {
+				attrs.Store("$fateLastRoll", r.ToVMValue())
+			}
+
+			if rank, ok := r.SuccessRank(); ok {
+				VarSetValueInt64(mctx, "$tSuccessRank", rank)
+			}
+			setCommandInfo(mctx, "fate", r)
+			ReplyToSender(mctx, msg, appendCommandInfo(mctx, cmdArgs, rollText(mctx, r)))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpInvoke := "" +
+		".invoke <特征> // 花费1点命运点援引特征，为上一次检定+2\n" +
+		".invoke <特征> reroll // 花费1点命运点援引特征，重骰上一次检定的4dF\n" +
+		".invoke <特征> free // 免费援引(如创造优势获得的免费援引)，不消耗命运点\n" +
+		"特征可以是自己角色的特征或场景特征"
+
+	cmdInvoke := &CmdItemInfo{
+		Name:      "invoke",
+		ShortHelp: helpInvoke,
+		Help:      "援引特征:\n" + helpInvoke,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if val := cmdArgs.GetArgN(1); val == "" || val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			reroll, free := false, false
+			var nameParts []string
+			for _, i := range cmdArgs.Args {
+				switch strings.ToLower(i) {
+				case "reroll", "重骰":
+					reroll = true
+				case "free", "免费":
+					free = true
+				default:
+					nameParts = append(nameParts, i)
+				}
+			}
+			aspect := strings.Join(nameParts, " ")
+			if aspect == "" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			attrs, err := ctx.Dice.AttrsManager.LoadByCtx(ctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			_, scene := fateSceneAspects(ctx)
+			if fateFindStr(fateLoadStrList(attrs, "$fateAspects"), aspect) == -1 && fateFindStr(scene, aspect) == -1 {
+				ReplyToSender(ctx, msg, fmt.Sprintf("未找到特征「%s」，可使用 .aspect 查看角色与场景特征", aspect))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			r := fateRollResultFromVMValue(attrs.Load("$fateLastRoll"))
+			if r == nil {
+				ReplyToSender(ctx, msg, "没有可以援引特征的检定，请先使用 .fate 进行检定")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			tmpl := getTmpl(ctx)
+			fp := tmpl.GetRealValueInt(ctx, "命运点")
+			if !free && fp <= 0 {
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的命运点不足，无法援引特征", ctx.Player.Name))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			// 先掷骰再扣命运点，物理骰等待录入时不改动角色卡
+			oldTotal := r.Total()
+			var effect string
+			if reroll {
+				rolled, err := fateRollDice(ctx)
+				if err != nil {
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				r.Dice = rolled
+				effect = "重骰"
+			} else {
+				r.Bonus += 2
+				effect = "+2"
+			}
+			if !free {
+				fp--
+				attrs.Store("命运点", ds.NewIntVal(ds.IntType(fp)))
+			}
+			r.Invoked = append(r.Invoked, aspect)
+			attrs.Store("$fateLastRoll", r.ToVMValue())
+
+			text := fmt.Sprintf("<%s>援引特征「%s」(%s)", ctx.Player.Name, aspect, effect)
+			if free {
+				text += "，免费援引"
+			} else {
+				text += fmt.Sprintf("，命运点剩余%d", fp)
+			}
+			text += fmt.Sprintf("\n%s → %s\n", fateFormatShift(oldTotal), fateFormatShift(r.Total())) + rollText(ctx, r)
+
+			if rank, ok := r.SuccessRank(); ok {
+				VarSetValueInt64(ctx, "$tSuccessRank", rank)
+			}
+			setCommandInfo(ctx, "invoke", r)
+			updateNameCard(ctx)
+			ReplyToSender(ctx, msg, appendCommandInfo(ctx, cmdArgs, text))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpCompel := "" +
+		".compel <特征> @某人 // 强制某人的特征，需要管理/邀请者权限，等待对方答复\n" +
+		".compel accept // 接受对自己的强制，获得1点命运点\n" +
+		".compel refuse // 拒绝对自己的强制，花费1点命运点"
+
+	cmdCompel := &CmdItemInfo{
+		Name:          "compel",
+		ShortHelp:     helpCompel,
+		Help:          "强制特征:\n" + helpCompel,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			aspect := strings.TrimSpace(cmdArgs.CleanArgs)
+			if aspect == "" || aspect == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			// 答复只能针对自己的强制
+			switch strings.ToLower(aspect) {
+			case "accept", "接受", "refuse", "拒绝":
+				attrs, err := ctx.Dice.AttrsManager.LoadByCtx(ctx)
+				if err != nil {
+					ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				pending, ok := attrs.LoadX("$fateCompel")
+				if !ok {
+					ReplyToSender(ctx, msg, fmt.Sprintf("<%s>当前没有待答复的强制", ctx.Player.Name))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				fp := getTmpl(ctx).GetRealValueInt(ctx, "命运点")
+				var text string
+				if a := strings.ToLower(aspect); a == "accept" || a == "接受" {
+					fp++
+					text = fmt.Sprintf("<%s>接受了对特征「%s」的强制，获得1点命运点，现有%d点", ctx.Player.Name, pending.ToString(), fp)
+				} else {
+					if fp < 1 {
+						ReplyToSender(ctx, msg, fmt.Sprintf("<%s>没有命运点，无法拒绝强制", ctx.Player.Name))
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+					fp--
+					text = fmt.Sprintf("<%s>花费1点命运点拒绝了对特征「%s」的强制，命运点剩余%d", ctx.Player.Name, pending.ToString(), fp)
+				}
+				attrs.Store("命运点", ds.NewIntVal(ds.IntType(fp)))
+				attrs.Delete("$fateCompel")
+				updateNameCard(ctx)
+				ReplyToSender(ctx, msg, text)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			if ctx.PrivilegeLevel < 40 {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			if mctx.Player.UserID == ctx.Player.UserID {
+				ReplyToSender(ctx, msg, "请@被强制特征的人")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			attrs, err := mctx.Dice.AttrsManager.LoadByCtx(mctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			attrs.Store("$fateCompel", ds.NewStrVal(aspect))
+
+			text := fmt.Sprintf("<%s>强制了<%s>的特征「%s」\n<%s>可用 .compel accept 接受并获得1点命运点，或 .compel refuse 花费1点命运点拒绝",
+				ctx.Player.Name, mctx.Player.Name, aspect, mctx.Player.Name)
+			ReplyToSender(ctx, msg, text)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpFp := "" +
+		".fp // 查看命运点\n" +
+		".fp +<数值>/-<数值> // 增减命运点\n" +
+		".fp refresh // 复原，命运点低于复原值时补至复原值\n" +
+		".fp @某人 // 查看某人的命运点"
+
+	cmdFp := &CmdItemInfo{
+		Name:          "fp",
+		ShortHelp:     helpFp,
+		Help:          "命运点:\n" + helpFp,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			attrs, err := mctx.Dice.AttrsManager.LoadByCtx(mctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			tmpl := getTmpl(mctx)
+			fp := tmpl.GetRealValueInt(mctx, "命运点")
+			refresh := tmpl.GetRealValueInt(mctx, "复原")
+
+			val := cmdArgs.GetArgN(1)
+			switch {
+			case val == "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case val == "":
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的命运点: %d (复原值%d)", mctx.Player.Name, fp, refresh))
+			case val == "refresh" || val == "复原":
+				newFp := fp
+				if newFp < refresh {
+					newFp = refresh
+				}
+				attrs.Store("命运点", ds.NewIntVal(ds.IntType(newFp)))
+				updateNameCard(mctx)
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>进行复原，命运点: %d → %d", mctx.Player.Name, fp, newFp))
+			case strings.HasPrefix(val, "+") || strings.HasPrefix(val, "-"):
+				n, err := strconv.ParseInt(val, 10, 64)
+				if err != nil {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				newFp := fp + n
+				if newFp < 0 {
+					ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的命运点不足，现有%d点", mctx.Player.Name, fp))
+					break
+				}
+				attrs.Store("命运点", ds.NewIntVal(ds.IntType(newFp)))
+				updateNameCard(mctx)
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的命运点: %d → %d", mctx.Player.Name, fp, newFp))
+			default:
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpAspect := "" +
+		".aspect // 查看角色特征与场景特征\n" +
+		".aspect add <特征> // 为角色添加特征\n" +
+		".aspect del <特征> // 删除角色特征\n" +
+		".aspect scene add <特征> // 添加场景特征\n" +
+		".aspect scene del <特征> // 删除场景特征\n" +
+		".aspect scene clr // 清空场景特征"
+
+	cmdAspect := &CmdItemInfo{
+		Name:      "aspect",
+		ShortHelp: helpAspect,
+		Help:      "特征:\n" + helpAspect,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			attrs, err := ctx.Dice.AttrsManager.LoadByCtx(ctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			aspects := fateLoadStrList(attrs, "$fateAspects")
+
+			if cmdArgs.IsArgEqual(1, "scene", "场景") {
+				fateSceneLock.Lock()
+				defer fateSceneLock.Unlock()
+				groupAttrs, scene := fateSceneAspects(ctx)
+				name := fateJoinArgs(cmdArgs, 2)
+
+				switch cmdArgs.GetArgN(2) {
+				case "":
+					if len(scene) == 0 {
+						ReplyToSender(ctx, msg, "当前场景没有特征")
+						break
+					}
+					ReplyToSender(ctx, msg, "场景特征:\n"+strings.Join(scene, "\n"))
+				case "add":
+					if name == "" {
+						return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+					}
+					if fateFindStr(scene, name) != -1 {
+						ReplyToSender(ctx, msg, "场景中已存在特征: "+name)
+						break
+					}
+					fateStoreStrList(groupAttrs, "fateSceneAspects", append(scene, name))
+					ReplyToSender(ctx, msg, "已添加场景特征: "+name)
+				case "del", "rm":
+					index := fateFindStr(scene, name)
+					if index == -1 {
+						ReplyToSender(ctx, msg, "未找到场景特征: "+name)
+						break
+					}
+					fateStoreStrList(groupAttrs, "fateSceneAspects", append(scene[:index], scene[index+1:]...))
+					ReplyToSender(ctx, msg, "已删除场景特征: "+name)
+				case "clr":
+					fateStoreStrList(groupAttrs, "fateSceneAspects", nil)
+					ReplyToSender(ctx, msg, "已清空场景特征")
+				default:
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			name := fateJoinArgs(cmdArgs, 1)
+			switch cmdArgs.GetArgN(1) {
+			case "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "":
+				text := fmt.Sprintf("<%s>的特征:\n", ctx.Player.Name)
+				if len(aspects) == 0 {
+					text += "(无)"
+				} else {
+					text += strings.Join(aspects, "\n")
+				}
+				if _, scene := fateSceneAspects(ctx); len(scene) > 0 {
+					text += "\n场景特征:\n" + strings.Join(scene, "\n")
+				}
+				ReplyToSender(ctx, msg, text)
+			case "add":
+				if name == "" {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				if fateFindStr(aspects, name) != -1 {
+					ReplyToSender(ctx, msg, "角色已有特征: "+name)
+					break
+				}
+				fateStoreStrList(attrs, "$fateAspects", append(aspects, name))
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>添加了特征: %s", ctx.Player.Name, name))
+			case "del", "rm":
+				index := fateFindStr(aspects, name)
+				if index == -1 {
+					ReplyToSender(ctx, msg, "未找到角色特征: "+name)
+					break
+				}
+				fateStoreStrList(attrs, "$fateAspects", append(aspects[:index], aspects[index+1:]...))
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>删除了特征: %s", ctx.Player.Name, name))
+			default:
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpStress := "" +
+		".stress // 查看压力槽与后果\n" +
+		".stress <体能|心智> <格> // 勾选压力格吸收伤害，格已占用时顺延至更高的空格，如 .stress 体能 2\n" +
+		".stress clr // 清空压力槽\n" +
+		".stress cons <轻度|中度|重度> <描述> // 承受后果，分别吸收2/4/6点伤害\n" +
+		".stress cons del <轻度|中度|重度> // 移除后果"
+
+	cmdStress := &CmdItemInfo{
+		Name:          "stress",
+		ShortHelp:     helpStress,
+		Help:          "压力与后果:\n" + helpStress,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			attrs, err := mctx.Dice.AttrsManager.LoadByCtx(mctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			tmpl := getTmpl(mctx)
+
+			// 压力格以位图形式存放，第n位表示第n+1格
+			loadBoxes := func(track string) int64 {
+				v := attrs.Load("$fateStress" + track)
+				if v == nil {
+					return 0
+				}
+				n, _ := v.ReadInt()
+				return int64(n)
+			}
+			renderTrack := func(track string) string {
+				boxes := loadBoxes(track)
+				size := tmpl.GetRealValueInt(mctx, track+"压力格")
+				var items []string
+				for i := int64(0); i < size; i++ {
+					if boxes&(1<<i) != 0 {
+						items = append(items, fmt.Sprintf("[%d:×]", i+1))
+					} else {
+						items = append(items, fmt.Sprintf("[%d: ]", i+1))
+					}
+				}
+				return fmt.Sprintf("%s压力: %s", track, strings.Join(items, ""))
+			}
+			showAll := func() string {
+				lines := []string{
+					fmt.Sprintf("<%s>的压力与后果:", mctx.Player.Name),
+					renderTrack("体能"),
+					renderTrack("心智"),
+				}
+				for _, i := range fateConsequences {
+					desc := "(无)"
+					if v := attrs.Load("$fateCons" + i.Name); v != nil && v.ToString() != "" {
+						desc = v.ToString()
+					}
+					lines = append(lines, fmt.Sprintf("%s后果(-%d): %s", i.Name, i.Shift, desc))
+				}
+				return strings.Join(lines, "\n")
+			}
+
+			val := cmdArgs.GetArgN(1)
+			if track, ok := fateStressTrackAlias[strings.ToLower(val)]; ok {
+				n, err := strconv.ParseInt(cmdArgs.GetArgN(2), 10, 64)
+				if err != nil || n <= 0 {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				boxes := loadBoxes(track)
+				size := tmpl.GetRealValueInt(mctx, track+"压力格")
+				var marked int64
+				for i := n; i <= size; i++ {
+					if boxes&(1<<(i-1)) == 0 {
+						marked = i
+						break
+					}
+				}
+				if marked == 0 {
+					ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的%s压力槽无法吸收%d点伤害，需要承受后果或被击倒\n%s",
+						mctx.Player.Name, track, n, renderTrack(track)))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				boxes |= 1 << (marked - 1)
+				attrs.Store("$fateStress"+track, ds.NewIntVal(ds.IntType(boxes)))
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>勾选了第%d格%s压力\n%s",
+					mctx.Player.Name, marked, track, renderTrack(track)))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			switch val {
+			case "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "":
+				ReplyToSender(ctx, msg, showAll())
+			case "clr":
+				attrs.Delete("$fateStress体能")
+				attrs.Delete("$fateStress心智")
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的压力槽已清空", mctx.Player.Name))
+			case "cons", "后果":
+				if cmdArgs.IsArgEqual(2, "del", "rm") {
+					level, ok := fateConsequenceAlias[strings.ToLower(cmdArgs.GetArgN(3))]
+					if !ok {
+						return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+					}
+					attrs.Delete("$fateCons" + level)
+					ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的%s后果已移除", mctx.Player.Name, level))
+					break
+				}
+				level, ok := fateConsequenceAlias[strings.ToLower(cmdArgs.GetArgN(2))]
+				desc := fateJoinArgs(cmdArgs, 2)
+				if !ok || desc == "" {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				if v := attrs.Load("$fateCons" + level); v != nil && v.ToString() != "" {
+					ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的%s后果栏已被「%s」占用", mctx.Player.Name, level, v.ToString()))
+					break
+				}
+				attrs.Store("$fateCons"+level, ds.NewStrVal(desc))
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>承受了%s后果「%s」", mctx.Player.Name, level, desc))
+			default:
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	cmdSt := getCmdStBase(CmdStOverrideInfo{
+		TemplateName: "fate",
+	})
+
+	theExt := &ExtInfo{
+		Name:       "fate", // 扩展的名称，需要用于开启和关闭指令中，写简短点
+		Aliases:    []string{"fatecore"},
+		Version:    "1.0.0",
+		Brief:      "提供命运核心(Fate Core)规则TRPG支持",
+		Author:     "海豹核心",
+		AutoActive: false, // 是否自动开启
+		Official:   true,
+		ConflictWith: []string{
+			"coc7",
+			"dnd5e",
+		},
+		OnCommandReceived: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+		},
+		GetDescText: GetExtensionDesc,
+		CmdMap: CmdMapCls{
+			"fate":   cmdFate,
+			"invoke": cmdInvoke,
+			"compel": cmdCompel,
+			"fp":     cmdFp,
+			"aspect": cmdAspect,
+			"stress": cmdStress,
+			"st":     cmdSt,
+		},
+	}
+
+	self.RegisterExtension(theExt)
+}
@@ -0,0 +1,71 @@
+package dice
+
+var _fateTmpl = &GameSystemTemplate{
+	Name:        "fate",
+	FullName:    "命运核心(Fate Core)",
+	Authors:     []string{"海豹核心"},
+	Version:     "1.0.0",
+	UpdatedTime: "20241019",
+	TemplateVer: "1.0",
+
+	SetConfig: SetConfig{
+		DiceSidesExpr: "6",
+		DiceSides:     6,
+		Keys:          []string{"fate", "fatecore"},
+		EnableTip:     "已切换至6面骰，并自动开启fate扩展",
+		RelatedExt:    []string{"fate"},
+	},
+
+	NameTemplate: map[string]NameTemplateItem{
+		"fate": {
+			Template: "{$t玩家_RAW} 命运点{命运点}",
+			HelpText: "自动设置命运核心名片",
+		},
+	},
+
+	AttrConfig: AttrConfig{
+		Top: []string{
+			"命运点", "复原",
+			"运动", "窃盗", "人脉", "工艺", "欺诈", "驾驶",
+			"共情", "格斗", "调查", "学识", "察觉", "体格",
+			"激励", "交际", "资源", "射击", "潜行", "意志",
+		},
+		SortBy:       "Name",
+		Ignores:      []string{"体能压力格", "心智压力格"},
+		ItemsPerLine: 4,
+	},
+
+	Defaults: map[string]int64{
+		"命运点": 3,
+		"复原":  3,
+	},
+	DefaultsComputed: map[string]string{
+		// 体格/意志 1-2级时3格，3级及以上4格
+		"体能压力格": "体格 >= 3 ? 4 : (体格 >= 1 ? 3 : 2)",
+		"心智压力格": "意志 >= 3 ? 4 : (意志 >= 1 ? 3 : 2)",
+	},
+
+	Alias: map[string][]string{
+		"命运点": {"fp", "fatepoint", "fatepoints"},
+		"复原":  {"refresh", "复原值"},
+
+		"运动": {"athletics"},
+		"窃盗": {"burglary", "盗窃"},
+		"人脉": {"contacts"},
+		"工艺": {"crafts"},
+		"欺诈": {"deceive", "欺骗"},
+		"驾驶": {"drive"},
+		"共情": {"empathy"},
+		"格斗": {"fight"},
+		"调查": {"investigate"},
+		"学识": {"lore"},
+		"察觉": {"notice"},
+		"体格": {"physique"},
+		"激励": {"provoke", "挑衅"},
+		"交际": {"rapport"},
+		"资源": {"resources"},
+		"射击": {"shoot"},
+		"潜行": {"stealth"},
+		"意志": {"will"},
+	},
+}
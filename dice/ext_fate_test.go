@@ -0,0 +1,105 @@
+package dice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFateLadder(t *testing.T) {
+	names := map[int64]string{
+		-2: "糟糕(Terrible)",
+		0:  "平庸(Mediocre)",
+		8:  "传奇(Legendary)",
+		10: "传奇(Legendary)+2",
+		-3: "糟糕(Terrible)-1",
+	}
+	for v, want := range names {
+		if got := fateLadderName(v); got != want {
+			t.Errorf("fateLadderName(%d) = %q, want %q", v, got, want)
+		}
+	}
+
+	for s, want := range map[string]int64{"3": 3, "-1": -1, "+2": 2, "良好": 3, "Great": 4, "legendary": 8} {
+		if v, ok := fateParseLadder(s); !ok || v != want {
+			t.Errorf("fateParseLadder(%q) = %d, %v", s, v, ok)
+		}
+	}
+	if _, ok := fateParseLadder("很好"); ok {
+		t.Error("unknown ladder word should fail")
+	}
+}
+
+func TestFateRoll(t *testing.T) {
+	dice, err := fateRollDice(newFixedDiceCtx(1, 2, 3, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{-1, 0, 1, 1}
+	for i := range want {
+		if dice[i] != want[i] {
+			t.Fatalf("dice %v, want %v", dice, want)
+		}
+	}
+
+	r := &fateRollResult{Dice: dice, Modifier: 2, Bonus: 2}
+	if r.DiceSum() != 1 || r.Total() != 5 {
+		t.Fatalf("sum=%d total=%d", r.DiceSum(), r.Total())
+	}
+	if _, ok := r.SuccessRank(); ok {
+		t.Error("no opposition should give no rank")
+	}
+
+	r.HasOpposition = true
+	for opp, want := range map[int64]int64{6: -1, 5: 0, 4: 1, 3: 1, 2: 2, 0: 2} {
+		r.Opposition = opp
+		if rank, ok := r.SuccessRank(); !ok || rank != want {
+			t.Errorf("total 5 vs %d: rank %d, want %d", opp, rank, want)
+		}
+	}
+}
+
+func TestFateCompel(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	send := chat.send
+	fp := func(user string) string {
+		return send(user, "", ".fp")
+	}
+
+	send("gm", "owner", ".ext fate on")
+	send("pc", "", ".st 命运点3")
+
+	if text := send("gm", "owner", ".compel 贪婪"); !strings.Contains(text, "请@") {
+		t.Fatalf("没有目标的强制应被拒绝: %q", text)
+	}
+	if text := send("pc", "", ".compel 贪婪"); !strings.Contains(text, "管理员") {
+		t.Fatalf("不能强制自己: %q", text)
+	}
+	if text := send("pc2", "", ".compel 贪婪 [CQ:at,qq=vtt:pc]"); !strings.Contains(text, "管理员") {
+		t.Fatalf("普通成员不能强制: %q", text)
+	}
+	if text := send("pc", "", ".compel accept"); !strings.Contains(text, "没有待答复") {
+		t.Fatalf("没有强制时不能接受: %q", text)
+	}
+	if text := fp("pc"); !strings.Contains(text, "命运点: 3") {
+		t.Fatalf("命运点不应变化: %q", text)
+	}
+
+	send("gm", "owner", ".compel 贪婪 [CQ:at,qq=vtt:pc]")
+	if text := fp("pc"); !strings.Contains(text, "命运点: 3") {
+		t.Fatalf("答复前命运点不应变化: %q", text)
+	}
+	send("pc", "", ".compel accept")
+	if text := fp("pc"); !strings.Contains(text, "命运点: 4") {
+		t.Fatalf("接受后应获得命运点: %q", text)
+	}
+	if text := send("pc", "", ".compel accept"); !strings.Contains(text, "没有待答复") {
+		t.Fatalf("强制只能答复一次: %q", text)
+	}
+
+	send("gm", "owner", ".compel 贪婪 [CQ:at,qq=vtt:pc]")
+	send("pc", "", ".compel refuse")
+	if text := fp("pc"); !strings.Contains(text, "命运点: 3") {
+		t.Fatalf("拒绝后应花费命运点: %q", text)
+	}
+}
@@ -167,6 +167,9 @@ func RegisterBuiltinExtFun(self *Dice) {
 		".alias del/rm --my <别名> // 删除个人快捷指令\n" +
 		".alias show/list // 显示目前可用的快捷指令\n" +
 		".alias help // 查看帮助\n" +
+		".alias <别名> {参数=默认值} <指令> // 定义带参数的快捷指令，指令中用 {参数} 引用，如 .alias atk {weapon=长剑} .ra 斗殴 {weapon}\n" +
+		".alias <别名> <指令1> && <指令2> // 多步快捷指令，&& 为上一步成功才执行，|| 为上一步失败才执行，;; 为总是执行\n" +
+		"// 成功与否按检定结果判断(带对抗值/DC的检定)，不产生检定结果的指令执行了即视为成功\n" +
 		"// 执行快捷命令见 .& 命令"
 	cmdAlias := CmdItemInfo{
 		Name:      "alias",
@@ -180,11 +183,10 @@ func RegisterBuiltinExtFun(self *Dice) {
 			_isPersonal := cmdArgs.GetKwarg("my")
 			isPersonal := ctx.MessageType == "private" || _isPersonal != nil
 
-			playerAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Player.UserID))
+			playerAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Dice.AttrsManager.UIDConvert(ctx.Player.UserID)))
 			groupAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Group.GroupID))
 			subCmd := cmdArgs.GetArgN(1)
 
-		subParse:
 			switch subCmd {
 			case "help":
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -259,16 +261,6 @@ func RegisterBuiltinExtFun(self *Dice) {
 					break
 				}
 				name := subCmd
-				if len(cmdArgs.Args) >= 2 {
-					targetCmd := cmdArgs.GetArgN(2)
-					for _, prefix := range ctx.Session.Parent.CommandPrefix {
-						// 这里依然拦截不了先定义了快捷指令，后添加了新的指令前缀导致出现递归的情况，但是一是这种情况少，二是后面执行阶段也有拦截所以问题不大
-						if targetCmd == prefix+"a" || targetCmd == prefix+"&" {
-							ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:快捷指令_禁止"))
-							break subParse
-						}
-					}
-				}
 				_args := cmdArgs.Args[1:]
 				for _, kwa := range cmdArgs.Kwargs {
 					if kwa.Name != "my" {
@@ -276,6 +268,11 @@ func RegisterBuiltinExtFun(self *Dice) {
 					}
 				}
 				cmd := strings.TrimSpace(strings.Join(_args, " "))
+				if err := aliasCheck(ctx.Dice, aliasParse(cmd)); err != nil {
+					// 这里依然拦截不了先定义了快捷指令，后添加了新的指令前缀导致出现递归的情况，但是一是这种情况少，二是后面执行阶段也有拦截所以问题不大
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:快捷指令_禁止")+"\n"+err.Error())
+					break
+				}
 
 				m := groupAttrs
 				key := "$g:alias:" + name
@@ -309,6 +306,7 @@ func RegisterBuiltinExtFun(self *Dice) {
 	}
 
 	aHelp := ".&/a <快捷指令名> [参数] // 执行对应快捷指令\n" +
+		".&/a <快捷指令名> 参数名=值 // 按名字指定参数\n" +
 		".& help // 查看帮助\n" +
 		"// 定义快捷指令见 .alias 命令"
 	cmdA := CmdItemInfo{
@@ -325,60 +323,66 @@ func RegisterBuiltinExtFun(self *Dice) {
 			}
 
 			log := self.Logger
-			args := cmdArgs.Args
-			for _, kwa := range cmdArgs.Kwargs {
-				args = append(args, kwa.String())
-			}
-
-			if msg.MessageType == "group" {
-				groupAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Group.GroupID))
-				cmdValue, ok := groupAttrs.LoadX("$g:alias:" + name)
-				if ok {
-					if cmdValue != nil && cmdValue.TypeId == ds.VMTypeString {
-						args[0] = cmdValue.Value.(string)
-						targetCmd := strings.Join(args, " ")
-						targetArgs := CommandParse(targetCmd, []string{}, self.CommandPrefix, msg.Platform, false)
-						if targetArgs != nil {
-							log.Infof("群快捷指令映射: .&%s -> %s", cmdArgs.CleanArgs, targetCmd)
-							if targetArgs.Command == "a" || targetArgs.Command == "&" {
-								return CmdExecuteResult{Matched: true, Solved: true}
-							}
+			runAlias := func(source string, cmdValue *ds.VMValue) bool {
+				if cmdValue == nil || cmdValue.TypeId != ds.VMTypeString {
+					return false
+				}
+				macro := aliasParse(cmdValue.Value.(string))
+				steps, err := macro.Expand(cmdArgs.Args[1:], cmdArgs.Kwargs)
+				if err == nil {
+					err = aliasCheck(ctx.Dice, &aliasMacro{Steps: steps})
+				}
+				if err != nil {
+					ReplyToSender(ctx, msg, fmt.Sprintf("快捷指令 %s 无法执行: %s", name, err.Error()))
+					return true
+				}
 
-							VarSetValueStr(ctx, "$t指令来源", "群")
-							VarSetValueStr(ctx, "$t目标指令", targetCmd)
-							ctx.AliasPrefixText = DiceFormatTmpl(ctx, "核心:快捷指令触发_前缀")
+				// 每一步成功与否以指令是否执行为准，检定指令(coc/dnd死亡豁免/blades/pf2e/fate)再看检定是否通过：
+				// 默认为写入的 $tSuccessRank 大于0，coc 等有难度要求的检定由指令用 aliasCheckResult 记录，其余指令执行了即视为成功
+				prevOK := true
+				for _, step := range steps {
+					if (step.Op == "&&" && !prevOK) || (step.Op == "||" && prevOK) {
+						continue
+					}
+					targetArgs := CommandParse(step.Text, []string{}, self.CommandPrefix, msg.Platform, false)
+					if targetArgs == nil {
+						prevOK = false
+						continue
+					}
+					log.Infof("%s快捷指令映射: .&%s -> %s", source, cmdArgs.CleanArgs, step.Text)
+					if targetArgs.Command == "a" || targetArgs.Command == "&" {
+						break
+					}
 
-							ctx.EndPoint.TriggerCommand(ctx, msg, targetArgs)
-							return CmdExecuteResult{Matched: true, Solved: true}
-						}
+					msg.Message = step.Text
+					VarSetValueStr(ctx, "$t指令来源", source)
+					VarSetValueStr(ctx, "$t目标指令", step.Text)
+					ctx.AliasPrefixText = DiceFormatTmpl(ctx, "核心:快捷指令触发_前缀")
+
+					// 检定可能代骰给@的人，结果写在复制出的 ctx 里，因此不从 ctx 读取而是记录在共用的 aliasStep 中
+					result := &aliasStepResult{}
+					ctx.aliasStep = result
+					prevOK = ctx.EndPoint.TriggerCommand(ctx, msg, targetArgs)
+					ctx.aliasStep = nil
+					if result.set && !result.passed {
+						prevOK = false
 					}
 				}
+				return true
 			}
 
-			playerAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Player.UserID))
-			cmdValue, ok := playerAttrs.LoadX("$m:alias:" + name)
-			if ok {
-				if cmdValue != nil && cmdValue.TypeId == ds.VMTypeString {
-					args[0] = cmdValue.Value.(string)
-					targetCmd := strings.Join(args, " ")
-					msg.Message = targetCmd
-					targetArgs := CommandParse(targetCmd, []string{}, self.CommandPrefix, msg.Platform, false)
-					if targetArgs != nil {
-						log.Infof("个人快捷指令映射: .&%s -> %s", cmdArgs.CleanArgs, targetCmd)
-						if targetArgs.Command == "a" || targetArgs.Command == "&" {
-							return CmdExecuteResult{Matched: true, Solved: true}
-						}
-
-						VarSetValueStr(ctx, "$t指令来源", "个人")
-						VarSetValueStr(ctx, "$t目标指令", targetCmd)
-						ctx.AliasPrefixText = DiceFormatTmpl(ctx, "核心:快捷指令触发_前缀")
-
-						ctx.EndPoint.TriggerCommand(ctx, msg, targetArgs)
-						return CmdExecuteResult{Matched: true, Solved: true}
-					}
+			if msg.MessageType == "group" {
+				groupAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Group.GroupID))
+				if cmdValue, ok := groupAttrs.LoadX("$g:alias:" + name); ok && runAlias("群", cmdValue) {
+					return CmdExecuteResult{Matched: true, Solved: true}
 				}
 			}
 
+			playerAttrs := lo.Must(ctx.Dice.AttrsManager.LoadById(ctx.Dice.AttrsManager.UIDConvert(ctx.Player.UserID)))
+			if cmdValue, ok := playerAttrs.LoadX("$m:alias:" + name); ok && runAlias("个人", cmdValue) {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
 			VarSetValueStr(ctx, "$t目标指令名", name)
 			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:快捷指令触发_无指令"))
 			return CmdExecuteResult{Matched: true, Solved: true}
@@ -1373,3 +1377,264 @@ func fingerprint(b string) uint64 {
 	_, _ = hash.Write([]byte(b))
 	return hash.Sum64()
 }
+
+// 快捷指令宏：
+// 开头的 {参数名} 或 {参数名=默认值} 为参数声明，其余部分是一条或多条指令，
+// 指令之间用 && (上一步成功才执行)、|| (上一步失败才执行)、;; (总是执行) 连接，
+// 指令中的 {参数名}、{参数名=默认值}、{1} 会被替换为实际参数，{*} 为多余的参数。
+// 只有声明过的参数名会被替换，其余的 {xxx} 原样保留，以兼容旧版本中带花括号的快捷指令。
+
+// aliasParam 快捷指令参数
+type aliasParam struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// aliasStep 快捷指令中的一步
+type aliasStep struct {
+	Op   string // 与上一步的关系，第一步为空
+	Text string
+}
+
+// aliasStepResult 快捷指令一步执行后的检定结果
+type aliasStepResult struct {
+	passed bool
+	set    bool
+}
+
+// aliasCheckResult 记录当前检定是否通过，供快捷指令的 &&/|| 判断，不在快捷指令中时什么也不做
+func (ctx *MsgContext) aliasCheckResult(passed bool) {
+	if ctx.aliasStep != nil {
+		ctx.aliasStep.passed, ctx.aliasStep.set = passed, true
+	}
+}
+
+// aliasMacro 解析后的快捷指令
+type aliasMacro struct {
+	Params []*aliasParam
+	Steps  []aliasStep
+
+	placeholderRe *regexp.Regexp
+}
+
+var (
+	aliasDeclRe = regexp.MustCompile(`^\s*\{([^{}=\s$][^{}=\s]*)(?:=([^{}]*))?\}`)
+	aliasUseRe  = regexp.MustCompile(`\{([^{}=\s$][^{}=\s]*)(?:=[^{}]*)?\}`)
+	aliasSepRe  = regexp.MustCompile(`\s+(&&|\|\||;;)\s+`)
+	aliasKvRe   = regexp.MustCompile(`^([^=\s]+)=(.*)$`)
+)
+
+// aliasParse 解析快捷指令的定义文本
+func aliasParse(text string) *aliasMacro {
+	m := &aliasMacro{}
+	findParam := func(name string) *aliasParam {
+		for _, p := range m.Params {
+			if p.Name == name {
+				return p
+			}
+		}
+		return nil
+	}
+
+	body := text
+	for {
+		loc := aliasDeclRe.FindStringSubmatchIndex(body)
+		if loc == nil {
+			break
+		}
+		name := body[loc[2]:loc[3]]
+		if findParam(name) == nil {
+			p := &aliasParam{Name: name}
+			if loc[4] >= 0 {
+				p.Default = body[loc[4]:loc[5]]
+				p.HasDefault = true
+			}
+			m.Params = append(m.Params, p)
+		}
+		body = body[loc[1]:]
+	}
+
+	names := []string{`\d+`, `\*`}
+	for _, p := range m.Params {
+		names = append(names, regexp.QuoteMeta(p.Name))
+	}
+	m.placeholderRe = regexp.MustCompile(`\{(` + strings.Join(names, "|") + `)(?:=([^{}]*))?\}`)
+
+	pos := 0
+	op := ""
+	for _, loc := range aliasSepRe.FindAllStringSubmatchIndex(body, -1) {
+		m.Steps = append(m.Steps, aliasStep{Op: op, Text: strings.TrimSpace(body[pos:loc[0]])})
+		op = body[loc[2]:loc[3]]
+		pos = loc[1]
+	}
+	m.Steps = append(m.Steps, aliasStep{Op: op, Text: strings.TrimSpace(body[pos:])})
+	return m
+}
+
+func isAllDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// HasPlaceholder 是否使用了参数，未使用参数的快捷指令保持旧行为，参数直接附加在指令末尾
+func (m *aliasMacro) HasPlaceholder() bool {
+	for _, step := range m.Steps {
+		if m.placeholderRe.MatchString(step.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Undeclared 指令中用到但没有声明的参数名，{1}、{*} 这样的序号参数不计入
+func (m *aliasMacro) Undeclared() []string {
+	var names []string
+	for _, step := range m.Steps {
+		for _, sub := range aliasUseRe.FindAllStringSubmatch(step.Text, -1) {
+			name := sub[1]
+			if name == "*" || isAllDigits(name) || lo.Contains(names, name) {
+				continue
+			}
+			if !lo.ContainsBy(m.Params, func(p *aliasParam) bool { return p.Name == name }) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Expand 代入参数，得到实际要执行的各步指令
+func (m *aliasMacro) Expand(args []string, kwargs []*Kwarg) ([]aliasStep, error) {
+	steps := make([]aliasStep, len(m.Steps))
+	copy(steps, m.Steps)
+
+	if !m.HasPlaceholder() {
+		var rest []string
+		rest = append(rest, args...)
+		for _, kwa := range kwargs {
+			rest = append(rest, kwa.String())
+		}
+		if len(rest) > 0 {
+			steps[0].Text = strings.TrimSpace(steps[0].Text + " " + strings.Join(rest, " "))
+		}
+		return steps, nil
+	}
+
+	named := map[string]string{}
+	var positional, extra []string
+	isParam := func(name string) bool {
+		for _, p := range m.Params {
+			if p.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, arg := range args {
+		if kv := aliasKvRe.FindStringSubmatch(arg); kv != nil && isParam(kv[1]) {
+			named[kv[1]] = kv[2]
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	for _, kwa := range kwargs {
+		if isParam(kwa.Name) {
+			named[kwa.Name] = kwa.Value
+		} else {
+			extra = append(extra, kwa.String())
+		}
+	}
+
+	// 未指定名字的参数依次填入具名参数
+	used := 0
+	for _, p := range m.Params {
+		if _, ok := named[p.Name]; ok {
+			continue
+		}
+		if used < len(positional) {
+			named[p.Name] = positional[used]
+			used++
+		}
+	}
+	// {1} 这样按序号引用过的参数也不算多余
+	for _, step := range steps {
+		for _, sub := range m.placeholderRe.FindAllStringSubmatch(step.Text, -1) {
+			if n, err := strconv.Atoi(sub[1]); err == nil && n > used && n <= len(positional) {
+				used = n
+			}
+		}
+	}
+	extra = append(append([]string{}, positional[used:]...), extra...)
+
+	var err error
+	for i := range steps {
+		steps[i].Text = m.placeholderRe.ReplaceAllStringFunc(steps[i].Text, func(s string) string {
+			sub := m.placeholderRe.FindStringSubmatch(s)
+			name, hasDefault := sub[1], strings.Contains(s, "=")
+			switch {
+			case name == "*":
+				return strings.Join(extra, " ")
+			case isAllDigits(name):
+				n, _ := strconv.Atoi(name)
+				if n >= 1 && n <= len(positional) && positional[n-1] != "" {
+					return positional[n-1]
+				}
+			default:
+				if v, ok := named[name]; ok {
+					return v
+				}
+			}
+			if hasDefault {
+				return sub[2]
+			}
+			for _, p := range m.Params {
+				if p.Name == name && p.HasDefault {
+					return p.Default
+				}
+			}
+			if err == nil {
+				err = fmt.Errorf("缺少参数: %s", name)
+			}
+			return s
+		})
+	}
+	return steps, err
+}
+
+// aliasMaxSteps 快捷指令最多能包含的步数
+func aliasMaxSteps(d *Dice) int {
+	if d.MaxExecuteTime <= 0 {
+		return 12
+	}
+	return int(d.MaxExecuteTime)
+}
+
+// aliasCheck 检查快捷指令定义，禁止步数过多以及嵌套调用快捷指令。
+// 声明了参数的快捷指令中，所有 {名字} 都必须声明过，未声明参数的旧式快捷指令不受影响
+func aliasCheck(d *Dice, m *aliasMacro) error {
+	if len(m.Steps) > aliasMaxSteps(d) {
+		return fmt.Errorf("快捷指令最多包含%d步", aliasMaxSteps(d))
+	}
+	if len(m.Params) > 0 {
+		if names := m.Undeclared(); len(names) > 0 {
+			return fmt.Errorf("参数 %s 未声明，请在指令前用 {名字} 或 {名字=默认值} 声明", strings.Join(names, "、"))
+		}
+	}
+	for _, step := range m.Steps {
+		if step.Text == "" {
+			return fmt.Errorf("存在空的步骤")
+		}
+		first := strings.Fields(step.Text)[0]
+		for _, prefix := range d.CommandPrefix {
+			if first == prefix+"a" || first == prefix+"&" {
+				return fmt.Errorf("快捷指令中不能调用快捷指令")
+			}
+		}
+	}
+	return nil
+}
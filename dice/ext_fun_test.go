@@ -0,0 +1,199 @@
+package dice
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+func TestAliasParse(t *testing.T) {
+	cases := []struct {
+		text   string
+		params []string
+		steps  []aliasStep
+	}{
+		{".ra 斗殴", nil, []aliasStep{{"", ".ra 斗殴"}}},
+		{"{weapon=长剑} .ra {weapon}", []string{"weapon=长剑"}, []aliasStep{{"", ".ra {weapon}"}}},
+		{"{a} {b=6} .r {a}d{b}", []string{"a", "b=6"}, []aliasStep{{"", ".r {a}d{b}"}}},
+		{"{x} .ra {x} && .r 1d6 || .r 1d4 ;; .text done", []string{"x"}, []aliasStep{
+			{"", ".ra {x}"}, {"&&", ".r 1d6"}, {"||", ".r 1d4"}, {";;", ".text done"},
+		}},
+		// 指令中未声明的名字不算参数
+		{".text {foo} {1}", nil, []aliasStep{{"", ".text {foo} {1}"}}},
+		// 以 $ 开头的是变量，不是参数声明
+		{"{$t玩家} .text", nil, []aliasStep{{"", "{$t玩家} .text"}}},
+		// 运算符两侧没有空白时不拆分
+		{".r 1d6&&.r 1d4", nil, []aliasStep{{"", ".r 1d6&&.r 1d4"}}},
+	}
+
+	for _, c := range cases {
+		m := aliasParse(c.text)
+		var params []string
+		for _, p := range m.Params {
+			if p.HasDefault {
+				params = append(params, p.Name+"="+p.Default)
+			} else {
+				params = append(params, p.Name)
+			}
+		}
+		if strings.Join(params, ",") != strings.Join(c.params, ",") {
+			t.Errorf("%q: params %v, want %v", c.text, params, c.params)
+		}
+		if len(m.Steps) != len(c.steps) {
+			t.Errorf("%q: steps %v, want %v", c.text, m.Steps, c.steps)
+			continue
+		}
+		for i := range m.Steps {
+			if m.Steps[i] != c.steps[i] {
+				t.Errorf("%q: step %d is %v, want %v", c.text, i, m.Steps[i], c.steps[i])
+			}
+		}
+	}
+}
+
+func TestAliasExpand(t *testing.T) {
+	cases := []struct {
+		text   string
+		args   []string
+		kwargs []*Kwarg
+		want   []string
+		err    bool
+	}{
+		// 不使用参数时保持旧行为，参数附加在末尾
+		{".ra 斗殴", []string{"50"}, nil, []string{".ra 斗殴 50"}, false},
+		{".ra 斗殴", nil, []*Kwarg{{Name: "ci"}}, []string{".ra 斗殴 --ci"}, false},
+		{"{weapon=长剑} .ra {weapon}", nil, nil, []string{".ra 长剑"}, false},
+		{"{weapon=长剑} .ra {weapon}", []string{"匕首"}, nil, []string{".ra 匕首"}, false},
+		{"{weapon=长剑} .ra {weapon}", []string{"weapon=斧"}, nil, []string{".ra 斧"}, false},
+		{"{weapon=长剑} .ra {weapon}", nil, []*Kwarg{{Name: "weapon", Value: "弓"}}, []string{".ra 弓"}, false},
+		{"{a} {b=6} .r {a}d{b}", []string{"3"}, nil, []string{".r 3d6"}, false},
+		{"{a} {b=6} .r {a}d{b}", []string{"3", "8"}, nil, []string{".r 3d8"}, false},
+		{"{a} {b=6} .r {a}d{b}", nil, nil, nil, true},
+		{"{a} .r {a=2}d6", nil, nil, []string{".r 2d6"}, false},
+		{".r {1}d{2} {*}", []string{"2", "6", "伤害", "加值"}, nil, []string{".r 2d6 伤害 加值"}, false},
+		{"{x} .ra {x} && .r 1d6 ;; .text {x}", []string{"侦查"}, nil, []string{".ra 侦查", ".r 1d6", ".text 侦查"}, false},
+		// 旧版本中带花括号的快捷指令，未声明的 {xxx} 原样保留
+		{".text {foo} 你好", []string{"x"}, nil, []string{".text {foo} 你好 x"}, false},
+		{"{who} .text {who} {foo}", []string{"x"}, nil, []string{".text x {foo}"}, false},
+	}
+
+	for _, c := range cases {
+		steps, err := aliasParse(c.text).Expand(c.args, c.kwargs)
+		if (err != nil) != c.err {
+			t.Errorf("%q %v: err = %v", c.text, c.args, err)
+			continue
+		}
+		if c.err {
+			continue
+		}
+		var got []string
+		for _, s := range steps {
+			got = append(got, s.Text)
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("%q %v: got %q, want %q", c.text, c.args, got, c.want)
+		}
+	}
+}
+
+func TestAliasCheck(t *testing.T) {
+	d := &Dice{CommandPrefix: []string{".", "。"}}
+	cases := []struct {
+		text string
+		err  bool
+	}{
+		{".ra 斗殴", false},
+		{"{x} .ra {x} && .r 1d6", false},
+		{".a foo", true},
+		{".r 1d6 ;; 。& foo", true},
+		{strings.Repeat(".r 1d6 ;; ", 12) + ".r 1d6", true},
+		{strings.Repeat(".r 1d6 ;; ", 11) + ".r 1d6", false},
+		// 声明了参数时，用到的参数都必须声明
+		{"{weapon=长剑} .ra 斗殴+{bonus}", true},
+		{"{who} .text {who} {foo}", true},
+		{"{a} .r {a=2}d6 {1} {*}", false},
+		{"{weapon=长剑} {bonus=0} .ra {weapon}+{bonus}", false},
+		// 旧式快捷指令中的花括号原样保留
+		{".text {foo} 你好", false},
+	}
+	for _, c := range cases {
+		if err := aliasCheck(d, aliasParse(c.text)); (err != nil) != c.err {
+			t.Errorf("%q: err = %v", c.text, err)
+		}
+	}
+
+	if aliasCheck(d, &aliasMacro{Steps: []aliasStep{{"", ".r"}, {";;", ""}}}) == nil {
+		t.Error("empty step should be rejected")
+	}
+}
+
+func TestAliasDelegatedCheck(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	send := func(user, text string) string { return chat.send(user, "", text) }
+
+	send("pc", ".st 侦查0")
+	send("gm", ".st 侦查99")
+	am := d.AttrsManager
+	attrs, err := am.LoadById(am.UIDConvert(FormatDiceIDHTTP("vtt", "gm")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs.Store("$m:alias:查", ds.NewStrVal(".ra 侦查 [CQ:at,qq=vtt:pc] && .r 1 成功分支 || .r 1 失败分支"))
+
+	// 检定是 pc 的，后续步骤应按 pc 的结果走分支
+	failed := false
+	for i := 0; i < 20; i++ {
+		text := send("gm", ".& 查")
+		success := strings.Contains(text, "成功分支")
+		if success == strings.Contains(text, "失败分支") {
+			t.Fatalf("exactly one branch should run: %q", text)
+		}
+		if strings.Contains(text, "失败") && !strings.Contains(text, "失败分支") && success {
+			t.Fatalf("failed delegated check took the success branch: %q", text)
+		}
+		failed = failed || !success
+	}
+	if !failed {
+		t.Fatal("侦查0 should fail at least once in 20 rolls")
+	}
+}
+
+func TestAliasCheckDifficulty(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	send := func(text string) string { return chat.send("gm", "", text) }
+
+	// 插件房规给出的成功等级可能不考虑难度，是否通过应以回复中的判定结果为准
+	d.CocExtraRulesAdd(&CocRuleInfo{Index: 20, Key: "plain", Name: "测试", Check: func(_ *MsgContext, d100 int64, checkValue int64, _ int) CocRuleCheckRet {
+		if d100 <= checkValue {
+			return CocRuleCheckRet{SuccessRank: 1}
+		}
+		return CocRuleCheckRet{SuccessRank: -1}
+	}})
+	send(".setcoc plain")
+	send(".st 侦查60")
+	am := d.AttrsManager
+	attrs, err := am.LoadById(am.UIDConvert(FormatDiceIDHTTP("vtt", "gm")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs.Store("$m:alias:查", ds.NewStrVal(".ra 困难侦查 && .r 1 成功分支 || .r 1 失败分支"))
+
+	hardFailed := false
+	for i := 0; i < 100 && !hardFailed; i++ {
+		text := send(".& 查")
+		if !strings.Contains(text, "还是有点难吧") {
+			continue
+		}
+		if !strings.Contains(text, "失败分支") || strings.Contains(text, "成功分支") {
+			t.Fatalf("failed hard check took the success branch: %q", text)
+		}
+		hardFailed = regexp.MustCompile(`=([1-9]|[1-5]\d|60)/30`).MatchString(text)
+	}
+	if !hardFailed {
+		t.Fatal("no regular success on a hard check in 100 rolls")
+	}
+}
@@ -36,6 +36,9 @@ func SetPlayerGroupCardByTemplate(ctx *MsgContext, tmpl string) (string, error)
 		return text, ErrGroupCardOverlong
 	}
 
+	if !ctx.EndPoint.Can(CapSetGroupCardName) {
+		return text, ErrCapabilityUnsupported
+	}
 	ctx.EndPoint.Adapter.SetGroupCardName(ctx, text)
 	return text, nil
 }
@@ -136,6 +139,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 		Help:      "日志指令:\n" + helpLog,
 		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
 			group := ctx.Group
+			logGroupID := ctx.Dice.BridgeMainGroupID(group.GroupID) // 桥接的群使用主群的日志
 			cmdArgs.ChopPrefixToArgsWith("on", "off", "del", "rm", "masterget",
 				"get", "end", "halt", "list", "new", "stat", "export")
 
@@ -152,7 +156,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 				if group.LogOn {
 					onText = "开启"
 				}
-				lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, group.GroupID, group.LogCurName)
+				lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, logGroupID, group.LogCurName)
 				text := fmt.Sprintf("当前故事: %s\n当前状态: %s\n已记录文本%d条", group.LogCurName, onText, lines)
 				ReplyToSender(ctx, msg, text)
 				return CmdExecuteResult{Matched: true, Solved: true}
@@ -195,7 +199,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 				}
 
 				if name != "" {
-					lines, exists := model.LogLinesCountGet(ctx.Dice.DBLogs, group.GroupID, name)
+					lines, exists := model.LogLinesCountGet(ctx.Dice.DBLogs, logGroupID, name)
 
 					if exists {
 						if groupNotActiveCheck() {
@@ -209,6 +213,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 						VarSetValueStr(ctx, "$t记录名称", name)
 						VarSetValueInt64(ctx, "$t当前记录条数", lines)
 						ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_开启_成功"))
+						webhookEmitLog(ctx, WebhookEventLogStart, group, name, lines)
 					} else {
 						VarSetValueStr(ctx, "$t记录名称", name)
 						ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_开启_失败_无此记录"))
@@ -221,10 +226,11 @@ func RegisterBuiltinExtLog(self *Dice) {
 				if group.LogCurName != "" && group.LogOn {
 					group.LogOn = false
 					group.UpdatedAtTime = time.Now().Unix()
-					lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, group.GroupID, group.LogCurName)
+					lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, logGroupID, group.LogCurName)
 					VarSetValueStr(ctx, "$t记录名称", group.LogCurName)
 					VarSetValueInt64(ctx, "$t当前记录条数", lines)
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_关闭_成功"))
+					webhookEmitLog(ctx, WebhookEventLogEnd, group, group.LogCurName, lines)
 				} else {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_关闭_失败"))
 				}
@@ -239,7 +245,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 				if name == group.LogCurName {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_删除_失败_正在进行"))
 				} else {
-					ok := model.LogDelete(ctx.Dice.DBLogs, group.GroupID, name)
+					ok := model.LogDelete(ctx.Dice.DBLogs, logGroupID, name)
 					if ok {
 						ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_删除_成功"))
 					} else {
@@ -273,14 +279,14 @@ func RegisterBuiltinExtLog(self *Dice) {
 					return CmdExecuteResult{Matched: true, Solved: true}
 				}
 
-				getAndUpload(group.GroupID, logName)
+				getAndUpload(logGroupID, logName)
 				return CmdExecuteResult{Matched: true, Solved: true}
 			} else if cmdArgs.IsArgEqual(1, "end") {
 				if group.LogCurName == "" {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_关闭_失败"))
 					return CmdExecuteResult{Matched: true, Solved: true}
 				}
-				lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, group.GroupID, group.LogCurName)
+				lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, logGroupID, group.LogCurName)
 				VarSetValueInt64(ctx, "$t当前记录条数", lines)
 				VarSetValueStr(ctx, "$t记录名称", group.LogCurName)
 				text := DiceFormatTmpl(ctx, "日志:记录_结束")
@@ -291,17 +297,26 @@ func RegisterBuiltinExtLog(self *Dice) {
 				ReplyToSender(ctx, msg, text)
 				group.LogOn = false
 				group.UpdatedAtTime = time.Now().Unix()
+				webhookEmitLog(ctx, WebhookEventLogEnd, group, group.LogCurName, lines)
+
+				if group.FairRollOn {
+					// 公平骰: 跑团结束时公开种子
+					if revealText, err := fairRollReveal(ctx); err == nil {
+						ReplyToSender(ctx, msg, revealText)
+					}
+				}
 
 				time.Sleep(time.Duration(0.3 * float64(time.Second)))
-				getAndUpload(group.GroupID, group.LogCurName)
+				getAndUpload(logGroupID, group.LogCurName)
 				group.LogCurName = ""
 				group.UpdatedAtTime = time.Now().Unix()
 				return CmdExecuteResult{Matched: true, Solved: true}
 			} else if cmdArgs.IsArgEqual(1, "halt") {
 				if len(group.LogCurName) > 0 {
-					lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, group.GroupID, group.LogCurName)
+					lines, _ := model.LogLinesCountGet(ctx.Dice.DBLogs, logGroupID, group.LogCurName)
 					VarSetValueInt64(ctx, "$t当前记录条数", lines)
 					VarSetValueStr(ctx, "$t记录名称", group.LogCurName)
+					webhookEmitLog(ctx, WebhookEventLogEnd, group, group.LogCurName, lines)
 				}
 				text := DiceFormatTmpl(ctx, "日志:记录_结束")
 				ReplyToSender(ctx, msg, text)
@@ -315,7 +330,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 					return CmdExecuteResult{Matched: true, Solved: true}
 				}
 				if groupID == "" {
-					groupID = ctx.Group.GroupID
+					groupID = logGroupID
 				}
 
 				text := DiceFormatTmpl(ctx, "日志:记录_列出_导入语") + "\n"
@@ -363,11 +378,12 @@ func RegisterBuiltinExtLog(self *Dice) {
 				group.UpdatedAtTime = time.Now().Unix()
 
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_新建"))
+				webhookEmitLog(ctx, WebhookEventLogStart, group, name, 0)
 				return CmdExecuteResult{Matched: true, Solved: true}
 			} else if cmdArgs.IsArgEqual(1, "stat") {
 				// group := ctx.Group
 				_, name := getLogName(ctx, msg, cmdArgs, 2)
-				items, err := model.LogGetAllLines(ctx.Dice.DBLogs, group.GroupID, name)
+				items, err := model.LogGetAllLines(ctx.Dice.DBLogs, logGroupID, name)
 				if err == nil && len(items) > 0 {
 					// showDetail := cmdArgs.GetKwarg("detail")
 					// var showDetail *Kwarg
@@ -415,7 +431,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 				VarSetValueStr(ctx, "$t日期", now.ToShortDateString())
 				VarSetValueStr(ctx, "$t时间", now.ToShortTimeString())
 				logFileNamePrefix := DiceFormatTmpl(ctx, "日志:记录_导出_文件名前缀")
-				logFile, err := GetLogTxt(ctx, group.GroupID, logName, logFileNamePrefix)
+				logFile, err := GetLogTxt(ctx, logGroupID, logName, logFileNamePrefix)
 				if err != nil {
 					ReplyToSenderRaw(ctx, msg, err.Error(), "skip")
 					return CmdExecuteResult{Matched: true, Solved: true}
@@ -482,7 +498,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 			case "log":
 				group := ctx.Group
 				_, name := getLogName(ctx, msg, cmdArgs, 2)
-				items, err := model.LogGetAllLines(ctx.Dice.DBLogs, group.GroupID, name)
+				items, err := model.LogGetAllLines(ctx.Dice.DBLogs, ctx.Dice.BridgeMainGroupID(group.GroupID), name)
 				if err == nil && len(items) > 0 {
 					// showDetail := cmdArgs.GetKwarg("detail")
 					// var showDetail *Kwarg
@@ -605,6 +621,16 @@ func RegisterBuiltinExtLog(self *Dice) {
 				return CmdExecuteResult{Matched: true, Solved: true}
 			}
 
+			// 取消或置空名片格式不需要修改名片的能力，平台不支持时也要允许关掉
+			switch strings.ToLower(val) {
+			case "", "help", "off", "cancel", "none":
+			default:
+				if !ctx.EndPoint.Can(CapSetGroupCardName) {
+					ReplyToSender(ctx, msg, capabilityUnsupportedText(ctx, CapSetGroupCardName))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+			}
+
 			switch strings.ToLower(val) {
 			case "help":
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -860,9 +886,11 @@ func FilenameReplace(name string) string {
 }
 
 func LogAppend(ctx *MsgContext, groupID string, logName string, logItem *model.LogOneItem) bool {
-	ok := model.LogAppend(ctx.Dice.DBLogs, groupID, logName, logItem)
+	// 桥接的群记在主群的日志中
+	logGroupID := ctx.Dice.BridgeMainGroupID(groupID)
+	ok := model.LogAppend(ctx.Dice.DBLogs, logGroupID, logName, logItem)
 	if ok {
-		if size, okCount := model.LogLinesCountGet(ctx.Dice.DBLogs, groupID, logName); okCount {
+		if size, okCount := model.LogLinesCountGet(ctx.Dice.DBLogs, logGroupID, logName); okCount {
 			// 默认每记录500条发出提示
 			if ctx.Dice.LogSizeNoticeEnable {
 				if ctx.Dice.LogSizeNoticeCount == 0 {
@@ -881,7 +909,7 @@ func LogAppend(ctx *MsgContext, groupID string, logName string, logItem *model.L
 }
 
 func LogDeleteByID(ctx *MsgContext, groupID string, logName string, messageID interface{}) bool {
-	err := model.LogMarkDeleteByMsgID(ctx.Dice.DBLogs, groupID, logName, messageID)
+	err := model.LogMarkDeleteByMsgID(ctx.Dice.DBLogs, ctx.Dice.BridgeMainGroupID(groupID), logName, messageID)
 	if err != nil {
 		ctx.Dice.Logger.Error("LogDeleteById:", zap.Error(err))
 		return false
@@ -892,7 +920,7 @@ func LogDeleteByID(ctx *MsgContext, groupID string, logName string, messageID in
 // LogEditByID finds the log item under logName with messageID and replace it with content.
 // If the log item cannot be found or an error happens, it returns false.
 func LogEditByID(ctx *MsgContext, groupID, logName, content string, messageID interface{}) bool {
-	err := model.LogEditByMsgID(ctx.Dice.DBLogs, groupID, logName, content, messageID)
+	err := model.LogEditByMsgID(ctx.Dice.DBLogs, ctx.Dice.BridgeMainGroupID(groupID), logName, content, messageID)
 	if err != nil {
 		ctx.Dice.Logger.Error("LogEditByID:", zap.Error(err))
 		return false
@@ -0,0 +1,441 @@
+package dice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// PF2e 的四级成功度
+const (
+	pf2eCriticalFailure = iota
+	pf2eFailure
+	pf2eSuccess
+	pf2eCriticalSuccess
+)
+
+var pf2eDegreeText = []string{"大失败", "失败", "成功", "大成功"}
+
+// 成功度对应的 $tSuccessRank，与 coc 一致大于0为成功
+var pf2eSuccessRank = []int64{-2, -1, 1, 2}
+
+var pf2eProfRankText = []string{"未受训", "受训", "专家", "大师", "传奇"}
+
+var pf2eProfRankAlias = map[string]int64{
+	"未受训": 0, "untrained": 0, "u": 0,
+	"受训": 1, "trained": 1, "t": 1,
+	"专家": 2, "expert": 2, "e": 2,
+	"大师": 3, "master": 3, "m": 3,
+	"传奇": 4, "legendary": 4, "l": 4,
+}
+
+var (
+	pf2eDCRe   = regexp.MustCompile(`(?i)(?:dc|ac|vs|难度)\s*[:：=]?\s*(\d+)`)
+	pf2eModeRe = regexp.MustCompile(`^(幸运|不幸|优势|劣势)`)
+	pf2eMapRe  = regexp.MustCompile(`(?i)map\s*(\d+)`) // .atk 手动指定第几次攻击
+)
+
+// pf2eDegreeOfSuccess 计算成功度：达到DC为成功，超出10以上为大成功，低于10以上为大失败，
+// 自然20提升一级，自然1降低一级
+func pf2eDegreeOfSuccess(total, dc, natural int64) int {
+	var degree int
+	switch {
+	case total >= dc+10:
+		degree = pf2eCriticalSuccess
+	case total >= dc:
+		degree = pf2eSuccess
+	case total <= dc-10:
+		degree = pf2eCriticalFailure
+	default:
+		degree = pf2eFailure
+	}
+
+	if natural == 20 && degree < pf2eCriticalSuccess {
+		degree++
+	}
+	if natural == 1 && degree > pf2eCriticalFailure {
+		degree--
+	}
+	return degree
+}
+
+// pf2eProfBonus 熟练加值，未受训为0，其余为等级+2倍熟练等级
+func pf2eProfBonus(rank int64, level int64) int64 {
+	if rank <= 0 {
+		return 0
+	}
+	return rank*2 + level
+}
+
+// pf2eMultipleAttackPenalty 多重攻击减值，灵巧武器为-4/-8，其余为-5/-10
+func pf2eMultipleAttackPenalty(attackNum int64, agile bool) int64 {
+	step := int64(5)
+	if agile {
+		step = 4
+	}
+	switch {
+	case attackNum <= 1:
+		return 0
+	case attackNum == 2:
+		return -step
+	default:
+		return -step * 2
+	}
+}
+
+type pf2eCheckResult struct {
+	Natural    int64
+	D20Detail  string
+	Expr       string // 调整值表达式
+	ExprValue  int64
+	ExprDetail string
+	Extra      int64 // 额外调整，如多重攻击减值
+	Modifier   int64
+	Total      int64
+	Reason     string
+}
+
+// pf2eCheck 进行一次d20检定，mode为"幸运"时取两次中较高者，"不幸"时取较低者
+func pf2eCheck(mctx *MsgContext, tmpl *GameSystemTemplate, expr string, mode string, extraMod int64) (*pf2eCheckResult, error) {
+	ret := &pf2eCheckResult{}
+
+	a, err := mctx.diceRoll64(20)
+	ret.Natural = a
+	ret.D20Detail = fmt.Sprintf("D20=%d", a)
+	if mode != "" {
+		// 物理骰点数不足时也掷完第二颗，一次提示玩家投掷两颗d20
+		b, errB := mctx.diceRoll64(20)
+		if err != nil || errB != nil {
+			return nil, errPhysicalDiceWaiting
+		}
+		if (mode == "幸运" && b > a) || (mode == "不幸" && b < a) {
+			ret.Natural = b
+		}
+		ret.D20Detail = fmt.Sprintf("D20%s=%d[%d,%d]", mode, ret.Natural, a, b)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if expr != "" {
+		tctx := tmpl.evalCtx(mctx)
+		tctx.Eval(tmpl.PreloadCode, nil)
+		r := tctx.Eval(expr, nil)
+		if r.vm.Error != nil {
+			return nil, r.vm.Error
+		}
+		n, ok := r.ReadInt()
+		if !ok {
+			return nil, fmt.Errorf("调整值必须为整数: %s", r.ToString())
+		}
+		ret.Expr = strings.TrimSpace(r.vm.Matched)
+		ret.ExprValue = int64(n)
+		ret.ExprDetail = r.vm.GetDetailText()
+		ret.Reason = strings.TrimSpace(r.vm.RestInput)
+		if ret.Reason == "" {
+			ret.Reason = ret.Expr
+		}
+	}
+
+	ret.Extra = extraMod
+	ret.Modifier = ret.ExprValue + ret.Extra
+	ret.Total = ret.Natural + ret.Modifier
+	return ret, nil
+}
+
+func (r *pf2eCheckResult) DetailText() string {
+	text := r.D20Detail
+	if r.Expr != "" {
+		text += "+" + r.Expr
+		detail := r.ExprDetail
+		if detail == "" {
+			detail = strconv.FormatInt(r.ExprValue, 10)
+		}
+		if detail != r.Expr {
+			text += "[" + detail + "]"
+		}
+	}
+	if r.Extra != 0 {
+		text += fmt.Sprintf("%+d", r.Extra)
+	}
+	if r.Expr != "" || r.Extra != 0 {
+		text += fmt.Sprintf("=%d", r.Total)
+	}
+	return text
+}
+
+// pf2eSplitArgs 从参数中拆出 DC、幸运/不幸 以及剩余的表达式文本
+func pf2eSplitArgs(text string) (expr string, dc int64, hasDC bool, mode string) {
+	text = strings.TrimSpace(text)
+	if m := pf2eDCRe.FindStringSubmatchIndex(text); m != nil {
+		dc, _ = strconv.ParseInt(text[m[2]:m[3]], 10, 64)
+		hasDC = true
+		text = strings.TrimSpace(text[:m[0]] + " " + text[m[1]:])
+	}
+
+	if m := pf2eModeRe.FindString(text); m != "" {
+		mode = map[string]string{"幸运": "幸运", "优势": "幸运", "不幸": "不幸", "劣势": "不幸"}[m]
+		text = strings.TrimSpace(text[len(m):])
+	}
+	return text, dc, hasDC, mode
+}
+
+func RegisterBuiltinExtPf2e(self *Dice) {
+	getTmpl := func(ctx *MsgContext) *GameSystemTemplate {
+		tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
+		if tmpl.Name != "pf2e" {
+			if tmpl2, _ := ctx.Dice.GameSystemMap.Load("pf2e"); tmpl2 != nil {
+				tmpl = tmpl2
+			}
+		}
+		return tmpl
+	}
+
+	replyCheck := func(mctx *MsgContext, msg *Message, cmdArgs *CmdArgs, cmd string, title string, r *pf2eCheckResult, dc int64, hasDC bool, extraText string) {
+		text := fmt.Sprintf("<%s>的「%s」%s: %s", mctx.Player.Name, r.Reason, title, r.DetailText())
+		if extraText != "" {
+			text += extraText
+		}
+
+		item := map[string]interface{}{
+			"reason":   r.Reason,
+			"natural":  r.Natural,
+			"modifier": r.Modifier,
+			"result":   r.Total,
+		}
+		if hasDC {
+			degree := pf2eDegreeOfSuccess(r.Total, dc, r.Natural)
+			text += fmt.Sprintf(" vs DC%d\n结果: %s", dc, pf2eDegreeText[degree])
+			switch {
+			case r.Natural == 20 && degree > pf2eDegreeOfSuccess(r.Total, dc, 0):
+				text += "(自然20，提升一级)"
+			case r.Natural == 1 && degree < pf2eDegreeOfSuccess(r.Total, dc, 0):
+				text += "(自然1，降低一级)"
+			}
+			item["dc"] = dc
+			item["degree"] = degree
+			VarSetValueInt64(mctx, "$tSuccessRank", pf2eSuccessRank[degree])
+		}
+
+		mctx.CommandInfo = map[string]interface{}{
+			"cmd":    cmd,
+			"rule":   "pf2e",
+			"pcName": mctx.Player.Name,
+			"items":  []interface{}{item},
+		}
+		if kw := cmdArgs.GetKwarg("ci"); kw != nil {
+			info, err := json.Marshal(mctx.CommandInfo)
+			if err == nil {
+				text += "\n" + string(info)
+			} else {
+				text += "\n" + "指令信息无法序列化"
+			}
+		}
+		ReplyToSender(mctx, msg, text)
+	}
+
+	helpRc := "" +
+		".rc <表达式> [DC<数值>] // 检定，如 .rc 运动 DC18、.rc 察觉+2 dc15\n" +
+		".rc 幸运 <表达式> // 幸运检定，投两次取高\n" +
+		".rc 不幸 <表达式> // 不幸检定，投两次取低\n" +
+		".rc <表达式> @某人 // 对某人做检定\n" +
+		"超出DC10点为大成功，低于DC10点为大失败，自然20/1使成功度提升/降低一级"
+
+	cmdRc := &CmdItemInfo{
+		Name:          "rc",
+		ShortHelp:     helpRc,
+		Help:          "PF2E 检定:\n" + helpRc,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			val := cmdArgs.GetArgN(1)
+			if val == "" || val == "help" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			expr, dc, hasDC, mode := pf2eSplitArgs(cmdArgs.CleanArgs)
+			r, err := pf2eCheck(mctx, getTmpl(mctx), expr, mode, 0)
+			if errors.Is(err, errPhysicalDiceWaiting) {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if err != nil {
+				ReplyToSender(ctx, msg, "无法解析表达式: "+expr)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			replyCheck(mctx, msg, cmdArgs, "rc", "检定", r, dc, hasDC, "")
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpAtk := "" +
+		".atk <表达式> [AC<数值>] [灵巧] // 攻击检定，自动累计本回合攻击次数并计算多重攻击减值\n" +
+		".atk map<次数> <表达式> // 手动指定这是第几次攻击，如 .atk map2 运动+7 ac18\n" +
+		".atk reset // 回合结束，重置攻击次数\n" +
+		"多重攻击减值: 第二次-5，第三次起-10；灵巧武器为-4/-8"
+
+	cmdAtk := &CmdItemInfo{
+		Name:          "atk",
+		ShortHelp:     helpAtk,
+		Help:          "PF2E 攻击检定:\n" + helpAtk,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			val := cmdArgs.GetArgN(1)
+			switch val {
+			case "", "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "reset", "end", "重置":
+				VarDelValue(mctx, "$pf2攻击次数")
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的攻击次数已重置", mctx.Player.Name))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			text := cmdArgs.CleanArgs
+			agile := false
+			for _, kw := range []string{"灵巧", "agile"} {
+				if strings.Contains(text, kw) {
+					agile = true
+					text = strings.Replace(text, kw, "", 1)
+				}
+			}
+
+			attackNum, _ := VarGetValueInt64(mctx, "$pf2攻击次数")
+			attackNum++
+			if m := pf2eMapRe.FindStringSubmatchIndex(text); m != nil {
+				attackNum, _ = strconv.ParseInt(text[m[2]:m[3]], 10, 64)
+				text = text[:m[0]] + text[m[1]:]
+			}
+
+			penalty := pf2eMultipleAttackPenalty(attackNum, agile)
+			expr, dc, hasDC, mode := pf2eSplitArgs(text)
+			r, err := pf2eCheck(mctx, getTmpl(mctx), expr, mode, penalty)
+			if errors.Is(err, errPhysicalDiceWaiting) {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if err != nil {
+				ReplyToSender(ctx, msg, "无法解析表达式: "+expr)
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			VarSetValueInt64(mctx, "$pf2攻击次数", attackNum)
+
+			extraText := fmt.Sprintf("\n(第%d次攻击", attackNum)
+			if penalty != 0 {
+				extraText += fmt.Sprintf("，多重攻击减值%d", penalty)
+			}
+			extraText += ")"
+			replyCheck(mctx, msg, cmdArgs, "atk", "攻击", r, dc, hasDC, extraText)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpProf := "" +
+		".prof // 查看熟练等级\n" +
+		".prof <技能> <熟练等级> // 设置熟练等级，如 .prof 运动 专家\n" +
+		"熟练等级: 未受训/受训/专家/大师/传奇，熟练加值为 等级+2/4/6/8，未受训为0\n" +
+		"豁免(强韧/反射/意志)、察觉与护甲同样适用"
+
+	cmdProf := &CmdItemInfo{
+		Name:          "prof",
+		ShortHelp:     helpProf,
+		Help:          "PF2E 熟练等级:\n" + helpProf,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+			tmpl := getTmpl(mctx)
+			attrs, err := mctx.Dice.AttrsManager.LoadByCtx(mctx)
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取角色卡失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			level := tmpl.GetRealValueInt(mctx, "等级")
+			name := cmdArgs.GetArgN(1)
+			switch name {
+			case "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case "":
+				var items []string
+				attrs.Range(func(key string, value *ds.VMValue) bool {
+					if strings.HasPrefix(key, "$prof_") {
+						rank, _ := value.ReadInt()
+						if rank > 0 && int(rank) < len(pf2eProfRankText) {
+							items = append(items, fmt.Sprintf("%s:%s(+%d)", key[len("$prof_"):],
+								pf2eProfRankText[rank], pf2eProfBonus(int64(rank), level)))
+						}
+					}
+					return true
+				})
+				if len(items) == 0 {
+					ReplyToSender(ctx, msg, fmt.Sprintf("<%s>当前没有受训的项目", mctx.Player.Name))
+					break
+				}
+				sort.Strings(items)
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的熟练等级(等级%d):\n%s", mctx.Player.Name, level, strings.Join(items, "\n")))
+			default:
+				rank, ok := pf2eProfRankAlias[strings.ToLower(cmdArgs.GetArgN(2))]
+				if !ok {
+					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				name = tmpl.GetAlias(name)
+				if rank == 0 {
+					attrs.Delete("$prof_" + name)
+				} else {
+					attrs.Store("$prof_"+name, ds.NewIntVal(ds.IntType(rank)))
+				}
+				attrs.SetModified()
+				ReplyToSender(ctx, msg, fmt.Sprintf("<%s>的%s熟练等级设置为%s(+%d)", mctx.Player.Name, name,
+					pf2eProfRankText[rank], pf2eProfBonus(rank, level)))
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	cmdSt := getCmdStBase(CmdStOverrideInfo{TemplateName: "pf2e"})
+
+	cmdMap := CmdMapCls{
+		"rc":   cmdRc,
+		"ra":   cmdRc,
+		"prc":  cmdRc,
+		"atk":  cmdAtk,
+		"prof": cmdProf,
+		"st":   cmdSt,
+		"pst":  cmdSt,
+	}
+	// 遭遇战的先攻与dnd5e共用
+	if dnd := self.ExtFind("dnd5e"); dnd != nil {
+		for _, name := range []string{"ri", "init"} {
+			if cmd := dnd.CmdMap[name]; cmd != nil {
+				cmdMap[name] = cmd
+			}
+		}
+	}
+
+	theExt := &ExtInfo{
+		Name:       "pf2e", // 扩展的名称，需要用于开启和关闭指令中，写简短点
+		Version:    "1.0.0",
+		Brief:      "提供开拓者2E(PF2E)规则TRPG支持",
+		Author:     "海豹核心",
+		AutoActive: false, // 是否自动开启
+		Official:   true,
+		ConflictWith: []string{
+			"coc7",
+			"dnd5e",
+		},
+		OnCommandReceived: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+		},
+		GetDescText: GetExtensionDesc,
+		CmdMap:      cmdMap,
+	}
+
+	self.RegisterExtension(theExt)
+}
@@ -0,0 +1,120 @@
+package dice
+
+// pf2eSkillParent 技能对应的属性
+var pf2eSkillParent = map[string]string{
+	"杂技":  "敏捷",
+	"奥秘":  "智力",
+	"运动":  "力量",
+	"工艺":  "智力",
+	"欺瞒":  "魅力",
+	"交涉":  "魅力",
+	"威吓":  "魅力",
+	"医药":  "感知",
+	"自然":  "感知",
+	"神秘学": "智力",
+	"表演":  "魅力",
+	"宗教":  "感知",
+	"社会":  "智力",
+	"隐匿":  "敏捷",
+	"生存":  "感知",
+	"盗贼":  "敏捷",
+
+	"察觉": "感知",
+	"强韧": "体质",
+	"反射": "敏捷",
+	"意志": "感知",
+}
+
+func getPf2eCharTemplate() *GameSystemTemplate {
+	tmpl := &GameSystemTemplate{
+		Name:        "pf2e",
+		FullName:    "开拓者2E",
+		Authors:     []string{"海豹核心"},
+		Version:     "1.0.0",
+		UpdatedTime: "20241019",
+		TemplateVer: "1.0",
+
+		SetConfig: SetConfig{
+			DiceSidesExpr: "20",
+			DiceSides:     20,
+			Keys:          []string{"pf2e", "pf2"},
+			EnableTip:     "已切换至20面骰，并自动开启pf2e扩展",
+			RelatedExt:    []string{"pf2e"},
+		},
+
+		NameTemplate: map[string]NameTemplateItem{
+			"pf2e": {
+				Template: "{$t玩家_RAW} HP{hp}/{hpmax} AC{ac} 察觉{察觉}",
+				HelpText: "自动设置pf2e名片",
+			},
+		},
+
+		// 熟练等级存放于 $prof_<技能名>，0-4 分别为未受训/受训/专家/大师/传奇
+		PreloadCode: "func pf2Prof(name) {\n" +
+			"  rank = load('$prof_' + name) ?? 0;\n" +
+			"  return rank > 0 ? rank * 2 + (等级 ?? 0) : 0\n" +
+			"}\n" +
+			"func pf2Skill(ab, name) {\n" +
+			"  return (load(ab) ?? 0) + pf2Prof(name)\n" +
+			"}\n",
+
+		AttrConfig: AttrConfig{
+			Top:    []string{"等级", "力量", "敏捷", "体质", "智力", "感知", "魅力", "hp", "ac", "察觉"},
+			SortBy: "Name",
+			Ignores: []string{
+				"hpmax",
+			},
+			ShowAs: map[string]string{
+				"hp": "{hp??0}/{hpmax??0}",
+			},
+		},
+
+		Defaults: map[string]int64{
+			"等级": 1,
+		},
+		DefaultsComputed: map[string]string{
+			"ac": "10 + 敏捷 + pf2Prof('护甲')",
+		},
+
+		Alias: map[string][]string{
+			"等级": {"level", "lv"},
+			"力量": {"str", "strength"},
+			"敏捷": {"dex", "dexterity"},
+			"体质": {"con", "constitution"},
+			"智力": {"int", "intelligence"},
+			"感知": {"wis", "wisdom"},
+			"魅力": {"cha", "charisma"},
+
+			"hp":    {"生命值", "HP"},
+			"hpmax": {"生命值上限", "HPMAX"},
+			"ac":    {"护甲等级", "AC"},
+
+			"杂技":  {"acrobatics"},
+			"奥秘":  {"arcana"},
+			"运动":  {"athletics"},
+			"工艺":  {"crafting"},
+			"欺瞒":  {"deception"},
+			"交涉":  {"diplomacy"},
+			"威吓":  {"intimidation"},
+			"医药":  {"medicine"},
+			"自然":  {"nature"},
+			"神秘学": {"occultism"},
+			"表演":  {"performance"},
+			"宗教":  {"religion"},
+			"社会":  {"society"},
+			"隐匿":  {"stealth"},
+			"生存":  {"survival"},
+			"盗贼":  {"thievery"},
+
+			"察觉": {"perception", "感知检定"},
+			"强韧": {"fortitude", "强韧豁免"},
+			"反射": {"reflex", "反射豁免"},
+			"意志": {"will", "意志豁免"},
+		},
+	}
+
+	for skill, ab := range pf2eSkillParent {
+		tmpl.DefaultsComputed[skill] = "pf2Skill('" + ab + "', '" + skill + "')"
+	}
+	return tmpl
+}
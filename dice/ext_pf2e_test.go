@@ -0,0 +1,51 @@
+package dice
+
+import "testing"
+
+func TestPf2eDegreeOfSuccess(t *testing.T) {
+	cases := []struct {
+		total, dc, natural int64
+		want               int
+	}{
+		{25, 15, 10, pf2eCriticalSuccess},
+		{15, 15, 10, pf2eSuccess},
+		{14, 15, 10, pf2eFailure},
+		{5, 15, 10, pf2eCriticalFailure},
+		// 自然20提升一级，自然1降低一级
+		{14, 15, 20, pf2eSuccess},
+		{15, 15, 20, pf2eCriticalSuccess},
+		{30, 15, 20, pf2eCriticalSuccess},
+		{5, 15, 20, pf2eFailure},
+		{15, 15, 1, pf2eFailure},
+		{25, 15, 1, pf2eSuccess},
+		{5, 15, 1, pf2eCriticalFailure},
+	}
+	for _, c := range cases {
+		if got := pf2eDegreeOfSuccess(c.total, c.dc, c.natural); got != c.want {
+			t.Errorf("total=%d dc=%d nat=%d: got %s, want %s", c.total, c.dc, c.natural, pf2eDegreeText[got], pf2eDegreeText[c.want])
+		}
+	}
+}
+
+func TestPf2eCheckMode(t *testing.T) {
+	cases := []struct {
+		mode    string
+		faces   []int64
+		natural int64
+	}{
+		{"", []int64{7}, 7},
+		{"幸运", []int64{7, 15}, 15},
+		{"幸运", []int64{15, 7}, 15},
+		{"不幸", []int64{7, 15}, 7},
+		{"不幸", []int64{15, 7}, 7},
+	}
+	for _, c := range cases {
+		r, err := pf2eCheck(newFixedDiceCtx(c.faces...), nil, "", c.mode, -5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Natural != c.natural || r.Total != c.natural-5 {
+			t.Errorf("%q %v: natural=%d total=%d", c.mode, c.faces, r.Natural, r.Total)
+		}
+	}
+}
@@ -267,3 +267,33 @@ func (t *GameSystemTemplate) GetRealValue(ctx *MsgContext, k string) (*ds.VMValu
 	}
 	return v, err
 }
+
+// evalCtx 复制一份使用本模板求值的 ctx，不改动原 ctx 的 SystemTemplate 与 vm。
+// vm 的回调绑定在创建它的 ctx 上，因此副本需要新建 vm
+func (t *GameSystemTemplate) evalCtx(ctx *MsgContext) *MsgContext {
+	c := *ctx
+	c.vm = nil
+	c.SystemTemplate = t
+	c.CreateVmIfNotExists()
+	return &c
+}
+
+// GetRealValueInt 读取整数属性，支持同义词，计算类型会被求值，不存在或非整数时返回0
+func (t *GameSystemTemplate) GetRealValueInt(ctx *MsgContext, k string) int64 {
+	k = t.GetAlias(k)
+	v, err := t.GetRealValue(ctx, k)
+	if err != nil || v == nil {
+		return 0
+	}
+	if v.TypeId == ds.VMTypeComputedValue {
+		r := t.evalCtx(ctx).Eval(k, nil)
+		if r.vm.Error != nil {
+			return 0
+		}
+		v = &r.VMValue
+	}
+	if n, ok := v.ReadInt(); ok {
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,392 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"sealdice-core/utils"
+)
+
+// 跨平台群桥接：把不同平台、不同帐号所在的群连成一张团桌。
+// 成员群之间互相转发聊天与骰子回复，共享规则、扩展、日志等群状态；
+// 第一个成员为主群，日志和群属性(如先攻列表)都记在主群下
+
+const (
+	bridgeMark         = "\u2063" // 附在转发消息末尾，收到带此标记的消息不再转发，防止循环
+	bridgeCodeTTL      = 10 * time.Minute
+	bridgeRelayBurst   = 10
+	bridgeRelayMaxText = 500 // 转发聊天消息的最大字数
+)
+
+// 单个桥接每秒最多转发的聊天消息数，超出的丢弃。骰子回复不受此限制，各平台自身的限速由发送队列处理
+var bridgeRelayRate = rate.Every(500 * time.Millisecond)
+
+var (
+	ErrBridgeCodeInvalid = errors.New("桥接码无效或已过期")
+	ErrBridgeJoined      = errors.New("当前群已在一个桥接中，请先 .bridge leave")
+	ErrBridgeNotJoined   = errors.New("当前群不在任何桥接中")
+	ErrBridgeNotFound    = errors.New("找不到该桥接")
+)
+
+type GroupBridgeMember struct {
+	GroupID    string `yaml:"groupId" json:"groupId"`
+	GroupName  string `yaml:"groupName" json:"groupName"`
+	EndPointID string `yaml:"endPointId" json:"endPointId"` // 用于向该群发消息的帐号
+}
+
+// GroupBridge 一组桥接在一起的群
+type GroupBridge struct {
+	ID        string               `yaml:"id" json:"id"`
+	Enable    bool                 `yaml:"enable" json:"enable"`
+	RelayChat bool                 `yaml:"relayChat" json:"relayChat"` // 转发普通聊天，关闭时只转发指令与骰子回复
+	Members   []*GroupBridgeMember `yaml:"members" json:"members"`     // 第一个为主群
+	CreatedAt int64                `yaml:"createdAt" json:"createdAt"`
+
+	Dropped int64 `yaml:"-" json:"dropped"` // 因转发过快被丢弃的聊天消息数
+	limiter *rate.Limiter
+}
+
+func (b *GroupBridge) member(groupID string) *GroupBridgeMember {
+	for _, m := range b.Members {
+		if m.GroupID == groupID {
+			return m
+		}
+	}
+	return nil
+}
+
+type bridgeCode struct {
+	bridgeID string
+	expires  time.Time
+}
+
+var bridgeCodes SyncMap[string, *bridgeCode]
+
+// BridgeByGroup 群所在的桥接，不在桥接中或桥接未启用时返回nil
+func (d *Dice) BridgeByGroup(groupID string) *GroupBridge {
+	if groupID == "" {
+		return nil
+	}
+	d.bridgeLock.RLock()
+	defer d.bridgeLock.RUnlock()
+	for _, b := range d.GroupBridges {
+		if b.Enable && b.member(groupID) != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// BridgeMainGroupID 桥接中的群返回主群ID，其余原样返回。日志与群属性按此存放
+func (d *Dice) BridgeMainGroupID(groupID string) string {
+	m := d.bridgeMain.Load()
+	if m == nil {
+		m = d.bridgeMainBuild()
+	}
+	if main, ok := (*m)[groupID]; ok {
+		return main
+	}
+	return groupID
+}
+
+// bridgeMainBuild 重建 群->主群 的缓存。持读锁期间写入，保证不会覆盖修改桥接时的清空
+func (d *Dice) bridgeMainBuild() *map[string]string {
+	d.bridgeLock.RLock()
+	defer d.bridgeLock.RUnlock()
+	m := map[string]string{}
+	for _, b := range d.GroupBridges {
+		if !b.Enable || len(b.Members) == 0 {
+			continue
+		}
+		for _, member := range b.Members {
+			m[member.GroupID] = b.Members[0].GroupID
+		}
+	}
+	d.bridgeMain.Store(&m)
+	return &m
+}
+
+// bridgeChangedLocked 桥接被修改后调用，需持有写锁
+func (d *Dice) bridgeChangedLocked() {
+	d.bridgeMain.Store(nil)
+	d.MarkModified()
+}
+
+// BridgeList 全部桥接的副本
+func (d *Dice) BridgeList() []*GroupBridge {
+	d.bridgeLock.RLock()
+	defer d.bridgeLock.RUnlock()
+	lst := make([]*GroupBridge, 0, len(d.GroupBridges))
+	for _, b := range d.GroupBridges {
+		c := *b
+		c.Members = append([]*GroupBridgeMember{}, b.Members...)
+		lst = append(lst, &c)
+	}
+	return lst
+}
+
+// BridgeSet 修改桥接的开关
+func (d *Dice) BridgeSet(id string, enable bool, relayChat bool) bool {
+	d.bridgeLock.Lock()
+	defer d.bridgeLock.Unlock()
+	for _, b := range d.GroupBridges {
+		if b.ID == id {
+			b.Enable = enable
+			b.RelayChat = relayChat
+			d.bridgeChangedLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// BridgeNew 以当前群为主群新建桥接
+func (d *Dice) BridgeNew(ctx *MsgContext) (*GroupBridge, error) {
+	d.bridgeLock.Lock()
+	defer d.bridgeLock.Unlock()
+	if d.bridgeFindLocked(ctx.Group.GroupID) != nil {
+		return nil, ErrBridgeJoined
+	}
+	b := &GroupBridge{
+		ID:        utils.NewID(),
+		Enable:    true,
+		RelayChat: true,
+		Members:   []*GroupBridgeMember{bridgeMemberFromCtx(ctx)},
+		CreatedAt: time.Now().Unix(),
+	}
+	d.GroupBridges = append(d.GroupBridges, b)
+	d.bridgeChangedLocked()
+	return b, nil
+}
+
+// BridgeCodeNew 生成加入桥接用的一次性代码
+func (d *Dice) BridgeCodeNew(b *GroupBridge) string {
+	now := time.Now()
+	bridgeCodes.Range(func(code string, v *bridgeCode) bool {
+		if v.bridgeID == b.ID || now.After(v.expires) {
+			bridgeCodes.Delete(code)
+		}
+		return true
+	})
+	code := utils.RandStr(8)
+	bridgeCodes.Store(code, &bridgeCode{bridgeID: b.ID, expires: now.Add(bridgeCodeTTL)})
+	return code
+}
+
+// BridgeJoin 当前群使用桥接码加入桥接
+func (d *Dice) BridgeJoin(ctx *MsgContext, code string) (*GroupBridge, error) {
+	c, ok := bridgeCodes.LoadAndDelete(strings.ToUpper(strings.TrimSpace(code)))
+	if !ok || time.Now().After(c.expires) {
+		return nil, ErrBridgeCodeInvalid
+	}
+
+	d.bridgeLock.Lock()
+	defer d.bridgeLock.Unlock()
+	if d.bridgeFindLocked(ctx.Group.GroupID) != nil {
+		return nil, ErrBridgeJoined
+	}
+	for _, b := range d.GroupBridges {
+		if b.ID == c.bridgeID {
+			b.Members = append(b.Members, bridgeMemberFromCtx(ctx))
+			d.bridgeChangedLocked()
+			return b, nil
+		}
+	}
+	return nil, ErrBridgeNotFound
+}
+
+// BridgeLeave 群退出桥接。主群退出时由下一个群接任，只剩一个群时桥接解散
+func (d *Dice) BridgeLeave(groupID string) error {
+	d.bridgeLock.Lock()
+	defer d.bridgeLock.Unlock()
+	b := d.bridgeFindLocked(groupID)
+	if b == nil {
+		return ErrBridgeNotJoined
+	}
+	members := make([]*GroupBridgeMember, 0, len(b.Members))
+	for _, m := range b.Members {
+		if m.GroupID != groupID {
+			members = append(members, m)
+		}
+	}
+	b.Members = members
+	if len(members) <= 1 {
+		d.bridgeDeleteLocked(b.ID)
+	}
+	d.bridgeChangedLocked()
+	return nil
+}
+
+// BridgeDelete 解散桥接
+func (d *Dice) BridgeDelete(id string) bool {
+	d.bridgeLock.Lock()
+	defer d.bridgeLock.Unlock()
+	if d.bridgeDeleteLocked(id) {
+		d.bridgeChangedLocked()
+		return true
+	}
+	return false
+}
+
+func (d *Dice) bridgeDeleteLocked(id string) bool {
+	for i, b := range d.GroupBridges {
+		if b.ID == id {
+			d.GroupBridges = append(d.GroupBridges[:i:i], d.GroupBridges[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dice) bridgeFindLocked(groupID string) *GroupBridge {
+	for _, b := range d.GroupBridges {
+		if b.member(groupID) != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+func bridgeMemberFromCtx(ctx *MsgContext) *GroupBridgeMember {
+	return &GroupBridgeMember{
+		GroupID:    ctx.Group.GroupID,
+		GroupName:  ctx.Group.GroupName,
+		EndPointID: ctx.EndPoint.ID,
+	}
+}
+
+// bridgeRelayMessage 将群内收到的消息转发到桥接的其他群
+func (d *Dice) bridgeRelayMessage(ctx *MsgContext, msg *Message, isCommand bool) {
+	if msg.MessageType != "group" || strings.Contains(msg.Message, bridgeMark) {
+		return
+	}
+	b := d.BridgeByGroup(msg.GroupID)
+	if b == nil || (!b.RelayChat && !isCommand) {
+		return
+	}
+	// 自己的帐号和其他骰子发出的消息不转发
+	for _, ep := range ctx.Session.EndPoints {
+		if ep.UserID == msg.Sender.UserID {
+			return
+		}
+	}
+	if ctx.Group != nil && ctx.Group.BotList.Exists(msg.Sender.UserID) {
+		return
+	}
+
+	d.bridgeLock.Lock()
+	if b.limiter == nil {
+		b.limiter = rate.NewLimiter(bridgeRelayRate, bridgeRelayBurst)
+	}
+	if !b.limiter.Allow() {
+		b.Dropped++
+		d.bridgeLock.Unlock()
+		return
+	}
+	d.bridgeLock.Unlock()
+
+	name := msg.Sender.Nickname
+	if ctx.Player != nil && ctx.Player.Name != "" {
+		name = ctx.Player.Name
+	}
+	text := msg.Message
+	if runes := []rune(text); len(runes) > bridgeRelayMaxText {
+		text = string(runes[:bridgeRelayMaxText]) + "……"
+	}
+	d.bridgeSend(b, msg.GroupID, fmt.Sprintf("[%s]<%s> %s", msg.Platform, name, text), OutboxPriorityNotice)
+}
+
+// bridgeRelayReply 将骰子在群内的回复转发到桥接的其他群
+func (d *Dice) bridgeRelayReply(groupID string, text string) {
+	if strings.Contains(text, bridgeMark) {
+		return
+	}
+	if b := d.BridgeByGroup(groupID); b != nil {
+		d.bridgeSend(b, groupID, text, OutboxPriorityReply)
+	}
+}
+
+func (d *Dice) bridgeSend(b *GroupBridge, fromGroupID string, text string, priority int) {
+	d.bridgeLock.RLock()
+	members := append([]*GroupBridgeMember{}, b.Members...)
+	d.bridgeLock.RUnlock()
+
+	for _, m := range members {
+		if m.GroupID == fromGroupID {
+			continue
+		}
+		ep := d.ImSession.GetEpByID(m.EndPointID)
+		if ep == nil || !ep.Enable || ep.Adapter == nil {
+			continue
+		}
+		mctx := &MsgContext{
+			EndPoint:    ep,
+			Session:     ep.Session,
+			Dice:        d,
+			MessageType: "group",
+			isNotice:    priority == OutboxPriorityNotice,
+		}
+		if group, ok := d.ImSession.ServiceAtNew.Load(m.GroupID); ok {
+			mctx.Group = group
+		}
+		var parts []string
+		for _, i := range mctx.SplitText(text) {
+			parts = append(parts, strings.TrimSpace(i)+bridgeMark)
+		}
+		GetOutbox(ep).PushSplit(mctx, true, m.GroupID, parts, "", priority)
+	}
+}
+
+// bridgeSyncState 将群的规则、扩展、日志状态同步到桥接的其他群，在指令执行后调用
+func (d *Dice) bridgeSyncState(src *GroupInfo) {
+	if src == nil {
+		return
+	}
+	b := d.BridgeByGroup(src.GroupID)
+	if b == nil {
+		return
+	}
+	d.bridgeLock.RLock()
+	members := append([]*GroupBridgeMember{}, b.Members...)
+	d.bridgeLock.RUnlock()
+
+	for _, m := range members {
+		if m.GroupID == src.GroupID {
+			continue
+		}
+		dst, ok := d.ImSession.ServiceAtNew.Load(m.GroupID)
+		if !ok {
+			continue
+		}
+		changed := dst.System != src.System || dst.LogCurName != src.LogCurName || dst.LogOn != src.LogOn ||
+			dst.CocRuleIndex != src.CocRuleIndex || dst.DiceSideExpr != src.DiceSideExpr ||
+			!bridgeExtListEqual(dst.ActivatedExtList, src.ActivatedExtList)
+		if !changed {
+			continue
+		}
+		dst.System = src.System
+		dst.LogCurName = src.LogCurName
+		dst.LogOn = src.LogOn
+		dst.CocRuleIndex = src.CocRuleIndex
+		dst.DiceSideExpr = src.DiceSideExpr
+		dst.DiceSideNum = src.DiceSideNum
+		dst.ActivatedExtList = append([]*ExtInfo{}, src.ActivatedExtList...)
+		dst.UpdatedAtTime = time.Now().Unix()
+	}
+}
+
+func bridgeExtListEqual(a, b []*ExtInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package dice
+
+import (
+	"testing"
+)
+
+func TestGroupBridge(t *testing.T) {
+	d := &Dice{}
+	d.ImSession = &IMSession{Parent: d, ServiceAtNew: new(SyncMap[string, *GroupInfo])}
+	qq := &GroupInfo{GroupID: "QQ-Group:1", System: "coc7", LogCurName: "第一章", LogOn: true}
+	dc := &GroupInfo{GroupID: "DISCORD-CH-Group:2"}
+	d.ImSession.ServiceAtNew.Store(qq.GroupID, qq)
+	d.ImSession.ServiceAtNew.Store(dc.GroupID, dc)
+	ctxQQ := &MsgContext{Dice: d, Group: qq, EndPoint: &EndPointInfo{}}
+	ctxDC := &MsgContext{Dice: d, Group: dc, EndPoint: &EndPointInfo{}}
+
+	b, err := d.BridgeNew(ctxQQ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.BridgeNew(ctxQQ); err != ErrBridgeJoined {
+		t.Fatal("a group can only be in one bridge")
+	}
+	if _, err = d.BridgeJoin(ctxDC, "bad"); err != ErrBridgeCodeInvalid {
+		t.Fatal("invalid code should be rejected")
+	}
+	code := d.BridgeCodeNew(b)
+	if _, err = d.BridgeJoin(ctxDC, code); err != nil {
+		t.Fatal(err)
+	}
+	if d.BridgeMainGroupID(dc.GroupID) != qq.GroupID || d.BridgeMainGroupID("QQ-Group:3") != "QQ-Group:3" {
+		t.Fatal("members should map to the main group")
+	}
+
+	d.bridgeSyncState(qq)
+	if dc.System != "coc7" || dc.LogCurName != "第一章" || !dc.LogOn {
+		t.Fatalf("state should be synced: %+v", dc)
+	}
+
+	d.BridgeSet(b.ID, false, true)
+	if d.BridgeByGroup(dc.GroupID) != nil || d.BridgeMainGroupID(dc.GroupID) != dc.GroupID {
+		t.Fatal("disabled bridge should be ignored")
+	}
+	d.BridgeSet(b.ID, true, true)
+	if d.BridgeMainGroupID(dc.GroupID) != qq.GroupID {
+		t.Fatal("re-enabled bridge should map to the main group again")
+	}
+
+	if err = d.BridgeLeave(qq.GroupID); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.GroupBridges) != 0 {
+		t.Fatal("bridge with a single group should be removed")
+	}
+	if d.BridgeMainGroupID(dc.GroupID) != dc.GroupID {
+		t.Fatal("main group cache should be cleared after leaving")
+	}
+}
@@ -0,0 +1,156 @@
+package dice
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sealdice-core/message"
+)
+
+var buttonCQRe = regexp.MustCompile(`\[CQ:button,[^\]]*]`)
+
+// EndPointSupportsButton 该帐号能否显示按钮
+func EndPointSupportsButton(ep *EndPointInfo) bool {
+	return ep.Can(CapButton)
+}
+
+// buttonFallback 平台不支持按钮时，把按钮替换为“标签：指令”形式的提示文本
+func buttonFallback(ep *EndPointInfo, text string) string {
+	if !strings.Contains(text, "[CQ:button,") || EndPointSupportsButton(ep) {
+		return text
+	}
+	var buttons []*message.ButtonElement
+	text = buttonCQRe.ReplaceAllStringFunc(text, func(code string) string {
+		for _, elem := range message.ConvertStringMessage(code) {
+			if b, ok := elem.(*message.ButtonElement); ok {
+				buttons = append(buttons, b)
+			}
+		}
+		return ""
+	})
+	if len(buttons) == 0 {
+		return text
+	}
+	return strings.TrimRight(text, " \n") + buttonHintText(buttons)
+}
+
+// buttonHintText 按钮的文字版，以换行开头
+func buttonHintText(buttons []*message.ButtonElement) string {
+	hints := []string{"\n可发送以下指令继续:"}
+	for _, b := range buttons {
+		if b.Label == b.Command {
+			hints = append(hints, "▸ "+b.Command)
+		} else {
+			hints = append(hints, "▸ "+b.Label+"："+b.Command)
+		}
+	}
+	return strings.Join(hints, "\n")
+}
+
+// buttonRepeat 生成“再来一次”按钮，帐号不支持按钮时返回空串，避免给每条回复都附上提示
+func buttonRepeat(ctx *MsgContext, cmdArgs *CmdArgs, label string) string {
+	if !EndPointSupportsButton(ctx.EndPoint) {
+		return ""
+	}
+	cmd, _ := AtParse(cmdArgs.RawText, cmdArgs.platformPrefix)
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return ""
+	}
+	return message.ButtonCQ(label, cmd)
+}
+
+// buttonCommand 执行指定指令的按钮，沿用触发当前指令时的前缀。
+// 与 buttonRepeat 不同，这类按钮只在特定结果下出现，平台不支持时保留文字提示
+func buttonCommand(cmdArgs *CmdArgs, label, command string) string {
+	prefix := cmdArgs.prefixStr
+	if prefix == "" {
+		prefix = "."
+	}
+	return message.ButtonCQ(label, prefix+command)
+}
+
+// buttonCocCheck coc 检定未达到难度要求(大失败除外)时附上孤注一掷，以及幸运足够时花费幸运改为成功的按钮。
+// 幸运与理智检定、要求大成功的检定不能花费幸运
+func buttonCocCheck(cmdArgs *CmdArgs, attrExpr string, rank, difficulty int, outcome, checkVal, luck int64) string {
+	if rank == -2 || cocCheckPassed(rank, difficulty) {
+		return ""
+	}
+	cmd, _ := AtParse(cmdArgs.RawText, cmdArgs.platformPrefix)
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return ""
+	}
+	text := message.ButtonCQ("孤注一掷", cmd)
+	need := outcome - checkVal
+	lower := strings.ToLower(attrExpr)
+	if need > 0 && luck >= need && difficulty < 4 && !strings.Contains(lower, "幸运") && !strings.Contains(lower, "理智") && !strings.Contains(lower, "san") {
+		text += buttonCommand(cmdArgs, fmt.Sprintf("花费%d点幸运", need), "ra spendluck")
+	}
+	return text
+}
+
+// 平台对按钮回传数据有长度限制(TG为64字节)，过长的指令暂存在这里，只回传编号
+
+const buttonDataPrefix = "#btn:"
+
+type buttonDataItem struct {
+	command string
+	time    int64
+}
+
+var (
+	buttonDataStore  sync.Map // id -> *buttonDataItem
+	buttonDataNextID int64
+	buttonDataLastGC int64
+)
+
+// buttonDataEncode 将指令编码为不超过 limit 字节的回传数据
+func buttonDataEncode(command string, limit int) string {
+	if len(command) <= limit && !strings.HasPrefix(command, buttonDataPrefix) {
+		return command
+	}
+	now := time.Now().Unix()
+	if last := atomic.LoadInt64(&buttonDataLastGC); now-last > 3600 && atomic.CompareAndSwapInt64(&buttonDataLastGC, last, now) {
+		buttonDataStore.Range(func(key, value any) bool {
+			if now-value.(*buttonDataItem).time > 86400 {
+				buttonDataStore.Delete(key)
+			}
+			return true
+		})
+	}
+	id := strconv.FormatInt(atomic.AddInt64(&buttonDataNextID, 1), 36)
+	buttonDataStore.Store(id, &buttonDataItem{command: command, time: now})
+	return buttonDataPrefix + id
+}
+
+// buttonDataDecode 还原回传数据对应的指令，过期时返回 false
+func buttonDataDecode(data string) (string, bool) {
+	if !strings.HasPrefix(data, buttonDataPrefix) {
+		return data, data != ""
+	}
+	v, ok := buttonDataStore.Load(strings.TrimPrefix(data, buttonDataPrefix))
+	if !ok {
+		return "", false
+	}
+	return v.(*buttonDataItem).command, true
+}
+
+// buttonRows 按每行 perRow 个把按钮分组
+func buttonRows(buttons []*message.ButtonElement, perRow int) [][]*message.ButtonElement {
+	var rows [][]*message.ButtonElement
+	for len(buttons) > 0 {
+		n := perRow
+		if len(buttons) < n {
+			n = len(buttons)
+		}
+		rows = append(rows, buttons[:n])
+		buttons = buttons[n:]
+	}
+	return rows
+}
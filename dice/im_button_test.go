@@ -0,0 +1,131 @@
+package dice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"sealdice-core/message"
+)
+
+func TestButtonCQRoundTrip(t *testing.T) {
+	code := message.ButtonCQ("重骰", ".r 1d20,[x]")
+	elems := message.ConvertStringMessage("结果" + code)
+	if len(elems) != 2 {
+		t.Fatalf("want 2 elements, got %d", len(elems))
+	}
+	b, ok := elems[1].(*message.ButtonElement)
+	if !ok || b.Label != "重骰" || b.Command != ".r 1d20,[x]" {
+		t.Fatalf("bad button: %#v", elems[1])
+	}
+}
+
+func TestButtonFallback(t *testing.T) {
+	text := "d20=12" + message.ButtonCQ("重骰", ".r d20")
+	got := buttonFallback(&EndPointInfo{}, text)
+	if strings.Contains(got, "[CQ:button") || !strings.Contains(got, "▸ 重骰：.r d20") {
+		t.Fatalf("unexpected fallback: %q", got)
+	}
+	ep := &EndPointInfo{}
+	ep.Adapter = &PlatformAdapterTelegram{}
+	if buttonFallback(ep, text) != text {
+		t.Fatal("button capable endpoint should keep the button")
+	}
+}
+
+func TestButtonDataEncode(t *testing.T) {
+	if buttonDataEncode(".r d20", 64) != ".r d20" {
+		t.Fatal("short command should be kept as is")
+	}
+	long := ".ra " + strings.Repeat("侦查", 30)
+	data := buttonDataEncode(long, 64)
+	if len(data) > 64 {
+		t.Fatalf("encoded data too long: %d", len(data))
+	}
+	cmd, ok := buttonDataDecode(data)
+	if !ok || cmd != long {
+		t.Fatalf("decode mismatch: %q", cmd)
+	}
+	if _, ok = buttonDataDecode(buttonDataPrefix + "none"); ok {
+		t.Fatal("unknown id should fail")
+	}
+}
+
+func TestButtonCocCheck(t *testing.T) {
+	cmdArgs := CommandParse("。ra 侦查", []string{}, []string{".", "。"}, "QQ", false)
+	cases := []struct {
+		expr               string
+		rank, difficulty   int
+		outcome, val, luck int64
+		want               []string
+	}{
+		{"侦查", 1, 1, 30, 50, 60, nil},
+		{"侦查", -2, 1, 100, 50, 60, nil},
+		{"侦查", -1, 1, 60, 50, 60, []string{message.ButtonCQ("孤注一掷", "。ra 侦查"), message.ButtonCQ("花费10点幸运", "。ra spendluck")}},
+		{"侦查", -1, 1, 90, 50, 30, []string{message.ButtonCQ("孤注一掷", "。ra 侦查")}},
+		{"幸运", -1, 1, 60, 50, 50, []string{message.ButtonCQ("孤注一掷", "。ra 侦查")}},
+		// 常规成功但未达到困难要求，按回复中的失败处理
+		{"侦查", 1, 2, 40, 25, 60, []string{message.ButtonCQ("孤注一掷", "。ra 侦查"), message.ButtonCQ("花费15点幸运", "。ra spendluck")}},
+		{"侦查", 2, 2, 20, 25, 60, nil},
+		// 要求大成功时不能花费幸运
+		{"侦查", 1, 4, 30, 1, 60, []string{message.ButtonCQ("孤注一掷", "。ra 侦查")}},
+	}
+	for _, c := range cases {
+		got := buttonCocCheck(cmdArgs, c.expr, c.rank, c.difficulty, c.outcome, c.val, c.luck)
+		if got != strings.Join(c.want, "") {
+			t.Errorf("%+v: got %q", c, got)
+		}
+	}
+}
+
+func TestCocSpendLuck(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	send := func(text string) string { return chat.send("pl", "", text) }
+	key := physicalDiceKey(chat.message("pl", "", ".ra"))
+
+	send(".st 侦查50 幸运99")
+	if text := send(".ra spendluck"); !strings.Contains(text, "没有可以花费幸运的检定") {
+		t.Fatalf("nothing to spend on: %q", text)
+	}
+
+	// 掷到常规失败为止，51-99 都可以用幸运补上
+	var p *cocLuckPending
+	for i := 0; i < 200 && p == nil; i++ {
+		send(".ra 侦查")
+		p, _ = cocLuckPendingMap.Load(key)
+	}
+	if p == nil {
+		t.Fatal("no failed check recorded")
+	}
+	need := p.Outcome - 50
+
+	text := send(".ra spendluck")
+	if !strings.Contains(text, "=50/50 成功") || strings.Contains(text, "失败") {
+		t.Fatalf("check should become a regular success: %q", text)
+	}
+	if !strings.Contains(text, fmt.Sprintf("花费%d点幸运(99→%d)", need, 99-need)) {
+		t.Fatalf("luck not deducted: %q", text)
+	}
+	if text = send(".st show 幸运"); !strings.Contains(text, fmt.Sprintf("幸运:%d", 99-need)) {
+		t.Fatalf("luck attribute not updated: %q", text)
+	}
+	// 同一次检定只能花费一次
+	if text = send(".ra spendluck"); !strings.Contains(text, "没有可以花费幸运的检定") {
+		t.Fatalf("spent twice: %q", text)
+	}
+
+	// 困难检定失败时，出目降到困难判定值，结果为困难成功
+	send(".st 幸运99")
+	p = nil
+	for i := 0; i < 200 && (p == nil || p.Difficulty != 2); i++ {
+		send(".ra 困难侦查")
+		p, _ = cocLuckPendingMap.Load(key)
+	}
+	if p == nil || p.Difficulty != 2 {
+		t.Fatal("no failed hard check recorded")
+	}
+	if text = send(".ra spendluck"); !strings.Contains(text, "=25/25 成功了！这要费点力气") {
+		t.Fatalf("hard check should become a hard success: %q", text)
+	}
+}
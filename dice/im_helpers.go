@@ -164,10 +164,22 @@ func replyToSenderRawNoCheck(ctx *MsgContext, msg *Message, text string, flag st
 }
 
 func ReplyToSender(ctx *MsgContext, msg *Message, text string) {
+	if ctx.physicalDiceWaiting() {
+		// 物理骰: 等待玩家录入点数，本次的结果作废
+		return
+	}
+	if ctx.outboxBypass() {
+		ReplyToSenderRaw(ctx, msg, text, "")
+		return
+	}
 	go ReplyToSenderRaw(ctx, msg, text, "")
 }
 
 func ReplyToSenderNoCheck(ctx *MsgContext, msg *Message, text string) {
+	if ctx.outboxBypass() {
+		replyToSenderRawNoCheck(ctx, msg, text, "")
+		return
+	}
 	go replyToSenderRawNoCheck(ctx, msg, text, "")
 }
 
@@ -233,16 +245,22 @@ func replyGroupRawNoCheck(ctx *MsgContext, msg *Message, text string, flag strin
 		ctx.Group.RecentDiceSendTime = now
 		ctx.Group.UpdatedAtTime = now
 	}
-	text = strings.TrimSpace(text)
+	text = strings.TrimSpace(buttonFallback(ctx.EndPoint, text))
+	// 经发送队列限速发出
+	var parts []string
 	for _, i := range ctx.SplitText(text) {
-		if ctx.EndPoint != nil && ctx.EndPoint.Platform == "QQ" {
-			doSleepQQ(ctx)
-		}
-		ctx.EndPoint.Adapter.SendToGroup(ctx, msg.GroupID, strings.TrimSpace(i), flag)
+		parts = append(parts, ctx.textImageConvert(strings.TrimSpace(i)))
+	}
+	GetOutbox(ctx.EndPoint).PushSplit(ctx, true, msg.GroupID, parts, flag, ctx.outboxPriority())
+	if ctx.Dice != nil {
+		ctx.Dice.bridgeRelayReply(msg.GroupID, text)
 	}
 }
 
 func ReplyGroup(ctx *MsgContext, msg *Message, text string) {
+	if ctx.physicalDiceWaiting() {
+		return
+	}
 	ReplyGroupRaw(ctx, msg, text, "")
 }
 
@@ -300,13 +318,12 @@ func replyPersonRawNoCheck(ctx *MsgContext, msg *Message, text string, flag stri
 	if lenWithoutBase64(text) > 15000 {
 		text = "要发送的文本过长"
 	}
-	text = strings.TrimSpace(text)
+	text = strings.TrimSpace(buttonFallback(ctx.EndPoint, text))
+	var parts []string
 	for _, i := range ctx.SplitText(text) {
-		if ctx.EndPoint != nil && ctx.EndPoint.Platform == "QQ" {
-			doSleepQQ(ctx)
-		}
-		ctx.EndPoint.Adapter.SendToPerson(ctx, msg.Sender.UserID, strings.TrimSpace(i), flag)
+		parts = append(parts, ctx.textImageConvert(strings.TrimSpace(i)))
 	}
+	GetOutbox(ctx.EndPoint).PushSplit(ctx, false, msg.Sender.UserID, parts, flag, ctx.outboxPriority())
 }
 
 // CrossMsgBySearch
@@ -343,6 +360,9 @@ func CrossMsgBySearch(se *IMSession, p, t, txt string, pr bool) bool {
 // TODO: CrossMsgById 用指定 Id 的 EndPoint 发送跨平台消息，现在似乎没有这个需求
 
 func ReplyPerson(ctx *MsgContext, msg *Message, text string) {
+	if ctx.physicalDiceWaiting() {
+		return
+	}
 	ReplyPersonRaw(ctx, msg, text, "")
 }
 
@@ -369,12 +389,22 @@ func SendFileToGroupRaw(ctx *MsgContext, msg *Message, path string, flag string)
 	ctx.EndPoint.Adapter.SendFileToGroup(ctx, msg.GroupID, path, flag)
 }
 
-func MemberBan(ctx *MsgContext, groupID string, userID string, duration int64) {
+// MemberBan 禁言，平台不支持时返回 ErrCapabilityUnsupported(JS中会抛出异常)
+func MemberBan(ctx *MsgContext, groupID string, userID string, duration int64) error {
+	if !ctx.EndPoint.Can(CapMemberBan) {
+		return ErrCapabilityUnsupported
+	}
 	ctx.EndPoint.Adapter.MemberBan(groupID, userID, duration)
+	return nil
 }
 
-func MemberKick(ctx *MsgContext, groupID string, userID string) {
+// MemberKick 踢人，平台不支持时返回 ErrCapabilityUnsupported(JS中会抛出异常)
+func MemberKick(ctx *MsgContext, groupID string, userID string) error {
+	if !ctx.EndPoint.Can(CapMemberKick) {
+		return ErrCapabilityUnsupported
+	}
 	ctx.EndPoint.Adapter.MemberKick(groupID, userID)
+	return nil
 }
 
 type ByLength []string
@@ -0,0 +1,407 @@
+package dice
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"sealdice-core/utils"
+)
+
+// 发送队列：每个帐号一个，按 单个目标(群/私聊) 与 帐号整体 两级令牌桶限速。
+// 指令回复优先于广播类通知；按分隔符拆开的部分是有意分开发送的，各自作为一条消息排队，不做合并。
+// 超长文本由适配器按平台长度上限切开，切开的各块在适配器内合并到不超过上限。
+// QQ 平台原有的随机回复延迟记在消息的最早发送时间上，由出队时统一调度，不阻塞队列中的其他消息
+
+const (
+	OutboxPriorityReply  = 0 // 指令回复
+	OutboxPriorityNotice = 1 // 通知、定时消息等广播
+)
+
+const (
+	outboxMaxQueued = 500             // 单个帐号最多排队条数，超出时丢弃最旧的
+	outboxMaxWait   = 5 * time.Minute // 排队超过这个时间的消息直接丢弃
+)
+
+// OutboxRateConfig 发送限速。速率格式同刷屏限制，如 "@every 1s"，或填整数表示每秒条数
+type OutboxRateConfig struct {
+	GlobalRate  string `yaml:"globalRate" json:"globalRate"`   // 帐号整体
+	GlobalBurst int    `yaml:"globalBurst" json:"globalBurst"` // 帐号整体可连发条数
+	TargetRate  string `yaml:"targetRate" json:"targetRate"`   // 单个群/私聊
+	TargetBurst int    `yaml:"targetBurst" json:"targetBurst"` // 单个群/私聊可连发条数
+}
+
+var DefaultOutboxRate = OutboxRateConfig{
+	GlobalRate:  "@every 300ms",
+	GlobalBurst: 10,
+	TargetRate:  "@every 1s",
+	TargetBurst: 3,
+}
+
+// Normalize 空白或无法解析的项使用默认值
+func (c OutboxRateConfig) Normalize() OutboxRateConfig {
+	if r, err := utils.ParseRate(c.GlobalRate); err != nil || r <= 0 {
+		c.GlobalRate = DefaultOutboxRate.GlobalRate
+	}
+	if r, err := utils.ParseRate(c.TargetRate); err != nil || r <= 0 {
+		c.TargetRate = DefaultOutboxRate.TargetRate
+	}
+	if c.GlobalBurst <= 0 {
+		c.GlobalBurst = DefaultOutboxRate.GlobalBurst
+	}
+	if c.TargetBurst <= 0 {
+		c.TargetBurst = DefaultOutboxRate.TargetBurst
+	}
+	return c
+}
+
+// OutboxRateFor 平台对应的限速设置，没有单独设置的平台使用通用设置
+func (d *Dice) OutboxRateFor(platform string) OutboxRateConfig {
+	if c, ok := d.OutboxPlatformRates[platform]; ok && c != nil {
+		return c.Normalize()
+	}
+	return d.OutboxRate.Normalize()
+}
+
+type outboxItem struct {
+	ctx     *MsgContext
+	isGroup bool
+	target  string
+	text    string
+	flag    string
+	time    time.Time
+	ready   time.Time // 最早发送时间
+}
+
+// OutboxBypasser 适配器可声明不经过发送队列，消息在回复时直接发出。
+// 用于需要在处理消息期间同步拿到回复的接入方式，如 HTTP 适配器的 sync 模式
+type OutboxBypasser interface {
+	OutboxBypass() bool
+}
+
+func (it *outboxItem) key() string {
+	if it.isGroup {
+		return "G:" + it.target
+	}
+	return "P:" + it.target
+}
+
+// OutboxMetrics 队列统计，供UI展示
+type OutboxMetrics struct {
+	EndPointID   string `json:"endpointId"`
+	Platform     string `json:"platform"`
+	Nickname     string `json:"nickname"`
+	UserID       string `json:"userId"`
+	QueuedReply  int    `json:"queuedReply"`
+	QueuedNotice int    `json:"queuedNotice"`
+	Sent         int64  `json:"sent"`
+	Dropped      int64  `json:"dropped"`
+	AvgWaitMs    int64  `json:"avgWaitMs"`
+	MaxWaitMs    int64  `json:"maxWaitMs"`
+	LastSentAt   int64  `json:"lastSentAt"`
+}
+
+// Outbox 一个帐号的发送队列
+type Outbox struct {
+	ep      *EndPointInfo
+	lock    sync.Mutex
+	queues  [2][]*outboxItem
+	signal  chan struct{}
+	running bool
+	stopped bool
+
+	cfg     OutboxRateConfig
+	global  *rate.Limiter
+	targets map[string]*rate.Limiter
+
+	sent      int64
+	dropped   int64
+	waitTotal time.Duration
+	maxWait   time.Duration
+	lastSent  time.Time
+}
+
+var outboxes sync.Map // *EndPointInfo -> *Outbox
+
+// GetOutbox 取得帐号的发送队列，不存在时创建
+func GetOutbox(ep *EndPointInfo) *Outbox {
+	if v, ok := outboxes.Load(ep); ok {
+		return v.(*Outbox)
+	}
+	ob := &Outbox{ep: ep, signal: make(chan struct{}, 1), targets: map[string]*rate.Limiter{}}
+	v, _ := outboxes.LoadOrStore(ep, ob)
+	return v.(*Outbox)
+}
+
+// RemoveOutbox 帐号停用或删除时丢弃其发送队列，未发出的消息一并丢弃
+func RemoveOutbox(ep *EndPointInfo) {
+	if v, ok := outboxes.LoadAndDelete(ep); ok {
+		v.(*Outbox).stop()
+	}
+}
+
+// OutboxMetricsAll 所有帐号的队列统计
+func OutboxMetricsAll(d *Dice) []*OutboxMetrics {
+	items := []*OutboxMetrics{}
+	for _, ep := range d.ImSession.EndPoints {
+		ob, ok := outboxes.Load(ep)
+		if !ok {
+			// 没有发过消息或已停用，不为此创建队列
+			ob = &Outbox{ep: ep}
+		}
+		items = append(items, ob.(*Outbox).Metrics())
+	}
+	return items
+}
+
+func (ob *Outbox) Metrics() *OutboxMetrics {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	m := &OutboxMetrics{
+		EndPointID:   ob.ep.ID,
+		Platform:     ob.ep.Platform,
+		Nickname:     ob.ep.Nickname,
+		UserID:       ob.ep.UserID,
+		QueuedReply:  len(ob.queues[OutboxPriorityReply]),
+		QueuedNotice: len(ob.queues[OutboxPriorityNotice]),
+		Sent:         ob.sent,
+		Dropped:      ob.dropped,
+		MaxWaitMs:    ob.maxWait.Milliseconds(),
+	}
+	if ob.sent > 0 {
+		m.AvgWaitMs = (ob.waitTotal / time.Duration(ob.sent)).Milliseconds()
+	}
+	if !ob.lastSent.IsZero() {
+		m.LastSentAt = ob.lastSent.Unix()
+	}
+	return m
+}
+
+// Push 消息入队，由后台按限速发出
+func (ob *Outbox) Push(ctx *MsgContext, isGroup bool, target, text, flag string, priority int) {
+	ob.push(&outboxItem{ctx: ctx, isGroup: isGroup, target: target, text: text, flag: flag}, priority)
+}
+
+// PushSplit 按分隔符拆分后的一组消息依次入队，每部分单独发送
+func (ob *Outbox) PushSplit(ctx *MsgContext, isGroup bool, target string, parts []string, flag string, priority int) {
+	for _, text := range parts {
+		ob.push(&outboxItem{ctx: ctx, isGroup: isGroup, target: target, text: text, flag: flag}, priority)
+	}
+}
+
+func (ob *Outbox) push(it *outboxItem, priority int) {
+	if b, ok := ob.ep.Adapter.(OutboxBypasser); ok && b.OutboxBypass() {
+		it.time = time.Now()
+		ob.send(it)
+		return
+	}
+	if priority != OutboxPriorityNotice {
+		priority = OutboxPriorityReply
+	}
+	it.time = time.Now()
+	it.ready = it.time
+	if ob.ep.Platform == "QQ" {
+		// 保留原有的随机延迟设置
+		it.ready = it.time.Add(qqSendDelay(it.ctx))
+	}
+
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	if ob.stopped {
+		return
+	}
+	q := ob.queues[priority]
+	if len(ob.queues[0])+len(ob.queues[1]) >= outboxMaxQueued {
+		// 先丢通知，再丢最旧的回复
+		p := OutboxPriorityNotice
+		if len(ob.queues[p]) == 0 {
+			p = OutboxPriorityReply
+		}
+		ob.queues[p] = ob.queues[p][1:]
+		ob.dropped++
+		q = ob.queues[priority]
+	}
+	ob.queues[priority] = append(q, it)
+
+	if !ob.running {
+		ob.running = true
+		go ob.run()
+	}
+	select {
+	case ob.signal <- struct{}{}:
+	default:
+	}
+}
+
+// stop 停止后台发送并清空队列
+func (ob *Outbox) stop() {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	ob.stopped = true
+	ob.dropped += int64(len(ob.queues[0]) + len(ob.queues[1]))
+	ob.queues = [2][]*outboxItem{}
+	select {
+	case ob.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (ob *Outbox) run() {
+	for {
+		it, wait := ob.next()
+		if it != nil {
+			ob.send(it)
+			continue
+		}
+		if wait <= 0 {
+			return // next 已在队列空时将 running 置为 false
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ob.signal:
+		}
+		timer.Stop()
+	}
+}
+
+// refreshLimiters 设置变化时重建限速器，需持有锁
+func (ob *Outbox) refreshLimiters() {
+	cfg := DefaultOutboxRate
+	if ob.ep.Session != nil && ob.ep.Session.Parent != nil {
+		cfg = ob.ep.Session.Parent.OutboxRateFor(ob.ep.Platform)
+	}
+	if ob.global != nil && cfg == ob.cfg {
+		return
+	}
+	ob.cfg = cfg
+	globalRate, _ := utils.ParseRate(cfg.GlobalRate)
+	ob.global = rate.NewLimiter(globalRate, cfg.GlobalBurst)
+	ob.targets = map[string]*rate.Limiter{}
+}
+
+func (ob *Outbox) targetLimiter(key string, now time.Time) *rate.Limiter {
+	if lim, ok := ob.targets[key]; ok {
+		return lim
+	}
+	if len(ob.targets) > 1000 {
+		// 清掉已经回满的，等同于新建
+		for k, lim := range ob.targets {
+			if lim.TokensAt(now) >= float64(lim.Burst()) {
+				delete(ob.targets, k)
+			}
+		}
+	}
+	targetRate, _ := utils.ParseRate(ob.cfg.TargetRate)
+	lim := rate.NewLimiter(targetRate, ob.cfg.TargetBurst)
+	ob.targets[key] = lim
+	return lim
+}
+
+// limiterWait 令牌桶还需多久才有一个令牌
+func limiterWait(lim *rate.Limiter, now time.Time) time.Duration {
+	tokens := lim.TokensAt(now)
+	if tokens >= 1 {
+		return 0
+	}
+	if lim.Limit() <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - tokens) / float64(lim.Limit()) * float64(time.Second))
+}
+
+// next 取出下一条可以发送的消息；都在限速中时返回需要等待的时间，队列为空时返回 0
+func (ob *Outbox) next() (*outboxItem, time.Duration) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	ob.refreshLimiters()
+	now := time.Now()
+
+	for p := range ob.queues {
+		q := ob.queues[p][:0]
+		for _, it := range ob.queues[p] {
+			if now.Sub(it.time) > outboxMaxWait {
+				ob.dropped++
+				continue
+			}
+			q = append(q, it)
+		}
+		ob.queues[p] = q
+	}
+	if ob.stopped || len(ob.queues[0])+len(ob.queues[1]) == 0 {
+		ob.running = false
+		return nil, 0
+	}
+
+	if wait := limiterWait(ob.global, now); wait > 0 {
+		return nil, wait
+	}
+	minWait := time.Duration(-1)
+	blocked := map[string]bool{} // 前面还有消息在等待的目标，后面的不能先发
+	for p, q := range ob.queues {
+		for idx, it := range q {
+			key := it.key()
+			if blocked[key] {
+				continue
+			}
+			wait := it.ready.Sub(now)
+			lim := ob.targetLimiter(key, now)
+			if wait <= 0 {
+				wait = limiterWait(lim, now)
+			}
+			if wait <= 0 {
+				lim.AllowN(now, 1)
+				ob.global.AllowN(now, 1)
+				ob.queues[p] = append(q[:idx:idx], q[idx+1:]...)
+				return it, 0
+			}
+			blocked[key] = true
+			if minWait < 0 || wait < minWait {
+				minWait = wait
+			}
+		}
+	}
+	return nil, minWait
+}
+
+func (ob *Outbox) send(it *outboxItem) {
+	defer func() {
+		if r := recover(); r != nil && it.ctx.Dice != nil {
+			it.ctx.Dice.Logger.Errorf("发送队列异常: %v 堆栈: %v", r, string(debug.Stack()))
+		}
+	}()
+	wait := time.Since(it.time)
+	if it.isGroup {
+		ob.ep.Adapter.SendToGroup(it.ctx, it.target, it.text, it.flag)
+	} else {
+		ob.ep.Adapter.SendToPerson(it.ctx, it.target, it.text, it.flag)
+	}
+
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	ob.sent++
+	ob.waitTotal += wait
+	if wait > ob.maxWait {
+		ob.maxWait = wait
+	}
+	ob.lastSent = time.Now()
+}
+
+// outboxBypass 适配器不经过发送队列时，回复也在当前协程内直接发出，保证处理消息返回前已送达
+func (ctx *MsgContext) outboxBypass() bool {
+	if ctx.EndPoint == nil {
+		return false
+	}
+	b, ok := ctx.EndPoint.Adapter.(OutboxBypasser)
+	return ok && b.OutboxBypass()
+}
+
+// outboxPriority 该上下文发出的消息的优先级
+func (ctx *MsgContext) outboxPriority() int {
+	if ctx.isNotice {
+		return OutboxPriorityNotice
+	}
+	return OutboxPriorityReply
+}
@@ -0,0 +1,108 @@
+package dice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxPriority(t *testing.T) {
+	ob := GetOutbox(&EndPointInfo{})
+	ob.running = true // 不启动后台发送，直接调用 next 检查出队顺序
+	ctx := &MsgContext{}
+
+	ob.Push(ctx, true, "QQ-Group:1", "notice", "", OutboxPriorityNotice)
+	ob.Push(ctx, true, "QQ-Group:2", "reply", "", OutboxPriorityReply)
+	ob.Push(ctx, false, "QQ:3", "private", "", OutboxPriorityReply)
+
+	for _, want := range []string{"reply", "private", "notice"} {
+		it, _ := ob.next()
+		if it == nil || it.text != want {
+			t.Fatalf("replies should go before notices, want %q, got %+v", want, it)
+		}
+	}
+	if it, wait := ob.next(); it != nil || wait != 0 || ob.running {
+		t.Fatal("queue should be empty")
+	}
+}
+
+func TestOutboxSplitPartsNotMerged(t *testing.T) {
+	ob := GetOutbox(&EndPointInfo{})
+	ob.running = true
+	ctx := &MsgContext{}
+
+	// 按分隔符拆开的部分是有意分开的，无论排队时前一部分是否已发出都不合并
+	ob.PushSplit(ctx, true, "QQ-Group:1", []string{"a", "b"}, "", OutboxPriorityReply)
+	ob.Push(ctx, true, "QQ-Group:1", "c", "", OutboxPriorityReply)
+	for _, want := range []string{"a", "b", "c"} {
+		it, _ := ob.next()
+		if it == nil || it.text != want {
+			t.Fatalf("want %q, got %+v", want, it)
+		}
+	}
+}
+
+func TestOutboxDelayDoesNotBlock(t *testing.T) {
+	ob := GetOutbox(&EndPointInfo{EndPointInfoBase: EndPointInfoBase{Platform: "QQ"}})
+	ob.running = true
+	slow := &MsgContext{Dice: &Dice{MessageDelayRangeStart: 10, MessageDelayRangeEnd: 10}}
+	fast := &MsgContext{Dice: &Dice{}}
+
+	// 回复延迟未到的消息不挡住其他目标，同一目标后面的消息仍排在它之后
+	ob.Push(slow, true, "QQ-Group:1", "slow", "", OutboxPriorityReply)
+	ob.Push(fast, true, "QQ-Group:1", "after", "", OutboxPriorityReply)
+	ob.Push(fast, true, "QQ-Group:2", "fast", "", OutboxPriorityNotice)
+	if it, _ := ob.next(); it == nil || it.text != "fast" {
+		t.Fatalf("delayed message should not block others, got %+v", it)
+	}
+	if it, wait := ob.next(); it != nil || wait < 9*time.Second {
+		t.Fatalf("should wait for the delayed message, got %+v %v", it, wait)
+	}
+}
+
+func TestRemoveOutbox(t *testing.T) {
+	ep := &EndPointInfo{}
+	ob := GetOutbox(ep)
+	ob.running = true
+	ob.Push(&MsgContext{}, true, "QQ-Group:1", "a", "", OutboxPriorityReply)
+
+	RemoveOutbox(ep)
+	if it, _ := ob.next(); it != nil || ob.running {
+		t.Fatal("removed outbox should stop sending")
+	}
+	ob.Push(&MsgContext{}, true, "QQ-Group:1", "b", "", OutboxPriorityReply)
+	if m := ob.Metrics(); m.QueuedReply != 0 || m.Dropped != 1 {
+		t.Fatalf("removed outbox should drop queued messages: %+v", m)
+	}
+	if GetOutbox(ep) == ob {
+		t.Fatal("outbox should be recreated after removal")
+	}
+}
+
+func TestOutboxTargetLimit(t *testing.T) {
+	ob := GetOutbox(&EndPointInfo{})
+	ob.running = true
+	ctx := &MsgContext{}
+
+	// 单个目标超出连发条数后需要等待
+	for i := 0; i <= DefaultOutboxRate.TargetBurst; i++ {
+		ob.Push(ctx, true, "QQ-Group:1", "msg", string(rune('a'+i)), OutboxPriorityReply)
+	}
+	for i := 0; i < DefaultOutboxRate.TargetBurst; i++ {
+		if it, _ := ob.next(); it == nil {
+			t.Fatalf("message %d should be sent within burst", i)
+		}
+	}
+	if it, wait := ob.next(); it != nil || wait <= 0 {
+		t.Fatal("target limiter should delay the next message")
+	}
+}
+
+func TestOutboxRateNormalize(t *testing.T) {
+	c := OutboxRateConfig{GlobalRate: "bad", TargetRate: "@every 2s", TargetBurst: 5}.Normalize()
+	if c.GlobalRate != DefaultOutboxRate.GlobalRate || c.GlobalBurst != DefaultOutboxRate.GlobalBurst {
+		t.Fatalf("invalid values should fall back to defaults: %+v", c)
+	}
+	if c.TargetRate != "@every 2s" || c.TargetBurst != 5 {
+		t.Fatalf("valid values should be kept: %+v", c)
+	}
+}
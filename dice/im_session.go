@@ -47,6 +47,7 @@ type Message struct {
 	Platform    string      `json:"platform" jsbind:"platform"` // 当前平台
 	GroupName   string      `json:"groupName"`
 	TmpUID      string      `json:"-" yaml:"-"`
+	ReplyTag    string      `json:"-" yaml:"-"` // 关联ID，由适配器设置，处理该消息时发出的回复会带上它
 	// Note(Szzrain): 这里是消息段，为了支持多种消息类型，目前只有 LagrangeGo 支持，其他平台也应该尽快迁移支持，并使用 Session.ExecuteNew 方法
 	Segment []message.IMessageElement `json:"-" yaml:"-" jsbind:"segment"`
 }
@@ -116,6 +117,16 @@ type GroupInfo struct {
 	UpdatedAtTime int64 `yaml:"-" json:"-"`
 
 	DefaultHelpGroup string `yaml:"defaultHelpGroup" json:"defaultHelpGroup"` // 当前群默认的帮助文档分组
+
+	FairRollOn bool `yaml:"fairRollOn" json:"fairRollOn" jsbind:"fairRollOn"` // 公平骰模式，掷骰由公开承诺的种子推导
+
+	RandSource      string `yaml:"randSource" json:"randSource" jsbind:"randSource"` // 随机源，见 .set rng
+	RandSeed        string `yaml:"randSeed" json:"randSeed"`                         // seeded随机源使用的种子
+	RandSeedCounter int64  `yaml:"randSeedCounter" json:"randSeedCounter"`           // seeded随机源已执行的指令数
+
+	RoomAlias string `yaml:"roomAlias" json:"roomAlias"` // Matrix 房间的公开别名，群号本身为房间ID
+
+	TextImageThreshold int `yaml:"textImageThreshold" json:"textImageThreshold"` // 长回复转图片的字数，0为跟随全局，-1为关闭
 }
 
 // ExtActive 开启扩展
@@ -404,6 +415,42 @@ func (ep *EndPointInfo) UnmarshalYAML(value *yaml.Node) error {
 			return err
 		}
 		ep.Adapter = val.Adapter
+	case "MATRIX":
+		var val struct {
+			Adapter *PlatformAdapterMatrix `yaml:"adapter"`
+		}
+		err = value.Decode(&val)
+		if err != nil {
+			return err
+		}
+		ep.Adapter = val.Adapter
+	case "IRC":
+		var val struct {
+			Adapter *PlatformAdapterIRC `yaml:"adapter"`
+		}
+		err = value.Decode(&val)
+		if err != nil {
+			return err
+		}
+		ep.Adapter = val.Adapter
+	case "FEISHU":
+		var val struct {
+			Adapter *PlatformAdapterFeishu `yaml:"adapter"`
+		}
+		err = value.Decode(&val)
+		if err != nil {
+			return err
+		}
+		ep.Adapter = val.Adapter
+	case "HTTP":
+		var val struct {
+			Adapter *PlatformAdapterHTTP `yaml:"adapter"`
+		}
+		err = value.Decode(&val)
+		if err != nil {
+			return err
+		}
+		ep.Adapter = val.Adapter
 	case "SEALCHAT":
 		var val struct {
 			Adapter *PlatformAdapterSealChat `yaml:"adapter"`
@@ -497,6 +544,12 @@ type MsgContext struct {
 	vm            *ds.Context
 	AttrsCurCache *AttributesItem
 	_v1Rand       *rand2.PCGSource
+	fairRoll      *fairRollState // 公平骰模式下当前指令的随机流
+	randSource    *randSourceState
+	statPaused    bool             // 概率模拟等场合的掷骰不计入骰点统计
+	isNotice      bool             // 广播类通知，在发送队列中让位于指令回复
+	replyTag      string           // 来源消息的 ReplyTag
+	aliasStep     *aliasStepResult // 快捷指令当前步骤的检定结果，代骰复制出的 ctx 共用同一份
 }
 
 // fillPrivilege 填写MsgContext中的权限字段, 并返回填写的权限等级
@@ -521,14 +574,12 @@ func (ctx *MsgContext) fillPrivilege(msg *Message) int {
 	}
 
 	// 加入黑名单相关权限
-	if val, exists := ctx.Dice.BanList.GetByID(ctx.Player.UserID); exists {
-		switch val.Rank {
-		case BanRankBanned:
-			ctx.PrivilegeLevel = -30
-		case BanRankTrusted:
-			ctx.PrivilegeLevel = 70
-		default: /* no-op */
-		}
+	switch ctx.Dice.BanList.EffectiveRank(ctx.Player.UserID) {
+	case BanRankBanned:
+		ctx.PrivilegeLevel = -30
+	case BanRankTrusted:
+		ctx.PrivilegeLevel = 70
+	default: /* no-op */
 	}
 
 	grpID := ""
@@ -552,6 +603,7 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 	mctx.IsPrivate = mctx.MessageType == "private"
 	mctx.Session = s
 	mctx.EndPoint = ep
+	mctx.replyTag = msg.ReplyTag
 	log := d.Logger
 
 	// 处理命令
@@ -757,6 +809,9 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 			}
 		}
 
+		// 跨平台桥接：转发到桥接的其他群
+		d.bridgeRelayMessage(mctx, msg, cmdArgs != nil)
+
 		if msg.MessageType == "private" {
 			if mctx.CommandID != 0 {
 				log.Infof("收到<%s>(%s)的私聊指令: %s", msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
@@ -834,6 +889,7 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 				}
 
 				ep.TriggerCommand(mctx, msg, cmdArgs)
+				d.bridgeSyncState(mctx.Group)
 			}
 			if runInSync {
 				f()
@@ -904,6 +960,7 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 	mctx.IsPrivate = mctx.MessageType == "private"
 	mctx.Session = s
 	mctx.EndPoint = ep
+	mctx.replyTag = msg.ReplyTag
 	log := d.Logger
 
 	// 处理消息段，如果 2.0 要完全抛弃依赖 Message.Message 的字符串解析，把这里删掉
@@ -1309,6 +1366,26 @@ func (s *IMSession) OnGroupJoined(ctx *MsgContext, msg *Message) {
 			})
 		}
 	}
+	d.Webhook.Emit(WebhookEventGroupJoin, map[string]interface{}{
+		"platform":  msg.Platform,
+		"groupId":   msg.GroupID,
+		"groupName": groupName,
+		"inviterId": msg.Sender.UserID,
+		"endpoint":  ep.UserID,
+	})
+}
+
+// OnGroupLeft 骰子离开群组(主动退出、被踢或群解散)后调用，operatorID 为空表示未知或是自己
+func (s *IMSession) OnGroupLeft(ep *EndPointInfo, groupID string, operatorID string, kicked bool) {
+	d := s.Parent
+	d.Webhook.Emit(WebhookEventGroupLeave, map[string]interface{}{
+		"platform":   ep.Platform,
+		"groupId":    groupID,
+		"groupName":  d.Parent.TryGetGroupName(groupID),
+		"operatorId": operatorID,
+		"kicked":     kicked,
+		"endpoint":   ep.UserID,
+	})
 }
 
 var lastWelcome *LastWelcomeInfo
@@ -1456,6 +1533,7 @@ func (s *IMSession) LongTimeQuitInactiveGroup(threshold, hint time.Time, roundIn
 				grp.DiceIDExistsMap.Delete(ep.UserID)
 				grp.UpdatedAtTime = time.Now().Unix()
 				ep.Adapter.QuitGroup(&MsgContext{Dice: s.Parent}, grp.GroupID)
+				s.OnGroupLeft(ep, grp.GroupID, "", false)
 				// 保证在多次点击时可以收到日志
 				groupLeaveNum++
 				(&MsgContext{Dice: s.Parent, EndPoint: ep, Session: s}).Notice(hint)
@@ -1516,6 +1594,7 @@ func checkBan(ctx *MsgContext, msg *Message) (notReply bool) {
 
 		time.Sleep(1 * time.Second)
 		ctx.EndPoint.Adapter.QuitGroup(ctx, groupID)
+		ctx.Session.OnGroupLeft(ctx.EndPoint, groupID, "", false)
 	}
 
 	if ctx.PrivilegeLevel == -30 {
@@ -1585,6 +1664,7 @@ func checkBan(ctx *MsgContext, msg *Message) (notReply bool) {
 
 				time.Sleep(1 * time.Second)
 				ctx.EndPoint.Adapter.QuitGroup(ctx, groupID)
+				ctx.Session.OnGroupLeft(ctx.EndPoint, groupID, "", false)
 			}
 		} else if d.BanList.BanBehaviorRefuseReply {
 			notReply = true
@@ -1689,6 +1769,9 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 			}
 		}
 
+		fr := ctx.fairRollBegin(msg, cmdArgs)
+		rs := ctx.randSourceBegin(msg)
+
 		var ret CmdExecuteResult
 		// 如果是js命令，那么加锁
 		if item.IsJsSolveFunc {
@@ -1708,6 +1791,8 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 		} else {
 			ret = item.Solve(ctx, msg, cmdArgs)
 		}
+		ctx.randSourceEnd(rs)
+		ctx.fairRollEnd(fr)
 
 		if ret.Solved {
 			if ret.ShowHelp {
@@ -1761,6 +1846,9 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 			}
 		}
 	}
+	if solved {
+		webhookEmitRoll(ctx, msg, cmdArgs)
+	}
 
 	return solved
 }
@@ -1846,12 +1934,24 @@ func (s *IMSession) GetEpByPlatform(p string) *EndPointInfo {
 	return nil
 }
 
+func (s *IMSession) GetEpByID(id string) *EndPointInfo {
+	for _, ep := range s.EndPoints {
+		if ep.ID == id {
+			return ep
+		}
+	}
+	return nil
+}
+
 // SetEnable
 /* 如果已连接，将断开连接，如果开着GCQ将自动结束。如果启用的话，则反过来  */
 func (ep *EndPointInfo) SetEnable(_ *Dice, enable bool) {
 	if ep.Enable != enable {
 		ep.Adapter.SetEnable(enable)
 	}
+	if !enable {
+		RemoveOutbox(ep)
+	}
 }
 
 func (ep *EndPointInfo) AdapterSetup() {
@@ -1915,6 +2015,22 @@ func (ep *EndPointInfo) AdapterSetup() {
 		pa := ep.Adapter.(*PlatformAdapterSealChat)
 		pa.Session = ep.Session
 		pa.EndPoint = ep
+	case "MATRIX":
+		pa := ep.Adapter.(*PlatformAdapterMatrix)
+		pa.Session = ep.Session
+		pa.EndPoint = ep
+	case "IRC":
+		pa := ep.Adapter.(*PlatformAdapterIRC)
+		pa.Session = ep.Session
+		pa.EndPoint = ep
+	case "FEISHU":
+		pa := ep.Adapter.(*PlatformAdapterFeishu)
+		pa.Session = ep.Session
+		pa.EndPoint = ep
+	case "HTTP":
+		pa := ep.Adapter.(*PlatformAdapterHTTP)
+		pa.Session = ep.Session
+		pa.EndPoint = ep
 	}
 }
 
@@ -1974,10 +2090,12 @@ func (d *Dice) NoticeForEveryEndpoint(txt string, allowCrossPlatform bool) {
 					if strings.HasSuffix(n[0], "-Group") {
 						msg := &Message{GroupID: i, MessageType: "private", Sender: SenderBase{UserID: i}}
 						ctx := CreateTempCtx(ep, msg)
+						ctx.isNotice = true
 						ReplyGroup(ctx, msg, txt)
 					} else {
 						msg := &Message{GroupID: i, MessageType: "group", Sender: SenderBase{UserID: i}}
 						ctx := CreateTempCtx(ep, msg)
+						ctx.isNotice = true
 						ReplyPerson(ctx, &Message{Sender: SenderBase{UserID: i}}, txt)
 					}
 				}
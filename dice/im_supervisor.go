@@ -0,0 +1,434 @@
+package dice
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sacOO7/gowebsocket"
+)
+
+// 连接守护：统一各适配器的断线重连。指数退避加随机抖动，默认不限次数；
+// 可选心跳检测；每次状态变化记录为事件，供UI查看连接历史，并推送 webhook
+
+// 连接状态，与 EndPointInfoBase.State 取值一致
+const (
+	ConnStateDisconnected = 0
+	ConnStateConnected    = 1
+	ConnStateConnecting   = 2
+	ConnStateFailed       = 3
+)
+
+const connHistoryLimit = 100
+
+// ReconnectPolicy 重连退避策略
+type ReconnectPolicy struct {
+	BaseDelay  time.Duration // 首次重连等待
+	MaxDelay   time.Duration // 等待上限
+	Factor     float64       // 每次失败后的倍数
+	Jitter     float64       // 随机抖动比例，0~1
+	MaxRetries int           // 连续失败多少次后放弃，0为不限
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{
+	BaseDelay: 2 * time.Second,
+	MaxDelay:  5 * time.Minute,
+	Factor:    2,
+	Jitter:    0.2,
+}
+
+// Delay 第 attempt 次(从1开始)重连前的等待时间
+func (p ReconnectPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := float64(p.BaseDelay) * math.Pow(p.Factor, float64(attempt-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1) //nolint:gosec
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+// ConnEvent 一次连接状态变化
+type ConnEvent struct {
+	Time    int64  `json:"time"`
+	State   int    `json:"state"`
+	Attempt int    `json:"attempt"`
+	Delay   int64  `json:"delay"` // 距下次重连的毫秒数，仅重连等待时有值
+	Message string `json:"message"`
+}
+
+// ConnSupervisor 一个帐号的连接守护
+type ConnSupervisor struct {
+	Policy *ReconnectPolicy // 为空时按骰子设置
+
+	// 心跳：连接成功后每隔 PingInterval 调用 Ping，出错或超过 PingTimeout 没有收到 Alive 时调用 OnDead。
+	// OnDead 一般只需关闭连接，之后由断线回调走正常的重连流程
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	Ping         func() error
+	OnDead       func()
+
+	ep        *EndPointInfo
+	lock      sync.Mutex
+	attempt   int
+	stopped   bool
+	pending   bool // 已安排重连，用于合并重复的断线回调
+	gen       int  // 每次状态切换加一，使过期的定时器与心跳失效
+	run       int  // 每次 Connecting/Stop 加一，使旧的重连循环退出
+	lastAlive time.Time
+	wake      chan struct{}
+	history   []ConnEvent
+}
+
+var connSupervisors sync.Map // *EndPointInfo -> *ConnSupervisor
+
+// GetConnSupervisor 取得帐号的连接守护，不存在时创建
+func GetConnSupervisor(ep *EndPointInfo) *ConnSupervisor {
+	if v, ok := connSupervisors.Load(ep); ok {
+		return v.(*ConnSupervisor)
+	}
+	sv := &ConnSupervisor{ep: ep, wake: make(chan struct{})}
+	v, _ := connSupervisors.LoadOrStore(ep, sv)
+	return v.(*ConnSupervisor)
+}
+
+// RemoveConnSupervisor 帐号删除时停止其连接守护(包括心跳)并丢弃连接历史
+func RemoveConnSupervisor(ep *EndPointInfo) {
+	if v, ok := connSupervisors.LoadAndDelete(ep); ok {
+		v.(*ConnSupervisor).Stop("连接已删除")
+	}
+}
+
+// WatchSocket 为 gowebsocket 连接开启心跳检测，socket 返回当前使用的连接。
+// 需在 OnPongReceived 或收到消息时调用 Alive
+func (sv *ConnSupervisor) WatchSocket(socket func() *gowebsocket.Socket) {
+	sv.watch(func() *websocket.Conn {
+		if s := socket(); s != nil {
+			return s.Conn
+		}
+		return nil
+	}, func() {
+		if s := socket(); s != nil && s.Conn != nil {
+			s.Close()
+		}
+	})
+}
+
+// WatchConn 同 WatchSocket，用于直接使用 gorilla/websocket 的连接，超时后关闭连接
+func (sv *ConnSupervisor) WatchConn(conn func() *websocket.Conn) {
+	sv.watch(conn, func() {
+		if c := conn(); c != nil {
+			_ = c.Close()
+		}
+	})
+}
+
+func (sv *ConnSupervisor) watch(conn func() *websocket.Conn, onDead func()) {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	if sv.Ping != nil {
+		return
+	}
+	sv.PingInterval = 30 * time.Second
+	sv.PingTimeout = 90 * time.Second
+	sv.Ping = func() error {
+		c := conn()
+		if c == nil {
+			return errors.New("连接不存在")
+		}
+		return c.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+	}
+	sv.OnDead = onDead
+}
+
+// ReconnectPolicy 由自定义设置得到的重连策略
+func (d *Dice) ReconnectPolicy() ReconnectPolicy {
+	p := DefaultReconnectPolicy
+	if d.ReconnectMaxRetries > 0 {
+		p.MaxRetries = d.ReconnectMaxRetries
+	}
+	if d.ReconnectMaxDelay > 0 {
+		p.MaxDelay = time.Duration(d.ReconnectMaxDelay) * time.Second
+	}
+	return p
+}
+
+// ConnHistory 帐号最近的连接事件，旧的在前
+func (ep *EndPointInfo) ConnHistory() []ConnEvent {
+	if v, ok := connSupervisors.Load(ep); ok {
+		return v.(*ConnSupervisor).History()
+	}
+	return []ConnEvent{}
+}
+
+func (sv *ConnSupervisor) History() []ConnEvent {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	return append([]ConnEvent{}, sv.history...)
+}
+
+// Attempt 当前连续失败次数
+func (sv *ConnSupervisor) Attempt() int {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	return sv.attempt
+}
+
+// record 需持有锁
+func (sv *ConnSupervisor) record(state int, delay time.Duration, msg string) {
+	ev := ConnEvent{
+		Time:    time.Now().Unix(),
+		State:   state,
+		Attempt: sv.attempt,
+		Delay:   delay.Milliseconds(),
+		Message: msg,
+	}
+	sv.ep.State = state
+	sv.appendEvent(ev)
+
+	if sv.ep.Session == nil || sv.ep.Session.Parent == nil {
+		return
+	}
+	d := sv.ep.Session.Parent
+	d.LastUpdatedTime = time.Now().Unix()
+	d.Webhook.Emit(WebhookEventConnState, map[string]interface{}{
+		"endpointId": sv.ep.ID,
+		"platform":   sv.ep.Platform,
+		"userId":     sv.ep.UserID,
+		"state":      ev.State,
+		"attempt":    ev.Attempt,
+		"delay":      ev.Delay,
+		"message":    ev.Message,
+	})
+}
+
+func (sv *ConnSupervisor) appendEvent(ev ConnEvent) {
+	sv.history = append(sv.history, ev)
+	if len(sv.history) > connHistoryLimit {
+		sv.history = sv.history[len(sv.history)-connHistoryLimit:]
+	}
+}
+
+// Connecting 开始(或重新开始)连接，会解除 Stop 的效果。返回值供 Wait 使用
+func (sv *ConnSupervisor) Connecting(msg string) int {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	if sv.stopped || sv.wake == nil {
+		sv.stopped = false
+		sv.wake = make(chan struct{})
+	}
+	sv.gen++
+	sv.run++
+	sv.pending = false
+	sv.record(ConnStateConnecting, 0, msg)
+	return sv.run
+}
+
+// Connected 连接成功，重置失败计数并开始心跳，同样会解除 Stop 的效果
+func (sv *ConnSupervisor) Connected(msg string) {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	if sv.stopped || sv.wake == nil {
+		sv.stopped = false
+		sv.wake = make(chan struct{})
+	}
+	sv.attempt = 0
+	sv.pending = false
+	sv.gen++
+	sv.lastAlive = time.Now()
+	sv.record(ConnStateConnected, 0, msg)
+	if sv.Ping != nil && sv.PingInterval > 0 {
+		go sv.pingLoop(sv.gen, sv.wake)
+	}
+}
+
+// Alive 收到对端的任何数据(如pong)时调用
+func (sv *ConnSupervisor) Alive() {
+	sv.lock.Lock()
+	sv.lastAlive = time.Now()
+	sv.lock.Unlock()
+}
+
+// Stop 主动断开，不再重连，并唤醒正在等待的 Wait
+func (sv *ConnSupervisor) Stop(msg string) {
+	sv.stop(ConnStateDisconnected, msg)
+}
+
+// Failed 出现无法靠重连解决的错误(如鉴权失败)，不再重连
+func (sv *ConnSupervisor) Failed(msg string) {
+	sv.stop(ConnStateFailed, msg)
+}
+
+func (sv *ConnSupervisor) stop(state int, msg string) {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	sv.gen++
+	sv.run++
+	sv.pending = false
+	if sv.wake != nil {
+		close(sv.wake)
+		sv.wake = nil
+	}
+	if !sv.stopped || sv.ep.State != state {
+		sv.record(state, 0, msg)
+	}
+	sv.stopped = true
+}
+
+// Dropped 连接断开，但由底层库自行重连(如 discordgo)，只记录状态
+func (sv *ConnSupervisor) Dropped(msg string) {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	if sv.stopped {
+		return
+	}
+	sv.gen++
+	sv.attempt++
+	sv.record(ConnStateConnecting, 0, msg)
+}
+
+// backoff 记录一次失败并给出等待时间，不应重连时返回 false
+func (sv *ConnSupervisor) backoff(reason string) (time.Duration, bool) {
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	if sv.pending {
+		return 0, false
+	}
+	sv.gen++
+	if sv.stopped || !sv.ep.Enable {
+		if sv.ep.State != ConnStateDisconnected {
+			sv.record(ConnStateDisconnected, 0, reason)
+		}
+		return 0, false
+	}
+	policy := DefaultReconnectPolicy
+	if sv.Policy != nil {
+		policy = *sv.Policy
+	} else if sv.ep.Session != nil && sv.ep.Session.Parent != nil {
+		policy = sv.ep.Session.Parent.ReconnectPolicy()
+	}
+	sv.attempt++
+	if policy.MaxRetries > 0 && sv.attempt > policy.MaxRetries {
+		sv.record(ConnStateFailed, 0, reason+"，重试次数已用尽")
+		return 0, false
+	}
+	delay := policy.Delay(sv.attempt)
+	sv.pending = true
+	sv.record(ConnStateConnecting, delay, reason)
+	return delay, true
+}
+
+// Lost 连接断开(回调式连接用)：按退避时间后调用 redial，返回是否安排了重连。
+// 同一次断线的重复回调会被合并
+func (sv *ConnSupervisor) Lost(reason string, redial func()) bool {
+	delay, ok := sv.backoff(reason)
+	if !ok {
+		return false
+	}
+	sv.lock.Lock()
+	gen := sv.gen
+	sv.lock.Unlock()
+	if sv.ep.Session != nil && sv.ep.Session.Parent != nil {
+		sv.ep.Session.Parent.Logger.Infof("%s，%.1f秒后进行第%d次重连: <%s>(%s)",
+			reason, delay.Seconds(), sv.Attempt(), sv.ep.Nickname, sv.ep.UserID)
+	}
+	time.AfterFunc(delay, func() {
+		sv.lock.Lock()
+		if sv.gen != gen || sv.stopped {
+			sv.lock.Unlock()
+			return
+		}
+		sv.pending = false
+		sv.lock.Unlock()
+		redial()
+	})
+	return true
+}
+
+// Wait 连接断开(循环式连接用)：阻塞到该重连的时候，返回 false 表示应当退出循环。
+// run 为本轮 Connecting 的返回值，期间若有别处重新发起连接或主动断开，旧循环会退出
+func (sv *ConnSupervisor) Wait(run int, reason string) bool {
+	sv.lock.Lock()
+	current := sv.run == run
+	sv.lock.Unlock()
+	if !current {
+		return false
+	}
+	delay, ok := sv.backoff(reason)
+	if !ok {
+		return false
+	}
+	sv.lock.Lock()
+	wake := sv.wake
+	sv.lock.Unlock()
+	if sv.ep.Session != nil && sv.ep.Session.Parent != nil {
+		sv.ep.Session.Parent.Logger.Infof("%s，%.1f秒后进行第%d次重连: <%s>(%s)",
+			reason, delay.Seconds(), sv.Attempt(), sv.ep.Nickname, sv.ep.UserID)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-wake:
+	}
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	if sv.run != run {
+		return false
+	}
+	sv.pending = false
+	return !sv.stopped && sv.ep.Enable
+}
+
+// pingLoop wake 在 Stop 时关闭，心跳随之立即退出
+func (sv *ConnSupervisor) pingLoop(gen int, wake chan struct{}) {
+	defer CrashLog()
+	ticker := time.NewTicker(sv.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-wake:
+			return
+		}
+		sv.lock.Lock()
+		if sv.gen != gen || sv.stopped {
+			sv.lock.Unlock()
+			return
+		}
+		last := sv.lastAlive
+		sv.lock.Unlock()
+
+		reason := ""
+		if err := sv.Ping(); err != nil {
+			reason = "心跳发送失败: " + err.Error()
+		} else if sv.PingTimeout > 0 && time.Since(last) > sv.PingTimeout {
+			reason = "心跳超时"
+		}
+		if reason == "" {
+			continue
+		}
+
+		sv.lock.Lock()
+		if sv.gen != gen {
+			sv.lock.Unlock()
+			return
+		}
+		sv.appendEvent(ConnEvent{Time: time.Now().Unix(), State: sv.ep.State, Message: reason})
+		sv.lock.Unlock()
+		if sv.OnDead != nil {
+			sv.OnDead()
+		}
+		return
+	}
+}
@@ -0,0 +1,107 @@
+package dice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	p := ReconnectPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Factor: 2}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Fatalf("attempt %d: want %v, got %v", i+1, w, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(1); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("jitter out of range: %v", d)
+		}
+	}
+}
+
+func TestConnSupervisorLost(t *testing.T) {
+	ep := &EndPointInfo{}
+	ep.Enable = true
+	sv := GetConnSupervisor(ep)
+	sv.Policy = &ReconnectPolicy{BaseDelay: time.Millisecond, Factor: 1, MaxRetries: 2}
+
+	redial := make(chan struct{}, 4)
+	sv.Connecting("connect")
+	if !sv.Lost("lost", func() { redial <- struct{}{} }) {
+		t.Fatal("first failure should schedule a reconnect")
+	}
+	if sv.Lost("lost again", func() { redial <- struct{}{} }) {
+		t.Fatal("duplicate callback should be merged")
+	}
+	<-redial
+	if ep.State != ConnStateConnecting || sv.Attempt() != 1 {
+		t.Fatalf("unexpected state %d attempt %d", ep.State, sv.Attempt())
+	}
+
+	if !sv.Lost("lost", func() {}) {
+		t.Fatal("second failure should still retry")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if sv.Lost("lost", func() {}) || ep.State != ConnStateFailed {
+		t.Fatal("should give up after MaxRetries")
+	}
+
+	sv.Connected("ok")
+	if sv.Attempt() != 0 || ep.State != ConnStateConnected {
+		t.Fatal("connected should reset attempts")
+	}
+	sv.Stop("stop")
+	if sv.Lost("lost", func() { t.Error("should not redial after stop") }) {
+		t.Fatal("stopped supervisor should not reconnect")
+	}
+	if n := len(ep.ConnHistory()); n < 6 {
+		t.Fatalf("history too short: %d", n)
+	}
+}
+
+func TestConnSupervisorWait(t *testing.T) {
+	ep := &EndPointInfo{}
+	ep.Enable = true
+	sv := GetConnSupervisor(ep)
+	sv.Policy = &ReconnectPolicy{BaseDelay: time.Hour, Factor: 1}
+
+	run := sv.Connecting("connect")
+	done := make(chan bool)
+	go func() { done <- sv.Wait(run, "lost") }()
+	time.Sleep(10 * time.Millisecond)
+	sv.Stop("stop")
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("wait should report stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stop should wake up wait")
+	}
+}
+
+func TestRemoveConnSupervisor(t *testing.T) {
+	ep := &EndPointInfo{}
+	ep.Enable = true
+	sv := GetConnSupervisor(ep)
+	sv.PingInterval = time.Hour
+	sv.Ping = func() error { return nil }
+	sv.Connected("ok")
+
+	RemoveConnSupervisor(ep)
+	if n := len(ep.ConnHistory()); n != 0 {
+		t.Fatalf("removed endpoint should have no history, got %d", n)
+	}
+	if GetConnSupervisor(ep) == sv {
+		t.Fatal("supervisor should be recreated after removal")
+	}
+	sv.lock.Lock()
+	stopped := sv.stopped
+	sv.lock.Unlock()
+	if !stopped {
+		t.Fatal("removed supervisor should be stopped")
+	}
+}
@@ -0,0 +1,49 @@
+package dice
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode/utf8"
+
+	"sealdice-core/utils/textimg"
+)
+
+// 长文本转图片：回复超过设定字数时渲染为PNG发出，避免被切成多条刷屏
+
+// TextImageThreshold 当前会话的转图片字数，0表示不转换
+func (ctx *MsgContext) TextImageThreshold() int {
+	n := 0
+	if ctx.Dice != nil {
+		n = ctx.Dice.TextImageThreshold
+	}
+	if ctx.Group != nil && ctx.Group.TextImageThreshold != 0 {
+		n = ctx.Group.TextImageThreshold
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// textImageConvert 文本过长时转为图片CQ码，不满足条件或渲染失败时原样返回
+func (ctx *MsgContext) textImageConvert(text string) string {
+	threshold := ctx.TextImageThreshold()
+	if threshold <= 0 || utf8.RuneCountInString(text) <= threshold {
+		return text
+	}
+	// 含图片、语音等富文本的不处理
+	if strings.Contains(text, "[CQ:") || sealCodeRe.MatchString(text) {
+		return text
+	}
+	if !ctx.EndPoint.Can(CapImage) {
+		return text
+	}
+	data, err := textimg.Render(text, nil)
+	if err != nil {
+		if ctx.Dice != nil {
+			ctx.Dice.Logger.Warnf("长文本转图片失败: %v", err)
+		}
+		return text
+	}
+	return "[CQ:image,file=base64://" + base64.StdEncoding.EncodeToString(data) + "]"
+}
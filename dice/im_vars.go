@@ -33,6 +33,11 @@ func VarSetValue(ctx *MsgContext, s string, v *ds.VMValue) {
 
 	// 临时变量
 	if strings.HasPrefix(s, "$t") {
+		if s == "$tSuccessRank" {
+			if rank, ok := v.ReadInt(); ok {
+				ctx.aliasCheckResult(rank > 0)
+			}
+		}
 		// 如果是内部设置的临时变量，不需要长期存活
 		//  if ctx.Player.ValueMapTemp == nil {
 		//  	ctx.Player.ValueMapTemp = &ds.ValueMap{}
@@ -46,7 +51,7 @@ func VarSetValue(ctx *MsgContext, s string, v *ds.VMValue) {
 	// 个人变量
 	if strings.HasPrefix(s, "$m") {
 		if ctx.Session != nil && ctx.Player != nil {
-			playerAttrs := lo.Must(am.LoadById(ctx.Player.UserID))
+			playerAttrs := lo.Must(am.LoadById(am.UIDConvert(ctx.Player.UserID)))
 			playerAttrs.Store(name, vClone)
 		}
 		return
@@ -81,7 +86,7 @@ func VarDelValue(ctx *MsgContext, s string) {
 	// 个人变量
 	if strings.HasPrefix(s, "$m") {
 		if ctx.Session != nil && ctx.Player != nil {
-			playerAttrs := lo.Must(am.LoadById(ctx.Player.UserID))
+			playerAttrs := lo.Must(am.LoadById(am.UIDConvert(ctx.Player.UserID)))
 			playerAttrs.Delete(name)
 			return
 		}
@@ -139,7 +144,7 @@ func VarGetValue(ctx *MsgContext, s string) (*ds.VMValue, bool) {
 	// 个人全局变量
 	if strings.HasPrefix(s, "$m") {
 		if ctx.Session != nil && ctx.Player != nil {
-			playerAttrs := lo.Must(am.LoadById(ctx.Player.UserID))
+			playerAttrs := lo.Must(am.LoadById(am.UIDConvert(ctx.Player.UserID)))
 			return playerAttrs.LoadX(name)
 		}
 	}
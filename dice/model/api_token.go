@@ -0,0 +1,143 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// APITokenModel 个人访问令牌，只保存令牌的哈希
+type APITokenModel struct {
+	ID         string `json:"id" db:"id"`
+	Name       string `json:"name" db:"name"`
+	TokenHash  string `json:"-" db:"token_hash"`
+	Owner      string `json:"owner" db:"owner"`   // 创建者的WebUI用户名，主人为空
+	Scopes     string `json:"scopes" db:"scopes"` // 权限，逗号分隔
+	CreatedAt  int64  `json:"createdAt" db:"created_at"`
+	ExpiresAt  int64  `json:"expiresAt" db:"expires_at"` // 0为永不过期
+	LastUsedAt int64  `json:"lastUsedAt" db:"last_used_at"`
+	LastUsedIP string `json:"lastUsedIp" db:"last_used_ip"`
+	Revoked    bool   `json:"revoked" db:"revoked"`
+}
+
+// APITokenLog 令牌使用记录
+type APITokenLog struct {
+	ID        int64  `json:"id" db:"id"`
+	TokenID   string `json:"tokenId" db:"token_id"`
+	Method    string `json:"method" db:"method"`
+	Path      string `json:"path" db:"path"`
+	IP        string `json:"ip" db:"ip"`
+	Status    int    `json:"status" db:"status"`
+	CreatedAt int64  `json:"createdAt" db:"created_at"`
+}
+
+type QueryAPITokenLog struct {
+	PageNum  int     `query:"pageNum"`
+	PageSize int     `query:"pageSize"`
+	TokenID  string  `query:"tokenId"`
+	Owner    *string `query:"-"` // 不为nil时只查该用户的令牌
+}
+
+const apiTokenFields = `id, name, token_hash, owner, scopes, created_at, expires_at, last_used_at, last_used_ip, revoked`
+
+func APITokenAdd(db *sqlx.DB, item *APITokenModel) error {
+	_, err := db.NamedExec(`insert into api_token (`+apiTokenFields+`)
+values (:id, :name, :token_hash, :owner, :scopes, :created_at, :expires_at, :last_used_at, :last_used_ip, :revoked)`, item)
+	return err
+}
+
+// APITokenGetByHash 按哈希查找令牌，不存在时返回nil
+func APITokenGetByHash(db *sqlx.DB, hash string) (*APITokenModel, error) {
+	item := &APITokenModel{}
+	err := db.Get(item, `select `+apiTokenFields+` from api_token where token_hash = ?`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// APITokenList 令牌列表，owner 为nil时列出全部
+func APITokenList(db *sqlx.DB, owner *string) ([]APITokenModel, error) {
+	res := []APITokenModel{}
+	q := `select ` + apiTokenFields + ` from api_token`
+	var args []interface{}
+	if owner != nil {
+		q += ` where owner = ?`
+		args = append(args, *owner)
+	}
+	err := db.Select(&res, q+` order by created_at desc`, args...)
+	return res, err
+}
+
+// APITokenRevoke 吊销令牌，owner 不为nil时只能吊销自己的
+func APITokenRevoke(db *sqlx.DB, id string, owner *string) (bool, error) {
+	q := `update api_token set revoked = 1 where id = ?`
+	args := []interface{}{id}
+	if owner != nil {
+		q += ` and owner = ?`
+		args = append(args, *owner)
+	}
+	r, err := db.Exec(q, args...)
+	if err != nil {
+		return false, err
+	}
+	n, _ := r.RowsAffected()
+	return n > 0, nil
+}
+
+// APITokenRevokeByOwner 吊销用户的全部令牌，用于删除或停用用户
+func APITokenRevokeByOwner(db *sqlx.DB, owner string) error {
+	_, err := db.Exec(`update api_token set revoked = 1 where owner = ?`, owner)
+	return err
+}
+
+func APITokenTouch(db *sqlx.DB, id string, ip string) error {
+	_, err := db.Exec(`update api_token set last_used_at = ?, last_used_ip = ? where id = ?`, time.Now().Unix(), ip, id)
+	return err
+}
+
+func APITokenLogAdd(db *sqlx.DB, item *APITokenLog) error {
+	if item.CreatedAt == 0 {
+		item.CreatedAt = time.Now().Unix()
+	}
+	_, err := db.NamedExec(`insert into api_token_log (token_id, method, path, ip, status, created_at)
+values (:token_id, :method, :path, :ip, :status, :created_at)`, item)
+	return err
+}
+
+func APITokenLogGetPage(db *sqlx.DB, params QueryAPITokenLog) (int, []APITokenLog, error) {
+	var conds []string
+	var args []interface{}
+	if params.TokenID != "" {
+		conds = append(conds, "l.token_id = ?")
+		args = append(args, params.TokenID)
+	}
+	if params.Owner != nil {
+		conds = append(conds, "t.owner = ?")
+		args = append(args, *params.Owner)
+	}
+	from := " from api_token_log l left join api_token t on t.id = l.token_id"
+	if len(conds) > 0 {
+		from += " where " + strings.Join(conds, " and ")
+	}
+
+	var total int
+	if err := db.Get(&total, "select count(*)"+from, args...); err != nil {
+		return 0, nil, err
+	}
+	res := make([]APITokenLog, 0, params.PageSize)
+	args = append(args, params.PageSize, (params.PageNum-1)*params.PageSize)
+	err := db.Select(&res, `select l.id, l.token_id, l.method, l.path, l.ip, l.status, l.created_at`+from+`
+order by l.id desc
+limit ? offset ?`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, res, nil
+}
@@ -48,14 +48,6 @@ func (m *AttributesItemModel) IsDataExists() bool {
 	return m.Data != nil && len(m.Data) > 0
 }
 
-// TOOD: 下面这个表记得添加 unique 索引
-
-// PlatformMappingModel 虚拟ID - 平台用户ID 映射表
-type PlatformMappingModel struct {
-	Id       string `json:"id" db:"id"`               // 虚拟ID，格式为 U:nanoid 意为 User / Uniform / Universal
-	IMUserID string `json:"IMUserID" db:"im_user_id"` // IM平台的用户ID
-}
-
 func AttrsGetById(db *sqlx.DB, id string) (*AttributesItemModel, error) {
 	var item AttributesItemModel
 	err := db.Get(&item, `select id, data, COALESCE(attrs_type, '') as attrs_type, binding_sheet_id, name, owner_id,
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditLog 管理操作记录，只增不改，仅按保留期限清理
+type AuditLog struct {
+	ID        int64  `json:"id" db:"id"`
+	CreatedAt int64  `json:"createdAt" db:"created_at"`
+	Source    string `json:"source" db:"source"`        // ui / token / im
+	Actor     string `json:"actor" db:"actor"`          // WebUI用户名、令牌ID或IM用户ID
+	ActorName string `json:"actorName" db:"actor_name"` // 便于阅读的名字
+	IP        string `json:"ip" db:"ip"`
+	Action    string `json:"action" db:"action"`
+	Target    string `json:"target" db:"target"`
+	Before    string `json:"before" db:"before_data"` // JSON，修改前的值
+	After     string `json:"after" db:"after_data"`   // JSON，修改后的值
+}
+
+type QueryAuditLog struct {
+	PageNum  int    `query:"pageNum"`
+	PageSize int    `query:"pageSize"`
+	Source   string `query:"source"`
+	Actor    string `query:"actor"`
+	Action   string `query:"action"` // 前缀匹配，如 ban 可查到 ban.add
+	Target   string `query:"target"`
+}
+
+func AuditLogAdd(db *sqlx.DB, item *AuditLog) error {
+	_, err := db.NamedExec(`insert into audit_log (created_at, source, actor, actor_name, ip, action, target, before_data, after_data)
+values (:created_at, :source, :actor, :actor_name, :ip, :action, :target, :before_data, :after_data)`, item)
+	return err
+}
+
+func AuditLogGetPage(db *sqlx.DB, params QueryAuditLog) (int, []AuditLog, error) {
+	var conds []string
+	var args []interface{}
+	if params.Source != "" {
+		conds = append(conds, "source = ?")
+		args = append(args, params.Source)
+	}
+	if params.Actor != "" {
+		conds = append(conds, "(actor = ? or actor_name = ?)")
+		args = append(args, params.Actor, params.Actor)
+	}
+	if params.Action != "" {
+		conds = append(conds, "action like ? escape '\\'")
+		args = append(args, likeEscape(params.Action)+"%")
+	}
+	if params.Target != "" {
+		conds = append(conds, "target like ? escape '\\'")
+		args = append(args, "%"+likeEscape(params.Target)+"%")
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " where " + strings.Join(conds, " and ")
+	}
+
+	var total int
+	if err := db.Get(&total, "select count(*) from audit_log"+where, args...); err != nil {
+		return 0, nil, err
+	}
+	res := make([]AuditLog, 0, params.PageSize)
+	args = append(args, params.PageSize, (params.PageNum-1)*params.PageSize)
+	err := db.Select(&res, `select id, created_at, source, actor, actor_name, ip, action, target, before_data, after_data
+from audit_log`+where+`
+order by id desc
+limit ? offset ?`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, res, nil
+}
+
+// AuditLogClean 删除早于该时间的记录
+func AuditLogClean(db *sqlx.DB, before int64) (int64, error) {
+	r, err := db.Exec(`delete from audit_log where created_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -152,6 +152,140 @@ CREATE TABLE IF NOT EXISTS attrs (
 		`create index if not exists idx_attrs_binding_sheet_id on attrs (binding_sheet_id);`,
 		`create index if not exists idx_attrs_owner_id_id on attrs (owner_id);`,
 		`create index if not exists idx_attrs_attrs_type_id on attrs (attrs_type);`,
+
+		`
+create table if not exists fair_roll_session
+(
+    id          INTEGER primary key autoincrement,
+    group_id    TEXT,
+    commitment  TEXT,
+    seed        TEXT,
+    next_nonce  INTEGER default 0,
+    created_at  INTEGER default 0,
+    revealed_at INTEGER default 0
+);`,
+		`create index if not exists idx_fair_roll_session_group_id on fair_roll_session (group_id);`,
+		`
+create table if not exists fair_roll_record
+(
+    id          INTEGER primary key autoincrement,
+    session_id  INTEGER,
+    nonce       INTEGER,
+    draw_offset INTEGER,
+    draws       INTEGER,
+    user_id     TEXT default '',
+    command     TEXT default '',
+    expr        TEXT default '',
+    env         TEXT default '',
+    result      TEXT default '',
+    created_at  INTEGER default 0
+);`,
+		`create index if not exists idx_fair_roll_record_session_id on fair_roll_record (session_id);`,
+
+		`
+create table if not exists dice_stat_face
+(
+    sides    INTEGER,
+    face     INTEGER,
+    group_id TEXT default '',
+    platform TEXT default '',
+    count    INTEGER default 0,
+    primary key (sides, face, group_id, platform)
+);`,
+		`
+create table if not exists dice_stat_runs
+(
+    sides      INTEGER,
+    group_id   TEXT default '',
+    platform   TEXT default '',
+    n_high     INTEGER default 0,
+    n_low      INTEGER default 0,
+    runs       INTEGER default 0,
+    last_sign  INTEGER default 0,
+    updated_at INTEGER default 0,
+    primary key (sides, group_id, platform)
+);`,
+
+		`
+create table if not exists webhook_dead_letter
+(
+    id         INTEGER primary key autoincrement,
+    hook_id    TEXT default '',
+    event_id   TEXT default '',
+    event_type TEXT default '',
+    payload    TEXT default '',
+    error      TEXT default '',
+    attempts   INTEGER default 0,
+    created_at INTEGER default 0
+);`,
+
+		`
+create table if not exists platform_mapping
+(
+    id         TEXT,
+    im_user_id TEXT primary key,
+    created_at INTEGER default 0
+);`,
+		`create index if not exists idx_platform_mapping_id on platform_mapping (id);`,
+		`
+create table if not exists platform_mapping_log
+(
+    id         INTEGER primary key autoincrement,
+    uniform_id TEXT default '',
+    im_user_id TEXT default '',
+    action     TEXT default '',
+    operator   TEXT default '',
+    created_at INTEGER default 0
+);`,
+		`
+create table if not exists api_token
+(
+    id           TEXT primary key,
+    name         TEXT default '',
+    token_hash   TEXT unique,
+    owner        TEXT default '',
+    scopes       TEXT default '',
+    created_at   INTEGER default 0,
+    expires_at   INTEGER default 0,
+    last_used_at INTEGER default 0,
+    last_used_ip TEXT default '',
+    revoked      BOOLEAN default 0
+);`,
+		`
+create table if not exists api_token_log
+(
+    id         INTEGER primary key autoincrement,
+    token_id   TEXT default '',
+    method     TEXT default '',
+    path       TEXT default '',
+    ip         TEXT default '',
+    status     INTEGER default 0,
+    created_at INTEGER default 0
+);`,
+		`create index if not exists idx_api_token_log_token_id on api_token_log (token_id);`,
+		`
+create table if not exists audit_log
+(
+    id          INTEGER primary key autoincrement,
+    created_at  INTEGER default 0,
+    source      TEXT default '',
+    actor       TEXT default '',
+    actor_name  TEXT default '',
+    ip          TEXT default '',
+    action      TEXT default '',
+    target      TEXT default '',
+    before_data TEXT default '',
+    after_data  TEXT default ''
+);`,
+		`create index if not exists idx_audit_log_created_at on audit_log (created_at);`,
+		// 只允许追加，记录不可修改
+		`
+create trigger if not exists audit_log_no_update
+    before update
+    on audit_log
+begin
+    select raise(abort, 'audit_log is append-only');
+end;`,
 	}
 	for _, i := range texts {
 		_, _ = dataDB.Exec(i)
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DiceStatFace 某种骰子某个点数的出现次数
+type DiceStatFace struct {
+	Sides    int64  `db:"sides" json:"sides"`
+	Face     int64  `db:"face" json:"face"`
+	GroupID  string `db:"group_id" json:"groupId"`
+	Platform string `db:"platform" json:"platform"`
+	Count    int64  `db:"count" json:"count"`
+}
+
+// DiceStatRuns 游程检验所需的累计量，以中位数为界划分高低
+type DiceStatRuns struct {
+	Sides    int64  `db:"sides" json:"sides"`
+	GroupID  string `db:"group_id" json:"groupId"`
+	Platform string `db:"platform" json:"platform"`
+	NHigh    int64  `db:"n_high" json:"nHigh"`
+	NLow     int64  `db:"n_low" json:"nLow"`
+	Runs     int64  `db:"runs" json:"runs"`
+	LastSign int64  `db:"last_sign" json:"lastSign"` // 1高 -1低 0无
+}
+
+// DiceStatRunsDelta 一段新的序列带来的增量
+type DiceStatRunsDelta struct {
+	NHigh     int64
+	NLow      int64
+	Runs      int64
+	FirstSign int64
+	LastSign  int64
+}
+
+// QueryDiceStat 查询条件，为空表示不限
+type QueryDiceStat struct {
+	GroupID  string `query:"groupId"`
+	Platform string `query:"platform"`
+	Sides    int64  `query:"sides"`
+}
+
+func (q *QueryDiceStat) where() (string, []interface{}) {
+	cond := " WHERE 1=1"
+	var args []interface{}
+	if q.GroupID != "" {
+		cond += " AND group_id = ?"
+		args = append(args, q.GroupID)
+	}
+	if q.Platform != "" {
+		cond += " AND platform = ?"
+		args = append(args, q.Platform)
+	}
+	if q.Sides != 0 {
+		cond += " AND sides = ?"
+		args = append(args, q.Sides)
+	}
+	return cond, args
+}
+
+// DiceStatFaceAdd 累加点数计数
+func DiceStatFaceAdd(tx *sqlx.Tx, sides, face int64, groupID, platform string, count int64) error {
+	_, err := tx.Exec(`INSERT INTO dice_stat_face (sides, face, group_id, platform, count) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (sides, face, group_id, platform) DO UPDATE SET count = count + excluded.count`,
+		sides, face, groupID, platform, count)
+	return err
+}
+
+// DiceStatRunsAdd 将新序列的游程信息接续到已有记录之后
+func DiceStatRunsAdd(tx *sqlx.Tx, sides int64, groupID, platform string, delta *DiceStatRunsDelta) error {
+	var cur DiceStatRuns
+	err := tx.Get(&cur, `SELECT sides, group_id, platform, n_high, n_low, runs, last_sign FROM dice_stat_runs WHERE sides = ? AND group_id = ? AND platform = ?`,
+		sides, groupID, platform)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	runs := cur.Runs + delta.Runs
+	if cur.LastSign != 0 && cur.LastSign == delta.FirstSign {
+		// 与上一段的结尾属于同一游程
+		runs--
+	}
+	lastSign := delta.LastSign
+	if lastSign == 0 {
+		lastSign = cur.LastSign
+	}
+	_, err = tx.Exec(`REPLACE INTO dice_stat_runs (sides, group_id, platform, n_high, n_low, runs, last_sign, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sides, groupID, platform, cur.NHigh+delta.NHigh, cur.NLow+delta.NLow, runs, lastSign, time.Now().Unix())
+	return err
+}
+
+// DiceStatFaceList 按条件列出点数计数
+func DiceStatFaceList(db *sqlx.DB, q *QueryDiceStat) ([]*DiceStatFace, error) {
+	cond, args := q.where()
+	var items []*DiceStatFace
+	err := db.Select(&items, `SELECT sides, face, group_id, platform, count FROM dice_stat_face`+cond, args...)
+	return items, err
+}
+
+// DiceStatRunsList 按条件列出游程信息
+func DiceStatRunsList(db *sqlx.DB, q *QueryDiceStat) ([]*DiceStatRuns, error) {
+	cond, args := q.where()
+	var items []*DiceStatRuns
+	err := db.Select(&items, `SELECT sides, group_id, platform, n_high, n_low, runs, last_sign FROM dice_stat_runs`+cond, args...)
+	return items, err
+}
+
+// DiceStatClear 清空统计
+func DiceStatClear(db *sqlx.DB) error {
+	if _, err := db.Exec(`DELETE FROM dice_stat_face`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM dice_stat_runs`)
+	return err
+}
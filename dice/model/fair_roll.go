@@ -0,0 +1,126 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FairRollSession 公平骰会话，开始时只公开种子的哈希承诺，结束时公开种子
+type FairRollSession struct {
+	ID         int64  `db:"id" json:"id"`
+	GroupID    string `db:"group_id" json:"groupId"`
+	Commitment string `db:"commitment" json:"commitment"`
+	Seed       string `db:"seed" json:"seed"` // 公开前不应对外展示
+	NextNonce  int64  `db:"next_nonce" json:"nextNonce"`
+	CreatedAt  int64  `db:"created_at" json:"createdAt"`
+	RevealedAt int64  `db:"revealed_at" json:"revealedAt"`
+}
+
+// FairRollRecord 一次消耗了随机数的表达式求值
+type FairRollRecord struct {
+	ID        int64  `db:"id" json:"id"`
+	SessionID int64  `db:"session_id" json:"sessionId"`
+	Nonce     int64  `db:"nonce" json:"nonce"`
+	Offset    int64  `db:"draw_offset" json:"offset"` // 本次求值前，该nonce的随机流已被消耗的次数
+	Draws     int64  `db:"draws" json:"draws"`        // 本次求值消耗的随机数个数
+	UserID    string `db:"user_id" json:"userId"`
+	Command   string `db:"command" json:"command"`
+	Expr      string `db:"expr" json:"expr"`
+	Env       string `db:"env" json:"env"` // 求值环境(变量快照与语法开关)，json
+	Result    string `db:"result" json:"result"`
+	CreatedAt int64  `db:"created_at" json:"createdAt"`
+}
+
+var ErrFairRollSessionNotFound = errors.New("fair roll session not found")
+
+// FairRollSessionCreate 新建会话，seed需在公开前保密
+func FairRollSessionCreate(db *sqlx.DB, groupID string, commitment string, seed string) (*FairRollSession, error) {
+	s := &FairRollSession{
+		GroupID:    groupID,
+		Commitment: commitment,
+		Seed:       seed,
+		CreatedAt:  time.Now().Unix(),
+	}
+	ret, err := db.Exec(`INSERT INTO fair_roll_session (group_id, commitment, seed, next_nonce, created_at, revealed_at) VALUES (?, ?, ?, 0, ?, 0)`,
+		s.GroupID, s.Commitment, s.Seed, s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.ID, err = ret.LastInsertId()
+	return s, err
+}
+
+// FairRollSessionGet 按id获取会话
+func FairRollSessionGet(db *sqlx.DB, id int64) (*FairRollSession, error) {
+	var s FairRollSession
+	err := db.Get(&s, `SELECT id, group_id, commitment, seed, next_nonce, created_at, revealed_at FROM fair_roll_session WHERE id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFairRollSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FairRollSessionGetActive 获取群内尚未公开种子的会话
+func FairRollSessionGetActive(db *sqlx.DB, groupID string) (*FairRollSession, error) {
+	var s FairRollSession
+	err := db.Get(&s, `SELECT id, group_id, commitment, seed, next_nonce, created_at, revealed_at FROM fair_roll_session WHERE group_id = ? AND revealed_at = 0 ORDER BY id DESC LIMIT 1`, groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFairRollSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FairRollSessionNextNonce 取出一个nonce并自增
+func FairRollSessionNextNonce(db *sqlx.DB, id int64) (int64, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	var nonce int64
+	if err = tx.Get(&nonce, `SELECT next_nonce FROM fair_roll_session WHERE id = ?`, id); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	if _, err = tx.Exec(`UPDATE fair_roll_session SET next_nonce = ? WHERE id = ?`, nonce+1, id); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	return nonce, tx.Commit()
+}
+
+// FairRollSessionReveal 标记种子已公开
+func FairRollSessionReveal(db *sqlx.DB, id int64) error {
+	_, err := db.Exec(`UPDATE fair_roll_session SET revealed_at = ? WHERE id = ? AND revealed_at = 0`, time.Now().Unix(), id)
+	return err
+}
+
+// FairRollSessionList 列出群内的会话，新的在前
+func FairRollSessionList(db *sqlx.DB, groupID string, limit int) ([]*FairRollSession, error) {
+	var items []*FairRollSession
+	err := db.Select(&items, `SELECT id, group_id, commitment, seed, next_nonce, created_at, revealed_at FROM fair_roll_session WHERE group_id = ? ORDER BY id DESC LIMIT ?`, groupID, limit)
+	return items, err
+}
+
+// FairRollRecordAppend 追加记录
+func FairRollRecordAppend(db *sqlx.DB, r *FairRollRecord) error {
+	r.CreatedAt = time.Now().Unix()
+	_, err := db.Exec(`INSERT INTO fair_roll_record (session_id, nonce, draw_offset, draws, user_id, command, expr, env, result, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.SessionID, r.Nonce, r.Offset, r.Draws, r.UserID, r.Command, r.Expr, r.Env, r.Result, r.CreatedAt)
+	return err
+}
+
+// FairRollRecordList 按掷骰顺序列出会话的全部记录
+func FairRollRecordList(db *sqlx.DB, sessionID int64) ([]*FairRollRecord, error) {
+	var items []*FairRollRecord
+	err := db.Select(&items, `SELECT id, session_id, nonce, draw_offset, draws, user_id, command, expr, env, result, created_at FROM fair_roll_record WHERE session_id = ? ORDER BY nonce, draw_offset`, sessionID)
+	return items, err
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlatformMappingModel 虚拟ID - 平台用户ID 映射表，一个平台用户ID只能属于一个虚拟ID
+type PlatformMappingModel struct {
+	Id        string `json:"id" db:"id"`               // 虚拟ID，格式为 U:nanoid 意为 User / Uniform / Universal
+	IMUserID  string `json:"IMUserID" db:"im_user_id"` // IM平台的用户ID
+	CreatedAt int64  `json:"createdAt" db:"created_at"`
+}
+
+// PlatformMappingLog 关联/解除关联记录
+type PlatformMappingLog struct {
+	ID        int64  `json:"id" db:"id"`
+	UniformID string `json:"uniformId" db:"uniform_id"`
+	IMUserID  string `json:"IMUserID" db:"im_user_id"`
+	Action    string `json:"action" db:"action"`     // link / unlink
+	Operator  string `json:"operator" db:"operator"` // 操作者的平台用户ID，UI操作时为空
+	CreatedAt int64  `json:"createdAt" db:"created_at"`
+}
+
+type QueryPlatformMappingLog struct {
+	PageNum  int    `query:"pageNum"`
+	PageSize int    `query:"pageSize"`
+	ID       string `query:"id"` // 虚拟ID或平台用户ID
+}
+
+// PlatformMappingGet 查询平台用户ID对应的虚拟ID，没有时返回空字符串
+func PlatformMappingGet(db *sqlx.DB, imUserID string) (string, error) {
+	var id string
+	err := db.Get(&id, `select id from platform_mapping where im_user_id = ?`, imUserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return id, nil
+}
+
+// PlatformMappingListByID 虚拟ID关联的所有平台用户ID
+func PlatformMappingListByID(db *sqlx.DB, id string) ([]PlatformMappingModel, error) {
+	res := []PlatformMappingModel{}
+	err := db.Select(&res, `select id, im_user_id, created_at from platform_mapping where id = ? order by created_at`, id)
+	return res, err
+}
+
+// PlatformMappingList 全部关联，按虚拟ID排列
+func PlatformMappingList(db *sqlx.DB) ([]PlatformMappingModel, error) {
+	res := []PlatformMappingModel{}
+	err := db.Select(&res, `select id, im_user_id, created_at from platform_mapping order by id, created_at`)
+	return res, err
+}
+
+func PlatformMappingSet(db *sqlx.DB, id string, imUserID string) error {
+	_, err := db.Exec(`insert into platform_mapping (id, im_user_id, created_at) values (?, ?, ?)
+on conflict (im_user_id) do update set id = excluded.id`, id, imUserID, time.Now().Unix())
+	return err
+}
+
+func PlatformMappingDelete(db *sqlx.DB, imUserID string) error {
+	_, err := db.Exec(`delete from platform_mapping where im_user_id = ?`, imUserID)
+	return err
+}
+
+func PlatformMappingLogAdd(db *sqlx.DB, uniformID, imUserID, action, operator string) error {
+	_, err := db.Exec(`insert into platform_mapping_log (uniform_id, im_user_id, action, operator, created_at)
+values (?, ?, ?, ?, ?)`, uniformID, imUserID, action, operator, time.Now().Unix())
+	return err
+}
+
+func PlatformMappingLogGetPage(db *sqlx.DB, params QueryPlatformMappingLog) (int, []PlatformMappingLog, error) {
+	cond := ""
+	var args []interface{}
+	if params.ID != "" {
+		cond = " where uniform_id = ? or im_user_id = ?"
+		args = append(args, params.ID, params.ID)
+	}
+
+	var total int
+	if err := db.Get(&total, "select count(*) from platform_mapping_log"+cond, args...); err != nil {
+		return 0, nil, err
+	}
+	res := make([]PlatformMappingLog, 0, params.PageSize)
+	args = append(args, params.PageSize, (params.PageNum-1)*params.PageSize)
+	err := db.Select(&res, `select id, uniform_id, im_user_id, action, operator, created_at
+from platform_mapping_log`+cond+`
+order by id desc
+limit ? offset ?`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, res, nil
+}
+
+// AttrsMigrateUser 将平台用户ID名下的数据转到虚拟ID下：角色卡归属、各群的默认卡、用户全局属性。
+// 虚拟ID下已有同一群的数据时保留原有的
+func AttrsMigrateUser(db *sqlx.DB, from string, to string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	stmts := []string{
+		`update attrs set owner_id = ?2 where owner_id = ?1`,
+		// 群内数据的id为 群id-用户id
+		`update or ignore attrs set id = substr(id, 1, length(id) - length(?1)) || ?2
+where length(id) > length(?1) + 1 and substr(id, -length(?1) - 1) = '-' || ?1`,
+		`update or ignore attrs set id = ?2 where id = ?1`,
+	}
+	for _, q := range stmts {
+		if _, err = tx.Exec(q, from, to); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WebhookDeadLetter 重试后仍投递失败的事件
+type WebhookDeadLetter struct {
+	ID        int64  `db:"id" json:"id"`
+	HookID    string `db:"hook_id" json:"hookId"`
+	EventID   string `db:"event_id" json:"eventId"`
+	EventType string `db:"event_type" json:"eventType"`
+	Payload   string `db:"payload" json:"payload"`
+	Error     string `db:"error" json:"error"`
+	Attempts  int64  `db:"attempts" json:"attempts"`
+	CreatedAt int64  `db:"created_at" json:"createdAt"`
+}
+
+type QueryWebhookDeadLetter struct {
+	PageNum  int    `query:"pageNum"`
+	PageSize int    `query:"pageSize"`
+	HookID   string `query:"hookId"`
+}
+
+func WebhookDeadLetterAdd(db *sqlx.DB, item *WebhookDeadLetter) error {
+	_, err := db.Exec(`INSERT INTO webhook_dead_letter (hook_id, event_id, event_type, payload, error, attempts, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		item.HookID, item.EventID, item.EventType, item.Payload, item.Error, item.Attempts, time.Now().Unix())
+	return err
+}
+
+func WebhookDeadLetterGetPage(db *sqlx.DB, params QueryWebhookDeadLetter) (int, []WebhookDeadLetter, error) {
+	cond := ""
+	var args []interface{}
+	if params.HookID != "" {
+		cond = " WHERE hook_id = ?"
+		args = append(args, params.HookID)
+	}
+
+	var total int
+	if err := db.Get(&total, "SELECT COUNT(*) FROM webhook_dead_letter"+cond, args...); err != nil {
+		return 0, nil, err
+	}
+	res := make([]WebhookDeadLetter, 0, params.PageSize)
+	args = append(args, params.PageSize, (params.PageNum-1)*params.PageSize)
+	err := db.Select(&res, `SELECT id, hook_id, event_id, event_type, payload, error, attempts, created_at
+FROM webhook_dead_letter`+cond+`
+ORDER BY id DESC
+LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, res, nil
+}
+
+func WebhookDeadLetterGet(db *sqlx.DB, id int64) (*WebhookDeadLetter, error) {
+	var item WebhookDeadLetter
+	err := db.Get(&item, `SELECT id, hook_id, event_id, event_type, payload, error, attempts, created_at
+FROM webhook_dead_letter WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func WebhookDeadLetterDelete(db *sqlx.DB, id int64) error {
+	_, err := db.Exec(`DELETE FROM webhook_dead_letter WHERE id = ?`, id)
+	return err
+}
+
+func WebhookDeadLetterClear(db *sqlx.DB, hookID string) error {
+	if hookID == "" {
+		_, err := db.Exec(`DELETE FROM webhook_dead_letter`)
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM webhook_dead_letter WHERE hook_id = ?`, hookID)
+	return err
+}
@@ -1,6 +1,53 @@
 package dice
 
-import "sealdice-core/message"
+import (
+	"errors"
+
+	"sealdice-core/message"
+)
+
+// AdapterCapability 适配器可选支持的功能，未声明的功能在该平台上调用不会生效
+type AdapterCapability string
+
+const (
+	CapEditMessage      AdapterCapability = "editMessage"      // 编辑消息
+	CapRecallMessage    AdapterCapability = "recallMessage"    // 撤回消息
+	CapMemberBan        AdapterCapability = "memberBan"        // 禁言成员
+	CapMemberKick       AdapterCapability = "memberKick"       // 踢出成员
+	CapSetGroupCardName AdapterCapability = "setGroupCardName" // 修改群名片
+	CapQuitGroup        AdapterCapability = "quitGroup"        // 退群
+	CapSendFile         AdapterCapability = "sendFile"         // 发送文件
+	CapButton           AdapterCapability = "button"           // 消息按钮
+	CapImage            AdapterCapability = "image"            // 发送图片
+)
+
+// AdapterCapabilityList 全部功能及其名称，顺序即界面展示顺序
+var AdapterCapabilityList = []struct {
+	Key  AdapterCapability `json:"key"`
+	Name string            `json:"name"`
+}{
+	{CapEditMessage, "编辑消息"},
+	{CapRecallMessage, "撤回消息"},
+	{CapMemberBan, "禁言"},
+	{CapMemberKick, "踢人"},
+	{CapSetGroupCardName, "修改群名片"},
+	{CapQuitGroup, "退群"},
+	{CapSendFile, "发送文件"},
+	{CapButton, "消息按钮"},
+	{CapImage, "发送图片"},
+}
+
+var ErrCapabilityUnsupported = errors.New("当前平台不支持该操作")
+
+// AdapterCapabilityName 功能的中文名
+func AdapterCapabilityName(c AdapterCapability) string {
+	for _, i := range AdapterCapabilityList {
+		if i.Key == c {
+			return i.Name
+		}
+	}
+	return string(c)
+}
 
 type PlatformAdapter interface {
 	Serve() int
@@ -31,6 +78,9 @@ type PlatformAdapter interface {
 	EditMessage(ctx *MsgContext, msgID, message string)
 	// RecallMessage recalls the message with msgID. Context is retrieved from ctx.
 	RecallMessage(ctx *MsgContext, msgID string)
+
+	// Capabilities 声明适配器实际支持的可选功能，指令与JS据此给出明确的提示
+	Capabilities() []AdapterCapability
 }
 
 // 实现检查
@@ -39,8 +89,11 @@ var (
 	_ PlatformAdapter = (*PlatformAdapterDiscord)(nil)
 	_ PlatformAdapter = (*PlatformAdapterDingTalk)(nil)
 	_ PlatformAdapter = (*PlatformAdapterDodo)(nil)
+	_ PlatformAdapter = (*PlatformAdapterFeishu)(nil)
 	_ PlatformAdapter = (*PlatformAdapterHTTP)(nil)
+	_ PlatformAdapter = (*PlatformAdapterIRC)(nil)
 	_ PlatformAdapter = (*PlatformAdapterKook)(nil)
+	_ PlatformAdapter = (*PlatformAdapterMatrix)(nil)
 	_ PlatformAdapter = (*PlatformAdapterMinecraft)(nil)
 	_ PlatformAdapter = (*PlatformAdapterOfficialQQ)(nil)
 	_ PlatformAdapter = (*PlatformAdapterRed)(nil)
@@ -51,3 +104,36 @@ var (
 	_ PlatformAdapter = (*PlatformAdapterSatori)(nil)
 	// _ PlatformAdapter = (*PlatformAdapterLagrangeGo)(nil)
 )
+
+// Can 该帐号是否支持某项可选功能
+func (ep *EndPointInfo) Can(c AdapterCapability) bool {
+	if ep == nil || ep.Adapter == nil {
+		return false
+	}
+	for _, i := range ep.Adapter.Capabilities() {
+		if i == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities 该帐号各项可选功能的支持情况
+func (ep *EndPointInfo) Capabilities() map[AdapterCapability]bool {
+	m := map[AdapterCapability]bool{}
+	for _, i := range AdapterCapabilityList {
+		m[i.Key] = false
+	}
+	if ep != nil && ep.Adapter != nil {
+		for _, i := range ep.Adapter.Capabilities() {
+			m[i] = true
+		}
+	}
+	return m
+}
+
+// capabilityUnsupportedText 给用户的“平台不支持”提示
+func capabilityUnsupportedText(ctx *MsgContext, c AdapterCapability) string {
+	VarSetValueStr(ctx, "$t功能", AdapterCapabilityName(c))
+	return DiceFormatTmpl(ctx, "核心:提示_平台不支持")
+}
@@ -32,13 +32,16 @@ func (pa *PlatformAdapterDingTalk) DoRelogin() bool {
 		pa.Serve()
 		return false
 	}
+	sv := GetConnSupervisor(pa.EndPoint)
+	sv.Connecting("重新连接")
 	err = pa.IntentSession.Open()
 	if err != nil {
 		pa.Session.Parent.Logger.Errorf("与DingTalk服务进行连接时出错:%s", err.Error())
-		pa.EndPoint.State = 3
+		sv.Failed("连接失败: " + err.Error())
 		pa.EndPoint.Enable = false
 		return false
 	}
+	sv.Connected("连接成功")
 	return true
 }
 
@@ -49,20 +52,24 @@ func (pa *PlatformAdapterDingTalk) SetEnable(enable bool) {
 			pa.Serve()
 			return
 		}
+		sv := GetConnSupervisor(pa.EndPoint)
+		sv.Connecting("正在连接DingTalk")
 		err := pa.IntentSession.Open()
 		if err != nil {
 			pa.Session.Parent.Logger.Errorf("与DingTalk服务进行连接时出错:%s", err.Error())
-			pa.EndPoint.State = 3
+			sv.Failed("连接失败: " + err.Error())
 			pa.EndPoint.Enable = false
 			return
 		}
+		pa.EndPoint.Enable = true
+		sv.Connected("连接成功")
 	} else {
 		err := pa.IntentSession.Close()
 		if err != nil {
 			pa.Session.Parent.Logger.Error("Dingtalk 断开连接失败: ", err)
 			return
 		}
-		pa.EndPoint.State = 0
+		GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
 		pa.EndPoint.Enable = false
 	}
 }
@@ -151,6 +158,10 @@ func (pa *PlatformAdapterDingTalk) GetGroupInfoAsync(groupID string) {
 
 }
 
+func (pa *PlatformAdapterDingTalk) Capabilities() []AdapterCapability {
+	return nil
+}
+
 func (pa *PlatformAdapterDingTalk) OnChatReceive(_ *dingtalk.Session, data *chatbot.BotCallbackDataModel) {
 	groupInfo, ok := pa.Session.ServiceAtNew.Load(FormatDiceIDDingTalkGroup(data.ConversationId))
 	if ok {
@@ -234,13 +245,21 @@ func (pa *PlatformAdapterDingTalk) Serve() int {
 	pa.IntentSession = dingtalk.New(pa.ClientID, pa.Token)
 	pa.IntentSession.AddEventHandler(pa.OnChatReceive)
 	pa.IntentSession.AddEventHandler(pa.OnGroupJoined)
+	// 断线重连由 Stream SDK 自行处理，连接守护负责首次连接的重试与状态记录
+	sv := GetConnSupervisor(pa.EndPoint)
+	sv.Connecting("正在连接DingTalk")
 	err := pa.IntentSession.Open()
 	if err != nil {
+		logger.Errorf("Dingtalk 连接失败: %s", err.Error())
+		pa.EndPoint.Enable = true
+		if sv.Lost("连接失败: "+err.Error(), func() { pa.Serve() }) {
+			return 0 // 已交给连接守护稍后重试
+		}
 		return 1
 	}
 	logger.Info("Dingtalk 连接成功")
-	pa.EndPoint.State = 1
 	pa.EndPoint.Enable = true
+	sv.Connected("连接成功")
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
@@ -24,6 +24,10 @@ type PlatformAdapterDiscord struct {
 	ReverseProxyCDNUrl string             `yaml:"reverseProxyCDNUrl" json:"reverseProxyCDNUrl"`
 	EndPoint           *EndPointInfo      `yaml:"-" json:"-"`
 	IntentSession      *discordgo.Session `yaml:"-" json:"-"`
+	// DisableSlashCommands 不注册斜杠指令(默认会将所有指令注册为Discord斜杠指令)
+	DisableSlashCommands bool `yaml:"disableSlashCommands" json:"disableSlashCommands"`
+
+	slash discordSlashState
 }
 
 // GetGroupInfoAsync 同步一下群组信息
@@ -231,6 +235,16 @@ func (pa *PlatformAdapterDiscord) Serve() int {
 		}
 		pa.Session.OnMessageEdit(mctx, msg)
 	})
+	dg.AddHandler(pa.onInteraction)
+	// 断线重连由 discordgo 自行处理，这里只把状态交给连接守护记录
+	sv := GetConnSupervisor(pa.EndPoint)
+	dg.AddHandler(func(s *discordgo.Session, _ *discordgo.Connect) {
+		pa.EndPoint.Enable = true
+		sv.Connected("连接成功")
+	})
+	dg.AddHandler(func(s *discordgo.Session, _ *discordgo.Disconnect) {
+		sv.Dropped("连接断开，等待自动重连")
+	})
 	// 这里只处理消息，未来根据需要再改这里
 	dg.Identify.Intents = discordgo.IntentsAll
 	pa.IntentSession = dg
@@ -247,6 +261,7 @@ func (pa *PlatformAdapterDiscord) Serve() int {
 	pa.EndPoint.State = 1
 	pa.EndPoint.Enable = true
 	pa.Session.Parent.Logger.Infof("Discord 服务连接成功，账号<%s>(%s)", pa.IntentSession.State.User.Username, FormatDiceIDDiscord(pa.IntentSession.State.User.ID))
+	go pa.syncSlashCommands()
 
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
@@ -270,6 +285,7 @@ func (pa *PlatformAdapterDiscord) DoRelogin() bool {
 	_ = pa.IntentSession.UpdateGameStatus(0, "SealDice")
 	pa.EndPoint.State = 1
 	pa.EndPoint.Enable = true
+	go pa.syncSlashCommands()
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
@@ -287,7 +303,7 @@ func (pa *PlatformAdapterDiscord) SetEnable(enable bool) {
 		err := pa.IntentSession.Open()
 		if err != nil {
 			pa.Session.Parent.Logger.Errorf("与Discord服务进行连接时出错:%s", err.Error())
-			pa.EndPoint.State = 3
+			GetConnSupervisor(pa.EndPoint).Failed("连接失败: " + err.Error())
 			pa.EndPoint.Enable = false
 			return
 		}
@@ -295,8 +311,9 @@ func (pa *PlatformAdapterDiscord) SetEnable(enable bool) {
 		pa.Session.Parent.Logger.Infof("Discord 服务连接成功，账号<%s>(%s)", pa.IntentSession.State.User.Username, FormatDiceIDDiscord(pa.IntentSession.State.User.ID))
 		pa.EndPoint.State = 1
 		pa.EndPoint.Enable = true
+		go pa.syncSlashCommands()
 	} else {
-		pa.EndPoint.State = 0
+		GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
 		pa.EndPoint.Enable = false
 		_ = pa.IntentSession.Close()
 	}
@@ -319,7 +336,7 @@ func (pa *PlatformAdapterDiscord) SendToPerson(ctx *MsgContext, userID string, t
 		pa.Session.Parent.Logger.Errorf("创建Discord用户#%s的私聊频道时出错:%s", userID, err)
 		return
 	}
-	pa.sendToChannelRaw(ch.ID, text)
+	pa.sendToChannelRaw(ctx, ch.ID, text)
 	pa.Session.OnMessageSend(ctx, &Message{
 		Platform:    "DISCORD",
 		MessageType: "private",
@@ -334,7 +351,7 @@ func (pa *PlatformAdapterDiscord) SendToPerson(ctx *MsgContext, userID string, t
 // SendToGroup 发送群聊（实际上是频道）消息
 func (pa *PlatformAdapterDiscord) SendToGroup(ctx *MsgContext, groupID string, text string, flag string) {
 	// _, err := pa.IntentSession.ChannelMessageSend(ExtractDiscordChannelId(groupId), text)
-	pa.sendToChannelRaw(groupID, text)
+	pa.sendToChannelRaw(ctx, groupID, text)
 	pa.Session.OnMessageSend(ctx, &Message{
 		Platform:    "DISCORD",
 		MessageType: "group",
@@ -347,21 +364,21 @@ func (pa *PlatformAdapterDiscord) SendToGroup(ctx *MsgContext, groupID string, t
 	}, flag)
 }
 
-func (pa *PlatformAdapterDiscord) SendFileToPerson(_ *MsgContext, userID string, path string, _ string) {
+func (pa *PlatformAdapterDiscord) SendFileToPerson(ctx *MsgContext, userID string, path string, _ string) {
 	is := pa.IntentSession
 	ch, err := is.UserChannelCreate(ExtractDiscordUserID(userID))
 	if err != nil {
 		pa.Session.Parent.Logger.Errorf("创建Discord用户#%s的私聊频道时出错:%s", userID, err)
 		return
 	}
-	pa.sendFileToChannelRaw(ch.ID, path)
+	pa.sendFileToChannelRaw(ctx, ch.ID, path)
 }
 
-func (pa *PlatformAdapterDiscord) SendFileToGroup(_ *MsgContext, groupID string, path string, _ string) {
-	pa.sendFileToChannelRaw(groupID, path)
+func (pa *PlatformAdapterDiscord) SendFileToGroup(ctx *MsgContext, groupID string, path string, _ string) {
+	pa.sendFileToChannelRaw(ctx, groupID, path)
 }
 
-func (pa *PlatformAdapterDiscord) sendFileToChannelRaw(channelID string, path string) {
+func (pa *PlatformAdapterDiscord) sendFileToChannelRaw(ctx *MsgContext, channelID string, path string) {
 	dice := pa.Session.Parent
 	e, err := message.FilepathToFileElement(path)
 	id := ExtractDiscordChannelID(channelID)
@@ -378,18 +395,19 @@ func (pa *PlatformAdapterDiscord) sendFileToChannelRaw(channelID string, path st
 	})
 	msgSend := &discordgo.MessageSend{Content: ""}
 	msgSend.Files = files
-	_, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
+	err = pa.channelSend(ctx, id, msgSend)
 	if err != nil {
 		dice.Logger.Errorf("向Discord频道#%s发送文件[path=%s]时出错:%s", id, path, err)
 		return
 	}
 }
 
-func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string) {
+func (pa *PlatformAdapterDiscord) sendToChannelRaw(ctx *MsgContext, channelID string, text string) {
 	logger := pa.Session.Parent.Logger
 	elem := message.ConvertStringMessage(text)
 	id := ExtractDiscordChannelID(channelID)
 	var err error
+	var buttons []*message.ButtonElement
 	msgSend := &discordgo.MessageSend{Content: ""}
 	for _, element := range elem {
 		switch e := element.(type) {
@@ -444,7 +462,7 @@ func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string
 			// msgSend = &discordgo.MessageSend{Content: ""}
 		case *message.TTSElement:
 			if msgSend.Content != "" || msgSend.Files != nil || msgSend.Embeds != nil {
-				_, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
+				err = pa.channelSend(ctx, id, msgSend)
 			}
 			if err != nil {
 				pa.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
@@ -463,14 +481,19 @@ func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string
 			}
 			ref := &discordgo.MessageReference{MessageID: e.ReplySeq, ChannelID: id, GuildID: channel.GuildID}
 			msgSend.Reference = ref
+		case *message.ButtonElement:
+			buttons = append(buttons, e)
 		}
 		if err != nil {
 			pa.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
 			return
 		}
 	}
+	if len(buttons) > 0 {
+		msgSend.Components = discordButtonComponents(buttons)
+	}
 	if msgSend.Content != "" || msgSend.Files != nil || msgSend.Embeds != nil {
-		_, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
+		err = pa.channelSend(ctx, id, msgSend)
 		// pa.Session.Parent.Logger.Infof("真的向Discord频道#%s发送消息:%s", id, msgSend.Content)
 	}
 	if err != nil {
@@ -533,6 +556,10 @@ func (pa *PlatformAdapterDiscord) RecallMessage(ctx *MsgContext, msgID string) {
 	_ = pa.IntentSession.ChannelMessageDelete(envID, msgID)
 }
 
+func (pa *PlatformAdapterDiscord) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapEditMessage, CapRecallMessage, CapSetGroupCardName, CapQuitGroup, CapSendFile, CapButton, CapImage}
+}
+
 // 下面四个函数是格式化和反格式化的
 
 func FormatDiceIDDiscord(diceDiscord string) string {
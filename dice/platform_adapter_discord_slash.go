@@ -0,0 +1,293 @@
+package dice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"sealdice-core/message"
+)
+
+// Discord 斜杠指令与消息按钮：斜杠指令由 Dice.CmdMap 与各扩展的 CmdMap 生成，
+// 两者触发后都按文本指令交给 IMSession.Execute
+
+const discordSlashMaxCommands = 100 // Discord 全局指令数量上限
+
+// discordSlashReplyWait 指令执行完后等待回复的时长，仍未回复(如暗骰、无权限)时删除“正在思考”的占位
+const discordSlashReplyWait = 5 * time.Second
+
+var discordSlashNameRe = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+
+// discordSlashState 斜杠指令相关的运行时状态
+type discordSlashState struct {
+	lock    sync.Mutex
+	hash    string
+	pending map[string]*discordgo.Interaction // 消息的 ReplyTag -> 尚未回复的延迟响应
+}
+
+func discordSlashDescription(item *CmdItemInfo) string {
+	desc := item.ShortHelp
+	if idx := strings.Index(desc, "//"); idx != -1 {
+		desc = desc[idx+2:]
+	}
+	if desc = strings.TrimSpace(desc); desc == "" {
+		desc = strings.TrimSpace(item.Help)
+	}
+	if idx := strings.IndexByte(desc, '\n'); idx != -1 {
+		desc = strings.TrimSpace(desc[:idx])
+	}
+	if desc == "" {
+		desc = "." + item.Name
+	}
+	if r := []rune(desc); len(r) > 100 {
+		desc = string(r[:99]) + "…"
+	}
+	return desc
+}
+
+// discordSlashCommands 按 内置指令、扩展(按加载顺序) 收集斜杠指令，重名与不合规的跳过
+func discordSlashCommands(d *Dice) ([]*discordgo.ApplicationCommand, int) {
+	var cmds []*discordgo.ApplicationCommand
+	seen := map[string]bool{}
+	skipped := 0
+	add := func(m CmdMapCls) {
+		names := make([]string, 0, len(m))
+		for k := range m {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			item := m[name]
+			if item == nil || seen[name] || strings.ToLower(name) != name || !discordSlashNameRe.MatchString(name) {
+				continue
+			}
+			seen[name] = true
+			if len(cmds) >= discordSlashMaxCommands {
+				skipped++
+				continue
+			}
+			cmds = append(cmds, &discordgo.ApplicationCommand{
+				Type:        discordgo.ChatApplicationCommand,
+				Name:        name,
+				Description: discordSlashDescription(item),
+				Options: []*discordgo.ApplicationCommandOption{{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "args",
+					Description: "指令参数，与文字指令中跟在指令名后的内容相同",
+				}},
+			})
+		}
+	}
+	add(d.CmdMap)
+	for _, ext := range d.ExtList {
+		add(ext.CmdMap)
+	}
+	return cmds, skipped
+}
+
+// syncSlashCommands 将当前指令集覆盖注册到 Discord，没有变化时不发请求
+func (pa *PlatformAdapterDiscord) syncSlashCommands() {
+	if pa.DisableSlashCommands || pa.IntentSession == nil || pa.IntentSession.State == nil || pa.IntentSession.State.User == nil {
+		return
+	}
+	log := pa.Session.Parent.Logger
+	cmds, skipped := discordSlashCommands(pa.Session.Parent)
+
+	h := sha256.New()
+	for _, c := range cmds {
+		h.Write([]byte(c.Name + "\x00" + c.Description + "\x00"))
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	pa.slash.lock.Lock()
+	same := pa.slash.hash == hash
+	pa.slash.lock.Unlock()
+	if same {
+		return
+	}
+
+	appID := pa.IntentSession.State.User.ID
+	if _, err := pa.IntentSession.ApplicationCommandBulkOverwrite(appID, "", cmds); err != nil {
+		log.Errorf("Discord 注册斜杠指令失败:%s", err.Error())
+		return
+	}
+	pa.slash.lock.Lock()
+	pa.slash.hash = hash
+	pa.slash.lock.Unlock()
+	if skipped > 0 {
+		log.Warnf("Discord 斜杠指令超出上限%d个，有%d个指令未注册", discordSlashMaxCommands, skipped)
+	}
+	log.Infof("Discord 已同步%d个斜杠指令", len(cmds))
+}
+
+// DiscordSlashCommandsResync 指令集变化(如JS重载)后，让所有 Discord 帐号重新同步
+func DiscordSlashCommandsResync(d *Dice) {
+	if d.ImSession == nil {
+		return
+	}
+	for _, ep := range d.ImSession.EndPoints {
+		if pa, ok := ep.Adapter.(*PlatformAdapterDiscord); ok && ep.Enable {
+			go pa.syncSlashCommands()
+		}
+	}
+}
+
+const discordButtonIDPrefix = "sealcmd:"
+
+// discordButtonComponents 把按钮排成 ActionsRow，每行最多5个、最多5行
+func discordButtonComponents(buttons []*message.ButtonElement) []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	for idx, row := range buttonRows(buttons, 5) {
+		if idx >= 5 {
+			break
+		}
+		var comps []discordgo.MessageComponent
+		for _, b := range row {
+			label := b.Label
+			if r := []rune(label); len(r) > 80 {
+				label = string(r[:80])
+			}
+			comps = append(comps, discordgo.Button{
+				Label:    label,
+				Style:    discordgo.SecondaryButton,
+				CustomID: discordButtonIDPrefix + buttonDataEncode(b.Command, 100-len(discordButtonIDPrefix)),
+			})
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: comps})
+	}
+	return rows
+}
+
+func (pa *PlatformAdapterDiscord) onInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var text string
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data := i.ApplicationCommandData()
+		text = "." + data.Name
+		for _, opt := range data.Options {
+			if opt.Name == "args" && opt.Type == discordgo.ApplicationCommandOptionString {
+				if args := strings.TrimSpace(opt.StringValue()); args != "" {
+					text += " " + args
+				}
+			}
+		}
+	case discordgo.InteractionMessageComponent:
+		customID := i.MessageComponentData().CustomID
+		if !strings.HasPrefix(customID, discordButtonIDPrefix) {
+			return
+		}
+		cmd, ok := buttonDataDecode(strings.TrimPrefix(customID, discordButtonIDPrefix))
+		if !ok {
+			_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Content: "按钮已过期", Flags: discordgo.MessageFlagsEphemeral},
+			})
+			return
+		}
+		text = cmd
+	default:
+		return
+	}
+	log := pa.Session.Parent.Logger
+
+	// 先延迟响应，避免耗时的指令超过3秒时限
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Errorf("Discord 响应交互失败:%s", err.Error())
+		return
+	}
+
+	msg := &Message{
+		Time:     time.Now().Unix(),
+		Message:  text,
+		RawID:    i.ID,
+		Platform: "DISCORD",
+	}
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		_ = s.InteractionResponseDelete(i.Interaction)
+		return
+	}
+	msg.Sender.UserID = FormatDiceIDDiscord(user.ID)
+	msg.Sender.Nickname = user.Username
+	if i.GuildID == "" {
+		msg.MessageType = "private"
+	} else {
+		msg.MessageType = "group"
+		msg.GroupID = FormatDiceIDDiscordChannel(i.ChannelID)
+		msg.GuildID = i.GuildID
+		if p := i.Member.Permissions; p&(1<<1|1<<2|1<<3) > 0 || p == discordgo.PermissionAll {
+			msg.Sender.GroupRole = "admin"
+		}
+	}
+
+	msg.ReplyTag = "discord:" + i.ID
+	pa.slashPendingPush(msg.ReplyTag, i.Interaction)
+	pa.Session.Execute(pa.EndPoint, msg, true)
+	// 回复是异步发出的，不在这里等待；超时后仍未回复的交互由定时器收尾
+	time.AfterFunc(discordSlashReplyWait, func() {
+		if pa.slashPendingPop(msg.ReplyTag, i.ChannelID) != nil {
+			_ = s.InteractionResponseDelete(i.Interaction)
+		}
+	})
+}
+
+func (pa *PlatformAdapterDiscord) slashPendingPush(tag string, i *discordgo.Interaction) {
+	pa.slash.lock.Lock()
+	defer pa.slash.lock.Unlock()
+	if pa.slash.pending == nil {
+		pa.slash.pending = map[string]*discordgo.Interaction{}
+	}
+	pa.slash.pending[tag] = i
+}
+
+// slashPendingPop 取出触发该回复的交互，只有发往交互所在频道的回复才能完成它
+func (pa *PlatformAdapterDiscord) slashPendingPop(tag string, channelID string) *discordgo.Interaction {
+	if tag == "" {
+		return nil
+	}
+	pa.slash.lock.Lock()
+	defer pa.slash.lock.Unlock()
+	i := pa.slash.pending[tag]
+	if i == nil || i.ChannelID != channelID {
+		return nil
+	}
+	delete(pa.slash.pending, tag)
+	return i
+}
+
+// channelSend 发送到频道；若 ctx 来自斜杠指令且其交互尚未回复，则用这条消息完成延迟响应。ctx 可为 nil
+func (pa *PlatformAdapterDiscord) channelSend(ctx *MsgContext, channelID string, msgSend *discordgo.MessageSend) error {
+	var tag string
+	if ctx != nil {
+		tag = ctx.replyTag
+	}
+	if i := pa.slashPendingPop(tag, channelID); i != nil {
+		edit := &discordgo.WebhookEdit{Files: msgSend.Files}
+		if msgSend.Content != "" {
+			edit.Content = &msgSend.Content
+		}
+		if msgSend.Embeds != nil {
+			edit.Embeds = &msgSend.Embeds
+		}
+		if msgSend.Components != nil {
+			edit.Components = &msgSend.Components
+		}
+		if _, err := pa.IntentSession.InteractionResponseEdit(i, edit); err == nil {
+			return nil
+		}
+		// 交互令牌过期等情况下退回普通消息
+	}
+	_, err := pa.IntentSession.ChannelMessageSendComplex(channelID, msgSend)
+	return err
+}
@@ -0,0 +1,87 @@
+package dice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDiscordSlashCommandsFilter(t *testing.T) {
+	d := &Dice{
+		CmdMap: CmdMapCls{
+			"r":                     {Name: "r", ShortHelp: ".r <表达式> // 掷骰"},
+			"R":                     {Name: "R"},
+			"help me":               {Name: "help me"},
+			"骰子":                    {Name: "骰子", Help: "第一行\n第二行"},
+			strings.Repeat("a", 33): {Name: "long"},
+			"nil":                   nil,
+			"long-desc":             {Name: "long-desc", ShortHelp: strings.Repeat("长", 150)},
+			"nodesc":                {Name: "nodesc"},
+		},
+		ExtList: []*ExtInfo{{CmdMap: CmdMapCls{
+			"r":  {Name: "r", ShortHelp: "扩展中的同名指令"},
+			"ra": {Name: "ra", ShortHelp: ".ra // 检定"},
+		}}},
+	}
+
+	cmds, skipped := discordSlashCommands(d)
+	got := map[string]string{}
+	var names []string
+	for _, c := range cmds {
+		got[c.Name] = c.Description
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "long-desc,nodesc,r,骰子,ra" || skipped != 0 {
+		t.Fatalf("names = %v, skipped = %d", names, skipped)
+	}
+	if got["r"] != "掷骰" || got["ra"] != "检定" || got["骰子"] != "第一行" || got["nodesc"] != ".nodesc" {
+		t.Errorf("descriptions = %v", got)
+	}
+	if n := utf8.RuneCountInString(got["long-desc"]); n != 100 || !strings.HasSuffix(got["long-desc"], "…") {
+		t.Errorf("long description should be cut to 100 runes, got %d", n)
+	}
+}
+
+func TestDiscordSlashCommandsLimit(t *testing.T) {
+	m := CmdMapCls{}
+	for i := 0; i < discordSlashMaxCommands+20; i++ {
+		name := fmt.Sprintf("c%03d", i)
+		m[name] = &CmdItemInfo{Name: name}
+	}
+	cmds, skipped := discordSlashCommands(&Dice{CmdMap: m})
+	if len(cmds) != discordSlashMaxCommands || skipped != 20 {
+		t.Fatalf("got %d commands, %d skipped", len(cmds), skipped)
+	}
+	if cmds[0].Name != "c000" || cmds[len(cmds)-1].Name != "c099" {
+		t.Errorf("commands should be kept in name order, got %s..%s", cmds[0].Name, cmds[len(cmds)-1].Name)
+	}
+}
+
+func TestDiscordSlashPendingByInteraction(t *testing.T) {
+	pa := &PlatformAdapterDiscord{}
+	a := &discordgo.Interaction{ID: "1", ChannelID: "c1"}
+	b := &discordgo.Interaction{ID: "2", ChannelID: "c1"}
+	pa.slashPendingPush("discord:1", a)
+	pa.slashPendingPush("discord:2", b)
+
+	// 同频道的普通消息回复不能占用任何交互
+	if pa.slashPendingPop("", "c1") != nil {
+		t.Fatal("a reply without tag should not answer an interaction")
+	}
+	// 暗骰等发往其他频道的回复不能完成交互
+	if pa.slashPendingPop("discord:2", "dm") != nil {
+		t.Fatal("a reply to another channel should not answer the interaction")
+	}
+	if pa.slashPendingPop("discord:2", "c1") != b {
+		t.Fatal("the later command should get its own interaction")
+	}
+	if pa.slashPendingPop("discord:2", "c1") != nil {
+		t.Fatal("an interaction should only be answered once")
+	}
+	if pa.slashPendingPop("discord:1", "c1") != a {
+		t.Fatal("the earlier command should still be pending")
+	}
+}
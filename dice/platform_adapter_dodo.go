@@ -19,14 +19,13 @@ import (
 )
 
 type PlatformAdapterDodo struct {
-	Session           *IMSession                                              `yaml:"-" json:"-"`
-	ClientID          string                                                  `yaml:"clientID" json:"clientID"`
-	Token             string                                                  `yaml:"token" json:"token"`
-	EndPoint          *EndPointInfo                                           `yaml:"-" json:"-"`
-	Client            client.Client                                           `yaml:"-" json:"-"`
-	WebSocket         websocket.Client                                        `yaml:"-" json:"-"`
-	UserPermCache     *SyncMap[string, *SyncMap[string, *GuildPermCacheItem]] `yaml:"-" json:"-"`
-	RetryConnectTimes int                                                     `yaml:"-" json:"-"` // 重连次数
+	Session       *IMSession                                              `yaml:"-" json:"-"`
+	ClientID      string                                                  `yaml:"clientID" json:"clientID"`
+	Token         string                                                  `yaml:"token" json:"token"`
+	EndPoint      *EndPointInfo                                           `yaml:"-" json:"-"`
+	Client        client.Client                                           `yaml:"-" json:"-"`
+	WebSocket     websocket.Client                                        `yaml:"-" json:"-"`
+	UserPermCache *SyncMap[string, *SyncMap[string, *GuildPermCacheItem]] `yaml:"-" json:"-"`
 }
 
 const (
@@ -103,51 +102,29 @@ func (pa *PlatformAdapterDodo) Serve() int {
 	msgHandlers.PersonalMessage = personalMessageHandler
 
 	ws, _ := websocket.New(instance, websocket.WithMessageHandlers(msgHandlers))
+	sv := GetConnSupervisor(pa.EndPoint)
+	pa.EndPoint.Enable = true
+	sv.Connecting("正在连接Dodo")
 	// 主动连接到 WebSocket 服务器
 	if err = ws.Connect(); err != nil {
 		logger.Errorf("Dodo连接错误:%s", err.Error())
-		for pa.RetryConnectTimes <= 5 {
-			pa.RetryConnectTimes++
-			time.Sleep(time.Second * 5)
-			pa.Session.Parent.Logger.Infof("Dodo 尝试重连, 第 [%d/5] 次", pa.RetryConnectTimes)
-			ws.Close()
-			if err = ws.Connect(); err == nil {
-				pa.RetryConnectTimes = 0
-				pa.EndPoint.State = 1
-				break
-			} else {
-				logger.Errorf("Dodo连接错误:%s", err.Error())
-			}
-		}
-		if err != nil {
-			logger.Errorf("Dodo 短时间内重试次数过多，先行中断")
-			pa.EndPoint.State = 3
-			d := pa.Session.Parent
-			d.LastUpdatedTime = time.Now().Unix()
-			d.Save(false)
-			return 1
+		if sv.Lost("连接失败: "+err.Error(), func() { pa.Serve() }) {
+			return 0 // 已交给连接守护稍后重试
 		}
+		return 1
 	}
 	pa.WebSocket = ws
-	pa.EndPoint.State = 1
-	pa.RetryConnectTimes = 0
+	sv.Connected("连接成功")
 	pa.Session.Parent.Logger.Infof("Dodo 连接成功")
-	pa.EndPoint.Enable = true
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
 	go func() {
 		err := ws.Listen()
-		if err != nil {
+		if err != nil && pa.WebSocket == ws {
 			logger.Errorf("Dodo监听错误:%s", err.Error())
-			if pa.EndPoint.Enable {
-				pa.EndPoint.State = 3
-				d := pa.Session.Parent
-				d.LastUpdatedTime = time.Now().Unix()
-				d.Save(false)
-				logger.Infof("Dodo 连接断开，正在尝试重连……")
-				pa.DoRelogin()
-			}
+			logger.Infof("Dodo 连接断开，正在尝试重连……")
+			sv.Lost("连接断开: "+err.Error(), func() { pa.Serve() })
 		}
 	}()
 	return 0
@@ -288,13 +265,7 @@ func (pa *PlatformAdapterDodo) DoRelogin() bool {
 		_ = recover()
 	}()
 	logger := pa.Session.Parent.Logger
-	if pa.WebSocket != nil {
-		pa.WebSocket.Close()
-		pa.Client = nil
-		pa.WebSocket = nil
-		pa.EndPoint.State = 0
-		pa.EndPoint.Enable = false
-	}
+	pa.closeWebSocket("重新连接")
 	logger.Infof("正在启用Dodo……")
 	pa.Serve()
 	return false
@@ -305,22 +276,22 @@ func (pa *PlatformAdapterDodo) SetEnable(enable bool) {
 		_ = recover()
 	}()
 	logger := pa.Session.Parent.Logger
+	pa.closeWebSocket("连接已停用")
 	if enable {
-		if pa.Client != nil && pa.WebSocket != nil {
-			pa.WebSocket.Close()
-			pa.Client = nil
-			pa.WebSocket = nil
-			pa.EndPoint.State = 0
-			pa.EndPoint.Enable = false
-		}
 		logger.Infof("正在启用Dodo……")
 		pa.Serve()
-	} else {
-		pa.WebSocket.Close()
+	}
+}
+
+// closeWebSocket 主动断开，连接守护不会因此重连
+func (pa *PlatformAdapterDodo) closeWebSocket(reason string) {
+	GetConnSupervisor(pa.EndPoint).Stop(reason)
+	pa.EndPoint.Enable = false
+	if pa.WebSocket != nil {
+		ws := pa.WebSocket
 		pa.Client = nil
 		pa.WebSocket = nil
-		pa.EndPoint.State = 0
-		pa.EndPoint.Enable = false
+		ws.Close()
 	}
 }
 
@@ -396,6 +367,10 @@ type DoDoTextMessageComponent struct {
 	} `json:"text"`
 }
 
+func (pa *PlatformAdapterDodo) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapSetGroupCardName, CapQuitGroup, CapImage}
+}
+
 type DoDoImageMessageComponent struct {
 	Type     string `json:"type"` // image-group
 	Elements []struct {
@@ -0,0 +1,1082 @@
+package dice
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"sealdice-core/message"
+)
+
+// 飞书/Lark 适配器，通过开放平台 HTTP 接口收发消息
+// 事件订阅支持长连接(无需公网地址，默认)和 webhook 两种方式
+// 群号形如 FEISHU-Group:oc_xxx，用户为 FEISHU:ou_xxx (open_id)
+
+type PlatformAdapterFeishu struct {
+	Session  *IMSession    `yaml:"-" json:"-"`
+	EndPoint *EndPointInfo `yaml:"-" json:"-"`
+
+	AppID     string `yaml:"appId" json:"appId"`
+	AppSecret string `yaml:"appSecret" json:"-"`
+	Lark      bool   `yaml:"lark" json:"lark"` // 使用国际版 Lark 的接口地址
+
+	Mode              string `yaml:"mode" json:"mode"`               // 事件订阅方式：ws 长连接 或 webhook
+	WebhookAddr       string `yaml:"webhookAddr" json:"webhookAddr"` // webhook 模式的监听地址，如 :9100，回调路径为 /feishu/event
+	VerificationToken string `yaml:"verificationToken" json:"-"`
+	EncryptKey        string `yaml:"encryptKey" json:"-"`
+
+	UseCard bool `yaml:"useCard" json:"useCard"` // 以消息卡片发送回复，排版更适合掷骰结果
+
+	baseURL     string // 测试用，覆盖接口地址
+	client      *http.Client
+	cancel      context.CancelFunc
+	server      *http.Server
+	lock        sync.Mutex
+	token       string
+	tokenExpire time.Time
+	eventSeen   SyncMap[string, int64]  // 飞书会重推未及时确认的事件，按 event_id 去重
+	nameCache   SyncMap[string, string] // open_id -> 名字
+}
+
+type feishuUserID struct {
+	OpenID  string `json:"open_id"`
+	UserID  string `json:"user_id"`
+	UnionID string `json:"union_id"`
+}
+
+type feishuEvent struct {
+	Schema string `json:"schema"`
+	Header struct {
+		EventID   string `json:"event_id"`
+		EventType string `json:"event_type"`
+		Token     string `json:"token"`
+	} `json:"header"`
+	Event json.RawMessage `json:"event"`
+
+	// url_verification 请求
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Token     string `json:"token"`
+}
+
+type feishuMessageEvent struct {
+	Sender struct {
+		SenderID   feishuUserID `json:"sender_id"`
+		SenderType string       `json:"sender_type"`
+	} `json:"sender"`
+	Message struct {
+		MessageID   string `json:"message_id"`
+		ParentID    string `json:"parent_id"`
+		CreateTime  string `json:"create_time"`
+		ChatID      string `json:"chat_id"`
+		ChatType    string `json:"chat_type"`
+		MessageType string `json:"message_type"`
+		Content     string `json:"content"`
+		Mentions    []struct {
+			Key  string       `json:"key"`
+			ID   feishuUserID `json:"id"`
+			Name string       `json:"name"`
+		} `json:"mentions"`
+	} `json:"message"`
+}
+
+func (pa *PlatformAdapterFeishu) apiBase() string {
+	if pa.baseURL != "" {
+		return pa.baseURL
+	}
+	if pa.Lark {
+		return "https://open.larksuite.com"
+	}
+	return "https://open.feishu.cn"
+}
+
+func (pa *PlatformAdapterFeishu) Serve() int {
+	ep := pa.EndPoint
+	d := pa.Session.Parent
+	log := d.Logger
+
+	pa.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	pa.lock.Lock()
+	pa.cancel = cancel
+	pa.lock.Unlock()
+
+	sv := GetConnSupervisor(ep)
+	run := sv.Connecting("正在连接飞书")
+	var info struct {
+		Bot struct {
+			OpenID  string `json:"open_id"`
+			AppName string `json:"app_name"`
+		} `json:"bot"`
+	}
+	if err := pa.call(http.MethodGet, "/open-apis/bot/v3/info", nil, &info); err != nil {
+		log.Errorf("飞书 获取机器人信息失败：%v", err)
+		sv.Failed("获取机器人信息失败: " + err.Error())
+		cancel()
+		return 1
+	}
+	ep.UserID = FormatDiceIDFeishu(info.Bot.OpenID)
+	ep.Nickname = info.Bot.AppName
+
+	if pa.Mode == "webhook" {
+		if !pa.WebhookSecured() {
+			log.Errorf("飞书 webhook 监听 %s 不限于本机，必须设置 Encrypt Key 或 Verification Token", pa.WebhookAddr)
+			sv.Failed("webhook 未设置 Encrypt Key 或 Verification Token")
+			cancel()
+			return 1
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/feishu/event", pa.webhookHandler)
+		ln, err := net.Listen("tcp", pa.WebhookAddr)
+		if err != nil {
+			log.Errorf("飞书 webhook 监听 %s 失败：%v", pa.WebhookAddr, err)
+			sv.Failed("webhook 监听失败: " + err.Error())
+			cancel()
+			return 1
+		}
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			_ = server.Serve(ln)
+		}()
+		pa.lock.Lock()
+		pa.server = server
+		pa.lock.Unlock()
+		sv.Connected("webhook 已开始监听")
+	} else {
+		// 长连接模式在连上之后才算连接成功
+		go pa.wsLoop(ctx, run)
+	}
+
+	ep.Enable = true
+	d.LastUpdatedTime = time.Now().Unix()
+	d.Save(false)
+	log.Infof("飞书 连接成功：机器人<%s>(%s)", ep.Nickname, ep.UserID)
+	return 0
+}
+
+func (pa *PlatformAdapterFeishu) stop() {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	if pa.cancel != nil {
+		pa.cancel()
+		pa.cancel = nil
+	}
+	if pa.server != nil {
+		_ = pa.server.Close()
+		pa.server = nil
+	}
+}
+
+// tenantToken 取 tenant_access_token，过期前自动刷新
+func (pa *PlatformAdapterFeishu) tenantToken() (string, error) {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	if pa.token != "" && time.Now().Before(pa.tokenExpire) {
+		return pa.token, nil
+	}
+	data, _ := json.Marshal(map[string]string{"app_id": pa.AppID, "app_secret": pa.AppSecret})
+	req, err := http.NewRequest(http.MethodPost, pa.apiBase()+"/open-apis/auth/v3/tenant_access_token/internal", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	var resp struct {
+		Token  string `json:"tenant_access_token"`
+		Expire int64  `json:"expire"`
+	}
+	if err = pa.do(req, &resp); err != nil {
+		return "", err
+	}
+	pa.token = resp.Token
+	pa.tokenExpire = time.Now().Add(time.Duration(resp.Expire-300) * time.Second)
+	return pa.token, nil
+}
+
+// do 发出请求并检查返回的错误码，out 接收完整的返回体
+func (pa *PlatformAdapterFeishu) do(req *http.Request, out interface{}) error {
+	if pa.client == nil {
+		pa.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := pa.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err = json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("飞书接口返回异常(%d)：%s", resp.StatusCode, string(data))
+	}
+	if r.Code != 0 {
+		return &feishuError{Code: r.Code, Msg: r.Msg}
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+type feishuError struct {
+	Code int
+	Msg  string
+}
+
+func (e *feishuError) Error() string {
+	return fmt.Sprintf("飞书接口错误 %d：%s", e.Code, e.Msg)
+}
+
+func (pa *PlatformAdapterFeishu) call(method, path string, in interface{}, out interface{}) error {
+	var data []byte
+	if in != nil {
+		data, _ = json.Marshal(in)
+	}
+	return pa.callRaw(method, path, "application/json; charset=utf-8", data, out)
+}
+
+func (pa *PlatformAdapterFeishu) callRaw(method, path string, contentType string, data []byte, out interface{}) error {
+	for retry := 0; ; retry++ {
+		token, err := pa.tenantToken()
+		if err != nil {
+			return err
+		}
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequest(method, pa.apiBase()+path, body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if data != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		err = pa.do(req, out)
+		var fe *feishuError
+		if retry == 0 && errors.As(err, &fe) && (fe.Code == 99991663 || fe.Code == 99991661 || fe.Code == 99991668) {
+			// 令牌失效，刷新后重试一次
+			pa.lock.Lock()
+			pa.token = ""
+			pa.lock.Unlock()
+			continue
+		}
+		return err
+	}
+}
+
+// callUpload 上传图片或文件
+func (pa *PlatformAdapterFeishu) callUpload(path string, fields map[string]string, fileField string, f *message.FileElement, out interface{}) error {
+	if f == nil || f.Stream == nil {
+		return errors.New("文件为空")
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		_ = w.WriteField(k, v)
+	}
+	part, err := w.CreateFormFile(fileField, f.File)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, f.Stream); err != nil {
+		return err
+	}
+	_ = w.Close()
+	return pa.callRaw(http.MethodPost, path, w.FormDataContentType(), buf.Bytes(), out)
+}
+
+func (pa *PlatformAdapterFeishu) uploadImage(f *message.FileElement) (string, error) {
+	var resp struct {
+		Data struct {
+			ImageKey string `json:"image_key"`
+		} `json:"data"`
+	}
+	err := pa.callUpload("/open-apis/im/v1/images", map[string]string{"image_type": "message"}, "image", f, &resp)
+	return resp.Data.ImageKey, err
+}
+
+func (pa *PlatformAdapterFeishu) uploadFile(f *message.FileElement) (string, error) {
+	var resp struct {
+		Data struct {
+			FileKey string `json:"file_key"`
+		} `json:"data"`
+	}
+	err := pa.callUpload("/open-apis/im/v1/files", map[string]string{"file_type": "stream", "file_name": f.File}, "file", f, &resp)
+	return resp.Data.FileKey, err
+}
+
+// wsLoop 长连接模式，断线后由连接守护安排重连。run 为 Serve 中 Connecting 的返回值
+func (pa *PlatformAdapterFeishu) wsLoop(ctx context.Context, run int) {
+	sv := GetConnSupervisor(pa.EndPoint)
+	for ctx.Err() == nil {
+		err := pa.wsRun(ctx, sv)
+		if ctx.Err() != nil {
+			return
+		}
+		if !sv.Wait(run, "飞书 长连接断开: "+err.Error()) {
+			return
+		}
+		run = sv.Connecting("正在重连飞书长连接")
+	}
+}
+
+func (pa *PlatformAdapterFeishu) wsRun(ctx context.Context, sv *ConnSupervisor) error {
+	data, _ := json.Marshal(map[string]string{"AppID": pa.AppID, "AppSecret": pa.AppSecret})
+	req, err := http.NewRequest(http.MethodPost, pa.apiBase()+"/callback/ws/endpoint", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("locale", "zh")
+	var endpoint struct {
+		Data struct {
+			URL          string `json:"URL"`
+			ClientConfig struct {
+				PingInterval int `json:"PingInterval"`
+			} `json:"ClientConfig"`
+		} `json:"data"`
+	}
+	if err = pa.do(req, &endpoint); err != nil {
+		return err
+	}
+	u, err := url.Parse(endpoint.Data.URL)
+	if err != nil {
+		return err
+	}
+	serviceID, _ := strconv.ParseInt(u.Query().Get("service_id"), 10, 32)
+	pingInterval := time.Duration(endpoint.Data.ClientConfig.PingInterval) * time.Second
+	if pingInterval <= 0 {
+		pingInterval = 120 * time.Second
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint.Data.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sv.Connected("长连接已建立")
+
+	var writeMu sync.Mutex
+	write := func(f *feishuFrame) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteMessage(websocket.BinaryMessage, f.marshal())
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				_ = conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = write(&feishuFrame{
+					Service: int32(serviceID),
+					Method:  feishuFrameControl,
+					Headers: []feishuHeader{{"type", "ping"}},
+				})
+			}
+		}
+	}()
+
+	// 大的事件会被拆成多帧发送
+	parts := map[string][][]byte{}
+	for {
+		msgType, raw, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		sv.Alive()
+		if msgType != websocket.BinaryMessage {
+			continue
+		}
+		frame, err := unmarshalFeishuFrame(raw)
+		if err != nil || frame.Method != feishuFrameData {
+			continue
+		}
+		payload := frame.Payload
+		if sum, _ := strconv.Atoi(frame.header("sum")); sum > 1 {
+			seq, _ := strconv.Atoi(frame.header("seq"))
+			id := frame.header("message_id")
+			if parts[id] == nil {
+				parts[id] = make([][]byte, sum)
+			}
+			if seq >= 0 && seq < sum {
+				parts[id][seq] = payload
+			}
+			payload = bytes.Join(parts[id], nil)
+			for _, p := range parts[id] {
+				if p == nil {
+					payload = nil
+				}
+			}
+			if payload == nil {
+				continue
+			}
+			delete(parts, id)
+		}
+
+		start := time.Now()
+		if frame.header("type") == "event" {
+			go pa.handleEvent(payload)
+		}
+		frame.Headers = append(frame.Headers, feishuHeader{"biz_rt", strconv.FormatInt(time.Since(start).Milliseconds(), 10)})
+		frame.Payload = []byte(`{"code":200}`)
+		if err = write(frame); err != nil {
+			return err
+		}
+	}
+}
+
+func (pa *PlatformAdapterFeishu) webhookHandler(w http.ResponseWriter, r *http.Request) {
+	log := pa.Session.Parent.Logger
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	payload, err := pa.decodeWebhook(r.Header, body)
+	if err != nil {
+		log.Errorf("飞书 事件校验失败：%v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var ev feishuEvent
+	if err = json.Unmarshal(payload, &ev); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	token := ev.Token
+	if ev.Header.Token != "" {
+		token = ev.Header.Token
+	}
+	if pa.VerificationToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(pa.VerificationToken)) != 1 {
+		log.Error("飞书 事件的 Verification Token 不匹配")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if ev.Type == "url_verification" {
+		_ = json.NewEncoder(w).Encode(map[string]string{"challenge": ev.Challenge})
+		return
+	}
+	_, _ = w.Write([]byte("{}"))
+	go pa.handleEvent(payload)
+}
+
+// WebhookSecured 回调来源能否校验：需要配置 Encrypt Key 或 Verification Token，
+// 否则只允许监听本机(如由反向代理转发)，以免任何人都能伪造任意用户的事件
+func (pa *PlatformAdapterFeishu) WebhookSecured() bool {
+	return pa.EncryptKey != "" || pa.VerificationToken != "" || HTTPListenIsLoopback(pa.WebhookAddr)
+}
+
+// decodeWebhook 配置了 Encrypt Key 时，事件必须加密且带有正确的签名
+func (pa *PlatformAdapterFeishu) decodeWebhook(header http.Header, body []byte) ([]byte, error) {
+	if pa.EncryptKey == "" {
+		return body, nil
+	}
+	var enc struct {
+		Encrypt string `json:"encrypt"`
+	}
+	if json.Unmarshal(body, &enc) != nil || enc.Encrypt == "" {
+		return nil, errors.New("事件未加密")
+	}
+	payload, err := feishuDecrypt(pa.EncryptKey, enc.Encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := header.Get("X-Lark-Signature")
+	if sig == "" {
+		// 只有配置回调地址时的验证请求不带签名
+		var ev feishuEvent
+		if json.Unmarshal(payload, &ev) != nil || ev.Type != "url_verification" {
+			return nil, errors.New("缺少签名")
+		}
+		return payload, nil
+	}
+	if !feishuVerifySignature(header.Get("X-Lark-Request-Timestamp"), header.Get("X-Lark-Request-Nonce"), pa.EncryptKey, body, sig) {
+		return nil, errors.New("签名不正确")
+	}
+	return payload, nil
+}
+
+func (pa *PlatformAdapterFeishu) handleEvent(payload []byte) {
+	var ev feishuEvent
+	if json.Unmarshal(payload, &ev) != nil || ev.Schema != "2.0" {
+		return
+	}
+	now := time.Now().Unix()
+	if _, seen := pa.eventSeen.LoadOrStore(ev.Header.EventID, now); seen {
+		return
+	}
+	if pa.eventSeen.Len() > 1000 {
+		pa.eventSeen.Range(func(key string, t int64) bool {
+			if now-t > 3600 {
+				pa.eventSeen.Delete(key)
+			}
+			return true
+		})
+	}
+
+	s := pa.Session
+	ep := pa.EndPoint
+	log := s.Parent.Logger
+	switch ev.Header.EventType {
+	case "im.message.receive_v1":
+		var e feishuMessageEvent
+		if json.Unmarshal(ev.Event, &e) != nil || e.Sender.SenderType == "app" {
+			return
+		}
+		if msg := pa.toStdMessage(&e); msg != nil {
+			s.Execute(ep, msg, false)
+		}
+	case "im.message.recalled_v1":
+		var e struct {
+			MessageID string `json:"message_id"`
+			ChatID    string `json:"chat_id"`
+		}
+		if json.Unmarshal(ev.Event, &e) != nil {
+			return
+		}
+		msg := &Message{
+			Platform:    "FEISHU",
+			MessageType: "group",
+			GroupID:     FormatDiceIDFeishuGroup(e.ChatID),
+			RawID:       e.MessageID,
+			Time:        now,
+		}
+		s.OnMessageDeleted(CreateTempCtx(ep, msg), msg)
+	case "im.chat.member.bot.added_v1":
+		var e struct {
+			ChatID     string       `json:"chat_id"`
+			Name       string       `json:"name"`
+			OperatorID feishuUserID `json:"operator_id"`
+		}
+		if json.Unmarshal(ev.Event, &e) != nil {
+			return
+		}
+		groupID := FormatDiceIDFeishuGroup(e.ChatID)
+		s.Parent.Parent.GroupNameCache.Store(groupID, &GroupNameCacheItem{
+			Name: e.Name,
+			time: now,
+		})
+		msg := &Message{
+			Platform:    "FEISHU",
+			MessageType: "group",
+			GroupID:     groupID,
+			GroupName:   e.Name,
+			Time:        now,
+			Sender: SenderBase{
+				UserID: FormatDiceIDFeishu(e.OperatorID.OpenID),
+			},
+		}
+		s.OnGroupJoined(CreateTempCtx(ep, msg), msg)
+	case "im.chat.member.user.added_v1":
+		var e struct {
+			ChatID string `json:"chat_id"`
+			Users  []struct {
+				Name   string       `json:"name"`
+				UserID feishuUserID `json:"user_id"`
+			} `json:"users"`
+		}
+		if json.Unmarshal(ev.Event, &e) != nil {
+			return
+		}
+		for _, u := range e.Users {
+			pa.nameCache.Store(u.UserID.OpenID, u.Name)
+			msg := &Message{
+				Platform:    "FEISHU",
+				MessageType: "group",
+				GroupID:     FormatDiceIDFeishuGroup(e.ChatID),
+				Time:        now,
+				Sender: SenderBase{
+					UserID:   FormatDiceIDFeishu(u.UserID.OpenID),
+					Nickname: u.Name,
+				},
+			}
+			s.OnGroupMemberJoined(CreateTempCtx(ep, msg), msg)
+		}
+	case "im.chat.member.bot.deleted_v1":
+		var e struct {
+			ChatID string `json:"chat_id"`
+			Name   string `json:"name"`
+		}
+		if json.Unmarshal(ev.Event, &e) != nil {
+			return
+		}
+		log.Infof("飞书 机器人被移出群聊 <%s>(%s)", e.Name, e.ChatID)
+		groupID := FormatDiceIDFeishuGroup(e.ChatID)
+		if group, ok := s.ServiceAtNew.Load(groupID); ok && group.DiceIDExistsMap != nil {
+			group.DiceIDExistsMap.Delete(ep.UserID)
+			group.UpdatedAtTime = now
+		}
+		s.OnGroupLeft(ep, groupID, "", true)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) toStdMessage(e *feishuMessageEvent) *Message {
+	m := &e.Message
+	text := feishuContentToText(m.MessageType, m.Content)
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	for _, mention := range m.Mentions {
+		text = strings.ReplaceAll(text, mention.Key, fmt.Sprintf(`<at user_id="%s">%s</at>`, mention.ID.OpenID, mention.Name))
+		pa.nameCache.Store(mention.ID.OpenID, mention.Name)
+	}
+	if m.ParentID != "" {
+		text = fmt.Sprintf("[CQ:reply,id=%s]", m.ParentID) + text
+	}
+
+	msg := &Message{
+		Platform: "FEISHU",
+		RawID:    m.MessageID,
+		Message:  text,
+		Time:     time.Now().Unix(),
+		Sender: SenderBase{
+			UserID:   FormatDiceIDFeishu(e.Sender.SenderID.OpenID),
+			Nickname: pa.userName(m.ChatID, e.Sender.SenderID.OpenID),
+		},
+	}
+	if ms, err := strconv.ParseInt(m.CreateTime, 10, 64); err == nil {
+		msg.Time = ms / 1000
+	}
+	if m.ChatType == "p2p" {
+		msg.MessageType = "private"
+	} else {
+		msg.MessageType = "group"
+		msg.GroupID = FormatDiceIDFeishuGroup(m.ChatID)
+	}
+	return msg
+}
+
+// feishuContentToText 取出文本和富文本消息中的文字，@ 保留占位符(如 @_user_1)
+func feishuContentToText(msgType string, content string) string {
+	switch msgType {
+	case "text":
+		var c struct {
+			Text string `json:"text"`
+		}
+		_ = json.Unmarshal([]byte(content), &c)
+		return c.Text
+	case "post":
+		var c struct {
+			Title   string `json:"title"`
+			Content [][]struct {
+				Tag    string `json:"tag"`
+				Text   string `json:"text"`
+				UserID string `json:"user_id"`
+			} `json:"content"`
+		}
+		_ = json.Unmarshal([]byte(content), &c)
+		var lines []string
+		for _, para := range c.Content {
+			var sb strings.Builder
+			for _, node := range para {
+				switch node.Tag {
+				case "text", "a":
+					sb.WriteString(node.Text)
+				case "at":
+					sb.WriteString(node.UserID)
+				}
+			}
+			lines = append(lines, sb.String())
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// userName 取用户名字，优先从群成员列表中找，其次查通讯录
+func (pa *PlatformAdapterFeishu) userName(chatID, openID string) string {
+	if name, ok := pa.nameCache.Load(openID); ok {
+		return name
+	}
+	if chatID != "" {
+		pageToken := ""
+		for page := 0; page < 5; page++ {
+			var resp struct {
+				Data struct {
+					Items []struct {
+						MemberID string `json:"member_id"`
+						Name     string `json:"name"`
+					} `json:"items"`
+					HasMore   bool   `json:"has_more"`
+					PageToken string `json:"page_token"`
+				} `json:"data"`
+			}
+			path := "/open-apis/im/v1/chats/" + url.PathEscape(chatID) + "/members?member_id_type=open_id&page_size=100"
+			if pageToken != "" {
+				path += "&page_token=" + url.QueryEscape(pageToken)
+			}
+			if pa.call(http.MethodGet, path, nil, &resp) != nil {
+				break
+			}
+			for _, item := range resp.Data.Items {
+				pa.nameCache.Store(item.MemberID, item.Name)
+			}
+			if !resp.Data.HasMore {
+				break
+			}
+			pageToken = resp.Data.PageToken
+		}
+		if name, ok := pa.nameCache.Load(openID); ok {
+			return name
+		}
+	}
+
+	var resp struct {
+		Data struct {
+			User struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+	name := "飞书用户"
+	if pa.call(http.MethodGet, "/open-apis/contact/v3/users/"+url.PathEscape(openID)+"?user_id_type=open_id", nil, &resp) == nil && resp.Data.User.Name != "" {
+		name = resp.Data.User.Name
+	}
+	pa.nameCache.Store(openID, name)
+	return name
+}
+
+// sendMessage 发送一条消息，replyTo 不为空时作为回复发送
+func (pa *PlatformAdapterFeishu) sendMessage(receiveIDType, receiveID, replyTo, msgType string, content interface{}) (string, error) {
+	c, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	body := map[string]string{"msg_type": msgType, "content": string(c)}
+	var resp struct {
+		Data struct {
+			MessageID string `json:"message_id"`
+		} `json:"data"`
+	}
+	if replyTo != "" {
+		err = pa.call(http.MethodPost, "/open-apis/im/v1/messages/"+url.PathEscape(replyTo)+"/reply", body, &resp)
+	} else {
+		body["receive_id"] = receiveID
+		err = pa.call(http.MethodPost, "/open-apis/im/v1/messages?receive_id_type="+receiveIDType, body, &resp)
+	}
+	return resp.Data.MessageID, err
+}
+
+func feishuImageFile(e *message.ImageElement) (*message.FileElement, error) {
+	if e.File != nil && e.File.Stream != nil {
+		return e.File, nil
+	}
+	u := e.URL
+	if u == "" && e.File != nil {
+		u = e.File.URL
+	}
+	return message.FilepathToFileElement(u)
+}
+
+var feishuMarkdownEscaper = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;", "*", "&#42;", "_", "&#95;", "~", "&#126;",
+	"`", "&#96;", "[", "&#91;", "]", "&#93;", "#", "&#35;",
+)
+
+// buildCard 将消息段组装为消息卡片，文字用 markdown 元素，图片用 img 元素
+func (pa *PlatformAdapterFeishu) buildCard(elems []message.IMessageElement) (map[string]interface{}, string, error) {
+	var cardElems []interface{}
+	var md strings.Builder
+	var replyTo string
+	flush := func() {
+		if strings.TrimSpace(md.String()) != "" {
+			cardElems = append(cardElems, map[string]string{"tag": "markdown", "content": md.String()})
+		}
+		md.Reset()
+	}
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case *message.TextElement:
+			md.WriteString(feishuMarkdownEscaper.Replace(e.Content))
+		case *message.TTSElement:
+			md.WriteString(feishuMarkdownEscaper.Replace(e.Content))
+		case *message.AtElement:
+			md.WriteString(fmt.Sprintf("<at id=%s></at>", ExtractFeishuUserID(e.Target)))
+		case *message.ReplyElement:
+			replyTo = e.ReplySeq
+		case *message.ImageElement:
+			f, err := feishuImageFile(e)
+			if err != nil {
+				return nil, "", err
+			}
+			key, err := pa.uploadImage(f)
+			if err != nil {
+				return nil, "", err
+			}
+			flush()
+			cardElems = append(cardElems, map[string]interface{}{
+				"tag":     "img",
+				"img_key": key,
+				"alt":     map[string]string{"tag": "plain_text", "content": ""},
+			})
+		}
+	}
+	flush()
+	card := map[string]interface{}{
+		"config":   map[string]bool{"wide_screen_mode": true},
+		"elements": cardElems,
+	}
+	return card, replyTo, nil
+}
+
+// sendSegments 发送消息段，文件总是单独发送
+func (pa *PlatformAdapterFeishu) sendSegments(receiveIDType, receiveID string, elems []message.IMessageElement) error {
+	var files []*message.FileElement
+	var rest []message.IMessageElement
+	for _, elem := range elems {
+		if f, ok := elem.(*message.FileElement); ok {
+			files = append(files, f)
+		} else {
+			rest = append(rest, elem)
+		}
+	}
+
+	if pa.UseCard {
+		card, replyTo, err := pa.buildCard(rest)
+		if err != nil {
+			return err
+		}
+		if cardElems, _ := card["elements"].([]interface{}); len(cardElems) > 0 {
+			if _, err = pa.sendMessage(receiveIDType, receiveID, replyTo, "interactive", card); err != nil {
+				return err
+			}
+		}
+	} else {
+		var text strings.Builder
+		var replyTo string
+		flush := func() error {
+			if strings.TrimSpace(text.String()) == "" {
+				text.Reset()
+				return nil
+			}
+			_, err := pa.sendMessage(receiveIDType, receiveID, replyTo, "text", map[string]string{"text": text.String()})
+			text.Reset()
+			replyTo = ""
+			return err
+		}
+		for _, elem := range rest {
+			switch e := elem.(type) {
+			case *message.TextElement:
+				text.WriteString(e.Content)
+			case *message.TTSElement:
+				text.WriteString(e.Content)
+			case *message.AtElement:
+				text.WriteString(fmt.Sprintf(`<at user_id="%s"></at>`, ExtractFeishuUserID(e.Target)))
+			case *message.ReplyElement:
+				replyTo = e.ReplySeq
+			case *message.ImageElement:
+				if err := flush(); err != nil {
+					return err
+				}
+				f, err := feishuImageFile(e)
+				if err != nil {
+					return err
+				}
+				key, err := pa.uploadImage(f)
+				if err != nil {
+					return err
+				}
+				if _, err = pa.sendMessage(receiveIDType, receiveID, "", "image", map[string]string{"image_key": key}); err != nil {
+					return err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		key, err := pa.uploadFile(f)
+		if err != nil {
+			return err
+		}
+		if _, err = pa.sendMessage(receiveIDType, receiveID, "", "file", map[string]string{"file_key": key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pa *PlatformAdapterFeishu) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
+	if err := pa.sendSegments("open_id", ExtractFeishuUserID(userID), message.ConvertStringMessage(text)); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 发送私聊消息失败：%v", err)
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "FEISHU",
+		MessageType: "private",
+		Message:     text,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+func (pa *PlatformAdapterFeishu) SendToGroup(ctx *MsgContext, groupID string, text string, flag string) {
+	if err := pa.sendSegments("chat_id", ExtractFeishuGroupID(groupID), message.ConvertStringMessage(text)); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 发送群消息失败：%v", err)
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "FEISHU",
+		MessageType: "group",
+		Message:     text,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+func (pa *PlatformAdapterFeishu) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	if err := pa.sendSegments("chat_id", ExtractFeishuGroupID(groupID), msg); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 发送群消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	if err := pa.sendSegments("open_id", ExtractFeishuUserID(userID), msg); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 发送私聊消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) SendFileToPerson(ctx *MsgContext, userID string, path string, flag string) {
+	fileElement, err := message.FilepathToFileElement(path)
+	if err != nil {
+		pa.SendToPerson(ctx, userID, fmt.Sprintf("[尝试发送文件出错: %s]", err.Error()), flag)
+		return
+	}
+	pa.SendSegmentToPerson(ctx, userID, []message.IMessageElement{fileElement}, flag)
+}
+
+func (pa *PlatformAdapterFeishu) SendFileToGroup(ctx *MsgContext, groupID string, path string, flag string) {
+	fileElement, err := message.FilepathToFileElement(path)
+	if err != nil {
+		pa.SendToGroup(ctx, groupID, fmt.Sprintf("[尝试发送文件出错: %s]", err.Error()), flag)
+		return
+	}
+	pa.SendSegmentToGroup(ctx, groupID, []message.IMessageElement{fileElement}, flag)
+}
+
+func (pa *PlatformAdapterFeishu) QuitGroup(ctx *MsgContext, id string) {
+	path := "/open-apis/im/v1/chats/" + url.PathEscape(ExtractFeishuGroupID(id)) + "/members?member_id_type=app_id"
+	if err := pa.call(http.MethodDelete, path, map[string][]string{"id_list": {pa.AppID}}, nil); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 退出群聊失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) SetGroupCardName(ctx *MsgContext, name string) {
+	pa.Session.Parent.Logger.Error("飞书 设置群名片失败：不支持")
+}
+
+func (pa *PlatformAdapterFeishu) MemberBan(groupID string, userID string, duration int64) {
+}
+
+func (pa *PlatformAdapterFeishu) MemberKick(groupID string, userID string) {
+	path := "/open-apis/im/v1/chats/" + url.PathEscape(ExtractFeishuGroupID(groupID)) + "/members?member_id_type=open_id"
+	if err := pa.call(http.MethodDelete, path, map[string][]string{"id_list": {ExtractFeishuUserID(userID)}}, nil); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 移出群成员失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) GetGroupInfoAsync(groupID string) {
+	go func() {
+		var resp struct {
+			Data struct {
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		if err := pa.call(http.MethodGet, "/open-apis/im/v1/chats/"+url.PathEscape(ExtractFeishuGroupID(groupID)), nil, &resp); err != nil {
+			return
+		}
+		dm := pa.Session.Parent.Parent
+		dm.GroupNameCache.Store(groupID, &GroupNameCacheItem{
+			Name: resp.Data.Name,
+			time: time.Now().Unix(),
+		})
+		groupInfo, ok := pa.Session.ServiceAtNew.Load(groupID)
+		if ok {
+			groupInfo.GroupName = resp.Data.Name
+		}
+	}()
+}
+
+func (pa *PlatformAdapterFeishu) EditMessage(_ *MsgContext, msgID, text string) {
+	var err error
+	path := "/open-apis/im/v1/messages/" + url.PathEscape(msgID)
+	if pa.UseCard {
+		var card map[string]interface{}
+		if card, _, err = pa.buildCard(message.ConvertStringMessage(text)); err == nil {
+			c, _ := json.Marshal(card)
+			err = pa.call(http.MethodPatch, path, map[string]string{"content": string(c)}, nil)
+		}
+	} else {
+		c, _ := json.Marshal(map[string]string{"text": text})
+		err = pa.call(http.MethodPut, path, map[string]string{"msg_type": "text", "content": string(c)}, nil)
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 编辑消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) RecallMessage(_ *MsgContext, msgID string) {
+	if err := pa.call(http.MethodDelete, "/open-apis/im/v1/messages/"+url.PathEscape(msgID), nil, nil); err != nil {
+		pa.Session.Parent.Logger.Errorf("飞书 撤回消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterFeishu) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapEditMessage, CapRecallMessage, CapMemberKick, CapQuitGroup, CapSendFile, CapImage}
+}
+
+func (pa *PlatformAdapterFeishu) DoRelogin() bool {
+	pa.stop()
+	GetConnSupervisor(pa.EndPoint).Stop("重新登录")
+	pa.EndPoint.Enable = false
+	go pa.Serve()
+	return true
+}
+
+func (pa *PlatformAdapterFeishu) SetEnable(enable bool) {
+	if enable {
+		go pa.Serve()
+		return
+	}
+	pa.stop()
+	GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
+	pa.EndPoint.Enable = false
+}
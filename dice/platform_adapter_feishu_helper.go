@@ -0,0 +1,286 @@
+package dice
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type AddFeishuEcho struct {
+	AppID             string `json:"appId"`
+	AppSecret         string `json:"appSecret"`
+	Lark              bool   `json:"lark"`
+	Mode              string `json:"mode"`
+	WebhookAddr       string `json:"webhookAddr"`
+	VerificationToken string `json:"verificationToken"`
+	EncryptKey        string `json:"encryptKey"`
+	UseCard           bool   `json:"useCard"`
+}
+
+func NewFeishuConnItem(v AddFeishuEcho) *EndPointInfo {
+	conn := new(EndPointInfo)
+	conn.ID = uuid.New().String()
+	conn.Platform = "FEISHU"
+	conn.ProtocolType = ""
+	conn.Enable = false
+	conn.RelWorkDir = "extra/feishu-" + conn.ID
+	conn.Adapter = &PlatformAdapterFeishu{
+		EndPoint:          conn,
+		AppID:             v.AppID,
+		AppSecret:         v.AppSecret,
+		Lark:              v.Lark,
+		Mode:              v.Mode,
+		WebhookAddr:       v.WebhookAddr,
+		VerificationToken: v.VerificationToken,
+		EncryptKey:        v.EncryptKey,
+		UseCard:           v.UseCard,
+	}
+	return conn
+}
+
+func ServeFeishu(d *Dice, ep *EndPointInfo) {
+	defer CrashLog()
+	if ep.Platform == "FEISHU" {
+		conn := ep.Adapter.(*PlatformAdapterFeishu)
+		conn.Session = d.ImSession
+		conn.EndPoint = ep
+		d.Logger.Infof("飞书 尝试连接")
+		if conn.Serve() != 0 {
+			d.Logger.Errorf("连接飞书失败")
+			ep.State = 3
+			ep.Enable = false
+			d.LastUpdatedTime = time.Now().Unix()
+			d.Save(false)
+		}
+	}
+}
+
+// 格式化
+
+func FormatDiceIDFeishu(openID string) string {
+	return "FEISHU:" + openID
+}
+
+func FormatDiceIDFeishuGroup(chatID string) string {
+	return "FEISHU-Group:" + chatID
+}
+
+func ExtractFeishuUserID(id string) string {
+	return strings.TrimPrefix(id, "FEISHU:")
+}
+
+func ExtractFeishuGroupID(id string) string {
+	return strings.TrimPrefix(id, "FEISHU-Group:")
+}
+
+// 事件加密与签名，见飞书开放平台“配置 Encrypt Key”
+
+// feishuDecrypt AES-256-CBC 解密，密钥为 Encrypt Key 的 SHA256，密文前16字节为 IV
+func feishuDecrypt(encryptKey string, encrypted string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aes.BlockSize*2 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("密文长度不正确")
+	}
+	key := sha256.Sum256([]byte(encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	iv, data := data[:aes.BlockSize], data[aes.BlockSize:]
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, errors.New("解密失败，请检查 Encrypt Key")
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("解密失败，请检查 Encrypt Key")
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// feishuVerifySignature 签名为 sha256(timestamp + nonce + encryptKey + body) 的十六进制
+func feishuVerifySignature(timestamp, nonce, encryptKey string, body []byte, signature string) bool {
+	h := sha256.New()
+	h.Write([]byte(timestamp + nonce + encryptKey))
+	h.Write(body)
+	expected := hex.EncodeToString(h.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) == 1
+}
+
+// 长连接的帧格式 (pbbp2.Frame)
+
+const (
+	feishuFrameControl int32 = 0
+	feishuFrameData    int32 = 1
+)
+
+type feishuHeader struct {
+	Key   string
+	Value string
+}
+
+type feishuFrame struct {
+	SeqID           uint64
+	LogID           uint64
+	Service         int32
+	Method          int32
+	Headers         []feishuHeader
+	PayloadEncoding string
+	PayloadType     string
+	Payload         []byte
+	LogIDNew        string
+}
+
+func (f *feishuFrame) header(key string) string {
+	for _, h := range f.Headers {
+		if h.Key == key {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func (f *feishuFrame) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, f.SeqID)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, f.LogID)
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(int64(f.Service)))
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(int64(f.Method)))
+	for _, h := range f.Headers {
+		var hb []byte
+		hb = protowire.AppendTag(hb, 1, protowire.BytesType)
+		hb = protowire.AppendString(hb, h.Key)
+		hb = protowire.AppendTag(hb, 2, protowire.BytesType)
+		hb = protowire.AppendString(hb, h.Value)
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, hb)
+	}
+	if f.PayloadEncoding != "" {
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendString(b, f.PayloadEncoding)
+	}
+	if f.PayloadType != "" {
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendString(b, f.PayloadType)
+	}
+	if f.Payload != nil {
+		b = protowire.AppendTag(b, 8, protowire.BytesType)
+		b = protowire.AppendBytes(b, f.Payload)
+	}
+	if f.LogIDNew != "" {
+		b = protowire.AppendTag(b, 9, protowire.BytesType)
+		b = protowire.AppendString(b, f.LogIDNew)
+	}
+	return b
+}
+
+var errFeishuFrame = errors.New("飞书长连接帧格式错误")
+
+func unmarshalFeishuFrame(b []byte) (*feishuFrame, error) {
+	f := &feishuFrame{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, errFeishuFrame
+		}
+		b = b[n:]
+		switch {
+		case typ == protowire.VarintType && num <= 4:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, errFeishuFrame
+			}
+			b = b[n:]
+			switch num {
+			case 1:
+				f.SeqID = v
+			case 2:
+				f.LogID = v
+			case 3:
+				f.Service = int32(v)
+			case 4:
+				f.Method = int32(v)
+			}
+		case typ == protowire.BytesType && num >= 5 && num <= 9:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, errFeishuFrame
+			}
+			b = b[n:]
+			switch num {
+			case 5:
+				h, err := unmarshalFeishuHeader(v)
+				if err != nil {
+					return nil, err
+				}
+				f.Headers = append(f.Headers, h)
+			case 6:
+				f.PayloadEncoding = string(v)
+			case 7:
+				f.PayloadType = string(v)
+			case 8:
+				f.Payload = append([]byte(nil), v...)
+			case 9:
+				f.LogIDNew = string(v)
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, errFeishuFrame
+			}
+			b = b[n:]
+		}
+	}
+	return f, nil
+}
+
+func unmarshalFeishuHeader(b []byte) (feishuHeader, error) {
+	var h feishuHeader
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return h, errFeishuFrame
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return h, errFeishuFrame
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return h, errFeishuFrame
+		}
+		b = b[n:]
+		switch num {
+		case 1:
+			h.Key = string(v)
+		case 2:
+			h.Value = string(v)
+		}
+	}
+	return h, nil
+}
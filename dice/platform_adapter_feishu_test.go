@@ -0,0 +1,114 @@
+package dice
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// feishuEncrypt 按飞书的方式加密，仅测试用
+func feishuEncrypt(key string, plain []byte) string {
+	k := sha256.Sum256([]byte(key))
+	block, _ := aes.NewCipher(k[:])
+	pad := aes.BlockSize - len(plain)%aes.BlockSize
+	plain = append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...)
+	iv := []byte("0123456789abcdef")
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	return base64.StdEncoding.EncodeToString(append(iv, out...))
+}
+
+func TestFeishuDecodeWebhook(t *testing.T) {
+	pa := &PlatformAdapterFeishu{EncryptKey: "key"}
+	payload := []byte(`{"schema":"2.0","header":{"event_id":"e1"}}`)
+	body, _ := json.Marshal(map[string]string{"encrypt": feishuEncrypt("key", payload)})
+
+	sum := sha256.Sum256(append([]byte("1700000000"+"nonce"+"key"), body...))
+	header := http.Header{}
+	header.Set("X-Lark-Request-Timestamp", "1700000000")
+	header.Set("X-Lark-Request-Nonce", "nonce")
+	header.Set("X-Lark-Signature", hex.EncodeToString(sum[:]))
+	got, err := pa.decodeWebhook(header, body)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("解密失败: %v %s", err, got)
+	}
+
+	header.Set("X-Lark-Signature", "00")
+	if _, err = pa.decodeWebhook(header, body); err == nil {
+		t.Error("错误签名应当被拒绝")
+	}
+	header.Del("X-Lark-Signature")
+	if _, err = pa.decodeWebhook(header, body); err == nil {
+		t.Error("缺少签名的普通事件应当被拒绝")
+	}
+	if _, err = pa.decodeWebhook(header, payload); err == nil {
+		t.Error("未加密的事件应当被拒绝")
+	}
+}
+
+func TestFeishuFrameRoundTrip(t *testing.T) {
+	f := &feishuFrame{
+		SeqID:   3,
+		Service: 7,
+		Method:  feishuFrameData,
+		Headers: []feishuHeader{{Key: "type", Value: "event"}, {Key: "sum", Value: "1"}},
+		Payload: []byte(`{"a":1}`),
+	}
+	g, err := unmarshalFeishuFrame(f.marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.SeqID != 3 || g.Service != 7 || g.Method != feishuFrameData || g.header("type") != "event" ||
+		g.header("sum") != "1" || string(g.Payload) != `{"a":1}` {
+		t.Errorf("帧编解码不对: %+v", g)
+	}
+}
+
+func TestFeishuIncomingMention(t *testing.T) {
+	pa := &PlatformAdapterFeishu{}
+	ev := &feishuMessageEvent{}
+	ev.Sender.SenderID.OpenID = "ou_a"
+	ev.Message.ChatID = "oc_1"
+	ev.Message.ChatType = "group"
+	ev.Message.MessageType = "text"
+	ev.Message.Content = `{"text":"@_user_1 .r d20"}`
+	ev.Message.Mentions = append(ev.Message.Mentions, struct {
+		Key  string       `json:"key"`
+		ID   feishuUserID `json:"id"`
+		Name string       `json:"name"`
+	}{Key: "@_user_1", ID: feishuUserID{OpenID: "ou_bot"}, Name: "骰子"})
+
+	msg := pa.toStdMessage(ev)
+	if msg == nil || msg.MessageType != "group" || msg.GroupID != "FEISHU-Group:oc_1" {
+		t.Fatalf("消息转换不对: %+v", msg)
+	}
+	rest, ats := AtParse(msg.Message, "FEISHU")
+	if len(ats) != 1 || ats[0].UserID != "FEISHU:ou_bot" || rest != " .r d20" {
+		t.Errorf("@解析不对: %q %+v", rest, ats)
+	}
+}
+
+func TestFeishuWebhookSecured(t *testing.T) {
+	cases := []struct {
+		pa   *PlatformAdapterFeishu
+		want bool
+	}{
+		{&PlatformAdapterFeishu{WebhookAddr: "0.0.0.0:9000"}, false},
+		{&PlatformAdapterFeishu{WebhookAddr: ":9000"}, false},
+		{&PlatformAdapterFeishu{WebhookAddr: "127.0.0.1:9000"}, true},
+		{&PlatformAdapterFeishu{WebhookAddr: "0.0.0.0:9000", EncryptKey: "key"}, true},
+		{&PlatformAdapterFeishu{WebhookAddr: "0.0.0.0:9000", VerificationToken: "tok"}, true},
+	}
+	for _, c := range cases {
+		if got := c.pa.WebhookSecured(); got != c.want {
+			t.Errorf("WebhookSecured(%q, key=%q, token=%q) = %v, want %v",
+				c.pa.WebhookAddr, c.pa.EncryptKey, c.pa.VerificationToken, got, c.want)
+		}
+	}
+}
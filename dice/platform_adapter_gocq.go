@@ -75,7 +75,6 @@ type PlatformAdapterGocq struct {
 	GoCqhttpLoginSucceeded     bool  `yaml:"inPackGoCqHttpLoginSucceeded" json:"-"`                            // 是否登录成功过
 	GoCqhttpLastRestrictedTime int64 `yaml:"inPackGoCqHttpLastRestricted" json:"inPackGoCqHttpLastRestricted"` // 上次风控时间
 	ForcePrintLog              bool  `yaml:"forcePrintLog" json:"forcePrintLog"`                               // 是否一定输出日志，隐藏配置项
-	reconnectTimes             int   // 重连次数
 
 	InPackGoCqhttpProtocol       int      `yaml:"inPackGoCqHttpProtocol" json:"inPackGoCqHttpProtocol"`
 	InPackGoCqhttpAppVersion     string   `yaml:"inPackGoCqHttpAppVersion" json:"inPackGoCqHttpAppVersion"`
@@ -387,15 +386,16 @@ func (pa *PlatformAdapterGocq) Serve() int {
 	}
 	pa.Socket = &socket
 
-	ep.State = 2
+	sv := GetConnSupervisor(ep)
+	sv.WatchSocket(func() *gowebsocket.Socket { return pa.Socket })
 	socket.OnConnected = func(socket gowebsocket.Socket) {
-		ep.State = 1
 		if pa.IsReverse {
+			sv.Connected("反向ws连接成功")
 			log.Info("onebot v11 反向ws连接成功")
 		} else {
+			sv.Connected("连接成功")
 			log.Info("onebot v11 连接成功")
 		}
-		pa.reconnectTimes = 0 // 重置连接重试次数
 		pa.lagrangeRebootTimes = 0
 		//  {"data":{"nickname":"闃斧鐗岃�佽檸鏈�","user_id":1001},"retcode":0,"status":"ok"}
 		pa.GetLoginInfo()
@@ -425,6 +425,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 	tempFriendInviteSent := map[string]int64{}     // gocq会重新发送已经发过的邀请
 
 	socket.OnTextMessage = func(message string, socket gowebsocket.Socket) {
+		sv.Alive()
 		// if strings.Contains(message, `.`) {
 		//	log.Info("...", message)
 		// }
@@ -594,11 +595,9 @@ func (pa *PlatformAdapterGocq) Serve() int {
 
 			// 邀请人在黑名单上
 			banInfo, ok := ctx.Dice.BanList.GetByID(uid)
-			if ok {
-				if banInfo.Rank == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
-					pa.SetGroupAddRequest(msgQQ.Flag, msgQQ.SubType, false, "黑名单")
-					return
-				}
+			if ctx.Dice.BanList.EffectiveRank(uid) == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
+				pa.SetGroupAddRequest(msgQQ.Flag, msgQQ.SubType, false, "黑名单")
+				return
 			}
 
 			// 信任模式，如果不是信任，又不是master则拒绝拉群邀请
@@ -685,16 +684,13 @@ func (pa *PlatformAdapterGocq) Serve() int {
 			// 检查黑名单
 			extra := ""
 			uid := FormatDiceIDQQ(string(msgQQ.UserID))
-			banInfo, ok := ctx.Dice.BanList.GetByID(uid)
-			if ok {
-				if banInfo.Rank == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
-					if willAccept {
-						extra = "。回答正确，但为被禁止用户，准备自动拒绝"
-					} else {
-						extra = "。回答错误，且为被禁止用户，准备自动拒绝"
-					}
-					willAccept = false
+			if ctx.Dice.BanList.EffectiveRank(uid) == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
+				if willAccept {
+					extra = "。回答正确，但为被禁止用户，准备自动拒绝"
+				} else {
+					extra = "。回答错误，且为被禁止用户，准备自动拒绝"
 				}
+				willAccept = false
 			}
 
 			if pa.IgnoreFriendRequest {
@@ -924,12 +920,9 @@ func (pa *PlatformAdapterGocq) Serve() int {
 
 				skip := false
 				skipReason := ""
-				banInfo, ok := ctx.Dice.BanList.GetByID(opUID)
-				if ok {
-					if banInfo.Rank == 30 {
-						skip = true
-						skipReason = "信任用户"
-					}
+				if ctx.Dice.BanList.EffectiveRank(opUID) == BanRankTrusted {
+					skip = true
+					skipReason = "信任用户"
 				}
 				if ctx.Dice.IsMaster(opUID) {
 					skip = true
@@ -946,6 +939,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 				txt := fmt.Sprintf("被踢出群: 在QQ群组<%s>(%s)中被踢出，操作者:<%s>(%s)%s", groupName, msgQQ.GroupID, userName, msgQQ.OperatorID, extra)
 				log.Info(txt)
 				ctx.Notice(txt)
+				pa.Session.OnGroupLeft(pa.EndPoint, msg.GroupID, opUID, true)
 			}
 			return
 		}
@@ -960,6 +954,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 			txt := fmt.Sprintf("离开群组或群解散: <%s>(%s)", groupName, msgQQ.GroupID)
 			log.Info(txt)
 			ctx.Notice(txt)
+			pa.Session.OnGroupLeft(pa.EndPoint, msg.GroupID, "", false)
 			return
 		}
 
@@ -1063,6 +1058,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 	}
 
 	socket.OnPongReceived = func(data string, socket gowebsocket.Socket) {
+		sv.Alive()
 		log.Debug("Recieved pong " + data)
 	}
 
@@ -157,13 +157,17 @@ func socketSendBinary(socket *gowebsocket.Socket, data []byte) { //nolint
 }
 
 func doSleepQQ(ctx *MsgContext) {
-	if ctx.Dice != nil {
+	time.Sleep(qqSendDelay(ctx))
+}
+
+// qqSendDelay 按设置的指令延迟区间随机取一个延迟
+func qqSendDelay(ctx *MsgContext) time.Duration {
+	if ctx != nil && ctx.Dice != nil {
 		d := ctx.Dice
 		offset := d.MessageDelayRangeEnd - d.MessageDelayRangeStart
-		time.Sleep(time.Duration((d.MessageDelayRangeStart + rand.Float64()*offset) * float64(time.Second)))
-	} else {
-		time.Sleep(time.Duration((0.4 + rand.Float64()/2) * float64(time.Second)))
+		return time.Duration((d.MessageDelayRangeStart + rand.Float64()*offset) * float64(time.Second))
 	}
+	return time.Duration((0.4 + rand.Float64()/2) * float64(time.Second))
 }
 
 func (pa *PlatformAdapterGocq) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -197,7 +201,7 @@ func (pa *PlatformAdapterGocq) SendToPerson(ctx *MsgContext, userID string, text
 
 	text = textAssetsConvert(text)
 	texts := textSplit(text)
-	for _, subText := range texts {
+	for index, subText := range texts {
 		a, _ := json.Marshal(oneBotCommand{
 			Action: "send_msg",
 			Params: GroupMessageParams{
@@ -206,7 +210,10 @@ func (pa *PlatformAdapterGocq) SendToPerson(ctx *MsgContext, userID string, text
 				Message:     subText,
 			},
 		})
-		doSleepQQ(ctx)
+		// 回复延迟已由发送队列安排，这里只在拆出的多条之间等待，同 SendToGroup
+		if index != 0 {
+			doSleepQQ(ctx)
+		}
 		socketSendText(pa.Socket, string(a))
 	}
 }
@@ -613,6 +620,10 @@ func (pa *PlatformAdapterGocq) GetLoginInfo() {
 	socketSendText(pa.Socket, string(a))
 }
 
+func (pa *PlatformAdapterGocq) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapSetGroupCardName, CapQuitGroup, CapSendFile, CapImage}
+}
+
 func textSplit(input string) []string {
 	re := regexp.MustCompile(`\[CQ:poke,(.+?)]`) // [img:] 或 [图:]
 	m := re.FindAllStringIndex(input, -1)
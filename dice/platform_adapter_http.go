@@ -1,8 +1,24 @@
 package dice
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"sealdice-core/message"
 	"sealdice-core/utils"
@@ -14,31 +30,573 @@ type HTTPSimpleMessage struct {
 	MessageType string `json:"messageType"`
 }
 
-type PlatformAdapterHTTP struct {
-	Session       *IMSession
-	EndPoint      *EndPointInfo
-	RecentMessage []HTTPSimpleMessage
+// HTTPSegment 收发共用的消息段
+type HTTPSegment struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	Platform    string `json:"platform,omitempty"` // at
+	User        string `json:"user,omitempty"`     // at
+	ID          string `json:"id,omitempty"`       // reply / face
+	URL         string `json:"url,omitempty"`
+	Name        string `json:"name,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Data        string `json:"data,omitempty"` // base64
 }
 
-func (pa *PlatformAdapterHTTP) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+type HTTPIncomingMessage struct {
+	Platform    string        `json:"platform"`
+	MessageType string        `json:"messageType"`
+	Group       string        `json:"group"`
+	GroupName   string        `json:"groupName"`
+	User        string        `json:"user"`
+	Nickname    string        `json:"nickname"`
+	Role        string        `json:"role"`
+	MessageID   string        `json:"messageId"`
+	Text        string        `json:"text"`
+	Segments    []HTTPSegment `json:"segments"`
 }
 
-func (pa *PlatformAdapterHTTP) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+type HTTPOutgoingMessage struct {
+	Type        string        `json:"type"` // message recall edit kick ban quitGroup setCard
+	MessageType string        `json:"messageType,omitempty"`
+	Platform    string        `json:"platform,omitempty"`
+	Group       string        `json:"group,omitempty"`
+	User        string        `json:"user,omitempty"`
+	MessageID   string        `json:"messageId,omitempty"`
+	Text        string        `json:"text,omitempty"`
+	Segments    []HTTPSegment `json:"segments,omitempty"`
+	Duration    int64         `json:"duration,omitempty"` // ban，秒
+	Time        int64         `json:"time"`
 }
 
-func (pa *PlatformAdapterHTTP) GetGroupInfoAsync(_ string) {}
+// PlatformAdapterHTTP HTTP 通用接入适配器
+//
+// 供自建的跑团工具、VTT、网页等以 JSON 与骰子交互，无需伪装成 OneBot。
+//
+// 收消息：POST <listenAddr>/message，若设置了 accessToken 需带上 `Authorization: Bearer <token>`。
+// 监听地址不是本机(127.0.0.1、localhost 等)时必须设置 accessToken，否则拒绝启动
+//
+//	{
+//	  "platform": "vtt",          // 来源标识，作为ID的命名空间，默认 web
+//	  "messageType": "group",     // group / private，不填时有 group 即为群聊
+//	  "group": "room1",
+//	  "groupName": "房间1",
+//	  "user": "u1",
+//	  "nickname": "Alice",
+//	  "role": "member",           // owner / admin / member，仅在设置了 accessToken 时采信
+//	  "messageId": "m1",
+//	  "text": ".r d20",
+//	  "segments": [{"type": "at", "user": "self"}, {"type": "text", "text": ".r d20"}]
+//	}
+//
+// segments 存在时会代替 text，段类型有 text、at、image、reply、face，at 的 user 为 self 时表示@骰子。
+// 用户ID会被记为 HTTP:<platform>:<user>，群为 HTTP-Group:<platform>:<group>。
+//
+// 回复：replyMode 为 sync(默认) 时，处理该消息所发出的回复(含暗骰的私聊)会直接放在响应的 replies 中，
+// 每个请求只拿到自己的回复；
+// 为 callback 时立即返回，回复与其余消息一并 POST 到 callbackUrl。
+// 回调内容为 HTTPOutgoingMessage，设置 secret 后带有签名头：
+//
+//	X-Seal-Timestamp: <unix 秒>
+//	X-Seal-Signature: sha256=<hex(HMAC-SHA256(secret, timestamp + "\n" + body))>
+//
+// 撤回、编辑、踢人、禁言、退群、改名片也以对应 type 的回调通知。与请求无关的消息(如定时任务)只走回调。
+//
+// 未配置监听地址时(即WebUI的测试窗口)，消息进入 RecentMessage 供 UI 拉取。
+type PlatformAdapterHTTP struct {
+	Session       *IMSession          `yaml:"-" json:"-"`
+	EndPoint      *EndPointInfo       `yaml:"-" json:"-"`
+	RecentMessage []HTTPSimpleMessage `yaml:"-" json:"-"`
+
+	ListenAddr  string `yaml:"listenAddr" json:"listenAddr"`
+	AccessToken string `yaml:"accessToken" json:"-"`
+	ReplyMode   string `yaml:"replyMode" json:"replyMode"` // sync callback
+	CallbackURL string `yaml:"callbackUrl" json:"callbackUrl"`
+	Secret      string `yaml:"secret" json:"-"`
+
+	lock    sync.Mutex
+	server  *http.Server
+	cancel  context.CancelFunc
+	outbox  chan *HTTPOutgoingMessage
+	client  *http.Client
+	waiters map[string]*[]*HTTPOutgoingMessage // ReplyTag -> 收集到的回复
+	seq     uint64
+}
+
+// isUI 未配置监听地址的是 WebUI 测试窗口
+func (pa *PlatformAdapterHTTP) isUI() bool {
+	return pa.ListenAddr == ""
+}
+
+// OutboxBypass sync 模式需要在处理消息期间拿到回复，测试窗口同理，因此不经过发送队列
+func (pa *PlatformAdapterHTTP) OutboxBypass() bool {
+	return pa.ReplyMode != "callback"
+}
 
 func (pa *PlatformAdapterHTTP) Serve() int {
+	if pa.isUI() {
+		return 0
+	}
+	ep := pa.EndPoint
+	d := pa.Session.Parent
+	log := d.Logger
+
+	pa.stop()
+	if pa.AccessToken == "" && !HTTPListenIsLoopback(pa.ListenAddr) {
+		log.Errorf("HTTP 监听 %s 不限于本机，必须设置 accessToken", pa.ListenAddr)
+		return 1
+	}
+	ep.State = 2
+	mux := http.NewServeMux()
+	mux.HandleFunc("/message", pa.messageHandler)
+	ln, err := net.Listen("tcp", pa.ListenAddr)
+	if err != nil {
+		log.Errorf("HTTP 监听 %s 失败：%v", pa.ListenAddr, err)
+		return 1
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	outbox := make(chan *HTTPOutgoingMessage, 256)
+	pa.lock.Lock()
+	pa.server = server
+	pa.cancel = cancel
+	pa.outbox = outbox
+	pa.lock.Unlock()
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	go pa.callbackLoop(ctx, outbox)
+
+	ep.State = 1
+	ep.Enable = true
+	d.LastUpdatedTime = time.Now().Unix()
+	d.Save(false)
+	log.Infof("HTTP 开始监听：%s", ln.Addr())
 	return 0
 }
 
+func (pa *PlatformAdapterHTTP) stop() {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	if pa.cancel != nil {
+		pa.cancel()
+		pa.cancel = nil
+	}
+	if pa.server != nil {
+		_ = pa.server.Close()
+		pa.server = nil
+	}
+	pa.outbox = nil
+}
+
 func (pa *PlatformAdapterHTTP) DoRelogin() bool {
-	return false
+	if pa.isUI() {
+		return false
+	}
+	pa.stop()
+	pa.EndPoint.Enable = false
+	pa.EndPoint.State = 0
+	go pa.Serve()
+	return true
 }
 
-func (pa *PlatformAdapterHTTP) SetEnable(_ bool) {}
+func (pa *PlatformAdapterHTTP) SetEnable(enable bool) {
+	if pa.isUI() {
+		return
+	}
+	if enable {
+		go pa.Serve()
+		return
+	}
+	pa.stop()
+	pa.EndPoint.Enable = false
+	pa.EndPoint.State = 0
+}
+
+func (pa *PlatformAdapterHTTP) authorized(r *http.Request) bool {
+	if pa.AccessToken == "" {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("access_token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(pa.AccessToken)) == 1
+}
+
+func (pa *PlatformAdapterHTTP) messageHandler(w http.ResponseWriter, r *http.Request) {
+	reply := func(code int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	if r.Method != http.MethodPost {
+		reply(http.StatusMethodNotAllowed, map[string]interface{}{"ok": false, "error": "仅支持 POST"})
+		return
+	}
+	if !pa.authorized(r) {
+		reply(http.StatusUnauthorized, map[string]interface{}{"ok": false, "error": "token 不正确"})
+		return
+	}
+	var in HTTPIncomingMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
+		reply(http.StatusBadRequest, map[string]interface{}{"ok": false, "error": "无法解析消息：" + err.Error()})
+		return
+	}
+	msg, err := pa.toStdMessage(&in)
+	if err != nil {
+		reply(http.StatusBadRequest, map[string]interface{}{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if pa.ReplyMode == "callback" {
+		go pa.Session.Execute(pa.EndPoint, msg, true)
+		reply(http.StatusOK, map[string]interface{}{"ok": true})
+		return
+	}
+
+	replies := pa.collect(msg, func() {
+		pa.Session.Execute(pa.EndPoint, msg, true)
+	})
+	reply(http.StatusOK, map[string]interface{}{"ok": true, "self": pa.EndPoint.UserID, "replies": replies})
+}
+
+// collect 为 msg 分配关联ID，执行 fn 期间处理该消息发出的回复都收集起来
+func (pa *PlatformAdapterHTTP) collect(msg *Message, fn func()) []*HTTPOutgoingMessage {
+	box := &[]*HTTPOutgoingMessage{}
+	pa.lock.Lock()
+	if pa.waiters == nil {
+		pa.waiters = map[string]*[]*HTTPOutgoingMessage{}
+	}
+	pa.seq++
+	tag := "http:" + strconv.FormatUint(pa.seq, 10)
+	msg.ReplyTag = tag
+	pa.waiters[tag] = box
+	pa.lock.Unlock()
+
+	defer func() {
+		pa.lock.Lock()
+		delete(pa.waiters, tag)
+		pa.lock.Unlock()
+	}()
+	fn()
+
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	ret := *box
+	if ret == nil {
+		ret = []*HTTPOutgoingMessage{}
+	}
+	return ret
+}
+
+func (pa *PlatformAdapterHTTP) toStdMessage(in *HTTPIncomingMessage) (*Message, error) {
+	if in.User == "" {
+		return nil, errors.New("缺少 user")
+	}
+	if in.Platform == "" {
+		in.Platform = "web"
+	}
+	if strings.Contains(in.Platform, ":") {
+		return nil, errors.New("platform 中不能含有冒号")
+	}
+	if in.MessageType == "" {
+		in.MessageType = "private"
+		if in.Group != "" {
+			in.MessageType = "group"
+		}
+	}
+	if in.MessageType != "group" && in.MessageType != "private" {
+		return nil, errors.New("messageType 只能是 group 或 private")
+	}
+	if in.MessageType == "group" && in.Group == "" {
+		return nil, errors.New("群聊消息缺少 group")
+	}
+
+	text := in.Text
+	if len(in.Segments) > 0 {
+		text = pa.segmentsToText(in.Platform, in.Segments)
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("消息内容为空")
+	}
+
+	msg := &Message{
+		Time:        time.Now().Unix(),
+		MessageType: in.MessageType,
+		Platform:    pa.EndPoint.Platform,
+		Message:     text,
+		RawID:       in.MessageID,
+		GroupName:   in.GroupName,
+		Sender: SenderBase{
+			UserID:   FormatDiceIDHTTP(in.Platform, in.User),
+			Nickname: in.Nickname,
+		},
+	}
+	if msg.Sender.Nickname == "" {
+		msg.Sender.Nickname = in.User
+	}
+	if in.MessageType == "group" {
+		msg.GroupID = FormatDiceIDHTTPGroup(in.Platform, in.Group)
+		// 未设置 accessToken 时无法确认调用方可信，不采信其声明的群身份
+		switch in.Role {
+		case "owner", "admin":
+			if pa.AccessToken != "" {
+				msg.Sender.GroupRole = in.Role
+			}
+		}
+		if in.GroupName != "" {
+			pa.Session.Parent.Parent.GroupNameCache.Store(msg.GroupID, &GroupNameCacheItem{
+				Name: in.GroupName,
+				time: time.Now().Unix(),
+			})
+		}
+	}
+	return msg, nil
+}
+
+func (pa *PlatformAdapterHTTP) segmentsToText(platform string, segs []HTTPSegment) string {
+	var sb strings.Builder
+	for _, seg := range segs {
+		switch seg.Type {
+		case "text":
+			sb.WriteString(seg.Text)
+		case "at":
+			target := platform + ":" + seg.User
+			if seg.User == "self" {
+				target = ExtractHTTPUserID(pa.EndPoint.UserID)
+			} else if seg.Platform != "" {
+				target = seg.Platform + ":" + seg.User
+			}
+			sb.WriteString(fmt.Sprintf("[CQ:at,qq=%s]", target))
+		case "image":
+			if seg.URL != "" {
+				sb.WriteString(fmt.Sprintf("[CQ:image,file=%s]", seg.URL))
+			}
+		case "reply":
+			sb.WriteString(fmt.Sprintf("[CQ:reply,id=%s]", seg.ID))
+		case "face":
+			sb.WriteString(fmt.Sprintf("[CQ:face,id=%s]", seg.ID))
+		}
+	}
+	return sb.String()
+}
+
+func (pa *PlatformAdapterHTTP) toSegments(elems []message.IMessageElement) []HTTPSegment {
+	fileSeg := func(typ string, f *message.FileElement, url string) HTTPSegment {
+		seg := HTTPSegment{Type: typ, URL: url}
+		if f == nil {
+			return seg
+		}
+		seg.Name = f.File
+		seg.ContentType = f.ContentType
+		if f.URL != "" {
+			seg.URL = f.URL
+		} else if f.Stream != nil {
+			data, err := io.ReadAll(f.Stream)
+			if err == nil {
+				seg.Data = base64.StdEncoding.EncodeToString(data)
+			}
+		}
+		return seg
+	}
+
+	var segs []HTTPSegment
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case *message.TextElement:
+			if n := len(segs); n > 0 && segs[n-1].Type == "text" {
+				segs[n-1].Text += e.Content
+			} else {
+				segs = append(segs, HTTPSegment{Type: "text", Text: e.Content})
+			}
+		case *message.AtElement:
+			seg := HTTPSegment{Type: "at", User: e.Target}
+			if platform, user, ok := strings.Cut(e.Target, ":"); ok {
+				seg.Platform, seg.User = platform, user
+			}
+			segs = append(segs, seg)
+		case *message.ReplyElement:
+			segs = append(segs, HTTPSegment{Type: "reply", ID: e.ReplySeq})
+		case *message.FaceElement:
+			segs = append(segs, HTTPSegment{Type: "face", ID: e.FaceID})
+		case *message.TTSElement:
+			segs = append(segs, HTTPSegment{Type: "tts", Text: e.Content})
+		case *message.ImageElement:
+			segs = append(segs, fileSeg("image", e.File, e.URL))
+		case *message.RecordElement:
+			segs = append(segs, fileSeg("record", e.File, ""))
+		case *message.FileElement:
+			segs = append(segs, fileSeg("file", e, ""))
+		}
+	}
+	return segs
+}
+
+// segmentsText 消息段的纯文本部分
+func segmentsText(segs []HTTPSegment) string {
+	var sb strings.Builder
+	for _, seg := range segs {
+		if seg.Type == "text" {
+			sb.WriteString(seg.Text)
+		}
+	}
+	return sb.String()
+}
+
+// deliver 优先交给 ctx 对应的正在等待同步回复的请求，否则走回调
+func (pa *PlatformAdapterHTTP) deliver(ctx *MsgContext, out *HTTPOutgoingMessage) {
+	out.Time = time.Now().Unix()
+	pa.lock.Lock()
+	if ctx != nil && ctx.replyTag != "" {
+		if box := pa.waiters[ctx.replyTag]; box != nil {
+			*box = append(*box, out)
+			pa.lock.Unlock()
+			return
+		}
+	}
+	outbox := pa.outbox
+	pa.lock.Unlock()
+
+	if outbox == nil || pa.CallbackURL == "" {
+		return
+	}
+	select {
+	case outbox <- out:
+	default:
+		pa.Session.Parent.Logger.Errorf("HTTP 回调队列已满，丢弃消息：%s", out.Type)
+	}
+}
+
+func (pa *PlatformAdapterHTTP) callbackLoop(ctx context.Context, outbox chan *HTTPOutgoingMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case out := <-outbox:
+			pa.postCallback(ctx, out)
+		}
+	}
+}
+
+// postCallback 投递到回调地址，失败时重试
+func (pa *PlatformAdapterHTTP) postCallback(ctx context.Context, out *HTTPOutgoingMessage) {
+	body, _ := json.Marshal(out)
+	if pa.client == nil {
+		pa.client = &http.Client{Timeout: 15 * time.Second}
+	}
+	var err error
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(i) * 2 * time.Second):
+			}
+		}
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, pa.CallbackURL, bytes.NewReader(body))
+		if err != nil {
+			break
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		if pa.Secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set("X-Seal-Timestamp", ts)
+			req.Header.Set("X-Seal-Signature", "sha256="+HTTPSign(pa.Secret, ts, body))
+		}
+		var resp *http.Response
+		resp, err = pa.client.Do(req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return
+			}
+			err = fmt.Errorf("HTTP %d", resp.StatusCode)
+			if resp.StatusCode < 500 {
+				break
+			}
+		}
+	}
+	pa.Session.Parent.Logger.Errorf("HTTP 回调 %s 失败：%v", pa.CallbackURL, err)
+}
+
+// HTTPSign 回调签名，hex(HMAC-SHA256(secret, timestamp + "\n" + body))
+func HTTPSign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (pa *PlatformAdapterHTTP) sendSegments(ctx *MsgContext, messageType, id string, elems []message.IMessageElement, flag string) {
+	platform, raw, _ := strings.Cut(ExtractHTTPUserID(id), ":")
+	if messageType == "group" {
+		platform, raw, _ = strings.Cut(ExtractHTTPGroupID(id), ":")
+	}
+	segs := pa.toSegments(elems)
+	out := &HTTPOutgoingMessage{
+		Type:        "message",
+		MessageType: messageType,
+		Platform:    platform,
+		Text:        segmentsText(segs),
+		Segments:    segs,
+	}
+	if messageType == "group" {
+		out.Group = raw
+	} else {
+		out.User = raw
+	}
+	pa.deliver(ctx, out)
+
+	msg := &Message{
+		MessageType: messageType,
+		Platform:    pa.EndPoint.Platform,
+		Message:     out.Text,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}
+	if messageType == "group" {
+		msg.GroupID = id
+	}
+	pa.Session.OnMessageSend(ctx, msg, flag)
+}
+
+// notify 发出撤回、踢人等通知，ctx 可为 nil
+func (pa *PlatformAdapterHTTP) notify(ctx *MsgContext, out *HTTPOutgoingMessage, groupID, userID string) {
+	if groupID != "" {
+		out.Platform, out.Group, _ = strings.Cut(ExtractHTTPGroupID(groupID), ":")
+	}
+	if userID != "" {
+		out.Platform, out.User, _ = strings.Cut(ExtractHTTPUserID(userID), ":")
+	}
+	pa.deliver(ctx, out)
+}
+
+func (pa *PlatformAdapterHTTP) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	if pa.isUI() {
+		return
+	}
+	pa.sendSegments(ctx, "group", groupID, msg, flag)
+}
+
+func (pa *PlatformAdapterHTTP) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	if pa.isUI() {
+		return
+	}
+	pa.sendSegments(ctx, "private", userID, msg, flag)
+}
+
+func (pa *PlatformAdapterHTTP) GetGroupInfoAsync(_ string) {}
 
 func (pa *PlatformAdapterHTTP) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
+	if !pa.isUI() {
+		pa.sendSegments(ctx, "private", uid, message.ConvertStringMessage(text), flag)
+		return
+	}
 	sp := utils.SplitLongText(text, 300, utils.DefaultSplitPaginationHint)
 	for _, sub := range sp {
 		pa.RecentMessage = append(pa.RecentMessage, HTTPSimpleMessage{uid, sub, "private"})
@@ -55,6 +613,10 @@ func (pa *PlatformAdapterHTTP) SendToPerson(ctx *MsgContext, uid string, text st
 }
 
 func (pa *PlatformAdapterHTTP) SendToGroup(ctx *MsgContext, uid string, text string, flag string) {
+	if !pa.isUI() {
+		pa.sendSegments(ctx, "group", uid, message.ConvertStringMessage(text), flag)
+		return
+	}
 	sp := utils.SplitLongText(text, 300, utils.DefaultSplitPaginationHint)
 	for _, sub := range sp {
 		pa.RecentMessage = append(pa.RecentMessage, HTTPSimpleMessage{uid, sub, "group"})
@@ -72,21 +634,91 @@ func (pa *PlatformAdapterHTTP) SendToGroup(ctx *MsgContext, uid string, text str
 }
 
 func (pa *PlatformAdapterHTTP) SendFileToPerson(ctx *MsgContext, uid string, path string, flag string) {
+	if !pa.isUI() {
+		if f, err := message.FilepathToFileElement(path); err == nil {
+			pa.sendSegments(ctx, "private", uid, []message.IMessageElement{f}, flag)
+			return
+		}
+	}
 	pa.SendToPerson(ctx, uid, fmt.Sprintf("[尝试发送文件: %s，但不支持]", filepath.Base(path)), flag)
 }
 
 func (pa *PlatformAdapterHTTP) SendFileToGroup(ctx *MsgContext, uid string, path string, flag string) {
+	if !pa.isUI() {
+		if f, err := message.FilepathToFileElement(path); err == nil {
+			pa.sendSegments(ctx, "group", uid, []message.IMessageElement{f}, flag)
+			return
+		}
+	}
 	pa.SendToGroup(ctx, uid, fmt.Sprintf("[尝试发送文件: %s，但不支持]", filepath.Base(path)), flag)
 }
 
-func (pa *PlatformAdapterHTTP) QuitGroup(_ *MsgContext, _ string) {}
+func (pa *PlatformAdapterHTTP) QuitGroup(ctx *MsgContext, groupID string) {
+	if pa.isUI() {
+		return
+	}
+	pa.notify(ctx, &HTTPOutgoingMessage{Type: "quitGroup"}, groupID, "")
+}
+
+func (pa *PlatformAdapterHTTP) SetGroupCardName(ctx *MsgContext, name string) {
+	if pa.isUI() || ctx.Group == nil || ctx.Player == nil {
+		return
+	}
+	out := &HTTPOutgoingMessage{Type: "setCard", Text: name}
+	out.Platform, out.Group, _ = strings.Cut(ExtractHTTPGroupID(ctx.Group.GroupID), ":")
+	_, out.User, _ = strings.Cut(ExtractHTTPUserID(ctx.Player.UserID), ":")
+	pa.deliver(ctx, out)
+}
 
-func (pa *PlatformAdapterHTTP) SetGroupCardName(_ *MsgContext, _ string) {}
+func (pa *PlatformAdapterHTTP) MemberBan(groupID string, userID string, duration int64) {
+	if pa.isUI() {
+		return
+	}
+	out := &HTTPOutgoingMessage{Type: "ban", Duration: duration}
+	_, out.User, _ = strings.Cut(ExtractHTTPUserID(userID), ":")
+	pa.notify(nil, out, groupID, "")
+}
 
-func (pa *PlatformAdapterHTTP) MemberBan(_ string, _ string, _ int64) {}
+func (pa *PlatformAdapterHTTP) MemberKick(groupID string, userID string) {
+	if pa.isUI() {
+		return
+	}
+	out := &HTTPOutgoingMessage{Type: "kick"}
+	_, out.User, _ = strings.Cut(ExtractHTTPUserID(userID), ":")
+	pa.notify(nil, out, groupID, "")
+}
 
-func (pa *PlatformAdapterHTTP) MemberKick(_ string, _ string) {}
+func (pa *PlatformAdapterHTTP) EditMessage(ctx *MsgContext, msgID, newContent string) {
+	if pa.isUI() {
+		return
+	}
+	segs := pa.toSegments(message.ConvertStringMessage(newContent))
+	out := &HTTPOutgoingMessage{Type: "edit", MessageID: msgID, Text: segmentsText(segs), Segments: segs}
+	pa.notifyCtx(ctx, out)
+}
+
+func (pa *PlatformAdapterHTTP) RecallMessage(ctx *MsgContext, msgID string) {
+	if pa.isUI() {
+		return
+	}
+	pa.notifyCtx(ctx, &HTTPOutgoingMessage{Type: "recall", MessageID: msgID})
+}
 
-func (pa *PlatformAdapterHTTP) EditMessage(_ *MsgContext, _, _ string) {}
+// Capabilities 各项操作都以通知的形式转交给对接方，由对方决定如何执行
+func (pa *PlatformAdapterHTTP) Capabilities() []AdapterCapability {
+	if pa.isUI() {
+		return nil
+	}
+	return []AdapterCapability{CapEditMessage, CapRecallMessage, CapMemberBan, CapMemberKick, CapSetGroupCardName, CapQuitGroup, CapSendFile, CapImage}
+}
 
-func (pa *PlatformAdapterHTTP) RecallMessage(_ *MsgContext, _ string) {}
+func (pa *PlatformAdapterHTTP) notifyCtx(ctx *MsgContext, out *HTTPOutgoingMessage) {
+	switch {
+	case ctx.MessageType == "group" && ctx.Group != nil:
+		out.MessageType = "group"
+		pa.notify(ctx, out, ctx.Group.GroupID, "")
+	case ctx.Player != nil:
+		out.MessageType = "private"
+		pa.notify(ctx, out, "", ctx.Player.UserID)
+	}
+}
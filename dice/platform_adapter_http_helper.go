@@ -0,0 +1,92 @@
+package dice
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AddHTTPEcho struct {
+	Nickname    string `json:"nickname"`
+	ListenAddr  string `json:"listenAddr"`
+	AccessToken string `json:"accessToken"`
+	ReplyMode   string `json:"replyMode"`
+	CallbackURL string `json:"callbackUrl"`
+	Secret      string `json:"secret"`
+}
+
+func NewHTTPConnItem(v AddHTTPEcho) *EndPointInfo {
+	conn := new(EndPointInfo)
+	conn.ID = uuid.New().String()
+	conn.Platform = "HTTP"
+	conn.ProtocolType = ""
+	conn.Enable = false
+	conn.RelWorkDir = "extra/http-" + conn.ID
+	conn.UserID = FormatDiceIDHTTP("bot", conn.ID[:8])
+	conn.Nickname = v.Nickname
+	if conn.Nickname == "" {
+		conn.Nickname = "海豹核心"
+	}
+	conn.Adapter = &PlatformAdapterHTTP{
+		EndPoint:    conn,
+		ListenAddr:  v.ListenAddr,
+		AccessToken: v.AccessToken,
+		ReplyMode:   v.ReplyMode,
+		CallbackURL: v.CallbackURL,
+		Secret:      v.Secret,
+	}
+	return conn
+}
+
+// HTTPListenIsLoopback 监听地址是否仅限本机。不限本机时必须设置 accessToken，
+// 否则任何人都能以任意身份向骰子发消息
+func HTTPListenIsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func ServeHTTPAdapter(d *Dice, ep *EndPointInfo) {
+	defer CrashLog()
+	if ep.Platform == "HTTP" {
+		conn := ep.Adapter.(*PlatformAdapterHTTP)
+		conn.Session = d.ImSession
+		conn.EndPoint = ep
+		d.Logger.Infof("HTTP 尝试启动")
+		if conn.Serve() != 0 {
+			d.Logger.Errorf("启动HTTP接入失败")
+			ep.State = 3
+			ep.Enable = false
+			d.LastUpdatedTime = time.Now().Unix()
+			d.Save(false)
+		}
+	}
+}
+
+// 格式化
+
+func FormatDiceIDHTTP(platform, user string) string {
+	return "HTTP:" + platform + ":" + user
+}
+
+func FormatDiceIDHTTPGroup(platform, group string) string {
+	return "HTTP-Group:" + platform + ":" + group
+}
+
+// ExtractHTTPUserID 返回 <platform>:<user>
+func ExtractHTTPUserID(id string) string {
+	return strings.TrimPrefix(id, "HTTP:")
+}
+
+// ExtractHTTPGroupID 返回 <platform>:<group>
+func ExtractHTTPGroupID(id string) string {
+	return strings.TrimPrefix(id, "HTTP-Group:")
+}
@@ -0,0 +1,232 @@
+package dice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"sealdice-core/message"
+)
+
+// newTestDiceFull 在临时目录中完整初始化一个骰子，用于走 Execute 的测试
+func newTestDiceFull(t *testing.T) *Dice {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	_ = os.MkdirAll("data/decks", 0o755)
+
+	dm := &DiceManager{Cron: cron.New()}
+	d := &Dice{BaseConfig: DiceConfig{Name: "default"}, Parent: dm}
+	dm.Dice = []*Dice{d}
+	d.Init()
+	return d
+}
+
+// testHTTPChat 挂在骰子上的 HTTP 适配器，模拟在群 room1 中收发消息
+type testHTTPChat struct {
+	t  *testing.T
+	d  *Dice
+	ep *EndPointInfo
+	pa *PlatformAdapterHTTP
+}
+
+func newTestHTTPChat(t *testing.T, d *Dice) *testHTTPChat {
+	ep := NewHTTPConnItem(AddHTTPEcho{ListenAddr: "127.0.0.1:0", AccessToken: "tok"})
+	pa := ep.Adapter.(*PlatformAdapterHTTP)
+	ep.Session, pa.Session, pa.EndPoint = d.ImSession, d.ImSession, ep
+	d.ImSession.EndPoints = []*EndPointInfo{ep}
+	return &testHTTPChat{t: t, d: d, ep: ep, pa: pa}
+}
+
+// message user 以群内身份 role 发出的消息。可能在其他协程中调用，出错时不能用 Fatal
+func (c *testHTTPChat) message(user, role, text string) *Message {
+	msg, err := c.pa.toStdMessage(&HTTPIncomingMessage{Platform: "vtt", Group: "room1", User: user, Nickname: user, Role: role, Text: text})
+	if err != nil {
+		c.t.Error(err)
+	}
+	return msg
+}
+
+// execute 处理消息，返回全部同步回复，以换行连接
+func (c *testHTTPChat) execute(msg *Message) string {
+	if msg == nil {
+		return ""
+	}
+	var texts []string
+	for _, r := range c.pa.collect(msg, func() { c.d.ImSession.Execute(c.ep, msg, true) }) {
+		texts = append(texts, r.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (c *testHTTPChat) send(user, role, text string) string {
+	return c.execute(c.message(user, role, text))
+}
+
+func TestHTTPIncomingMessage(t *testing.T) {
+	ep := NewHTTPConnItem(AddHTTPEcho{ListenAddr: "127.0.0.1:0", AccessToken: "tok"})
+	pa := ep.Adapter.(*PlatformAdapterHTTP)
+
+	msg, err := pa.toStdMessage(&HTTPIncomingMessage{
+		Platform: "vtt",
+		Group:    "room1",
+		User:     "u1",
+		Role:     "admin",
+		Segments: []HTTPSegment{{Type: "at", User: "self"}, {Type: "text", Text: " .r d20 "}, {Type: "at", User: "u2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MessageType != "group" || msg.GroupID != "HTTP-Group:vtt:room1" || msg.Sender.UserID != "HTTP:vtt:u1" ||
+		msg.Sender.Nickname != "u1" || msg.Sender.GroupRole != "admin" {
+		t.Errorf("消息转换不对: %+v", msg)
+	}
+	rest, ats := AtParse(msg.Message, "HTTP")
+	if rest != " .r d20 " || len(ats) != 2 || ats[0].UserID != ep.UserID || ats[1].UserID != "HTTP:vtt:u2" {
+		t.Errorf("@解析不对: %q %+v", rest, ats)
+	}
+	if AtBuild("HTTP:vtt:u2") != "[CQ:at,qq=vtt:u2]" {
+		t.Errorf("@构建不对: %s", AtBuild("HTTP:vtt:u2"))
+	}
+
+	if _, err = pa.toStdMessage(&HTTPIncomingMessage{User: "u1", MessageType: "group", Text: ".r"}); err == nil {
+		t.Error("缺少 group 的群消息应当报错")
+	}
+}
+
+func TestHTTPUntrustedCaller(t *testing.T) {
+	ep := NewHTTPConnItem(AddHTTPEcho{ListenAddr: "127.0.0.1:0"})
+	pa := ep.Adapter.(*PlatformAdapterHTTP)
+	msg, err := pa.toStdMessage(&HTTPIncomingMessage{Group: "room1", User: "u1", Role: "owner", Text: ".r"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Sender.GroupRole != "" {
+		t.Errorf("未设置 accessToken 时不应采信 role: %q", msg.Sender.GroupRole)
+	}
+
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.2:8080":  false,
+		"bad":            false,
+	} {
+		if HTTPListenIsLoopback(addr) != ok {
+			t.Errorf("%s 是否本机判断错误", addr)
+		}
+	}
+
+	ep = NewHTTPConnItem(AddHTTPEcho{ListenAddr: "0.0.0.0:0"})
+	pa = ep.Adapter.(*PlatformAdapterHTTP)
+	pa.Session = &IMSession{Parent: &Dice{Logger: zap.NewNop().Sugar()}}
+	if pa.Serve() == 0 || pa.server != nil {
+		t.Fatal("监听外部地址且未设置 accessToken 时应拒绝启动")
+	}
+}
+
+func TestHTTPReplyDelivery(t *testing.T) {
+	var got HTTPOutgoingMessage
+	var sig, ts string
+	done := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		ts, sig = r.Header.Get("X-Seal-Timestamp"), r.Header.Get("X-Seal-Signature")
+		if sig != "sha256="+HTTPSign("key", ts, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		done <- struct{}{}
+	}))
+	defer srv.Close()
+
+	ep := NewHTTPConnItem(AddHTTPEcho{ListenAddr: "127.0.0.1:0", CallbackURL: srv.URL, Secret: "key"})
+	pa := ep.Adapter.(*PlatformAdapterHTTP)
+	segs := pa.toSegments(message.ConvertStringMessage("[CQ:at,qq=vtt:u1] 结果: 20"))
+	out := &HTTPOutgoingMessage{Type: "message", Text: segmentsText(segs), Segments: segs}
+
+	// 同步等待期间直接收集
+	msg := &Message{MessageType: "group", GroupID: "HTTP-Group:vtt:room1"}
+	replies := pa.collect(msg, func() {
+		pa.deliver(&MsgContext{replyTag: msg.ReplyTag}, out)
+		// 没有关联ID的消息不混入
+		pa.deliver(&MsgContext{}, &HTTPOutgoingMessage{Type: "message"})
+	})
+	if len(replies) != 1 || replies[0].Segments[0].Platform != "vtt" || replies[0].Segments[0].User != "u1" ||
+		replies[0].Text != " 结果: 20" {
+		t.Fatalf("同步回复不对: %+v", replies)
+	}
+
+	// 无人等待时走回调
+	pa.postCallback(context.Background(), out)
+	<-done
+	if got.Type != "message" || got.Text != " 结果: 20" || ts == "" {
+		t.Errorf("回调内容不对: %+v", got)
+	}
+}
+
+func TestHTTPSyncReplyThroughExecute(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	ep, pa := chat.ep, chat.pa
+
+	for i := 0; i < 20; i++ {
+		msg, err := pa.toStdMessage(&HTTPIncomingMessage{Platform: "vtt", Group: "room1", User: fmt.Sprint("u", i), Text: ".r 1d1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		replies := pa.collect(msg, func() {
+			d.ImSession.Execute(ep, msg, true)
+		})
+		if len(replies) != 1 {
+			t.Fatalf("第%d条指令的同步回复数量为%d", i, len(replies))
+		}
+	}
+}
+
+func TestHTTPSyncReplyConcurrent(t *testing.T) {
+	d := newTestDiceFull(t)
+	chat := newTestHTTPChat(t, d)
+	ep, pa := chat.ep, chat.pa
+
+	msgA, _ := pa.toStdMessage(&HTTPIncomingMessage{Platform: "vtt", Group: "room1", User: "a", Nickname: "Alice", Text: ".r 1d1"})
+	msgB, _ := pa.toStdMessage(&HTTPIncomingMessage{Platform: "vtt", Group: "room1", User: "b", Nickname: "Bob", Text: ".r 1d1"})
+
+	// 两个请求的等待期完全重叠，各自处理时另一方都在等待
+	bWaiting, aDone, bDone := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	var repliesB []*HTTPOutgoingMessage
+	go func() {
+		repliesB = pa.collect(msgB, func() {
+			close(bWaiting)
+			<-aDone
+			d.ImSession.Execute(ep, msgB, true)
+		})
+		close(bDone)
+	}()
+	repliesA := pa.collect(msgA, func() {
+		<-bWaiting
+		d.ImSession.Execute(ep, msgA, true)
+		close(aDone)
+		<-bDone
+	})
+
+	if len(repliesA) != 1 || !strings.Contains(repliesA[0].Text, "Alice") {
+		t.Errorf("A 的回复不对: %+v", repliesA)
+	}
+	if len(repliesB) != 1 || !strings.Contains(repliesB[0].Text, "Bob") {
+		t.Errorf("B 的回复不对: %+v", repliesB)
+	}
+}
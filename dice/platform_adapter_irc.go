@@ -0,0 +1,667 @@
+package dice
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"sealdice-core/message"
+	"sealdice-core/utils"
+)
+
+// IRC 适配器，频道对应群组，群号形如 IRC-Group:#channel；昵称不区分大小写，ID统一为小写
+
+const (
+	ircLineLimit  = 510 // 不含结尾 CRLF
+	ircSplitHint  = "[%d/%d] "
+	ircPrefixSlop = 80 // 服务器转发时会加上 :nick!user@host 前缀，为其预留
+)
+
+type PlatformAdapterIRC struct {
+	Session  *IMSession    `yaml:"-" json:"-"`
+	EndPoint *EndPointInfo `yaml:"-" json:"-"`
+
+	Server             string   `yaml:"server" json:"server"` // host:port
+	UseTLS             bool     `yaml:"useTLS" json:"useTLS"`
+	InsecureSkipVerify bool     `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
+	Nick               string   `yaml:"nick" json:"nick"`
+	Username           string   `yaml:"username" json:"username"`
+	RealName           string   `yaml:"realName" json:"realName"`
+	ServerPassword     string   `yaml:"serverPassword" json:"-"`
+	SASLUser           string   `yaml:"saslUser" json:"saslUser"`
+	SASLPassword       string   `yaml:"saslPassword" json:"-"`
+	NickServPassword   string   `yaml:"nickServPassword" json:"-"`
+	Channels           []string `yaml:"channels" json:"channels"` // 自动加入的频道，受邀加入的频道也会记在这里
+
+	conn    net.Conn
+	cancel  context.CancelFunc
+	lock    sync.Mutex
+	writeMu sync.Mutex
+	limiter *rate.Limiter
+	curNick string
+	invites SyncMap[string, string] // 频道 -> 邀请人
+}
+
+type ircMessage struct {
+	Tags    map[string]string
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// Nick 取前缀中的昵称
+func (m *ircMessage) Nick() string {
+	if i := strings.IndexAny(m.Prefix, "!@"); i >= 0 {
+		return m.Prefix[:i]
+	}
+	return m.Prefix
+}
+
+func (m *ircMessage) Param(i int) string {
+	if i < len(m.Params) {
+		return m.Params[i]
+	}
+	return ""
+}
+
+func parseIRCLine(line string) *ircMessage {
+	m := &ircMessage{}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		var tags string
+		tags, line, _ = strings.Cut(line[1:], " ")
+		m.Tags = map[string]string{}
+		for _, tag := range strings.Split(tags, ";") {
+			k, v, _ := strings.Cut(tag, "=")
+			m.Tags[k] = v
+		}
+	}
+	line = strings.TrimLeft(line, " ")
+	if strings.HasPrefix(line, ":") {
+		m.Prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	for line != "" {
+		line = strings.TrimLeft(line, " ")
+		if strings.HasPrefix(line, ":") {
+			m.Params = append(m.Params, line[1:])
+			break
+		}
+		var p string
+		p, line, _ = strings.Cut(line, " ")
+		if p == "" {
+			continue
+		}
+		if m.Command == "" {
+			m.Command = strings.ToUpper(p)
+		} else {
+			m.Params = append(m.Params, p)
+		}
+	}
+	return m
+}
+
+type ircConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (pa *PlatformAdapterIRC) Serve() int {
+	ep := pa.EndPoint
+	d := pa.Session.Parent
+	log := d.Logger
+
+	pa.lock.Lock()
+	if pa.cancel != nil {
+		pa.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pa.cancel = cancel
+	pa.lock.Unlock()
+
+	sv := GetConnSupervisor(ep)
+	run := sv.Connecting("正在连接 IRC")
+	conn, err := pa.connect(ctx)
+	if err != nil {
+		log.Errorf("IRC 连接 %s 失败：%v", pa.Server, err)
+		sv.Failed("连接失败: " + err.Error())
+		cancel()
+		return 1
+	}
+	ep.Enable = true
+	sv.Connected("连接成功")
+	d.LastUpdatedTime = time.Now().Unix()
+	d.Save(false)
+	log.Infof("IRC 连接成功：%s 昵称<%s>", pa.Server, pa.curNick)
+
+	go pa.run(ctx, run, conn)
+	return 0
+}
+
+// run 读取消息直到断线，断线后由连接守护安排重连
+func (pa *PlatformAdapterIRC) run(ctx context.Context, run int, conn *ircConn) {
+	log := pa.Session.Parent.Logger
+	sv := GetConnSupervisor(pa.EndPoint)
+	for {
+		c, done := conn, make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = c.Close()
+			case <-done:
+			}
+		}()
+		err := pa.readLoop(conn)
+		close(done)
+		_ = conn.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("IRC 连接 %s 断开：%v", pa.Server, err)
+		reason := "连接断开: " + err.Error()
+
+		for {
+			if !sv.Wait(run, reason) {
+				return
+			}
+			run = sv.Connecting("正在重新连接")
+			conn, err = pa.connect(ctx)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, errIRCLoginRejected) {
+				log.Errorf("IRC 重连 %s 失败：%v", pa.Server, err)
+				sv.Failed(err.Error())
+				return
+			}
+			reason = "重连失败: " + err.Error()
+		}
+		sv.Connected("重连成功")
+		log.Infof("IRC 重连成功：%s", pa.Server)
+	}
+}
+
+var errIRCLoginRejected = errors.New("服务器拒绝登录")
+
+// connect 建立连接并完成注册(包括 SASL 认证)，收到 001 后返回
+func (pa *PlatformAdapterIRC) connect(ctx context.Context) (*ircConn, error) {
+	log := pa.Session.Parent.Logger
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var raw net.Conn
+	var err error
+	if pa.UseTLS {
+		host, _, _ := net.SplitHostPort(pa.Server)
+		raw, err = tls.DialWithDialer(dialer, "tcp", pa.Server, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: pa.InsecureSkipVerify, //nolint:gosec
+		})
+	} else {
+		raw, err = dialer.DialContext(ctx, "tcp", pa.Server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn := &ircConn{Conn: raw, reader: bufio.NewReader(raw)}
+	pa.lock.Lock()
+	pa.conn = conn
+	if pa.limiter == nil {
+		// 多数服务器对刷屏很敏感，允许短时间连发几行，之后每秒两行
+		pa.limiter = rate.NewLimiter(rate.Every(500*time.Millisecond), 5)
+	}
+	pa.lock.Unlock()
+
+	nick := pa.Nick
+	user := pa.Username
+	if user == "" {
+		user = nick
+	}
+	realName := pa.RealName
+	if realName == "" {
+		realName = "SealDice"
+	}
+	useSASL := pa.SASLUser != "" && pa.SASLPassword != ""
+	if pa.ServerPassword != "" {
+		pa.sendRaw("PASS " + pa.ServerPassword)
+	}
+	if useSASL {
+		pa.sendRaw("CAP REQ :sasl")
+	}
+	pa.sendRaw("NICK " + nick)
+	pa.sendRaw("USER " + user + " 0 * :" + realName)
+
+	_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	defer func() {
+		_ = conn.SetReadDeadline(time.Time{})
+	}()
+	saslOK := false
+	for {
+		line, err := conn.reader.ReadString('\n')
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		m := parseIRCLine(line)
+		switch m.Command {
+		case "PING":
+			pa.sendRaw("PONG :" + m.Param(0))
+		case "CAP":
+			switch m.Param(1) {
+			case "ACK":
+				pa.sendRaw("AUTHENTICATE PLAIN")
+			case "NAK":
+				log.Error("IRC 服务器不支持 SASL 认证")
+				pa.sendRaw("CAP END")
+			}
+		case "AUTHENTICATE":
+			if m.Param(0) == "+" {
+				payload := pa.SASLUser + "\x00" + pa.SASLUser + "\x00" + pa.SASLPassword
+				pa.sendRaw("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte(payload)))
+			}
+		case "903":
+			saslOK = true
+			pa.sendRaw("CAP END")
+		case "902", "904", "905", "906", "908":
+			log.Errorf("IRC SASL 认证失败：%s", m.Param(len(m.Params)-1))
+			pa.sendRaw("CAP END")
+		case "433":
+			// 昵称被占用
+			nick += "_"
+			pa.sendRaw("NICK " + nick)
+		case "464", "465":
+			_ = conn.Close()
+			return nil, fmt.Errorf("%w：%s", errIRCLoginRejected, m.Param(len(m.Params)-1))
+		case "ERROR":
+			_ = conn.Close()
+			return nil, errors.New(m.Param(0))
+		case "001":
+			pa.curNick = m.Param(0)
+			// 昵称可能因占用而变化，账号ID始终以配置的昵称为准
+			pa.EndPoint.UserID = FormatDiceIDIRC(pa.Nick)
+			pa.EndPoint.Nickname = pa.curNick
+			if pa.NickServPassword != "" && !saslOK {
+				pa.sendRaw("PRIVMSG NickServ :IDENTIFY " + pa.NickServPassword)
+			}
+			for _, ch := range pa.Channels {
+				pa.sendRaw("JOIN " + ch)
+			}
+			return conn, nil
+		}
+	}
+}
+
+func (pa *PlatformAdapterIRC) readLoop(conn *ircConn) error {
+	sv := GetConnSupervisor(pa.EndPoint)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		line, err := conn.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		sv.Alive()
+		m := parseIRCLine(line)
+		if m.Command == "ERROR" {
+			return errors.New(m.Param(0))
+		}
+		pa.handleMessage(m)
+	}
+}
+
+func (pa *PlatformAdapterIRC) isMe(nick string) bool {
+	return strings.EqualFold(nick, pa.curNick)
+}
+
+func (pa *PlatformAdapterIRC) handleMessage(m *ircMessage) {
+	s := pa.Session
+	ep := pa.EndPoint
+	log := s.Parent.Logger
+	switch m.Command {
+	case "PING":
+		pa.sendRaw("PONG :" + m.Param(0))
+	case "NICK":
+		if pa.isMe(m.Nick()) {
+			pa.curNick = m.Param(0)
+			ep.Nickname = pa.curNick
+		}
+	case "PRIVMSG":
+		pa.handlePrivmsg(m)
+	case "NOTICE":
+		if strings.EqualFold(m.Nick(), "NickServ") {
+			log.Infof("IRC NickServ：%s", m.Param(1))
+		}
+	case "INVITE":
+		ch := m.Param(1)
+		if s.Parent.RefuseGroupInvite {
+			log.Infof("IRC 收到 %s 的频道邀请 %s，设置为拒绝加群，忽略", m.Nick(), ch)
+			return
+		}
+		pa.invites.Store(strings.ToLower(ch), FormatDiceIDIRC(m.Nick()))
+		pa.sendRaw("JOIN " + ch)
+	case "JOIN":
+		ch := m.Param(0)
+		groupID := FormatDiceIDIRCChannel(ch)
+		msg := &Message{
+			MessageType: "group",
+			GroupID:     groupID,
+			Platform:    "IRC",
+			Time:        time.Now().Unix(),
+			Sender: SenderBase{
+				UserID:   FormatDiceIDIRC(m.Nick()),
+				Nickname: m.Nick(),
+			},
+		}
+		if !pa.isMe(m.Nick()) {
+			s.OnGroupMemberJoined(CreateTempCtx(ep, msg), msg)
+			return
+		}
+		pa.addChannel(ch)
+		if inviter, ok := pa.invites.LoadAndDelete(strings.ToLower(ch)); ok {
+			msg.Sender.UserID = inviter
+			go s.OnGroupJoined(CreateTempCtx(ep, msg), msg)
+			return
+		}
+		// 配置中的频道，仅在首次加入时建立群组信息
+		if group, ok := s.ServiceAtNew.Load(groupID); ok && group.DiceIDExistsMap != nil {
+			group.DiceIDExistsMap.Store(ep.UserID, true)
+		} else if !ok {
+			group = SetBotOnAtGroup(CreateTempCtx(ep, msg), groupID)
+			group.DiceIDExistsMap.Store(ep.UserID, true)
+			group.GroupName = ch
+			group.EnteredTime = time.Now().Unix()
+		}
+	case "PART", "KICK":
+		ch := m.Param(0)
+		target := m.Nick()
+		if m.Command == "KICK" {
+			target = m.Param(1)
+		}
+		if !pa.isMe(target) {
+			return
+		}
+		log.Infof("IRC 已离开频道 %s", ch)
+		pa.removeChannel(ch)
+		if group, ok := s.ServiceAtNew.Load(FormatDiceIDIRCChannel(ch)); ok && group.DiceIDExistsMap != nil {
+			group.DiceIDExistsMap.Delete(ep.UserID)
+			group.UpdatedAtTime = time.Now().Unix()
+		}
+		operator := ""
+		if m.Command == "KICK" {
+			operator = FormatDiceIDIRC(m.Nick())
+		}
+		s.OnGroupLeft(ep, FormatDiceIDIRCChannel(ch), operator, m.Command == "KICK")
+	case "471", "473", "474", "475":
+		log.Errorf("IRC 无法加入频道 %s：%s", m.Param(1), m.Param(2))
+	}
+}
+
+var ircFormatRe = regexp.MustCompile(`\x03(\d{1,2}(,\d{1,2})?)?|[\x02\x0F\x11\x16\x1D\x1E\x1F]`)
+
+func (pa *PlatformAdapterIRC) handlePrivmsg(m *ircMessage) {
+	target, text := m.Param(0), m.Param(1)
+	if target == "" {
+		return
+	}
+	if strings.HasPrefix(text, "\x01") {
+		ctcp := strings.Trim(text, "\x01")
+		switch {
+		case strings.HasPrefix(ctcp, "ACTION "):
+			text = m.Nick() + " " + strings.TrimPrefix(ctcp, "ACTION ")
+		case ctcp == "VERSION":
+			pa.sendRaw(fmt.Sprintf("NOTICE %s :\x01VERSION SealDice %s\x01", m.Nick(), VERSION.String()))
+			return
+		default:
+			return
+		}
+	}
+	text = ircFormatRe.ReplaceAllString(text, "")
+
+	// 按惯例以 "昵称: " 开头表示对某人说话，指向自己时转为@
+	if name, rest, ok := strings.Cut(text, " "); ok && len(name) > 1 {
+		if last := name[len(name)-1]; (last == ':' || last == ',') && pa.isMe(name[:len(name)-1]) {
+			text = "<@" + name[:len(name)-1] + "> " + rest
+		}
+	}
+
+	msg := &Message{
+		Platform: "IRC",
+		Message:  text,
+		Time:     time.Now().Unix(),
+		RawID:    m.Tags["msgid"],
+		Sender: SenderBase{
+			UserID:   FormatDiceIDIRC(m.Nick()),
+			Nickname: m.Nick(),
+		},
+	}
+	if strings.ContainsAny(target[:1], "#&+!") {
+		msg.MessageType = "group"
+		msg.GroupID = FormatDiceIDIRCChannel(target)
+	} else {
+		msg.MessageType = "private"
+	}
+	pa.Session.Execute(pa.EndPoint, msg, false)
+}
+
+func (pa *PlatformAdapterIRC) addChannel(ch string) {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	for _, i := range pa.Channels {
+		if strings.EqualFold(i, ch) {
+			return
+		}
+	}
+	pa.Channels = append(pa.Channels, ch)
+	pa.Session.Parent.LastUpdatedTime = time.Now().Unix()
+}
+
+func (pa *PlatformAdapterIRC) removeChannel(ch string) {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	for index, i := range pa.Channels {
+		if strings.EqualFold(i, ch) {
+			pa.Channels = append(pa.Channels[:index], pa.Channels[index+1:]...)
+			pa.Session.Parent.LastUpdatedTime = time.Now().Unix()
+			return
+		}
+	}
+}
+
+func (pa *PlatformAdapterIRC) sendRaw(line string) {
+	pa.lock.Lock()
+	conn, limiter := pa.conn, pa.limiter
+	pa.lock.Unlock()
+	if conn == nil {
+		return
+	}
+	if limiter != nil {
+		_ = limiter.Wait(context.Background())
+	}
+	// 防止换行混入导致注入额外的指令
+	line = strings.NewReplacer("\r", "", "\n", " ").Replace(line)
+	pa.writeMu.Lock()
+	defer pa.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if _, err := conn.Write([]byte(line + "\r\n")); err != nil {
+		pa.Session.Parent.Logger.Errorf("IRC 发送失败：%v", err)
+	}
+}
+
+// flattenIRCElements IRC 只能发送纯文本，将消息段展开为文字
+func flattenIRCElements(elems []message.IMessageElement) string {
+	var sb strings.Builder
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case *message.TextElement:
+			sb.WriteString(e.Content)
+		case *message.TTSElement:
+			sb.WriteString(e.Content)
+		case *message.AtElement:
+			sb.WriteString(ExtractIRCUserID(e.Target) + ": ")
+		case *message.ImageElement:
+			switch {
+			case e.URL != "":
+				sb.WriteString("[图片: " + e.URL + "]")
+			case e.File != nil && e.File.URL != "":
+				sb.WriteString("[图片: " + e.File.URL + "]")
+			default:
+				sb.WriteString("[图片]")
+			}
+		case *message.FileElement:
+			if e.URL != "" {
+				sb.WriteString("[文件: " + e.URL + "]")
+			} else {
+				sb.WriteString("[文件: " + e.File + "]")
+			}
+		case *message.RecordElement:
+			sb.WriteString("[语音]")
+		}
+	}
+	return sb.String()
+}
+
+// sendText 按行发送，超出单行上限的部分切分
+func (pa *PlatformAdapterIRC) sendText(target string, text string) {
+	limit := ircLineLimit - len("PRIVMSG "+target+" :") - len(pa.curNick) - ircPrefixSlop - len(ircSplitHint)
+	for _, chunk := range utils.SplitLongText(text, limit, ircSplitHint) {
+		for _, line := range strings.Split(chunk, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			pa.sendRaw("PRIVMSG " + target + " :" + line)
+		}
+	}
+}
+
+func (pa *PlatformAdapterIRC) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
+	pa.sendText(ExtractIRCUserID(userID), flattenIRCElements(message.ConvertStringMessage(text)))
+	pa.Session.OnMessageSend(ctx, &Message{
+		MessageType: "private",
+		Platform:    "IRC",
+		Message:     text,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+func (pa *PlatformAdapterIRC) SendToGroup(ctx *MsgContext, groupID string, text string, flag string) {
+	pa.sendText(ExtractIRCChannel(groupID), flattenIRCElements(message.ConvertStringMessage(text)))
+	pa.Session.OnMessageSend(ctx, &Message{
+		MessageType: "group",
+		Platform:    "IRC",
+		Message:     text,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+func (pa *PlatformAdapterIRC) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	pa.sendText(ExtractIRCChannel(groupID), flattenIRCElements(msg))
+}
+
+func (pa *PlatformAdapterIRC) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	pa.sendText(ExtractIRCUserID(userID), flattenIRCElements(msg))
+}
+
+func (pa *PlatformAdapterIRC) SendFileToPerson(ctx *MsgContext, userID string, path string, flag string) {
+	fileElement, err := message.FilepathToFileElement(path)
+	if err == nil {
+		pa.SendToPerson(ctx, userID, fmt.Sprintf("[尝试发送文件: %s，但不支持]", fileElement.File), flag)
+	} else {
+		pa.SendToPerson(ctx, userID, fmt.Sprintf("[尝试发送文件出错: %s]", err.Error()), flag)
+	}
+}
+
+func (pa *PlatformAdapterIRC) SendFileToGroup(ctx *MsgContext, groupID string, path string, flag string) {
+	fileElement, err := message.FilepathToFileElement(path)
+	if err == nil {
+		pa.SendToGroup(ctx, groupID, fmt.Sprintf("[尝试发送文件: %s，但不支持]", fileElement.File), flag)
+	} else {
+		pa.SendToGroup(ctx, groupID, fmt.Sprintf("[尝试发送文件出错: %s]", err.Error()), flag)
+	}
+}
+
+func (pa *PlatformAdapterIRC) QuitGroup(ctx *MsgContext, id string) {
+	ch := ExtractIRCChannel(id)
+	pa.sendRaw("PART " + ch + " :Bye")
+	pa.removeChannel(ch)
+}
+
+func (pa *PlatformAdapterIRC) SetGroupCardName(ctx *MsgContext, name string) {
+	pa.Session.Parent.Logger.Error("IRC 设置群名片失败：不支持")
+}
+
+func (pa *PlatformAdapterIRC) MemberBan(groupID string, userID string, duration int64) {
+	// IRC 的封禁没有时限，不做处理
+}
+
+func (pa *PlatformAdapterIRC) MemberKick(groupID string, userID string) {
+	pa.sendRaw("KICK " + ExtractIRCChannel(groupID) + " " + ExtractIRCUserID(userID))
+}
+
+func (pa *PlatformAdapterIRC) GetGroupInfoAsync(groupID string) {
+	// 频道名即群名
+	name := ExtractIRCChannel(groupID)
+	dm := pa.Session.Parent.Parent
+	dm.GroupNameCache.Store(groupID, &GroupNameCacheItem{
+		Name: name,
+		time: time.Now().Unix(),
+	})
+	groupInfo, ok := pa.Session.ServiceAtNew.Load(groupID)
+	if ok {
+		groupInfo.GroupName = name
+	}
+}
+
+func (pa *PlatformAdapterIRC) EditMessage(_ *MsgContext, _, _ string) {}
+
+func (pa *PlatformAdapterIRC) RecallMessage(_ *MsgContext, _ string) {}
+
+func (pa *PlatformAdapterIRC) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapMemberKick, CapQuitGroup}
+}
+
+func (pa *PlatformAdapterIRC) DoRelogin() bool {
+	GetConnSupervisor(pa.EndPoint).Stop("重新登录")
+	pa.EndPoint.Enable = false
+	go pa.Serve()
+	return true
+}
+
+func (pa *PlatformAdapterIRC) SetEnable(enable bool) {
+	if enable {
+		go pa.Serve()
+		return
+	}
+	pa.lock.Lock()
+	conn := pa.conn
+	pa.lock.Unlock()
+	if conn != nil {
+		pa.writeMu.Lock()
+		_, _ = conn.Write([]byte("QUIT :Bye\r\n"))
+		pa.writeMu.Unlock()
+	}
+	pa.lock.Lock()
+	if pa.cancel != nil {
+		pa.cancel()
+		pa.cancel = nil
+	}
+	pa.lock.Unlock()
+	GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
+	pa.EndPoint.Enable = false
+}
@@ -0,0 +1,79 @@
+package dice
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AddIRCEcho struct {
+	Server             string   `json:"server"`
+	UseTLS             bool     `json:"useTLS"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
+	Nick               string   `json:"nick"`
+	Username           string   `json:"username"`
+	RealName           string   `json:"realName"`
+	ServerPassword     string   `json:"serverPassword"`
+	SASLUser           string   `json:"saslUser"`
+	SASLPassword       string   `json:"saslPassword"`
+	NickServPassword   string   `json:"nickServPassword"`
+	Channels           []string `json:"channels"`
+}
+
+func NewIRCConnItem(v AddIRCEcho) *EndPointInfo {
+	conn := new(EndPointInfo)
+	conn.ID = uuid.New().String()
+	conn.Platform = "IRC"
+	conn.ProtocolType = ""
+	conn.Enable = false
+	conn.RelWorkDir = "extra/irc-" + conn.ID
+	conn.Adapter = &PlatformAdapterIRC{
+		EndPoint:           conn,
+		Server:             v.Server,
+		UseTLS:             v.UseTLS,
+		InsecureSkipVerify: v.InsecureSkipVerify,
+		Nick:               v.Nick,
+		Username:           v.Username,
+		RealName:           v.RealName,
+		ServerPassword:     v.ServerPassword,
+		SASLUser:           v.SASLUser,
+		SASLPassword:       v.SASLPassword,
+		NickServPassword:   v.NickServPassword,
+		Channels:           v.Channels,
+	}
+	return conn
+}
+
+func ServeIRC(d *Dice, ep *EndPointInfo) {
+	defer CrashLog()
+	if ep.Platform == "IRC" {
+		conn := ep.Adapter.(*PlatformAdapterIRC)
+		conn.Session = d.ImSession
+		conn.EndPoint = ep
+		if conn.Serve() != 0 {
+			ep.State = 3
+			d.LastUpdatedTime = time.Now().Unix()
+			d.Save(false)
+			d.Logger.Info("连接失败！")
+		}
+	}
+}
+
+// 格式化
+
+func FormatDiceIDIRC(nick string) string {
+	return "IRC:" + strings.ToLower(nick)
+}
+
+func FormatDiceIDIRCChannel(channel string) string {
+	return "IRC-Group:" + strings.ToLower(channel)
+}
+
+func ExtractIRCUserID(id string) string {
+	return strings.TrimPrefix(id, "IRC:")
+}
+
+func ExtractIRCChannel(id string) string {
+	return strings.TrimPrefix(id, "IRC-Group:")
+}
@@ -0,0 +1,165 @@
+package dice
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+func TestParseIRCLine(t *testing.T) {
+	cases := []struct {
+		line string
+		want ircMessage
+	}{
+		{"PING :irc.example.net\r\n", ircMessage{Command: "PING", Params: []string{"irc.example.net"}}},
+		{":alice!a@host PRIVMSG #trpg :.r d20 看看", ircMessage{Prefix: "alice!a@host", Command: "PRIVMSG", Params: []string{"#trpg", ".r d20 看看"}}},
+		{"@time=2024-01-01T00:00:00Z;msgid=abc :bob JOIN #trpg", ircMessage{
+			Tags:   map[string]string{"time": "2024-01-01T00:00:00Z", "msgid": "abc"},
+			Prefix: "bob", Command: "JOIN", Params: []string{"#trpg"},
+		}},
+		{":srv 001 seal :Welcome", ircMessage{Prefix: "srv", Command: "001", Params: []string{"seal", "Welcome"}}},
+		{":srv  mode   seal  +i", ircMessage{Prefix: "srv", Command: "MODE", Params: []string{"seal", "+i"}}},
+		{"PRIVMSG #c ::)", ircMessage{Command: "PRIVMSG", Params: []string{"#c", ":)"}}},
+		{"PRIVMSG #c :", ircMessage{Command: "PRIVMSG", Params: []string{"#c", ""}}},
+	}
+	for _, c := range cases {
+		got := parseIRCLine(c.line)
+		if !reflect.DeepEqual(*got, c.want) {
+			t.Errorf("%q: got %+v, want %+v", c.line, *got, c.want)
+		}
+	}
+	if nick := parseIRCLine(":alice!a@host QUIT").Nick(); nick != "alice" {
+		t.Errorf("nick = %q", nick)
+	}
+}
+
+// ircSendLines 调用 sendText 并收集写出的各行
+func ircSendLines(t *testing.T, target, text string) []string {
+	server, client := net.Pipe()
+	pa := &PlatformAdapterIRC{conn: client, curNick: "seal"}
+	go func() {
+		pa.sendText(target, text)
+		_ = client.Close()
+	}()
+	var lines []string
+	sc := bufio.NewScanner(server)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+func TestIRCSendTextSplit(t *testing.T) {
+	lines := ircSendLines(t, "#trpg", "第一行\n\n第二行\r\n")
+	if !reflect.DeepEqual(lines, []string{"PRIVMSG #trpg :第一行", "PRIVMSG #trpg :第二行"}) {
+		t.Fatalf("多行消息应逐行发送并跳过空行: %q", lines)
+	}
+
+	text := strings.Repeat("骰子结果", 200) + strings.Repeat("x", 700)
+	lines = ircSendLines(t, "#trpg", text)
+	if len(lines) < 3 {
+		t.Fatalf("过长的消息应被切分，实际 %d 行", len(lines))
+	}
+	hint := regexp.MustCompile(`^\[\d+/\d+\] `)
+	var sb strings.Builder
+	for _, line := range lines {
+		// 留出服务器转发时添加的前缀
+		if len(line)+ircPrefixSlop > ircLineLimit {
+			t.Errorf("单行过长: %d", len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("切分破坏了 UTF-8: %q", line)
+		}
+		body, ok := strings.CutPrefix(line, "PRIVMSG #trpg :")
+		if !ok || !hint.MatchString(body) {
+			t.Fatalf("格式不对: %q", line)
+		}
+		sb.WriteString(hint.ReplaceAllString(body, ""))
+	}
+	if sb.String() != text {
+		t.Error("切分后的内容拼起来应与原文一致")
+	}
+}
+
+func TestIRCReconnectSupervised(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// 完成注册后立即断开，迫使适配器反复重连
+	var accepted int32
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			go func() {
+				defer c.Close()
+				sc := bufio.NewScanner(c)
+				for sc.Scan() {
+					if strings.HasPrefix(sc.Text(), "USER ") {
+						_, _ = c.Write([]byte(":irc.test 001 seal :welcome\r\n"))
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	ep := &EndPointInfo{EndPointInfoBase: EndPointInfoBase{Enable: true}}
+	pa := &PlatformAdapterIRC{Server: ln.Addr().String(), Nick: "seal", EndPoint: ep,
+		Session: &IMSession{Parent: &Dice{Logger: zap.NewNop().Sugar()}}}
+	sv := GetConnSupervisor(ep)
+	sv.Policy = &ReconnectPolicy{BaseDelay: 10 * time.Millisecond, Factor: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := sv.Connecting("connect")
+	conn, err := pa.connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv.Connected("connected")
+	done := make(chan struct{})
+	go func() {
+		pa.run(ctx, run, conn)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&accepted) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&accepted) < 3 {
+		t.Fatalf("disconnects should be retried by the supervisor, history: %+v", ep.ConnHistory())
+	}
+	reconnects := 0
+	for _, ev := range ep.ConnHistory() {
+		if ev.State == ConnStateConnecting && ev.Delay > 0 {
+			reconnects++
+		}
+	}
+	if reconnects < 2 {
+		t.Fatalf("reconnects should wait for the supervisor's backoff, history: %+v", ep.ConnHistory())
+	}
+
+	sv.Stop("stop")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run should exit after stop")
+	}
+}
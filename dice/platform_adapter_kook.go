@@ -124,6 +124,22 @@ type CardMessageModuleFile struct {
 	// Size  string `json:"size"`
 }
 
+type CardMessageModuleActionGroup struct {
+	Type     string                    `json:"type"`
+	Elements []CardMessageModuleButton `json:"elements"`
+}
+
+type CardMessageModuleButton struct {
+	Type  string `json:"type"`
+	Theme string `json:"theme"`
+	Value string `json:"value"`
+	Click string `json:"click"`
+	Text  struct {
+		Content string `json:"content"`
+		Type    string `json:"type"`
+	} `json:"text"`
+}
+
 // PlatformAdapterKook 与 PlatformAdapterDiscord 基本相同的实现，因此不详细写注释了，可以去参考隔壁的实现
 type PlatformAdapterKook struct {
 	Session       *IMSession    `yaml:"-" json:"-"`
@@ -343,15 +359,49 @@ func (pa *PlatformAdapterKook) Serve() int {
 		pa.Session.OnMessageEdit(mctx, msg)
 	})
 
+	// 卡片按钮点击，Value 为按钮上的指令
+	s.AddHandler(func(ctx *kook.MessageButtonClickContext) {
+		cmd, ok := buttonDataDecode(ctx.Extra.Value)
+		if !ok || ctx.Extra.UserInfo.Bot {
+			return
+		}
+		msg := new(Message)
+		msg.Time = time.Now().Unix()
+		msg.RawID = ctx.Extra.MsgID
+		msg.Message = cmd
+		msg.Platform = "KOOK"
+		msg.Sender.UserID = FormatDiceIDKook(ctx.Extra.UserID)
+		msg.Sender.Nickname = ctx.Extra.UserInfo.Nickname
+		if ctx.Extra.GuildID == "" {
+			msg.MessageType = "private"
+		} else {
+			msg.MessageType = "group"
+			msg.GroupID = FormatDiceIDKookChannel(ctx.Extra.TargetID)
+			msg.GuildID = FormatDiceIDKookGuild(ctx.Extra.GuildID)
+			perm := pa.memberPermissions(&ctx.Extra.GuildID, &ctx.Extra.TargetID, ctx.Extra.UserID, ctx.Extra.UserInfo.Roles)
+			if perm&int64(RolePermissionAdmin|RolePermissionBanUser|RolePermissionKickUser) > 0 || perm == int64(RolePermissionAll) {
+				msg.Sender.GroupRole = "admin"
+			}
+		}
+		pa.Session.Execute(pa.EndPoint, msg, false)
+	})
+
+	sv := GetConnSupervisor(pa.EndPoint)
+	sv.Connecting("正在连接KOOK")
 	err := s.Open()
 	if err != nil {
 		pa.Session.Parent.Logger.Errorf("与KOOK服务建立连接时出错:%s", err.Error())
+		pa.EndPoint.Enable = true
+		if sv.Lost("连接失败: "+err.Error(), func() { pa.Serve() }) {
+			return 0 // 已交给连接守护稍后重试
+		}
 		return 1
 	}
 	pa.IntentSession = s
 	go pa.updateGameStatus()
-	pa.EndPoint.State = 1
 	pa.EndPoint.Enable = true
+	sv.Connected("连接成功")
+	go pa.watchHeartbeat(s)
 	self, _ := s.UserMe()
 	pa.EndPoint.Nickname = self.Nickname
 	pa.EndPoint.UserID = FormatDiceIDKook(self.ID)
@@ -362,9 +412,32 @@ func (pa *PlatformAdapterKook) Serve() int {
 	return 0
 }
 
+// watchHeartbeat KOOK 库自行处理断线重连且不提供断线回调，这里按心跳回应判断连接状态，交给连接守护记录
+func (pa *PlatformAdapterKook) watchHeartbeat(s *kook.Session) {
+	defer CrashLog()
+	sv := GetConnSupervisor(pa.EndPoint)
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if pa.IntentSession != s {
+			return
+		}
+		s.RLock()
+		last := s.LastHeartbeatAck
+		s.RUnlock()
+		alive := time.Since(last) < 90*time.Second
+		switch {
+		case !alive && pa.EndPoint.State == ConnStateConnected:
+			sv.Dropped("心跳超时，等待自动重连")
+		case alive && pa.EndPoint.State != ConnStateConnected:
+			sv.Connected("重连成功")
+		}
+	}
+}
+
 func (pa *PlatformAdapterKook) DoRelogin() bool {
 	pa.Session.Parent.Logger.Infof("正在重新登录KOOK服务……")
-	pa.EndPoint.State = 0
+	GetConnSupervisor(pa.EndPoint).Stop("重新登录")
 	pa.EndPoint.Enable = false
 	if pa.IntentSession != nil {
 		_ = pa.IntentSession.Close()
@@ -380,22 +453,25 @@ func (pa *PlatformAdapterKook) SetEnable(enable bool) {
 			pa.Serve()
 			return
 		}
+		sv := GetConnSupervisor(pa.EndPoint)
+		sv.Connecting("正在连接KOOK")
 		err := pa.IntentSession.Open()
 		if err != nil {
 			pa.Session.Parent.Logger.Errorf("与KOOK服务进行连接时出错:%s", err)
-			pa.EndPoint.State = 0
+			sv.Failed("连接失败: " + err.Error())
 			pa.EndPoint.Enable = false
 			return
 		}
 		pa.updateGameStatus()
-		pa.EndPoint.State = 1
 		pa.EndPoint.Enable = true
+		sv.Connected("连接成功")
+		go pa.watchHeartbeat(pa.IntentSession)
 		pa.Session.Parent.Logger.Infof("KOOK 连接成功，账号<%s>(%s)", pa.EndPoint.Nickname, pa.EndPoint.UserID)
 	} else {
 		if pa.IntentSession == nil {
 			return
 		}
-		pa.EndPoint.State = 0
+		GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
 		pa.EndPoint.Enable = false
 		_ = pa.IntentSession.Close()
 		pa.IntentSession = nil
@@ -477,6 +553,10 @@ func (pa *PlatformAdapterKook) RecallMessage(ctx *MsgContext, msgID string) {
 	_ = pa.IntentSession.MessageDelete(msgID)
 }
 
+func (pa *PlatformAdapterKook) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapEditMessage, CapRecallMessage, CapSetGroupCardName, CapQuitGroup, CapSendFile, CapButton, CapImage}
+}
+
 func (pa *PlatformAdapterKook) SendFileToChannelRaw(id string, path string, private bool) {
 	bot := pa.IntentSession
 	dice := pa.Session.Parent
@@ -549,6 +629,7 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 		Theme: "primary",
 		Size:  "lg",
 	}
+	var buttons []*message.ButtonElement
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
@@ -603,7 +684,25 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 			// msgb.Content += antiMarkdownFormat(e.Content)
 		case *message.ReplyElement:
 			msgb.Quote = e.ReplySeq
+		case *message.ButtonElement:
+			buttons = append(buttons, e)
+		}
+	}
+	// 按钮组每组最多4个
+	for _, row := range buttonRows(buttons, 4) {
+		group := CardMessageModuleActionGroup{Type: "action-group"}
+		for _, b := range row {
+			btn := CardMessageModuleButton{
+				Type:  "button",
+				Theme: "primary",
+				Value: buttonDataEncode(b.Command, 100),
+				Click: "return-val",
+			}
+			btn.Text.Content = b.Label
+			btn.Text.Type = "plain-text"
+			group.Elements = append(group.Elements, btn)
 		}
+		card.Modules = append(card.Modules, group)
 	}
 	cardArray := []CardMessage{card}
 	if true {
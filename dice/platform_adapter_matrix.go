@@ -0,0 +1,979 @@
+package dice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sealdice-core/message"
+)
+
+// Matrix 适配器，直接使用 client-server API (v3)
+// 房间对应群组，群号形如 MATRIX-Group:!room:server；标记为 is_direct 的房间视为私聊
+
+const (
+	matrixClientPrefix = "/_matrix/client/v3"
+	matrixMediaPrefix  = "/_matrix/media/v3"
+	matrixSyncFilter   = `{"presence":{"not_types":["*"]},"account_data":{"types":["m.direct"]},"room":{"timeline":{"limit":50},"ephemeral":{"not_types":["*"]},"state":{"lazy_load_members":true}}}`
+)
+
+type PlatformAdapterMatrix struct {
+	Session  *IMSession    `yaml:"-" json:"-"`
+	EndPoint *EndPointInfo `yaml:"-" json:"-"`
+
+	HomeServer  string `yaml:"homeServer" json:"homeServer"` // 如 https://matrix.org
+	UserID      string `yaml:"userId" json:"userId"`         // 如 @bot:matrix.org
+	Password    string `yaml:"password" json:"-"`            // 仅用于首次登录，换到令牌后清空
+	AccessToken string `yaml:"accessToken" json:"-"`
+	DeviceID    string `yaml:"deviceId" json:"deviceId"`
+
+	DirectRooms map[string]string `yaml:"directRooms" json:"-"` // 用户 -> 私聊房间
+
+	client     *http.Client
+	cancel     context.CancelFunc
+	lock       sync.Mutex
+	txnID      atomic.Int64
+	aliasCache SyncMap[string, string] // 房间别名 -> 房间ID
+	nameCache  SyncMap[string, string] // 房间ID|用户 -> 房间内昵称
+}
+
+type matrixError struct {
+	Status       int    `json:"-"`
+	ErrCode      string `json:"errcode"`
+	Message      string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+func (e *matrixError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.ErrCode, e.Message)
+}
+
+type matrixEvent struct {
+	Type     string          `json:"type"`
+	EventID  string          `json:"event_id"`
+	Sender   string          `json:"sender"`
+	StateKey *string         `json:"state_key,omitempty"`
+	Redacts  string          `json:"redacts,omitempty"`
+	OriginTS int64           `json:"origin_server_ts"`
+	Content  json.RawMessage `json:"content"`
+	Unsigned struct {
+		PrevContent json.RawMessage `json:"prev_content"`
+	} `json:"unsigned"`
+}
+
+type matrixEventList struct {
+	Events []matrixEvent `json:"events"`
+}
+
+type matrixSyncResponse struct {
+	NextBatch   string          `json:"next_batch"`
+	AccountData matrixEventList `json:"account_data"`
+	Rooms       struct {
+		Join map[string]struct {
+			Timeline matrixEventList `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]struct {
+			InviteState matrixEventList `json:"invite_state"`
+		} `json:"invite"`
+		Leave map[string]json.RawMessage `json:"leave"`
+	} `json:"rooms"`
+}
+
+type matrixEventRef struct {
+	EventID string `json:"event_id"`
+}
+
+type matrixRelatesTo struct {
+	RelType   string          `json:"rel_type,omitempty"`
+	EventID   string          `json:"event_id,omitempty"`
+	InReplyTo *matrixEventRef `json:"m.in_reply_to,omitempty"`
+}
+
+type matrixMentions struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type matrixMessageContent struct {
+	MsgType       string                 `json:"msgtype"`
+	Body          string                 `json:"body"`
+	Format        string                 `json:"format,omitempty"`
+	FormattedBody string                 `json:"formatted_body,omitempty"`
+	URL           string                 `json:"url,omitempty"`
+	Info          map[string]interface{} `json:"info,omitempty"`
+	Mentions      *matrixMentions        `json:"m.mentions,omitempty"`
+	RelatesTo     *matrixRelatesTo       `json:"m.relates_to,omitempty"`
+	NewContent    *matrixMessageContent  `json:"m.new_content,omitempty"`
+}
+
+type matrixMemberContent struct {
+	Membership  string `json:"membership"`
+	DisplayName string `json:"displayname"`
+	IsDirect    bool   `json:"is_direct"`
+}
+
+func (pa *PlatformAdapterMatrix) Serve() int {
+	ep := pa.EndPoint
+	d := pa.Session.Parent
+	log := d.Logger
+
+	pa.lock.Lock()
+	if pa.cancel != nil {
+		pa.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pa.cancel = cancel
+	pa.lock.Unlock()
+
+	sv := GetConnSupervisor(ep)
+	run := sv.Connecting("正在登录 Matrix")
+	if err := pa.login(ctx); err != nil {
+		log.Errorf("Matrix 登录失败：%v", err)
+		sv.Failed("登录失败: " + err.Error())
+		cancel()
+		return 1
+	}
+	ep.UserID = FormatDiceIDMatrix(pa.UserID)
+	ep.Nickname = pa.memberName(ctx, "", pa.UserID)
+	ep.Enable = true
+	sv.Connected("连接成功")
+	d.LastUpdatedTime = time.Now().Unix()
+	d.Save(false)
+	log.Infof("Matrix 连接成功：账号<%s>(%s)", ep.Nickname, ep.UserID)
+
+	go pa.syncLoop(ctx, run)
+	return 0
+}
+
+func (pa *PlatformAdapterMatrix) login(ctx context.Context) error {
+	if pa.client == nil {
+		// sync 长轮询最多挂起30秒，留出余量
+		pa.client = &http.Client{Timeout: 60 * time.Second}
+	}
+	if pa.AccessToken == "" {
+		if pa.Password == "" {
+			return errors.New("访问令牌和密码至少需要填写一项")
+		}
+		req := map[string]interface{}{
+			"type":                        "m.login.password",
+			"identifier":                  map[string]string{"type": "m.id.user", "user": pa.UserID},
+			"password":                    pa.Password,
+			"initial_device_display_name": "SealDice",
+		}
+		if pa.DeviceID != "" {
+			req["device_id"] = pa.DeviceID
+		}
+		var resp struct {
+			AccessToken string `json:"access_token"`
+			DeviceID    string `json:"device_id"`
+		}
+		if err := pa.requestJSON(ctx, http.MethodPost, matrixClientPrefix+"/login", nil, req, &resp); err != nil {
+			return err
+		}
+		pa.AccessToken = resp.AccessToken
+		pa.DeviceID = resp.DeviceID
+		pa.Password = ""
+	}
+
+	var who struct {
+		UserID   string `json:"user_id"`
+		DeviceID string `json:"device_id"`
+	}
+	if err := pa.requestJSON(ctx, http.MethodGet, matrixClientPrefix+"/account/whoami", nil, nil, &who); err != nil {
+		return err
+	}
+	pa.UserID = who.UserID
+	if who.DeviceID != "" {
+		pa.DeviceID = who.DeviceID
+	}
+	return nil
+}
+
+// syncLoop 持续同步，失败后由连接守护安排重试。run 为 Serve 中 Connecting 的返回值
+func (pa *PlatformAdapterMatrix) syncLoop(ctx context.Context, run int) {
+	ep := pa.EndPoint
+	log := pa.Session.Parent.Logger
+	sv := GetConnSupervisor(ep)
+	since := ""
+	for ctx.Err() == nil {
+		resp, err := pa.sync(ctx, since)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var me *matrixError
+			if errors.As(err, &me) && me.ErrCode == "M_UNKNOWN_TOKEN" {
+				log.Errorf("Matrix 账号 <%s> 令牌失效，需要重新登录", ep.UserID)
+				ep.Enable = false
+				sv.Failed("令牌失效，需要重新登录")
+				return
+			}
+			if !sv.Wait(run, "Matrix 同步失败: "+err.Error()) {
+				return
+			}
+			run = sv.Connecting("正在重新同步")
+			continue
+		}
+		if ep.State != ConnStateConnected {
+			sv.Connected("同步恢复")
+		}
+		sv.Alive()
+		pa.handleSync(ctx, resp, since == "")
+		since = resp.NextBatch
+	}
+}
+
+func (pa *PlatformAdapterMatrix) sync(ctx context.Context, since string) (*matrixSyncResponse, error) {
+	query := url.Values{}
+	query.Set("filter", matrixSyncFilter)
+	if since == "" {
+		query.Set("timeout", "0")
+	} else {
+		query.Set("since", since)
+		query.Set("timeout", "30000")
+	}
+	var resp matrixSyncResponse
+	if err := pa.requestJSON(ctx, http.MethodGet, matrixClientPrefix+"/sync", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// handleSync 处理一次同步结果，首次同步的时间线是历史消息，跳过
+func (pa *PlatformAdapterMatrix) handleSync(ctx context.Context, resp *matrixSyncResponse, initial bool) {
+	for _, ev := range resp.AccountData.Events {
+		if ev.Type != "m.direct" {
+			continue
+		}
+		var direct map[string][]string
+		if json.Unmarshal(ev.Content, &direct) != nil {
+			continue
+		}
+		pa.lock.Lock()
+		if pa.DirectRooms == nil {
+			pa.DirectRooms = map[string]string{}
+		}
+		for user, rooms := range direct {
+			if len(rooms) > 0 {
+				pa.DirectRooms[user] = rooms[len(rooms)-1]
+			}
+		}
+		pa.lock.Unlock()
+	}
+
+	for roomID, room := range resp.Rooms.Invite {
+		pa.handleInvite(ctx, roomID, room.InviteState.Events)
+	}
+	if initial {
+		return
+	}
+	for roomID, room := range resp.Rooms.Join {
+		for i := range room.Timeline.Events {
+			pa.handleEvent(roomID, &room.Timeline.Events[i])
+		}
+	}
+}
+
+func (pa *PlatformAdapterMatrix) handleInvite(ctx context.Context, roomID string, events []matrixEvent) {
+	d := pa.Session.Parent
+	var inviter string
+	var isDirect bool
+	for _, ev := range events {
+		if ev.Type != "m.room.member" || ev.StateKey == nil || *ev.StateKey != pa.UserID {
+			continue
+		}
+		var c matrixMemberContent
+		if json.Unmarshal(ev.Content, &c) == nil && c.Membership == "invite" {
+			inviter = ev.Sender
+			isDirect = c.IsDirect
+		}
+	}
+	if inviter == "" {
+		return
+	}
+
+	if !isDirect && d.RefuseGroupInvite {
+		d.Logger.Infof("Matrix 收到房间邀请 %s，设置为拒绝加群，忽略", roomID)
+		_ = pa.requestJSON(ctx, http.MethodPost, matrixClientPrefix+"/rooms/"+url.PathEscape(roomID)+"/leave", nil, struct{}{}, nil)
+		return
+	}
+	if err := pa.requestJSON(ctx, http.MethodPost, matrixClientPrefix+"/join/"+url.PathEscape(roomID), nil, struct{}{}, nil); err != nil {
+		d.Logger.Errorf("Matrix 加入房间 %s 失败：%v", roomID, err)
+		return
+	}
+	if isDirect {
+		pa.setDirectRoom(inviter, roomID)
+		return
+	}
+
+	msg := &Message{
+		MessageType: "group",
+		GroupID:     FormatDiceIDMatrixRoom(roomID),
+		Platform:    "MATRIX",
+		Time:        time.Now().Unix(),
+		Sender: SenderBase{
+			UserID: FormatDiceIDMatrix(inviter),
+		},
+	}
+	go pa.Session.OnGroupJoined(CreateTempCtx(pa.EndPoint, msg), msg)
+}
+
+func (pa *PlatformAdapterMatrix) handleEvent(roomID string, ev *matrixEvent) {
+	if ev.Sender == pa.UserID {
+		return
+	}
+	s := pa.Session
+	switch ev.Type {
+	case "m.room.message":
+		var c matrixMessageContent
+		if json.Unmarshal(ev.Content, &c) != nil {
+			return
+		}
+		if c.RelatesTo != nil && c.RelatesTo.RelType == "m.replace" {
+			// 编辑过的消息不再重复处理
+			return
+		}
+		s.Execute(pa.EndPoint, pa.toStdMessage(roomID, ev, &c), false)
+	case "m.room.redaction":
+		msg := pa.baseMessage(roomID, ev)
+		msg.RawID = ev.Redacts
+		s.OnMessageDeleted(CreateTempCtx(pa.EndPoint, msg), msg)
+	case "m.room.member":
+		if ev.StateKey == nil || *ev.StateKey == pa.UserID {
+			return
+		}
+		var c, prev matrixMemberContent
+		_ = json.Unmarshal(ev.Content, &c)
+		_ = json.Unmarshal(ev.Unsigned.PrevContent, &prev)
+		if c.DisplayName != "" {
+			pa.nameCache.Store(roomID+"|"+*ev.StateKey, c.DisplayName)
+		}
+		if c.Membership != "join" || prev.Membership == "join" {
+			return
+		}
+		msg := pa.baseMessage(roomID, ev)
+		if msg.MessageType != "group" {
+			return
+		}
+		msg.Sender.UserID = FormatDiceIDMatrix(*ev.StateKey)
+		msg.Sender.Nickname = c.DisplayName
+		s.OnGroupMemberJoined(CreateTempCtx(pa.EndPoint, msg), msg)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) baseMessage(roomID string, ev *matrixEvent) *Message {
+	msg := &Message{
+		Platform: "MATRIX",
+		RawID:    ev.EventID,
+		Time:     ev.OriginTS / 1000,
+		Sender: SenderBase{
+			UserID: FormatDiceIDMatrix(ev.Sender),
+		},
+	}
+	if msg.Time == 0 {
+		msg.Time = time.Now().Unix()
+	}
+	if pa.directUser(roomID) == ev.Sender {
+		msg.MessageType = "private"
+	} else {
+		msg.MessageType = "group"
+		msg.GroupID = FormatDiceIDMatrixRoom(roomID)
+	}
+	return msg
+}
+
+func (pa *PlatformAdapterMatrix) toStdMessage(roomID string, ev *matrixEvent, c *matrixMessageContent) *Message {
+	msg := pa.baseMessage(roomID, ev)
+	msg.Sender.Nickname = pa.memberName(context.Background(), roomID, ev.Sender)
+
+	text := c.Body
+	if c.Format == "org.matrix.custom.html" && c.FormattedBody != "" {
+		text = matrixHTMLToText(c.FormattedBody)
+	} else if c.RelatesTo != nil && c.RelatesTo.InReplyTo != nil {
+		// 纯文本回复会以 "> " 引用原文，去掉
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			if !strings.HasPrefix(line, "> ") && !(len(lines) == 0 && line == "") {
+				lines = append(lines, line)
+			}
+		}
+		text = strings.Join(lines, "\n")
+	}
+	switch c.MsgType {
+	case "m.image":
+		text = fmt.Sprintf("[CQ:image,file=%s]", pa.mediaURL(c.URL))
+	case "m.file", "m.audio", "m.video":
+		text = fmt.Sprintf("[CQ:file,file=%s,name=%s]", pa.mediaURL(c.URL), c.Body)
+	}
+	if c.RelatesTo != nil && c.RelatesTo.InReplyTo != nil {
+		text = fmt.Sprintf("[CQ:reply,id=%s]", c.RelatesTo.InReplyTo.EventID) + text
+	}
+	msg.Message = strings.TrimSpace(text)
+	return msg
+}
+
+var (
+	matrixReplyRe = regexp.MustCompile(`(?s)<mx-reply>.*?</mx-reply>`)
+	matrixPillRe  = regexp.MustCompile(`(?s)<a href="https://matrix\.to/#/([^"]+)"[^>]*>.*?</a>`)
+	matrixBrRe    = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	matrixTagRe   = regexp.MustCompile(`<[^>]+>`)
+	matrixAtRe    = regexp.MustCompile("\uE000([^\uE001]+)\uE001")
+)
+
+// matrixHTMLToText 将 formatted_body 转为纯文本，用户提及转为 <@user:server> 以便 AtParse 识别
+func matrixHTMLToText(s string) string {
+	s = matrixReplyRe.ReplaceAllString(s, "")
+	s = matrixPillRe.ReplaceAllStringFunc(s, func(m string) string {
+		id, _ := url.PathUnescape(matrixPillRe.FindStringSubmatch(m)[1])
+		if !strings.HasPrefix(id, "@") {
+			return m
+		}
+		return "\uE000" + id + "\uE001"
+	})
+	s = matrixBrRe.ReplaceAllString(s, "\n")
+	s = matrixTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return matrixAtRe.ReplaceAllString(s, "<$1>")
+}
+
+func (pa *PlatformAdapterMatrix) mediaURL(mxc string) string {
+	if !strings.HasPrefix(mxc, "mxc://") {
+		return mxc
+	}
+	return strings.TrimRight(pa.HomeServer, "/") + matrixMediaPrefix + "/download/" + strings.TrimPrefix(mxc, "mxc://")
+}
+
+// memberName 取用户在房间内的昵称，roomID 为空时取全局昵称
+func (pa *PlatformAdapterMatrix) memberName(ctx context.Context, roomID, userID string) string {
+	key := roomID + "|" + userID
+	if name, ok := pa.nameCache.Load(key); ok {
+		return name
+	}
+	var resp struct {
+		DisplayName string `json:"displayname"`
+	}
+	var err error
+	if roomID == "" {
+		err = pa.requestJSON(ctx, http.MethodGet, matrixClientPrefix+"/profile/"+url.PathEscape(userID)+"/displayname", nil, nil, &resp)
+	} else {
+		err = pa.requestJSON(ctx, http.MethodGet, matrixClientPrefix+"/rooms/"+url.PathEscape(roomID)+"/state/m.room.member/"+url.PathEscape(userID), nil, nil, &resp)
+	}
+	name := resp.DisplayName
+	if err != nil || name == "" {
+		// 退而使用 localpart
+		name = strings.TrimPrefix(strings.SplitN(userID, ":", 2)[0], "@")
+	}
+	pa.nameCache.Store(key, name)
+	return name
+}
+
+func (pa *PlatformAdapterMatrix) directUser(roomID string) string {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+	for user, room := range pa.DirectRooms {
+		if room == roomID {
+			return user
+		}
+	}
+	return ""
+}
+
+func (pa *PlatformAdapterMatrix) setDirectRoom(userID, roomID string) {
+	pa.lock.Lock()
+	if pa.DirectRooms == nil {
+		pa.DirectRooms = map[string]string{}
+	}
+	pa.DirectRooms[userID] = roomID
+	direct := map[string][]string{}
+	for u, r := range pa.DirectRooms {
+		direct[u] = []string{r}
+	}
+	pa.lock.Unlock()
+
+	// 同步到账号数据，其他客户端也能将其识别为私聊
+	_ = pa.requestJSON(context.Background(), http.MethodPut, matrixClientPrefix+"/user/"+url.PathEscape(pa.UserID)+"/account_data/m.direct", nil, direct, nil)
+	if d := pa.Session.Parent; d != nil {
+		d.LastUpdatedTime = time.Now().Unix()
+	}
+}
+
+// directRoom 取与用户的私聊房间，没有则创建
+func (pa *PlatformAdapterMatrix) directRoom(userID string) (string, error) {
+	pa.lock.Lock()
+	roomID, ok := pa.DirectRooms[userID]
+	pa.lock.Unlock()
+	if ok {
+		return roomID, nil
+	}
+
+	req := map[string]interface{}{
+		"is_direct": true,
+		"invite":    []string{userID},
+		"preset":    "trusted_private_chat",
+	}
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := pa.requestJSON(context.Background(), http.MethodPost, matrixClientPrefix+"/createRoom", nil, req, &resp); err != nil {
+		return "", err
+	}
+	pa.setDirectRoom(userID, resp.RoomID)
+	return resp.RoomID, nil
+}
+
+// resolveRoom 将群号转为房间ID，支持 #alias:server 形式的房间别名
+func (pa *PlatformAdapterMatrix) resolveRoom(groupID string) (string, error) {
+	id := ExtractMatrixRoomID(groupID)
+	if !strings.HasPrefix(id, "#") {
+		return id, nil
+	}
+	if roomID, ok := pa.aliasCache.Load(id); ok {
+		return roomID, nil
+	}
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := pa.requestJSON(context.Background(), http.MethodGet, matrixClientPrefix+"/directory/room/"+url.PathEscape(id), nil, nil, &resp); err != nil {
+		return "", err
+	}
+	pa.aliasCache.Store(id, resp.RoomID)
+	return resp.RoomID, nil
+}
+
+// ctxRoom 取当前消息所在的房间
+func (pa *PlatformAdapterMatrix) ctxRoom(ctx *MsgContext) (string, error) {
+	if ctx.IsPrivate || ctx.MessageType == "private" {
+		if ctx.Player == nil {
+			return "", errors.New("无法确定私聊对象")
+		}
+		return pa.directRoom(ExtractMatrixUserID(ctx.Player.UserID))
+	}
+	if ctx.Group == nil {
+		return "", errors.New("无法确定所在房间")
+	}
+	return pa.resolveRoom(ctx.Group.GroupID)
+}
+
+func (pa *PlatformAdapterMatrix) nextTxnID() string {
+	return fmt.Sprintf("sealdice.%d.%d", time.Now().UnixNano(), pa.txnID.Add(1))
+}
+
+func (pa *PlatformAdapterMatrix) sendEvent(roomID string, evType string, content interface{}) (string, error) {
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	path := fmt.Sprintf("%s/rooms/%s/send/%s/%s", matrixClientPrefix, url.PathEscape(roomID), evType, pa.nextTxnID())
+	err := pa.requestJSON(context.Background(), http.MethodPut, path, nil, content, &resp)
+	return resp.EventID, err
+}
+
+func (pa *PlatformAdapterMatrix) upload(name, contentType string, data []byte) (string, error) {
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	query := url.Values{}
+	query.Set("filename", name)
+	var resp struct {
+		ContentURI string `json:"content_uri"`
+	}
+	err := pa.request(context.Background(), http.MethodPost, matrixMediaPrefix+"/upload", query, contentType, data, &resp)
+	return resp.ContentURI, err
+}
+
+func (pa *PlatformAdapterMatrix) sendFile(roomID string, f *message.FileElement, msgType string) error {
+	if f == nil || f.Stream == nil {
+		return errors.New("文件为空")
+	}
+	data, err := io.ReadAll(f.Stream)
+	if err != nil {
+		return err
+	}
+	uri, err := pa.upload(f.File, f.ContentType, data)
+	if err != nil {
+		return err
+	}
+	if msgType == "" {
+		msgType = "m.file"
+		if strings.HasPrefix(f.ContentType, "image/") {
+			msgType = "m.image"
+		}
+	}
+	_, err = pa.sendEvent(roomID, "m.room.message", &matrixMessageContent{
+		MsgType: msgType,
+		Body:    f.File,
+		URL:     uri,
+		Info: map[string]interface{}{
+			"mimetype": f.ContentType,
+			"size":     len(data),
+		},
+	})
+	return err
+}
+
+// sendSegments 发送消息段，文字与提及合并为一条，图片和文件单独发送
+func (pa *PlatformAdapterMatrix) sendSegments(roomID string, elems []message.IMessageElement) error {
+	var body, formatted strings.Builder
+	var mentions []string
+	var replyTo string
+	useHTML := false
+
+	flush := func() error {
+		if strings.TrimSpace(body.String()) == "" {
+			body.Reset()
+			formatted.Reset()
+			return nil
+		}
+		content := &matrixMessageContent{
+			MsgType:  "m.text",
+			Body:     body.String(),
+			Mentions: &matrixMentions{UserIDs: mentions},
+		}
+		if content.Mentions.UserIDs == nil {
+			content.Mentions.UserIDs = []string{}
+		}
+		if useHTML {
+			content.Format = "org.matrix.custom.html"
+			content.FormattedBody = formatted.String()
+		}
+		if replyTo != "" {
+			content.RelatesTo = &matrixRelatesTo{InReplyTo: &matrixEventRef{EventID: replyTo}}
+			replyTo = ""
+		}
+		body.Reset()
+		formatted.Reset()
+		mentions = nil
+		useHTML = false
+		_, err := pa.sendEvent(roomID, "m.room.message", content)
+		return err
+	}
+	writeText := func(text string) {
+		body.WriteString(text)
+		formatted.WriteString(strings.ReplaceAll(html.EscapeString(text), "\n", "<br/>"))
+	}
+
+	for _, elem := range elems {
+		var err error
+		switch e := elem.(type) {
+		case *message.TextElement:
+			writeText(e.Content)
+		case *message.TTSElement:
+			writeText(e.Content)
+		case *message.AtElement:
+			userID := ExtractMatrixUserID(e.Target)
+			if !strings.HasPrefix(userID, "@") {
+				writeText("@" + e.Target)
+				continue
+			}
+			name := pa.memberName(context.Background(), roomID, userID)
+			body.WriteString(name)
+			formatted.WriteString(fmt.Sprintf(`<a href="https://matrix.to/#/%s">%s</a>`, url.PathEscape(userID), html.EscapeString(name)))
+			mentions = append(mentions, userID)
+			useHTML = true
+		case *message.ReplyElement:
+			replyTo = e.ReplySeq
+		case *message.ImageElement:
+			if err = flush(); err == nil {
+				err = pa.sendFile(roomID, e.File, "m.image")
+			}
+		case *message.FileElement:
+			if err = flush(); err == nil {
+				err = pa.sendFile(roomID, e, "")
+			}
+		case *message.RecordElement:
+			if err = flush(); err == nil {
+				err = pa.sendFile(roomID, e.File, "m.audio")
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+func (pa *PlatformAdapterMatrix) sendToRoom(roomID string, text string) {
+	if err := pa.sendSegments(roomID, message.ConvertStringMessage(text)); err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 发送消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
+	roomID, err := pa.directRoom(ExtractMatrixUserID(userID))
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 创建私聊房间失败：%v", err)
+		return
+	}
+	pa.sendToRoom(roomID, text)
+	pa.Session.OnMessageSend(ctx, &Message{
+		MessageType: "private",
+		Platform:    "MATRIX",
+		Message:     text,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+func (pa *PlatformAdapterMatrix) SendToGroup(ctx *MsgContext, groupID string, text string, flag string) {
+	roomID, err := pa.resolveRoom(groupID)
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 查找房间 %s 失败：%v", groupID, err)
+		return
+	}
+	pa.sendToRoom(roomID, text)
+	pa.Session.OnMessageSend(ctx, &Message{
+		MessageType: "group",
+		Platform:    "MATRIX",
+		Message:     text,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+func (pa *PlatformAdapterMatrix) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	roomID, err := pa.resolveRoom(groupID)
+	if err == nil {
+		err = pa.sendSegments(roomID, msg)
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 发送消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	roomID, err := pa.directRoom(ExtractMatrixUserID(userID))
+	if err == nil {
+		err = pa.sendSegments(roomID, msg)
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 发送消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) SendFileToPerson(ctx *MsgContext, userID string, path string, flag string) {
+	fileElement, err := message.FilepathToFileElement(path)
+	if err != nil {
+		pa.SendToPerson(ctx, userID, fmt.Sprintf("[尝试发送文件出错: %s]", err.Error()), flag)
+		return
+	}
+	pa.SendSegmentToPerson(ctx, userID, []message.IMessageElement{fileElement}, flag)
+}
+
+func (pa *PlatformAdapterMatrix) SendFileToGroup(ctx *MsgContext, groupID string, path string, flag string) {
+	fileElement, err := message.FilepathToFileElement(path)
+	if err != nil {
+		pa.SendToGroup(ctx, groupID, fmt.Sprintf("[尝试发送文件出错: %s]", err.Error()), flag)
+		return
+	}
+	pa.SendSegmentToGroup(ctx, groupID, []message.IMessageElement{fileElement}, flag)
+}
+
+func (pa *PlatformAdapterMatrix) QuitGroup(ctx *MsgContext, id string) {
+	roomID, err := pa.resolveRoom(id)
+	if err == nil {
+		err = pa.requestJSON(context.Background(), http.MethodPost, matrixClientPrefix+"/rooms/"+url.PathEscape(roomID)+"/leave", nil, struct{}{}, nil)
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 退出房间 %s 失败：%v", id, err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) SetGroupCardName(ctx *MsgContext, name string) {
+	pa.Session.Parent.Logger.Error("Matrix 设置群名片失败：不支持修改他人的房间昵称")
+}
+
+func (pa *PlatformAdapterMatrix) MemberBan(groupID string, userID string, duration int64) {
+	// Matrix 没有限时禁言，封禁是永久的，不做处理
+}
+
+func (pa *PlatformAdapterMatrix) MemberKick(groupID string, userID string) {
+	roomID, err := pa.resolveRoom(groupID)
+	if err == nil {
+		err = pa.requestJSON(context.Background(), http.MethodPost, matrixClientPrefix+"/rooms/"+url.PathEscape(roomID)+"/kick", nil,
+			map[string]string{"user_id": ExtractMatrixUserID(userID)}, nil)
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 踢出成员 %s 失败：%v", userID, err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) GetGroupInfoAsync(groupID string) {
+	go func() {
+		roomID, err := pa.resolveRoom(groupID)
+		if err != nil {
+			return
+		}
+		var nameResp struct {
+			Name string `json:"name"`
+		}
+		var aliasResp struct {
+			Alias string `json:"alias"`
+		}
+		prefix := matrixClientPrefix + "/rooms/" + url.PathEscape(roomID) + "/state/"
+		_ = pa.requestJSON(context.Background(), http.MethodGet, prefix+"m.room.name", nil, nil, &nameResp)
+		_ = pa.requestJSON(context.Background(), http.MethodGet, prefix+"m.room.canonical_alias", nil, nil, &aliasResp)
+
+		name := nameResp.Name
+		if name == "" {
+			name = aliasResp.Alias
+		}
+		if name != "" {
+			dm := pa.Session.Parent.Parent
+			dm.GroupNameCache.Store(groupID, &GroupNameCacheItem{
+				Name: name,
+				time: time.Now().Unix(),
+			})
+		}
+		groupInfo, ok := pa.Session.ServiceAtNew.Load(groupID)
+		if ok {
+			if name != "" {
+				groupInfo.GroupName = name
+			}
+			if aliasResp.Alias != "" && groupInfo.RoomAlias != aliasResp.Alias {
+				groupInfo.RoomAlias = aliasResp.Alias
+				groupInfo.UpdatedAtTime = time.Now().Unix()
+			}
+		}
+	}()
+}
+
+func (pa *PlatformAdapterMatrix) EditMessage(ctx *MsgContext, msgID, text string) {
+	roomID, err := pa.ctxRoom(ctx)
+	if err == nil {
+		_, err = pa.sendEvent(roomID, "m.room.message", &matrixMessageContent{
+			MsgType:    "m.text",
+			Body:       "* " + text,
+			NewContent: &matrixMessageContent{MsgType: "m.text", Body: text},
+			RelatesTo:  &matrixRelatesTo{RelType: "m.replace", EventID: msgID},
+		})
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 编辑消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) RecallMessage(ctx *MsgContext, msgID string) {
+	roomID, err := pa.ctxRoom(ctx)
+	if err == nil {
+		path := fmt.Sprintf("%s/rooms/%s/redact/%s/%s", matrixClientPrefix, url.PathEscape(roomID), url.PathEscape(msgID), pa.nextTxnID())
+		err = pa.requestJSON(context.Background(), http.MethodPut, path, nil, struct{}{}, nil)
+	}
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("Matrix 撤回消息失败：%v", err)
+	}
+}
+
+func (pa *PlatformAdapterMatrix) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapEditMessage, CapRecallMessage, CapMemberKick, CapQuitGroup, CapSendFile, CapImage}
+}
+
+func (pa *PlatformAdapterMatrix) DoRelogin() bool {
+	GetConnSupervisor(pa.EndPoint).Stop("重新登录")
+	pa.EndPoint.Enable = false
+	go pa.Serve()
+	return true
+}
+
+func (pa *PlatformAdapterMatrix) SetEnable(enable bool) {
+	if enable {
+		go pa.Serve()
+		return
+	}
+	pa.lock.Lock()
+	if pa.cancel != nil {
+		pa.cancel()
+		pa.cancel = nil
+	}
+	pa.lock.Unlock()
+	GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
+	pa.EndPoint.Enable = false
+}
+
+func (pa *PlatformAdapterMatrix) requestJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	return pa.request(ctx, method, path, query, "application/json", data, out)
+}
+
+// request 发起请求，遇到限流时按服务器给出的时间重试
+func (pa *PlatformAdapterMatrix) request(ctx context.Context, method, path string, query url.Values, contentType string, data []byte, out interface{}) error {
+	if pa.client == nil {
+		pa.client = &http.Client{Timeout: 60 * time.Second}
+	}
+	u := strings.TrimRight(pa.HomeServer, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	for retry := 0; ; retry++ {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, body)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if pa.AccessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+pa.AccessToken)
+		}
+		resp, err := pa.client.Do(req)
+		if err != nil {
+			return err
+		}
+		respData, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode >= 300 {
+			me := &matrixError{Status: resp.StatusCode}
+			_ = json.Unmarshal(respData, me)
+			if me.ErrCode == "M_LIMIT_EXCEEDED" && retry < 3 {
+				wait := time.Duration(me.RetryAfterMs) * time.Millisecond
+				if wait <= 0 {
+					wait = time.Second
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+				continue
+			}
+			if me.ErrCode == "" {
+				me.ErrCode = strconv.Itoa(resp.StatusCode)
+				me.Message = string(respData)
+			}
+			return me
+		}
+		if out != nil && len(respData) > 0 {
+			return json.Unmarshal(respData, out)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package dice
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func NewMatrixConnItem(homeServer string, userID string, password string, accessToken string) *EndPointInfo {
+	conn := new(EndPointInfo)
+	conn.ID = uuid.New().String()
+	conn.Platform = "MATRIX"
+	conn.ProtocolType = ""
+	conn.Enable = false
+	conn.RelWorkDir = "extra/matrix-" + conn.ID
+	conn.Adapter = &PlatformAdapterMatrix{
+		EndPoint:    conn,
+		HomeServer:  strings.TrimRight(homeServer, "/"),
+		UserID:      userID,
+		Password:    password,
+		AccessToken: accessToken,
+	}
+	return conn
+}
+
+func ServeMatrix(d *Dice, ep *EndPointInfo) {
+	defer CrashLog()
+	if ep.Platform == "MATRIX" {
+		conn := ep.Adapter.(*PlatformAdapterMatrix)
+		conn.Session = d.ImSession
+		conn.EndPoint = ep
+		if conn.Serve() != 0 {
+			ep.State = 3
+			d.LastUpdatedTime = time.Now().Unix()
+			d.Save(false)
+			d.Logger.Info("连接失败！")
+		}
+	}
+}
+
+// 格式化
+
+func FormatDiceIDMatrix(id string) string {
+	return "MATRIX:" + id
+}
+
+func FormatDiceIDMatrixRoom(id string) string {
+	return "MATRIX-Group:" + id
+}
+
+func ExtractMatrixUserID(id string) string {
+	return strings.TrimPrefix(id, "MATRIX:")
+}
+
+// ExtractMatrixRoomID 取出房间ID，也可能是 #alias:server 形式的房间别名
+func ExtractMatrixRoomID(id string) string {
+	return strings.TrimPrefix(id, "MATRIX-Group:")
+}
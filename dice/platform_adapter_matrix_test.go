@@ -0,0 +1,199 @@
+package dice
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"sealdice-core/message"
+)
+
+// matrixStub 最小化的 homeserver，记录收到的请求
+type matrixStub struct {
+	lock     sync.Mutex
+	requests []string
+	bodies   map[string][]byte
+}
+
+func (s *matrixStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := r.URL.EscapedPath()
+	s.lock.Lock()
+	s.requests = append(s.requests, r.Method+" "+path)
+	s.bodies[r.Method+" "+path] = body
+	s.lock.Unlock()
+
+	reply := func(v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	switch {
+	case path == "/_matrix/client/v3/login":
+		reply(map[string]string{"access_token": "tok", "user_id": "@bot:stub", "device_id": "DEV"})
+	case r.Header.Get("Authorization") != "Bearer tok":
+		w.WriteHeader(http.StatusUnauthorized)
+		reply(map[string]string{"errcode": "M_UNKNOWN_TOKEN", "error": "bad token"})
+	case path == "/_matrix/client/v3/account/whoami":
+		reply(map[string]string{"user_id": "@bot:stub", "device_id": "DEV"})
+	case strings.HasPrefix(path, "/_matrix/client/v3/rooms/") && strings.Contains(path, "/state/m.room.member/"):
+		reply(map[string]string{"displayname": "Alice"})
+	case strings.Contains(path, "/send/") || strings.Contains(path, "/redact/"):
+		reply(map[string]string{"event_id": "$sent"})
+	case path == "/_matrix/media/v3/upload":
+		reply(map[string]string{"content_uri": "mxc://stub/abc"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		reply(map[string]string{"errcode": "M_NOT_FOUND", "error": path})
+	}
+}
+
+func (s *matrixStub) last(prefix string) (string, []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if strings.HasPrefix(s.requests[i], prefix) {
+			return s.requests[i], s.bodies[s.requests[i]]
+		}
+	}
+	return "", nil
+}
+
+func newMatrixStub(t *testing.T) (*matrixStub, *PlatformAdapterMatrix) {
+	stub := &matrixStub{bodies: map[string][]byte{}}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	ep := NewMatrixConnItem(srv.URL+"/", "@bot:stub", "pass", "")
+	return stub, ep.Adapter.(*PlatformAdapterMatrix)
+}
+
+func TestMatrixLoginAndSend(t *testing.T) {
+	stub, pa := newMatrixStub(t)
+	if err := pa.login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pa.AccessToken != "tok" || pa.Password != "" || pa.DeviceID != "DEV" {
+		t.Fatalf("登录结果不对: %+v", pa)
+	}
+
+	err := pa.sendSegments("!room:stub", message.ConvertStringMessage("[CQ:at,qq=@alice:stub] 你好<b>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, body := stub.last("PUT /_matrix/client/v3/rooms/%21room:stub/send/m.room.message/")
+	if req == "" {
+		t.Fatalf("没有发送消息: %v", stub.requests)
+	}
+	var c matrixMessageContent
+	_ = json.Unmarshal(body, &c)
+	if c.Body != "Alice 你好<b>" || c.Mentions == nil || len(c.Mentions.UserIDs) != 1 || c.Mentions.UserIDs[0] != "@alice:stub" {
+		t.Errorf("消息内容不对: %s", body)
+	}
+	if !strings.Contains(c.FormattedBody, `href="https://matrix.to/#/@alice:stub"`) || !strings.Contains(c.FormattedBody, "&lt;b&gt;") {
+		t.Errorf("HTML内容不对: %s", c.FormattedBody)
+	}
+}
+
+func TestMatrixEditAndRedact(t *testing.T) {
+	stub, pa := newMatrixStub(t)
+	if err := pa.login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &MsgContext{MessageType: "group", Group: &GroupInfo{GroupID: FormatDiceIDMatrixRoom("!room:stub")}}
+
+	pa.RecallMessage(ctx, "$evt")
+	if req, _ := stub.last("PUT /_matrix/client/v3/rooms/%21room:stub/redact/$evt/"); req == "" {
+		t.Errorf("没有发出撤回请求: %v", stub.requests)
+	}
+
+	pa.EditMessage(ctx, "$evt", "新内容")
+	_, body := stub.last("PUT /_matrix/client/v3/rooms/%21room:stub/send/m.room.message/")
+	var c matrixMessageContent
+	_ = json.Unmarshal(body, &c)
+	if c.RelatesTo == nil || c.RelatesTo.RelType != "m.replace" || c.RelatesTo.EventID != "$evt" ||
+		c.NewContent == nil || c.NewContent.Body != "新内容" {
+		t.Errorf("编辑内容不对: %s", body)
+	}
+}
+
+func TestMatrixIncomingMessage(t *testing.T) {
+	_, pa := newMatrixStub(t)
+	if err := pa.login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pa.DirectRooms = map[string]string{"@carol:stub": "!dm:stub"}
+
+	ev := &matrixEvent{Type: "m.room.message", EventID: "$1", Sender: "@alice:stub", OriginTS: 1700000000000}
+	c := &matrixMessageContent{
+		MsgType:       "m.text",
+		Body:          "> 引用\n\nbot: .r d20",
+		Format:        "org.matrix.custom.html",
+		FormattedBody: `<mx-reply><blockquote>引用</blockquote></mx-reply><a href="https://matrix.to/#/%40bot%3Astub">bot</a> .r d20 &amp; 1`,
+		RelatesTo:     &matrixRelatesTo{InReplyTo: &matrixEventRef{EventID: "$0"}},
+	}
+	msg := pa.toStdMessage("!room:stub", ev, c)
+	if msg.MessageType != "group" || msg.GroupID != "MATRIX-Group:!room:stub" || msg.Sender.UserID != "MATRIX:@alice:stub" ||
+		msg.Sender.Nickname != "Alice" || msg.Time != 1700000000 {
+		t.Errorf("消息转换不对: %+v", msg)
+	}
+	if msg.Message != "[CQ:reply,id=$0]<@bot:stub> .r d20 & 1" {
+		t.Errorf("消息内容不对: %q", msg.Message)
+	}
+	rest, ats := AtParse(msg.Message, "MATRIX")
+	if len(ats) != 1 || ats[0].UserID != "MATRIX:@bot:stub" || strings.Contains(rest, "@bot") {
+		t.Errorf("@解析不对: %q %+v", rest, ats)
+	}
+
+	ev.Sender = "@carol:stub"
+	msg = pa.toStdMessage("!dm:stub", ev, &matrixMessageContent{MsgType: "m.text", Body: ".r"})
+	if msg.MessageType != "private" || msg.GroupID != "" {
+		t.Errorf("私聊识别不对: %+v", msg)
+	}
+}
+
+func TestMatrixSyncLoopSupervised(t *testing.T) {
+	_, pa := newMatrixStub(t)
+	if err := pa.login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ep := pa.EndPoint
+	ep.Enable = true
+	pa.Session = &IMSession{Parent: &Dice{Logger: zap.NewNop().Sugar()}}
+	sv := GetConnSupervisor(ep)
+	sv.Policy = &ReconnectPolicy{BaseDelay: 10 * time.Millisecond, Factor: 1}
+
+	// stub 没有 /sync，同步失败后应由连接守护安排重试
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pa.syncLoop(ctx, sv.Connecting("connect"))
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for sv.Attempt() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sv.Attempt() < 2 {
+		t.Fatalf("sync failures should go through the supervisor, history: %+v", ep.ConnHistory())
+	}
+	cancel()
+	sv.Stop("stop")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sync loop should exit after stop")
+	}
+
+	// 令牌失效不再重连
+	pa.AccessToken = "bad"
+	pa.syncLoop(context.Background(), sv.Connecting("connect"))
+	if ep.State != ConnStateFailed || ep.Enable {
+		t.Fatalf("unknown token should fail the endpoint, state=%d enable=%v", ep.State, ep.Enable)
+	}
+}
@@ -12,12 +12,10 @@ import (
 )
 
 type PlatformAdapterMinecraft struct {
-	Session      *IMSession          `yaml:"-" json:"-"`
-	EndPoint     *EndPointInfo       `yaml:"-" json:"-"`
-	Socket       *gowebsocket.Socket `yaml:"-" json:"-"`
-	RetryTimes   int                 `yaml:"-" json:"-"`
-	Reconnecting bool                `yaml:"-" json:"-"`
-	ConnectURL   string              `yaml:"connectUrl" json:"connectUrl"` // 连接地址
+	Session    *IMSession          `yaml:"-" json:"-"`
+	EndPoint   *EndPointInfo       `yaml:"-" json:"-"`
+	Socket     *gowebsocket.Socket `yaml:"-" json:"-"`
+	ConnectURL string              `yaml:"connectUrl" json:"connectUrl"` // 连接地址
 }
 
 type MessageMinecraft struct {
@@ -46,79 +44,63 @@ func (pa *PlatformAdapterMinecraft) Serve() int {
 	if !strings.HasPrefix(pa.ConnectURL, "ws://") {
 		pa.ConnectURL = "ws://" + pa.ConnectURL
 	}
-	socket := gowebsocket.New(pa.ConnectURL)
-	pa.Socket = &socket
 	pa.EndPoint.Nickname = "A Minecraft Server"
 	pa.EndPoint.UserID = "WebSocket"
+	pa.EndPoint.Enable = true
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
-	pa.socketSetup()
-	socket.Connect()
+	pa.connect()
 	return 0
 }
 
-func (pa *PlatformAdapterMinecraft) tryReconnect(socket gowebsocket.Socket) bool {
-	log := pa.Session.Parent.Logger
-	if pa.Reconnecting {
-		return false
-	}
-	pa.Reconnecting = true
-	if pa.RetryTimes <= 5 && !socket.IsConnected {
-		pa.RetryTimes++
-		log.Infof("MC server 尝试重新连接中[%d/5]", pa.RetryTimes)
-		socket = gowebsocket.New(pa.ConnectURL)
-		pa.Socket = &socket
-		pa.socketSetup()
-		socket.Connect()
-	}
-	return true
+// connect 建立一次连接，断线后由连接守护按退避时间再次调用
+func (pa *PlatformAdapterMinecraft) connect() {
+	sv := GetConnSupervisor(pa.EndPoint)
+	sv.WatchSocket(func() *gowebsocket.Socket { return pa.Socket })
+	socket := gowebsocket.New(pa.ConnectURL)
+	pa.Socket = &socket
+	pa.socketSetup()
+	sv.Connecting("正在连接MC服务器")
+	socket.Connect()
 }
 
 func (pa *PlatformAdapterMinecraft) socketSetup() {
 	ep := pa.EndPoint
 	log := pa.Session.Parent.Logger
+	sv := GetConnSupervisor(ep)
 	socket := pa.Socket
 	socket.OnConnected = func(socket gowebsocket.Socket) {
-		pa.Reconnecting = true
-		ep.State = 1
 		ep.Enable = true
-		pa.RetryTimes = 0
+		sv.Connected("连接成功")
 
 		d := pa.Session.Parent
 		d.LastUpdatedTime = time.Now().Unix()
 		d.Save(false)
 
 		log.Info("Minecraft 连接成功")
-		time.Sleep(time.Duration(5) * time.Second)
-		pa.Reconnecting = false
 	}
 	socket.OnTextMessage = func(message string, socket gowebsocket.Socket) {
+		sv.Alive()
 		msgMC := new(MessageMinecraft)
 		err := json.Unmarshal([]byte(message), msgMC)
 		if err == nil {
 			pa.Session.Execute(ep, pa.toStdMessage(msgMC), false)
 		}
 	}
+	socket.OnPongReceived = func(data string, socket gowebsocket.Socket) {
+		sv.Alive()
+	}
 	socket.OnConnectError = func(err error, socket gowebsocket.Socket) {
 		log.Errorf("MC websocket出现错误: %s", err)
-		if !socket.IsConnected {
-			// socket.Close()
-			if !pa.tryReconnect(*pa.Socket) {
-				log.Errorf("短时间内连接失败次数过多，不再进行重连")
-				ep.State = 3
-				ep.Enable = false
-			}
-		}
+		sv.Lost("连接失败: "+err.Error(), pa.connect)
 	}
 	socket.OnDisconnected = func(err error, socket gowebsocket.Socket) {
-		log.Errorf("与MC服务器断开连接")
-		time.Sleep(time.Duration(2) * time.Second)
-		if !pa.tryReconnect(*pa.Socket) {
-			log.Errorf("短时间内连接失败次数过多，不再进行重连")
-			ep.State = 3
-			ep.Enable = false
+		if pa.Socket != nil && socket.Conn != pa.Socket.Conn {
+			return // 已被替换的旧连接
 		}
+		log.Errorf("与MC服务器断开连接")
+		sv.Lost("连接断开", pa.connect)
 	}
 	pa.Socket = socket
 }
@@ -155,43 +137,30 @@ func ExtractMCUserID(id string) string {
 
 func (pa *PlatformAdapterMinecraft) DoRelogin() bool {
 	log := pa.Session.Parent.Logger
-	pa.Reconnecting = true
-	pa.Socket.Close()
-	socket := gowebsocket.New(pa.ConnectURL)
+	pa.closeSocket("重新连接")
 	log.Infof("MC server 重新连接")
-	pa.Socket = &socket
-	pa.socketSetup()
-	socket.Connect()
-	pa.Reconnecting = false
+	pa.EndPoint.Enable = true
+	pa.connect()
 	return true
 }
 
 func (pa *PlatformAdapterMinecraft) SetEnable(enable bool) {
 	log := pa.Session.Parent.Logger
+	pa.closeSocket("连接已停用")
 	if enable {
 		log.Infof("MC server 连接中")
-		if pa.Socket != nil && pa.Socket.IsConnected {
-			pa.Reconnecting = true
-			pa.Socket.Close()
-			socket := gowebsocket.New(pa.ConnectURL)
-			pa.Socket = &socket
-			pa.socketSetup()
-			socket.Connect()
-			pa.Reconnecting = false
-		} else {
-			pa.Reconnecting = true
-			socket := gowebsocket.New(pa.ConnectURL)
-			pa.Socket = &socket
-			pa.socketSetup()
-			socket.Connect()
-			pa.Reconnecting = false
-		}
+		pa.EndPoint.Enable = true
+		pa.connect()
 	} else {
-		pa.Reconnecting = true
-		if pa.Socket != nil && pa.Socket.IsConnected {
-			pa.Socket.Close()
-		}
-		pa.Reconnecting = false
+		pa.EndPoint.Enable = false
+	}
+}
+
+// closeSocket 主动关闭当前连接，连接守护不会因此重连
+func (pa *PlatformAdapterMinecraft) closeSocket(reason string) {
+	GetConnSupervisor(pa.EndPoint).Stop(reason)
+	if pa.Socket != nil && pa.Socket.IsConnected {
+		pa.Socket.Close()
 	}
 }
 
@@ -267,3 +236,7 @@ func (pa *PlatformAdapterMinecraft) SetGroupCardName(_ *MsgContext, _ string) {}
 func (pa *PlatformAdapterMinecraft) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterMinecraft) RecallMessage(_ *MsgContext, _ string) {}
+
+func (pa *PlatformAdapterMinecraft) Capabilities() []AdapterCapability {
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 
 	qqbot "github.com/sealdice/botgo"
 	"github.com/sealdice/botgo/dto"
+	"github.com/sealdice/botgo/dto/keyboard"
 	qqapi "github.com/sealdice/botgo/openapi"
 	qqtoken "github.com/sealdice/botgo/token"
 	qqws "github.com/sealdice/botgo/websocket"
@@ -70,17 +71,28 @@ func (pa *PlatformAdapterOfficialQQ) Serve() int {
 		)
 	}
 
+	sv := GetConnSupervisor(ep)
+	ctx := pa.Ctx
 	go func() {
 		defer func() {
 			// 防止崩掉进程
 			if r := recover(); r != nil {
 				log.Error("official qq 启动失败: ", r)
+				sv.Failed(fmt.Sprintf("启动失败: %v", r))
 			}
 		}()
-		_ = pa.SessionManager.Start(pa.Ctx, ws, token, &intent)
+		err := pa.SessionManager.Start(ctx, ws, token, &intent)
+		if ctx.Err() == nil {
+			// 不是主动断开的，按退避时间重新建立会话
+			reason := "会话已结束"
+			if err != nil {
+				reason += ": " + err.Error()
+			}
+			sv.Lost(reason, func() { pa.DoRelogin() })
+		}
 	}()
-	ep.State = 1
 	ep.Enable = true
+	sv.Connected("连接成功")
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
 	log.Info("official qq 连接成功")
@@ -179,7 +191,7 @@ func (pa *PlatformAdapterOfficialQQ) groupMsgToStdMsg(msgQQ *dto.WSGroupATMessag
 func (pa *PlatformAdapterOfficialQQ) DoRelogin() bool {
 	pa.CancelFunc()
 	pa.Session.Parent.Logger.Infof("正在启用 official qq 服务")
-	pa.EndPoint.State = 0
+	GetConnSupervisor(pa.EndPoint).Connecting("重新连接")
 	pa.EndPoint.Enable = false
 	pa.Api = nil
 	pa.SessionManager = nil
@@ -202,7 +214,7 @@ func (pa *PlatformAdapterOfficialQQ) SetEnable(enable bool) {
 			ep.State = 1
 		}
 	} else {
-		ep.State = 0
+		GetConnSupervisor(ep).Stop("连接已停用")
 		ep.Enable = false
 		if pa.CancelFunc != nil {
 			pa.CancelFunc()
@@ -268,13 +280,20 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGuildDirectMsgRaw(ctx *MsgContext, ro
 		toCreate *dto.MessageToCreate
 	)
 
+	var buttons []*message.ButtonElement
 	for _, elem := range elems {
 		switch e := elem.(type) {
 		case *message.TextElement:
 			content += e.Content
 		case *message.ImageElement:
+		case *message.ButtonElement:
+			buttons = append(buttons, e)
 		}
 	}
+	if len(buttons) > 0 {
+		// 私信不支持按钮
+		content += buttonHintText(buttons)
+	}
 
 	dMsg := &dto.DirectMessage{
 		GuildID:   guildID,
@@ -320,6 +339,7 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw(ctx *MsgContext, rowMsgID
 		MsgID: rowMsgID,
 	}
 
+	var buttons []*message.ButtonElement
 	for _, element := range elems {
 		switch elem := element.(type) {
 		case *message.TextElement:
@@ -373,22 +393,60 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw(ctx *MsgContext, rowMsgID
 			toCreate.Media = &dto.Media{
 				FileInfo: media.FileInfo,
 			}
+		case *message.ButtonElement:
+			buttons = append(buttons, elem)
 		}
 	}
 
 	toCreate.Content = content
-
-	if _, err := pa.Api.PostGroupMessage(qctx, groupID, toCreate); err != nil {
+	toCreate.Keyboard = officialQQKeyboard(buttons)
+
+	_, err := pa.Api.PostGroupMessage(qctx, groupID, toCreate)
+	if err != nil && toCreate.Keyboard != nil {
+		// 没有按钮权限时，退回文字提示再发一次
+		toCreate.Keyboard = nil
+		toCreate.Content += buttonHintText(buttons)
+		_, err = pa.Api.PostGroupMessage(qctx, groupID, toCreate)
+	}
+	if err != nil {
 		pa.Session.Parent.Logger.Error("official qq 发送群聊消息失败：" + err.Error())
 	}
 }
 
+// officialQQKeyboard 自定义按钮，点击后在输入框填入“@机器人 指令”，由用户发出
+func officialQQKeyboard(buttons []*message.ButtonElement) *keyboard.MessageKeyboard {
+	if len(buttons) == 0 {
+		return nil
+	}
+	kb := &keyboard.CustomKeyboard{}
+	for idx, row := range buttonRows(buttons, 4) {
+		if idx >= 5 {
+			break
+		}
+		r := &keyboard.Row{}
+		for _, b := range row {
+			r.Buttons = append(r.Buttons, &keyboard.Button{
+				ID:         strconv.Itoa(idx) + "-" + strconv.Itoa(len(r.Buttons)),
+				RenderData: &keyboard.RenderData{Label: b.Label, VisitedLabel: b.Label, Style: 1},
+				Action: &keyboard.Action{
+					Type:       keyboard.ActionTypeAtBot,
+					Permission: &keyboard.Permission{Type: keyboard.PermissionTypAll},
+					Data:       b.Command,
+				},
+			})
+		}
+		kb.Rows = append(kb.Rows, r)
+	}
+	return &keyboard.MessageKeyboard{Content: kb}
+}
+
 func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw(ctx *MsgContext, rowMsgID, channelID string, text string) {
 	qctx := context.Background()
 	elems := message.ConvertStringMessage(text)
 	var (
 		content  string
 		toCreate *dto.MessageToCreate
+		buttons  []*message.ButtonElement
 	)
 
 	for _, elem := range elems {
@@ -403,15 +461,24 @@ func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw(ctx *MsgContext, rowMsg
 				content += fmt.Sprintf("<@%s>", e.Target)
 			}
 		case *message.ImageElement:
+		case *message.ButtonElement:
+			buttons = append(buttons, e)
 		}
 	}
 
 	toCreate = &dto.MessageToCreate{
-		Content: content,
-		MsgType: 0,
-		MsgID:   rowMsgID,
+		Content:  content,
+		MsgType:  0,
+		MsgID:    rowMsgID,
+		Keyboard: officialQQKeyboard(buttons),
+	}
+	_, err := pa.Api.PostMessage(qctx, channelID, toCreate)
+	if err != nil && toCreate.Keyboard != nil {
+		toCreate.Keyboard = nil
+		toCreate.Content += buttonHintText(buttons)
+		_, err = pa.Api.PostMessage(qctx, channelID, toCreate)
 	}
-	if _, err := pa.Api.PostMessage(qctx, channelID, toCreate); err != nil {
+	if err != nil {
 		pa.Session.Parent.Logger.Error("official qq 发送频道消息失败：" + err.Error())
 	}
 }
@@ -521,3 +588,7 @@ func (pa *PlatformAdapterOfficialQQ) MemberKick(_ string, _ string) {
 func (pa *PlatformAdapterOfficialQQ) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterOfficialQQ) RecallMessage(_ *MsgContext, _ string) {}
+
+func (pa *PlatformAdapterOfficialQQ) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapButton}
+}
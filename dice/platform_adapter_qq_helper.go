@@ -70,14 +70,15 @@ func serverGocq(d *Dice, ep *EndPointInfo, conn *PlatformAdapterGocq) {
 		return false
 	}
 
-	conn.reconnectTimes = 0
+	sv := GetConnSupervisor(ep)
 	for {
 		if checkQuit() {
 			break
 		}
 
 		// 骰子开始连接
-		d.Logger.Infof("开始连接 onebot 服务，帐号 <%s>(%s)，重试计数[%d/%d]", ep.Nickname, ep.UserID, conn.reconnectTimes, 5)
+		d.Logger.Infof("开始连接 onebot 服务，帐号 <%s>(%s)，重试计数[%d]", ep.Nickname, ep.UserID, sv.Attempt())
+		run := sv.Connecting("正在连接 onebot 服务")
 		ret := ep.Adapter.Serve()
 
 		if ret == 0 {
@@ -93,15 +94,9 @@ func serverGocq(d *Dice, ep *EndPointInfo, conn *PlatformAdapterGocq) {
 			continue
 		}
 
-		conn.reconnectTimes++
-		if conn.reconnectTimes > 5 {
-			d.Logger.Infof("onebot 连接重试次数过多，先行中断: <%s>(%s)", ep.Nickname, ep.UserID)
-			ep.State = 0
-			conn.GoCqhttpState = StateCodeLoginFailed
+		if !sv.Wait(run, "onebot 连接断开") {
 			break
 		}
-
-		time.Sleep(15 * time.Second)
 	}
 
 	conn.diceServing = false
@@ -133,14 +128,15 @@ func serverWalleQ(d *Dice, ep *EndPointInfo, conn *PlatformAdapterWalleQ) {
 		return false
 	}
 
-	waitTimes := 0
+	sv := GetConnSupervisor(ep)
 	for {
 		if checkQuit() {
 			break
 		}
 
 		// 骰子开始连接
-		d.Logger.Infof("开始连接 onebot 服务，帐号 <%s>(%s)，重试计数[%d/%d]", ep.Nickname, ep.UserID, waitTimes, 5)
+		d.Logger.Infof("开始连接 onebot 服务，帐号 <%s>(%s)，重试计数[%d]", ep.Nickname, ep.UserID, sv.Attempt())
+		run := sv.Connecting("正在连接 onebot 服务")
 		ret := ep.Adapter.Serve()
 
 		if ret == 0 {
@@ -151,14 +147,10 @@ func serverWalleQ(d *Dice, ep *EndPointInfo, conn *PlatformAdapterWalleQ) {
 			break
 		}
 
-		waitTimes++
-		if waitTimes > 5 {
-			d.Logger.Infof("onebot 连接重试次数过多，先行中断: <%s>(%s)", ep.Nickname, ep.UserID)
+		if !sv.Wait(run, "onebot 连接断开") {
 			conn.DiceServing = false
 			break
 		}
-
-		time.Sleep(15 * time.Second)
 	}
 }
 
@@ -172,25 +164,21 @@ func serverRed(d *Dice, ep *EndPointInfo, conn *PlatformAdapterRed) {
 	ep.State = 2 // 连接中
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
-	waitTimes := 0
-
+	sv := GetConnSupervisor(ep)
 	for {
 		// 骰子开始连接
-		d.Logger.Infof("开始连接 red 服务，帐号 <%s>(%s)，重试计数[%d/%d]", ep.Nickname, ep.UserID, waitTimes, 5)
+		d.Logger.Infof("开始连接 red 服务，帐号 <%s>(%s)，重试计数[%d]", ep.Nickname, ep.UserID, sv.Attempt())
+		run := sv.Connecting("正在连接 red 服务")
 		ret := ep.Adapter.Serve()
 
 		if ret == 0 {
 			break
 		}
 
-		waitTimes += 1
-		if waitTimes > 5 {
-			d.Logger.Infof("red 连接重试次数过多，先行中断: <%s>(%s)", ep.Nickname, ep.UserID)
+		if !sv.Wait(run, "red 连接断开") {
 			conn.DiceServing = false
 			break
 		}
-
-		time.Sleep(15 * time.Second)
 	}
 }
 
@@ -204,28 +192,21 @@ func serverSatori(d *Dice, ep *EndPointInfo, conn *PlatformAdapterSatori) {
 	ep.State = 2 // 连接中
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
-	waitTimes := 0
-
+	sv := GetConnSupervisor(ep)
 	for {
-		if ep.State != 2 {
-			break
-		}
 		// 骰子开始连接
-		d.Logger.Infof("开始连接 satori 服务，帐号 <%s>(%s)，重试计数[%d/%d]", ep.Nickname, ep.UserID, waitTimes, 5)
+		d.Logger.Infof("开始连接 satori 服务，帐号 <%s>(%s)，重试计数[%d]", ep.Nickname, ep.UserID, sv.Attempt())
+		run := sv.Connecting("正在连接 satori 服务")
 		ret := ep.Adapter.Serve()
 
 		if ret == 0 {
 			break
 		}
 
-		waitTimes += 1
-		if waitTimes > 5 {
-			d.Logger.Infof("satori 连接重试次数过多，先行中断: <%s>(%s)", ep.Nickname, ep.UserID)
+		if !sv.Wait(run, "satori 连接断开") {
 			conn.DiceServing = false
 			break
 		}
-
-		time.Sleep(15 * time.Second)
 	}
 }
 
@@ -239,24 +220,20 @@ func serverOfficialQQ(d *Dice, ep *EndPointInfo, conn *PlatformAdapterOfficialQQ
 	ep.State = 2 // 连接中
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
-	waitTimes := 0
-
+	sv := GetConnSupervisor(ep)
 	for {
 		// 骰子开始连接
-		d.Logger.Infof("开始连接 official qq，帐号 <%s>(%s)，重试计数[%d/%d]", ep.Nickname, ep.UserID, waitTimes, 5)
+		d.Logger.Infof("开始连接 official qq，帐号 <%s>(%s)，重试计数[%d]", ep.Nickname, ep.UserID, sv.Attempt())
+		run := sv.Connecting("正在连接 official qq")
 		ret := ep.Adapter.Serve()
 
 		if ret == 0 {
 			break
 		}
 
-		waitTimes += 1
-		if waitTimes > 5 {
-			d.Logger.Infof("official qq 连接重试次数过多，先行中断: <%s>(%s)", ep.Nickname, ep.UserID)
+		if !sv.Wait(run, "official qq 连接断开") {
 			conn.DiceServing = false
 			break
 		}
-
-		time.Sleep(15 * time.Second)
 	}
 }
@@ -357,6 +357,7 @@ func (pa *PlatformAdapterRed) Serve() int {
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
 
 	wsUrl := url.URL{
 		Scheme: "ws",
@@ -411,7 +412,13 @@ func (pa *PlatformAdapterRed) Serve() int {
 	pa.wsUrl = &wsUrl
 	pa.httpUrl = &httpUrl
 	pa.RedVersion = authResp.Payload.Version
-	pa.EndPoint.State = 1
+	sv := GetConnSupervisor(ep)
+	sv.WatchConn(func() *websocket.Conn { return pa.conn })
+	conn.SetPongHandler(func(string) error {
+		sv.Alive()
+		return nil
+	})
+	sv.Connected("连接成功")
 
 	// 获得用户信息
 	botInfo := pa.getBotInfo()
@@ -443,9 +450,9 @@ func (pa *PlatformAdapterRed) Serve() int {
 		for {
 			msgType, msgData, err := conn.ReadMessage()
 			if err != nil {
-				interrupt <- os.Interrupt
 				return
 			}
+			sv.Alive()
 			switch msgType {
 			case websocket.TextMessage:
 				log.Debugf("message=%s", msgData)
@@ -466,43 +473,45 @@ func (pa *PlatformAdapterRed) Serve() int {
 			case websocket.BinaryMessage:
 			case websocket.CloseMessage:
 				log.Debug("server close")
-				pa.conn = nil
-				done <- struct{}{}
+				return
 			case websocket.PingMessage:
 			case websocket.PongMessage:
 			}
 		}
 	}()
 
-	for {
+	select {
+	case <-done:
+		// 连接断开，返回后由 serverRed 按退避时间重连
+		log.Info("red 连接已断开")
+		pa.conn = nil
+		return 1
+	case <-interrupt:
+		log.Debug("red interrupt")
+		if pa.conn != nil {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			_ = pa.conn.Close()
+			pa.conn = nil
+		}
 		select {
 		case <-done:
-		case <-interrupt:
-			log.Debug("red interrupt")
-
-			if pa.conn != nil {
-				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-				_ = pa.conn.Close()
-				pa.conn = nil
-			}
-
-			select {
-			case <-done:
-			case <-time.After(time.Second):
-			}
+		case <-time.After(time.Second):
 		}
+		return 0
 	}
 }
 
 func (pa *PlatformAdapterRed) DoRelogin() bool {
-	pa.Session.Parent.Logger.Infof("正在启用 red 连接……")
-	pa.EndPoint.State = 0
-	pa.EndPoint.Enable = false
+	pa.Session.Parent.Logger.Infof("正在重新连接 red……")
+	GetConnSupervisor(pa.EndPoint).Stop("重新连接")
 	if pa.conn != nil {
 		_ = pa.conn.Close()
+		pa.conn = nil
 	}
-	pa.conn = nil
-	return pa.Serve() == 0
+	pa.EndPoint.Enable = true
+	pa.DiceServing = false
+	go ServeQQ(pa.Session.Parent, pa.EndPoint)
+	return true
 }
 
 func (pa *PlatformAdapterRed) SetEnable(enable bool) {
@@ -516,7 +525,7 @@ func (pa *PlatformAdapterRed) SetEnable(enable bool) {
 			go ServeQQ(d, e)
 		}
 	} else {
-		e.State = 0
+		GetConnSupervisor(e).Stop("连接已停用")
 		e.Enable = false
 		if pa.conn != nil {
 			_ = pa.conn.Close()
@@ -751,6 +760,10 @@ func (pa *PlatformAdapterRed) GetGroupInfoAsync(_ string) {
 	go refresh()
 }
 
+func (pa *PlatformAdapterRed) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapSendFile, CapImage}
+}
+
 type BotInfo struct {
 	SelfId string
 	Name   string
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"sealdice-core/message"
@@ -127,11 +128,13 @@ func (pa *PlatformAdapterSatori) Serve() int {
 
 	pa.wsUrl = &wsUrl
 	pa.httpUrl = &httpUrl
-	pa.EndPoint.State = 1
 	ep.UserID = formatDiceIDSatori(pa.Platform, login.SelfID)
 	if login.User != nil {
 		ep.Nickname = login.User.Name
 	}
+	sv := GetConnSupervisor(ep)
+	sv.Connected("连接成功")
+	var lost int32 // 非主动断开时置1，返回后由 serverSatori 重连
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
 	log.Infof("satori 连接成功，账号<%s>(%s)", pa.EndPoint.Nickname, pa.EndPoint.UserID)
@@ -148,12 +151,14 @@ func (pa *PlatformAdapterSatori) Serve() int {
 					if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 						log.Error("satori read failed:", err)
 					}
+					atomic.StoreInt32(&lost, 1)
 					if pa.CancelFunc != nil {
 						pa.CancelFunc()
 						pa.CancelFunc = nil
 					}
 					return
 				}
+				sv.Alive()
 				switch msgType {
 				case websocket.TextMessage:
 					log.Debugf("message=%s", msgData)
@@ -200,6 +205,7 @@ func (pa *PlatformAdapterSatori) Serve() int {
 			err := conn.WriteMessage(websocket.TextMessage, hb)
 			if err != nil {
 				log.Error("satori heartbeat failed:", err)
+				atomic.StoreInt32(&lost, 1)
 				if pa.CancelFunc != nil {
 					pa.CancelFunc()
 				}
@@ -208,6 +214,9 @@ func (pa *PlatformAdapterSatori) Serve() int {
 			ticker.Stop()
 			_ = conn.Close()
 			pa.conn = nil
+			if atomic.LoadInt32(&lost) == 1 {
+				return 1
+			}
 			return 0
 		}
 	}
@@ -392,13 +401,15 @@ func (pa *PlatformAdapterSatori) refreshMembers(group SatoriGuild) {
 }
 
 func (pa *PlatformAdapterSatori) DoRelogin() bool {
-	pa.Session.Parent.Logger.Infof("正在启用 satori 连接，请稍后...")
-	pa.EndPoint.State = 0
-	pa.EndPoint.Enable = false
+	pa.Session.Parent.Logger.Infof("正在重新连接 satori，请稍后...")
+	GetConnSupervisor(pa.EndPoint).Stop("重新连接")
 	if pa.CancelFunc != nil {
 		pa.CancelFunc()
 	}
-	return pa.Serve() == 0
+	pa.EndPoint.Enable = true
+	pa.DiceServing = false
+	go ServeQQ(pa.Session.Parent, pa.EndPoint)
+	return true
 }
 
 func (pa *PlatformAdapterSatori) SetEnable(enable bool) {
@@ -411,7 +422,7 @@ func (pa *PlatformAdapterSatori) SetEnable(enable bool) {
 			go ServeQQ(d, e)
 		}
 	} else {
-		e.State = 0
+		GetConnSupervisor(e).Stop("连接已停用")
 		e.Enable = false
 		if pa.CancelFunc != nil {
 			pa.CancelFunc()
@@ -545,6 +556,10 @@ func (pa *PlatformAdapterSatori) RecallMessage(ctx *MsgContext, msgID string) {
 	log.Errorf("satori %s 平台暂不支持撤回消息", pa.Platform)
 }
 
+func (pa *PlatformAdapterSatori) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapMemberKick, CapImage}
+}
+
 func (pa *PlatformAdapterSatori) post(resource string, body io.Reader) ([]byte, error) {
 	apiUrl := pa.httpUrl.String() + "/" + resource
 	client := http.Client{}
@@ -810,11 +825,9 @@ func (pa *PlatformAdapterSatori) guildRequestHandle(e *SatoriEvent) {
 	eid := e.ID.String()
 	// 邀请人在黑名单上
 	banInfo, ok := d.BanList.GetByID(uid)
-	if ok {
-		if banInfo.Rank == BanRankBanned && d.BanList.BanBehaviorRefuseInvite {
-			pa.sendGuildRequestResult(eid, false, "黑名单")
-			return
-		}
+	if d.BanList.EffectiveRank(uid) == BanRankBanned && d.BanList.BanBehaviorRefuseInvite {
+		pa.sendGuildRequestResult(eid, false, "黑名单")
+		return
 	}
 	// 信任模式，如果不是信任，又不是 master 则拒绝拉群邀请
 	isMaster := d.IsMaster(uid)
@@ -870,12 +883,9 @@ func (pa *PlatformAdapterSatori) friendRequestHandle(e *SatoriEvent) {
 
 	eid := e.ID.String()
 	// 申请人在黑名单上
-	banInfo, ok := d.BanList.GetByID(uid)
-	if ok {
-		if banInfo.Rank == BanRankBanned && d.BanList.BanBehaviorRefuseInvite {
-			pa.sendGuildRequestResult(eid, false, "为被禁止用户，准备自动拒绝")
-			return
-		}
+	if d.BanList.EffectiveRank(uid) == BanRankBanned && d.BanList.BanBehaviorRefuseInvite {
+		pa.sendGuildRequestResult(eid, false, "为被禁止用户，准备自动拒绝")
+		return
 	}
 
 	if strings.TrimSpace(d.FriendAddComment) == "" {
@@ -23,25 +23,30 @@ type PlatformAdapterSealChat struct {
 	Socket     *gowebsocket.Socket       `yaml:"-" json:"-"`
 	EchoMap    SyncMap[string, chan any] `yaml:"-" json:"-"`
 	UserID     string                    `yaml:"-" json:"-"`
-
-	Reconnecting bool `yaml:"-" json:"-"`
-	RetryTimes   int  `yaml:"-" json:"-"`
 }
 
 func (pa *PlatformAdapterSealChat) Serve() int {
 	if !strings.HasPrefix(pa.ConnectURL, "ws://") {
 		pa.ConnectURL = "ws://" + pa.ConnectURL
 	}
-	socket := gowebsocket.New(pa.ConnectURL)
-	pa.Socket = &socket
 	pa.EndPoint.Nickname = "SealChat Bot"
 	pa.EndPoint.UserID = "SEALCHAT:BOT"
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
+	pa.connect()
+	return 0
+}
+
+// connect 建立一次连接，断线后由连接守护按退避时间再次调用
+func (pa *PlatformAdapterSealChat) connect() {
+	sv := GetConnSupervisor(pa.EndPoint)
+	sv.WatchSocket(func() *gowebsocket.Socket { return pa.Socket })
+	socket := gowebsocket.New(pa.ConnectURL)
+	pa.Socket = &socket
 	pa.socketSetup()
+	sv.Connecting("正在连接SealChat")
 	socket.Connect()
-	return 0
 }
 
 func (pa *PlatformAdapterSealChat) _sendJSON(socket *gowebsocket.Socket, data any) bool {
@@ -57,12 +62,10 @@ func (pa *PlatformAdapterSealChat) _sendJSON(socket *gowebsocket.Socket, data an
 func (pa *PlatformAdapterSealChat) socketSetup() {
 	ep := pa.EndPoint
 	log := pa.Session.Parent.Logger
+	sv := GetConnSupervisor(ep)
 	socket := pa.Socket
 	socket.OnConnected = func(socket gowebsocket.Socket) {
-		pa.Reconnecting = true
-		ep.State = 2
 		ep.Enable = true
-		pa.RetryTimes = 0
 
 		d := pa.Session.Parent
 		d.LastUpdatedTime = time.Now().Unix()
@@ -76,9 +79,12 @@ func (pa *PlatformAdapterSealChat) socketSetup() {
 		})
 
 		log.Info("SealChat 已连接，正在发送身份验证信息")
-		pa.Reconnecting = false
+	}
+	socket.OnPongReceived = func(data string, socket gowebsocket.Socket) {
+		sv.Alive()
 	}
 	socket.OnTextMessage = func(message string, socket gowebsocket.Socket) {
+		sv.Alive()
 		gatewayMsg := satori.GatewayPayloadStructure2{}
 		err := json.Unmarshal([]byte(message), &gatewayMsg)
 		if len(message) == 0 {
@@ -93,7 +99,7 @@ func (pa *PlatformAdapterSealChat) socketSetup() {
 				info := gatewayMsg.Body.(map[string]any)
 				if info["errorMsg"] != nil {
 					log.Infof("SealChat 连接失败: %s", info["errorMsg"])
-					ep.State = 3
+					sv.Failed(fmt.Sprintf("身份验证失败: %v", info["errorMsg"]))
 				} else {
 					data := struct {
 						Body struct {
@@ -107,7 +113,7 @@ func (pa *PlatformAdapterSealChat) socketSetup() {
 						pa.UserID = data.Body.User.ID
 						ep.UserID = FormatDiceIDSealChat(data.Body.User.ID)
 						ep.Nickname = data.Body.User.Nick
-						ep.State = 1
+						sv.Connected("连接成功")
 						log.Infof("SealChat 连接成功: %s", ep.Nickname)
 					}
 
@@ -117,7 +123,6 @@ func (pa *PlatformAdapterSealChat) socketSetup() {
 						pa.registerCommands()
 					}()
 				}
-				// TODO: 心跳
 				solved = true
 			case satori.OpEvent:
 				pa.dispatchMessage(message)
@@ -141,50 +146,18 @@ func (pa *PlatformAdapterSealChat) socketSetup() {
 	}
 	socket.OnConnectError = func(err error, socket gowebsocket.Socket) {
 		log.Errorf("SealChat websocket出现错误: %s", err)
-		if !socket.IsConnected && !pa.Reconnecting {
-			// socket.Close()
-			if !pa.tryReconnect(*pa.Socket) {
-				log.Errorf("短时间内连接失败次数过多，不再进行重连")
-				ep.State = 3
-			}
-		}
+		sv.Lost("连接失败: "+err.Error(), pa.connect)
 	}
 	socket.OnDisconnected = func(err error, socket gowebsocket.Socket) {
-		log.Errorf("与SealChat服务器断开连接")
-		time.Sleep(time.Duration(2) * time.Second)
-		if !pa.tryReconnect(*pa.Socket) {
-			log.Errorf("尝试进行重连")
-			ep.State = 3
+		if pa.Socket != nil && socket.Conn != pa.Socket.Conn {
+			return // 已被替换的旧连接
 		}
+		log.Errorf("与SealChat服务器断开连接")
+		sv.Lost("连接断开", pa.connect)
 	}
 	pa.Socket = socket
 }
 
-func (pa *PlatformAdapterSealChat) tryReconnect(socket gowebsocket.Socket) bool {
-	log := pa.Session.Parent.Logger
-	if pa.Reconnecting {
-		return false
-	}
-	pa.Reconnecting = true
-	pa.RetryTimes = 0
-	allTimes := 500
-	for pa.RetryTimes <= allTimes && !socket.IsConnected {
-		if !pa.EndPoint.Enable {
-			return false
-		}
-
-		pa.RetryTimes++
-		log.Infof("尝试重新连接SealChat中[%d/%d]", pa.RetryTimes, allTimes)
-		socket = gowebsocket.New(pa.ConnectURL)
-		pa.Socket = &socket
-		pa.socketSetup()
-		socket.Connect()
-		time.Sleep(time.Duration(10) * time.Second)
-	}
-	pa.Reconnecting = false
-	return true
-}
-
 func (pa *PlatformAdapterSealChat) GetGroupInfoAsync(_ string) {}
 
 func FormatDiceIDSealChat(id string) string {
@@ -201,48 +174,30 @@ func FormatDiceIDSealChatGroup(id string) string {
 
 func (pa *PlatformAdapterSealChat) DoRelogin() bool {
 	log := pa.Session.Parent.Logger
-	pa.Reconnecting = true
-	if pa.Socket != nil {
-		pa.Socket.Close()
-	}
-
-	socket := gowebsocket.New(pa.ConnectURL)
+	pa.closeSocket("重新连接")
 	log.Infof("SealChat 重新连接")
-	pa.Socket = &socket
-	pa.socketSetup()
-	socket.Connect()
-	pa.Reconnecting = false
+	pa.EndPoint.Enable = true
+	pa.connect()
 	return true
 }
 
 func (pa *PlatformAdapterSealChat) SetEnable(enable bool) {
 	log := pa.Session.Parent.Logger
+	pa.closeSocket("连接已停用")
 	if enable {
 		pa.EndPoint.Enable = true
 		log.Infof("Sealchat 连接中")
-		if pa.Socket != nil && pa.Socket.IsConnected {
-			pa.Reconnecting = true
-			pa.Socket.Close()
-			socket := gowebsocket.New(pa.ConnectURL)
-			pa.Socket = &socket
-			pa.socketSetup()
-			socket.Connect()
-			pa.Reconnecting = false
-		} else {
-			pa.Reconnecting = true
-			socket := gowebsocket.New(pa.ConnectURL)
-			pa.Socket = &socket
-			pa.socketSetup()
-			socket.Connect()
-			pa.Reconnecting = false
-		}
+		pa.connect()
 	} else {
 		pa.EndPoint.Enable = false
-		pa.Reconnecting = true
-		if pa.Socket != nil && pa.Socket.IsConnected {
-			pa.Socket.Close()
-		}
-		pa.Reconnecting = false
+	}
+}
+
+// closeSocket 主动关闭当前连接，连接守护不会因此重连
+func (pa *PlatformAdapterSealChat) closeSocket(reason string) {
+	GetConnSupervisor(pa.EndPoint).Stop(reason)
+	if pa.Socket != nil && pa.Socket.IsConnected {
+		pa.Socket.Close()
 	}
 }
 
@@ -401,6 +356,10 @@ func (pa *PlatformAdapterSealChat) dispatchMessage(msg string) {
 	}
 }
 
+func (pa *PlatformAdapterSealChat) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapSetGroupCardName}
+}
+
 func (pa *PlatformAdapterSealChat) toStdMessage(scMsg *satori.Message) *Message {
 	msg := new(Message)
 
@@ -2,6 +2,7 @@ package dice
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -25,37 +26,90 @@ type PlatformAdapterSlack struct {
 	// msgCache  *SyncMap[string, int]
 }
 
+var errSlackInvalidAuth = errors.New("鉴权失败，需要重新登录")
+
+// Serve 阻塞运行，连接失败时由连接守护安排重连
 func (pa *PlatformAdapterSlack) Serve() int {
+	ep := pa.EndPoint
+	log := pa.Session.Parent.Logger
+	if pa.cancel != nil {
+		pa.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pa.cancel = cancel
+
+	sv := GetConnSupervisor(ep)
+	ep.Enable = true
+	run := sv.Connecting("正在连接 Slack")
+	for {
+		err := pa.runOnce(ctx)
+		if ctx.Err() != nil {
+			return 0
+		}
+		if errors.Is(err, errSlackInvalidAuth) {
+			log.Errorf("Slack 账号 <%s> %v", ep.UserID, err)
+			sv.Failed(err.Error())
+			return 1
+		}
+		if !sv.Wait(run, "连接失败: "+err.Error()) {
+			return 1
+		}
+		run = sv.Connecting("正在重新连接")
+	}
+}
+
+// runOnce 建立一次 Socket Mode 连接。库自带的连接重试会被打断，改由连接守护按统一的退避策略重连；
+// Slack 要求的例行断开重连仍由库立即完成
+func (pa *PlatformAdapterSlack) runOnce(ctx context.Context) error {
 	ep := pa.EndPoint
 	s := pa.Session
 	log := s.Parent.Logger
+	sv := GetConnSupervisor(ep)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errc := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case errc <- err:
+		default:
+		}
+		cancel()
+	}
+
 	api := slack.New(pa.BotToken, slack.OptionAppLevelToken(pa.AppToken))
 	client := sm.New(api)
 	sh := sm.NewSocketmodeHandler(client)
 	// Connect
 	sh.Handle(sm.EventTypeConnecting, func(event *sm.Event, client *sm.Client) {
-		ep.State = 2
 		log.Info("使用 Socket Mode/套接字模式 连接到 Slack 中")
 	})
 	sh.Handle(sm.EventTypeConnected, func(event *sm.Event, client *sm.Client) {
 		test, err := api.AuthTest()
 		if err != nil {
 			log.Error("Slack 测试连接失败，需要重新登录：", err.Error())
+			fail(fmt.Errorf("%w: %v", errSlackInvalidAuth, err))
 			return
 		}
 		log.Infof("Slack 连接成功：账号<%s>(%s)", test.User, FormatDiceIDSlack(test.UserID))
 		pa.EndPoint.UserID = FormatDiceIDSlack(test.UserID)
 		pa.EndPoint.Nickname = test.User
-		ep.State = 1
 		ep.Enable = true
+		sv.Connected("连接成功")
 	})
 	sh.Handle(sm.EventTypeConnectionError, func(event *sm.Event, client *sm.Client) {
-		ep.State = 0
 		log.Errorf("Slack 账号 <%s> 连接失败: %v", pa.EndPoint.UserID, event.Data)
+		if e, ok := event.Data.(*slack.ConnectionErrorEvent); ok && e.ErrorObj != nil {
+			fail(e.ErrorObj)
+			return
+		}
+		fail(fmt.Errorf("%v", event.Data))
+	})
+	sh.Handle(sm.EventTypeInvalidAuth, func(event *sm.Event, client *sm.Client) {
+		fail(errSlackInvalidAuth)
 	})
 	sh.Handle(sm.EventTypeDisconnect, func(event *sm.Event, client *sm.Client) {
-		ep.State = 0
 		log.Errorf("Slack 账号 <%s> 连接断开：%v", pa.EndPoint.UserID, event.Data)
+		sv.Dropped("连接断开，正在重连")
 	})
 	sh.HandleEvents(se.AppMention, func(event *sm.Event, client *sm.Client) {
 		go client.Ack(*event.Request)
@@ -147,14 +201,27 @@ func (pa *PlatformAdapterSlack) Serve() int {
 	})
 	// Start
 	pa.Client = client
-	ctx, cancel := context.WithCancel(context.Background())
-	pa.cancel = cancel
 	err := sh.RunEventLoopContext(ctx)
-	if err != nil {
-		log.Error("SlackEventLoopErr：", err.Error())
-		return 1
+	select {
+	case e := <-errc:
+		return e
+	default:
 	}
-	return 0
+	switch {
+	case err == nil:
+		return errors.New("连接已关闭")
+	case slackAuthErrors[err.Error()]:
+		return fmt.Errorf("%w: %v", errSlackInvalidAuth, err)
+	}
+	return err
+}
+
+// 库在这些错误时直接放弃连接
+var slackAuthErrors = map[string]bool{
+	"invalid_auth":     true,
+	"account_inactive": true,
+	"not_authed":       true,
+	"token_revoked":    true,
 }
 
 func (pa *PlatformAdapterSlack) SendFileToPerson(ctx *MsgContext, userID string, path string, flag string) {
@@ -181,9 +248,9 @@ func (pa *PlatformAdapterSlack) DoRelogin() bool {
 	if pa.cancel != nil {
 		pa.cancel()
 	}
+	GetConnSupervisor(pa.EndPoint).Stop("重新登录")
 	pa.Client = nil
 	pa.EndPoint.Enable = false
-	pa.EndPoint.State = 0
 	go pa.Serve()
 	return true
 }
@@ -201,9 +268,9 @@ func (pa *PlatformAdapterSlack) SetEnable(enable bool) {
 		if pa.cancel != nil {
 			pa.cancel()
 		}
+		GetConnSupervisor(pa.EndPoint).Stop("连接已停用")
 		pa.Client = nil
 		pa.EndPoint.Enable = false
-		pa.EndPoint.State = 0
 	}
 }
 
@@ -273,6 +340,10 @@ func (pa *PlatformAdapterSlack) send(_ *MsgContext, id string, text string, _ st
 	}
 }
 
+func (pa *PlatformAdapterSlack) Capabilities() []AdapterCapability {
+	return nil
+}
+
 func (pa *PlatformAdapterSlack) getUser(user string) *slack.User {
 	if pa.userCache == nil {
 		pa.userCache = new(SyncMap[string, *slack.User])
@@ -55,6 +55,8 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 
 	var bot *tgbotapi.BotAPI
 	var err error
+	sv := GetConnSupervisor(ep)
+	run := sv.Connecting("正在连接Telegram")
 
 	if len(pa.ProxyURL) > 0 {
 		var u *url.URL
@@ -69,7 +71,7 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 	}
 	if err != nil {
 		pa.Session.Parent.Logger.Errorf("与Telegram服务进行连接时出错:%s", err.Error())
-		ep.State = 3
+		sv.Failed("连接失败: " + err.Error())
 		ep.Enable = false
 		d := pa.Session.Parent
 		d.LastUpdatedTime = time.Now().Unix()
@@ -80,8 +82,8 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 	pa.IntentSession = bot
 	ep.UserID = FormatDiceIDTelegram(strconv.FormatInt(bot.Self.ID, 10))
 	ep.Nickname = bot.Self.UserName
-	ep.State = 1
 	ep.Enable = true
+	sv.Connected("连接成功")
 	d := pa.Session.Parent
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
@@ -91,7 +93,7 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 	updateConfig := tgbotapi.NewUpdate(0)
 	updateConfig.Timeout = 30
 
-	updates := bot.GetUpdatesChan(updateConfig)
+	updates := pa.pollUpdates(bot, updateConfig, run)
 	pa.ActiveTime = time.Now()
 
 	go func() {
@@ -122,6 +124,11 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 				continue
 			}
 
+			if update.CallbackQuery != nil {
+				go pa.onCallbackQuery(update.CallbackQuery)
+				continue
+			}
+
 			if update.Message == nil {
 				continue
 			}
@@ -160,6 +167,25 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 	return 0
 }
 
+// onCallbackQuery 处理内联按钮点击，按点击者发送了按钮上的指令来执行
+func (pa *PlatformAdapterTelegram) onCallbackQuery(cq *tgbotapi.CallbackQuery) {
+	bot := pa.IntentSession
+	cmd, ok := buttonDataDecode(cq.Data)
+	if !ok || cq.Message == nil || cq.From == nil {
+		_, _ = bot.Request(tgbotapi.NewCallback(cq.ID, "按钮已过期"))
+		return
+	}
+	_, _ = bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	msg := pa.toStdMessage(&tgbotapi.Message{
+		MessageID: cq.Message.MessageID,
+		From:      cq.From,
+		Chat:      cq.Message.Chat,
+		Date:      int(time.Now().Unix()),
+		Text:      cmd,
+	})
+	pa.Session.Execute(pa.EndPoint, msg, false)
+}
+
 func (pa *PlatformAdapterTelegram) groupNewMember(msg *Message, msgRaw *tgbotapi.Message, member *tgbotapi.User) {
 	ucache := &pa.Session.Parent.Parent.UserIDCache
 	logger := pa.Session.Parent.Logger
@@ -345,6 +371,39 @@ func (pa *PlatformAdapterTelegram) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterTelegram) MemberKick(_ string, _ string) {}
 
+// pollUpdates 代替 GetUpdatesChan，拉取失败时由连接守护安排重试
+func (pa *PlatformAdapterTelegram) pollUpdates(bot *tgbotapi.BotAPI, config tgbotapi.UpdateConfig, run int) tgbotapi.UpdatesChannel {
+	sv := GetConnSupervisor(pa.EndPoint)
+	ch := make(chan tgbotapi.Update, bot.Buffer)
+	go func() {
+		defer close(ch)
+		for pa.IntentSession == bot {
+			updates, err := bot.GetUpdates(config)
+			if pa.IntentSession != bot {
+				return
+			}
+			if err != nil {
+				if !sv.Wait(run, "拉取消息失败: "+err.Error()) {
+					return
+				}
+				run = sv.Connecting("正在重新连接")
+				continue
+			}
+			if pa.EndPoint.State != ConnStateConnected {
+				sv.Connected("连接恢复")
+			}
+			sv.Alive()
+			for _, update := range updates {
+				if update.UpdateID >= config.Offset {
+					config.Offset = update.UpdateID + 1
+					ch <- update
+				}
+			}
+		}
+	}()
+	return ch
+}
+
 func (pa *PlatformAdapterTelegram) DoRelogin() bool {
 	pa.Session.Parent.Logger.Infof("正在启用Telegram服务……")
 	if pa.IntentSession == nil {
@@ -355,6 +414,7 @@ func (pa *PlatformAdapterTelegram) DoRelogin() bool {
 		pa.IntentSession.StopReceivingUpdates()
 		pa.IntentSession = nil
 	}
+	GetConnSupervisor(pa.EndPoint).Stop("重新登录")
 	go pa.Serve()
 	return true
 }
@@ -371,13 +431,14 @@ func (pa *PlatformAdapterTelegram) SetEnable(enable bool) {
 			pa.IntentSession.StopReceivingUpdates()
 			pa.IntentSession = nil
 		}
+		GetConnSupervisor(ep).Stop("重新连接")
 		go pa.Serve()
 	} else {
 		if pa.IntentSession != nil {
 			pa.IntentSession.StopReceivingUpdates()
 			pa.IntentSession = nil
 		}
-		pa.EndPoint.State = 0
+		GetConnSupervisor(ep).Stop("连接已停用")
 		ep.Enable = false
 	}
 }
@@ -464,6 +525,10 @@ type RequestFileDataImpl struct {
 	File   string
 }
 
+func (pa *PlatformAdapterTelegram) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapQuitGroup, CapSendFile, CapButton, CapImage}
+}
+
 func (r *RequestFileDataImpl) NeedsUpload() bool {
 	return true
 }
@@ -479,6 +544,7 @@ func (pa *PlatformAdapterTelegram) SendToChatRaw(uid string, text string) {
 	elem := message.ConvertStringMessage(text)
 	msg := tgbotapi.NewMessage(id, "")
 	var err error
+	var buttons []*message.ButtonElement
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
@@ -527,12 +593,29 @@ func (pa *PlatformAdapterTelegram) SendToChatRaw(uid string, text string) {
 				break
 			}
 			msg.BaseChat.ReplyToMessageID = int(parseInt)
+		case *message.ButtonElement:
+			buttons = append(buttons, e)
 		}
 		if err != nil {
 			pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
 			return
 		}
 	}
+	if len(buttons) > 0 {
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, row := range buttonRows(buttons, 3) {
+			var line []tgbotapi.InlineKeyboardButton
+			for _, b := range row {
+				line = append(line, tgbotapi.NewInlineKeyboardButtonData(b.Label, buttonDataEncode(b.Command, 64)))
+			}
+			rows = append(rows, line)
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+		if msg.Text == "" {
+			// 按钮必须依附于一条消息
+			msg.Text = "▸"
+		}
+	}
 	if msg.Text != "" {
 		_, err = bot.Send(msg)
 	}
@@ -0,0 +1,28 @@
+package dice
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEndPointCapabilities(t *testing.T) {
+	ep := &EndPointInfo{Adapter: &PlatformAdapterMinecraft{}}
+	if ep.Can(CapMemberKick) {
+		t.Fatal("minecraft adapter should not support kick")
+	}
+	caps := ep.Capabilities()
+	if len(caps) != len(AdapterCapabilityList) {
+		t.Fatalf("capability matrix should list every capability, got %d", len(caps))
+	}
+	if err := MemberKick(&MsgContext{EndPoint: ep}, "g", "u"); !errors.Is(err, ErrCapabilityUnsupported) {
+		t.Fatalf("want ErrCapabilityUnsupported, got %v", err)
+	}
+
+	ep = &EndPointInfo{Adapter: &PlatformAdapterDiscord{}}
+	if !ep.Can(CapEditMessage) || !ep.Capabilities()[CapButton] || ep.Can(CapMemberBan) {
+		t.Fatal("unexpected discord capabilities")
+	}
+	if (*EndPointInfo)(nil).Can(CapQuitGroup) {
+		t.Fatal("nil endpoint supports nothing")
+	}
+}
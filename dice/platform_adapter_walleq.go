@@ -184,8 +184,10 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 	socket := gowebsocket.New(pa.ConnectURL)
 	pa.Socket = &socket
 
+	sv := GetConnSupervisor(ep)
+	sv.WatchSocket(func() *gowebsocket.Socket { return pa.Socket })
 	socket.OnConnected = func(socket gowebsocket.Socket) {
-		ep.State = 1
+		sv.Connected("连接成功")
 		log.Info("onebot 连接成功")
 	}
 
@@ -205,6 +207,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 	// tempFriendInviteSent := map[string]int64{}     // gocq会重新发送已经发过的邀请
 
 	socket.OnTextMessage = func(message string, socket gowebsocket.Socket) {
+		sv.Alive()
 		fmt.Println(message)
 		event := new(EventWalleQBase)
 		err := json.Unmarshal([]byte(message), event)
@@ -404,12 +407,9 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 				if event.UserID == event.Self.UserID {
 					skip := false
 					skipReason := ""
-					banInfo, ok := ctx.Dice.BanList.GetByID(opUID)
-					if ok {
-						if banInfo.Rank == 30 {
-							skip = true
-							skipReason = "信任用户"
-						}
+					if ctx.Dice.BanList.EffectiveRank(opUID) == BanRankTrusted {
+						skip = true
+						skipReason = "信任用户"
 					}
 					if ctx.Dice.IsMaster(opUID) {
 						skip = true
@@ -426,6 +426,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 					txt := fmt.Sprintf("被踢出群: 在QQ群组<%s>(%s)中被踢出，操作者:<%s>(%s)%s", groupName, event.GroupID, userName, n.OperatorID, extra)
 					log.Info(txt)
 					ctx.Notice(txt)
+					pa.Session.OnGroupLeft(pa.EndPoint, msg.GroupID, opUID, true)
 				}
 			case "group_member_ban": // 被禁言
 				if event.UserID == event.Self.UserID {
@@ -510,16 +511,13 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 				// 检查黑名单
 				extra := ""
 				uid := msg.Sender.UserID
-				banInfo, ok := ctx.Dice.BanList.GetByID(uid)
-				if ok {
-					if banInfo.Rank == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
-						if willAccept {
-							extra = "。回答正确，但为被禁止用户，准备自动拒绝"
-						} else {
-							extra = "。回答错误，且为被禁止用户，准备自动拒绝"
-						}
-						willAccept = false
+				if ctx.Dice.BanList.EffectiveRank(uid) == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
+					if willAccept {
+						extra = "。回答正确，但为被禁止用户，准备自动拒绝"
+					} else {
+						extra = "。回答错误，且为被禁止用户，准备自动拒绝"
 					}
+					willAccept = false
 				}
 
 				if pa.IgnoreFriendRequest {
@@ -565,11 +563,9 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 
 				// 邀请人在黑名单上
 				banInfo, ok := ctx.Dice.BanList.GetByID(uid)
-				if ok {
-					if banInfo.Rank == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
-						pa.SetGroupAddRequest(req.RequestID, event.GroupID, false)
-						return
-					}
+				if ctx.Dice.BanList.EffectiveRank(uid) == BanRankBanned && ctx.Dice.BanList.BanBehaviorRefuseInvite {
+					pa.SetGroupAddRequest(req.RequestID, event.GroupID, false)
+					return
 				}
 				// 信任模式，如果不是信任，又不是master则拒绝拉群邀请
 				isMaster := ctx.Dice.IsMaster(uid)
@@ -691,6 +687,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 	}
 
 	socket.OnPongReceived = func(data string, socket gowebsocket.Socket) {
+		sv.Alive()
 		log.Debug("Recieved pong " + data)
 	}
 
@@ -966,6 +963,10 @@ func (pa *PlatformAdapterWalleQ) waitGroupMemberInfoEcho(echo string, beforeWait
 	return <-ch
 }
 
+func (pa *PlatformAdapterWalleQ) Capabilities() []AdapterCapability {
+	return []AdapterCapability{CapMemberBan, CapMemberKick, CapQuitGroup}
+}
+
 // func (pa *PlatformAdapterWalleQ) waitEcho2(echo string, value interface{}, beforeWait func(emi *echoMapInfo)) error {
 //	if pa.echoMap2 == nil {
 //		pa.echoMap2 = InitializeSyncMap[string, *echoMapInfo]()
@@ -0,0 +1,519 @@
+package dice
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+	rand2 "golang.org/x/exp/rand"
+)
+
+// 可切换的随机源：群内用 .set rng 选择。
+// dicescript只接受PCGSource，所以每条指令开始时生成一个随机流，v1与v2共用；
+// 物理骰比较特殊，不提供随机流，而是在求值时把骰子替换为玩家录入的点数。
+
+// RandSource 随机源
+type RandSource interface {
+	Name() string
+	Brief() string
+	// Begin 在指令执行前调用，返回本条指令使用的随机流，返回nil表示沿用默认随机流
+	Begin(ctx *MsgContext) *rand2.PCGSource
+}
+
+var randSources = []RandSource{
+	&randSourceDefault{},
+	&randSourceCrypto{},
+	&randSourceSeeded{},
+	&randSourcePhysical{},
+}
+
+// RandSourceRegister 注册随机源，同名的会被替换，应在启动时调用
+func RandSourceRegister(s RandSource) {
+	for i, item := range randSources {
+		if item.Name() == s.Name() {
+			randSources[i] = s
+			return
+		}
+	}
+	randSources = append(randSources, s)
+}
+
+// RandSourceFind 按名字查找随机源
+func RandSourceFind(name string) RandSource {
+	for _, item := range randSources {
+		if strings.EqualFold(item.Name(), name) {
+			return item
+		}
+	}
+	return nil
+}
+
+type randSourceDefault struct{}
+
+func (s *randSourceDefault) Name() string {
+	return "default"
+}
+
+func (s *randSourceDefault) Brief() string {
+	return "默认随机源"
+}
+
+func (s *randSourceDefault) Begin(_ *MsgContext) *rand2.PCGSource {
+	return nil
+}
+
+type randSourceCrypto struct{}
+
+func (s *randSourceCrypto) Name() string {
+	return "crypto"
+}
+
+func (s *randSourceCrypto) Brief() string {
+	return "每条指令以系统密码学随机数重新播种"
+}
+
+func (s *randSourceCrypto) Begin(_ *MsgContext) *rand2.PCGSource {
+	seed := make([]byte, 16)
+	if _, err := rand.Read(seed); err != nil {
+		return nil
+	}
+	return FairRollDeriveSource(seed, 0)
+}
+
+type randSourceSeeded struct{}
+
+var randSeedLocks sync.Map // 群号 -> *sync.Mutex
+
+// randSeedLockFor 群的种子计数锁，同群并发的指令不能取到同一个计数，否则无法重现
+func randSeedLockFor(groupID string) *sync.Mutex {
+	v, _ := randSeedLocks.LoadOrStore(groupID, &sync.Mutex{})
+	return v.(*sync.Mutex)
+}
+
+func (s *randSourceSeeded) Name() string {
+	return "seeded"
+}
+
+func (s *randSourceSeeded) Brief() string {
+	return "由种子和指令计数推导，相同种子可以重现整场掷骰"
+}
+
+func (s *randSourceSeeded) Begin(ctx *MsgContext) *rand2.PCGSource {
+	group := ctx.Group
+	lock := randSeedLockFor(group.GroupID)
+	lock.Lock()
+	defer lock.Unlock()
+	src := FairRollDeriveSource([]byte(group.RandSeed), group.RandSeedCounter)
+	group.RandSeedCounter++
+	group.UpdatedAtTime = time.Now().Unix()
+	return src
+}
+
+type randSourcePhysical struct{}
+
+func (s *randSourcePhysical) Name() string {
+	return "physical"
+}
+
+func (s *randSourcePhysical) Brief() string {
+	return "玩家投掷实体骰子，再用 .face 录入点数"
+}
+
+func (s *randSourcePhysical) Begin(ctx *MsgContext) *rand2.PCGSource {
+	pd := &physicalDiceState{}
+	key := physicalDiceKey(ctx.randSource.msg)
+	if p, ok := physicalDicePendingMap.Load(key); ok && p.Faces != nil {
+		physicalDicePendingMap.Delete(key)
+		pd.Faces = p.Faces
+	}
+	ctx.randSource.physical = pd
+	return nil
+}
+
+// randSourceState 一条指令执行期间的随机源状态
+type randSourceState struct {
+	msg      *Message
+	src      *rand2.PCGSource
+	physical *physicalDiceState
+
+	oldV1Rand *rand2.PCGSource
+	oldVMRand *rand2.PCGSource
+}
+
+// randSourceBegin 在指令执行前切换为群内设置的随机源，公平骰开启时不生效
+func (ctx *MsgContext) randSourceBegin(msg *Message) *randSourceState {
+	if ctx.Group == nil || ctx.Group.RandSource == "" || ctx.fairRoll != nil || ctx.randSource != nil {
+		return nil
+	}
+	rs := RandSourceFind(ctx.Group.RandSource)
+	if rs == nil {
+		return nil
+	}
+
+	ctx.CreateVmIfNotExists()
+	st := &randSourceState{msg: msg, oldV1Rand: ctx._v1Rand, oldVMRand: ctx.vm.RandSrc}
+	ctx.randSource = st
+	st.src = rs.Begin(ctx)
+	if st.src == nil && st.physical == nil {
+		ctx.randSource = nil
+		return nil
+	}
+	if st.src != nil {
+		ctx._v1Rand = st.src
+		ctx.vm.RandSrc = st.src
+	}
+	return st
+}
+
+// randSourceEnd 指令执行完毕，还原随机流。物理骰缺少点数时提示玩家投掷
+func (ctx *MsgContext) randSourceEnd(st *randSourceState) {
+	if st == nil {
+		return
+	}
+	ctx.randSource = nil
+	ctx._v1Rand = st.oldV1Rand
+	if ctx.vm != nil {
+		ctx.vm.RandSrc = st.oldVMRand
+	}
+
+	pd := st.physical
+	if pd == nil || !pd.Waiting || st.msg == nil {
+		return
+	}
+	m := *st.msg
+	physicalDicePendingMap.Store(physicalDiceKey(&m), &physicalDicePending{
+		Msg:   &m,
+		Count: pd.count,
+		Time:  time.Now().Unix(),
+	})
+	dice := append(append([]string{}, pd.Used...), pd.Need...)
+	text := fmt.Sprintf("<%s>请投掷 %s (共%d个点数)，然后发送 .face <点数...> 按顺序录入", ctx.Player.Name, strings.Join(dice, "、"), pd.count)
+	if pd.Invalid != "" {
+		text = pd.Invalid + "\n" + text
+	}
+	ReplyToSender(ctx, &m, text)
+}
+
+// randSourcePause 暂停随机源，用于概率模拟等场合，返回恢复函数
+func (ctx *MsgContext) randSourcePause() func() {
+	st := ctx.randSource
+	if st == nil {
+		return func() {}
+	}
+	ctx.randSource = nil
+	ctx._v1Rand = st.oldV1Rand
+	if ctx.vm != nil {
+		ctx.vm.RandSrc = st.oldVMRand
+	}
+	return func() {
+		ctx.randSource = st
+		if st.src != nil {
+			ctx._v1Rand = st.src
+			if ctx.vm != nil {
+				ctx.vm.RandSrc = st.src
+			}
+		}
+	}
+}
+
+// physicalDiceWaiting 当前指令正在等待录入点数，此时不应发出回复
+func (ctx *MsgContext) physicalDiceWaiting() bool {
+	return ctx.randSource != nil && ctx.randSource.physical != nil && ctx.randSource.physical.Waiting
+}
+
+var errPhysicalDiceWaiting = errors.New("等待录入物理骰点数")
+
+// physicalDiceState 物理骰模式下一条指令的点数消耗情况
+type physicalDiceState struct {
+	Faces   []int64  // 玩家录入的点数
+	Used    []string // 已取得点数的骰子，如3d6
+	Need    []string // 缺少点数的骰子
+	Invalid string   // 录入的点数不合法时的说明
+	Waiting bool
+
+	pos     int
+	count   int  // 本条指令总共需要的点数个数
+	probing bool // 正在试算表达式，此时照常使用随机数
+}
+
+// take 按顺序取n个sides面骰的点数，点数不足或不合法时转为等待
+func (pd *physicalDiceState) take(n, sides int64) ([]int64, bool) {
+	desc := fmt.Sprintf("%dd%d", n, sides)
+	pd.count += int(n)
+	if pd.Waiting || int64(len(pd.Faces)-pd.pos) < n {
+		pd.Waiting = true
+		pd.Need = append(pd.Need, desc)
+		return nil, false
+	}
+	faces := pd.Faces[pd.pos : pd.pos+int(n)]
+	for _, v := range faces {
+		if v < 1 || v > sides {
+			pd.Waiting = true
+			pd.Invalid = fmt.Sprintf("点数%d不是%s的合法结果", v, desc)
+			pd.Need = append(pd.Need, desc)
+			return nil, false
+		}
+	}
+	pd.pos += int(n)
+	pd.Used = append(pd.Used, desc)
+	return faces, true
+}
+
+// physicalDicePending 等待录入点数的指令
+type physicalDicePending struct {
+	Msg   *Message
+	Count int
+	Faces []int64
+	Time  int64
+}
+
+const physicalDicePendingTimeout = 10 * 60
+
+var physicalDicePendingMap = new(SyncMap[string, *physicalDicePending])
+
+func physicalDiceKey(msg *Message) string {
+	return msg.GroupID + "|" + msg.Sender.UserID
+}
+
+var physicalDiceTermRe = regexp.MustCompile(`(?i)^(\d*)d(\d*)(?:(kh|kl|dh|dl|k|q)(\d*))?$`)
+
+type physicalDiceReplace struct {
+	begin, end int
+	text       string
+}
+
+// physicalDiceRun 先试算出表达式中的骰子，将其替换为录入的点数后再正式求值
+func (ctx *MsgContext) physicalDiceRun(vm *ds.Context, expr string) error {
+	pd := ctx.randSource.physical
+	fail := func(err error) error {
+		vm.Ret = nil
+		vm.Error = err
+		return err
+	}
+
+	probe, drew := ctx.physicalDiceProbe(vm, expr)
+	if probe.Error != nil || !drew {
+		// 语法错误或不涉及随机数，照常执行
+		return vm.Run(expr)
+	}
+
+	spans := make([]ds.BufferSpan, 0, len(probe.DetailSpans))
+	for _, span := range probe.DetailSpans {
+		if strings.HasPrefix(span.Tag, "dice") {
+			spans = append(spans, span)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Begin < spans[j].Begin })
+
+	var reps []physicalDiceReplace
+	lastEnd := 0
+	for _, span := range spans {
+		begin, end := int(span.Begin), int(span.End)
+		if begin < lastEnd || end > len(expr) || begin >= end {
+			return fail(fmt.Errorf("物理骰模式不支持嵌套的骰子表达式"))
+		}
+		src := expr[begin:end]
+		m := physicalDiceTermRe.FindStringSubmatch(src)
+		if span.Tag != "dice" || m == nil {
+			return fail(fmt.Errorf("物理骰模式不支持: %s", src))
+		}
+		n := int64(1)
+		if m[1] != "" {
+			n, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		sidesText := m[2]
+		if sidesText == "" {
+			sidesText = vm.Config.DefaultDiceSideExpr
+		}
+		sides, _ := strconv.ParseInt(sidesText, 10, 64)
+		if sides < 1 || n < 1 || int64(len(diceStatNumRe.FindAllString(span.Text, -1))) != n {
+			return fail(fmt.Errorf("物理骰模式不支持: %s", src))
+		}
+
+		faces, ok := pd.take(n, sides)
+		if !ok {
+			lastEnd = end
+			continue
+		}
+		x := int64(1)
+		if m[4] != "" {
+			x, _ = strconv.ParseInt(m[4], 10, 64)
+		}
+		kept := physicalDiceKeep(faces, strings.ToLower(m[3]), x)
+		items := make([]string, 0, len(kept))
+		for _, v := range kept {
+			items = append(items, strconv.FormatInt(v, 10))
+		}
+		if len(items) == 0 {
+			items = append(items, "0")
+		}
+		reps = append(reps, physicalDiceReplace{begin, end, "(" + strings.Join(items, "+") + ")"})
+		lastEnd = end
+	}
+	if pd.Waiting {
+		return fail(errPhysicalDiceWaiting)
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for _, r := range reps {
+		sb.WriteString(expr[pos:r.begin])
+		sb.WriteString(r.text)
+		pos = r.end
+	}
+	sb.WriteString(expr[pos:])
+	rewritten := sb.String()
+
+	// 替换后仍会消耗随机数，说明有无法识别的骰子
+	if _, drew = ctx.physicalDiceProbe(vm, rewritten); drew {
+		return fail(fmt.Errorf("物理骰模式不支持该表达式: %s", expr))
+	}
+	err := vm.Run(rewritten)
+
+	// 还原匹配到的原始文本，以便回复中显示玩家输入的表达式
+	if strings.HasPrefix(rewritten, vm.Matched) {
+		n := len(vm.Matched)
+		shift := 0
+		for _, r := range reps {
+			if r.begin+shift >= n {
+				break
+			}
+			shift += len(r.text) - (r.end - r.begin)
+		}
+		if n-shift >= 0 && n-shift <= len(expr) {
+			vm.Matched = expr[:n-shift]
+		}
+	}
+	return err
+}
+
+// physicalDiceProbe 在沙盒中试算表达式，返回试算用的vm以及是否消耗了随机数
+func (ctx *MsgContext) physicalDiceProbe(vm *ds.Context, expr string) (*ds.Context, bool) {
+	probe := ds.NewVM()
+	probe.Config = vm.Config
+	probe.Config.HookFuncValueStore = nil
+	probe.Config.CallbackSt = nil
+	probe.GlobalValueLoadFunc = vm.GlobalValueLoadFunc
+	probe.GlobalValueLoadOverwriteFunc = vm.GlobalValueLoadOverwriteFunc
+	if vm.Attrs != nil {
+		probe.Attrs = &ds.ValueMap{}
+		vm.Attrs.Range(func(key string, value *ds.VMValue) bool {
+			probe.Attrs.Store(key, value)
+			return true
+		})
+	}
+
+	src := FairRollDeriveSource([]byte("physical"), 0)
+	start := *src
+	probe.RandSrc = src
+
+	pd := ctx.randSource.physical
+	pd.probing = true
+	_ = probe.Run(expr)
+	pd.probing = false
+	return probe, *src != start
+}
+
+// physicalDiceKeep 按取高/取低规则选出计入结果的点数
+func physicalDiceKeep(faces []int64, mode string, x int64) []int64 {
+	if mode == "" {
+		return faces
+	}
+	sorted := append([]int64{}, faces...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	n := int64(len(sorted))
+	if x > n {
+		x = n
+	}
+	if x < 0 {
+		x = 0
+	}
+	switch mode {
+	case "k", "kh":
+		return sorted[:x]
+	case "kl", "q":
+		return sorted[n-x:]
+	case "dh":
+		return sorted[x:]
+	case "dl":
+		return sorted[:n-x]
+	}
+	return faces
+}
+
+// physicalDiceSubmit 录入点数并重新执行等待中的指令
+func physicalDiceSubmit(ctx *MsgContext, msg *Message, faces []int64) string {
+	key := physicalDiceKey(msg)
+	p, ok := physicalDicePendingMap.Load(key)
+	if !ok || time.Now().Unix()-p.Time > physicalDicePendingTimeout {
+		physicalDicePendingMap.Delete(key)
+		return "当前没有等待录入点数的指令"
+	}
+	if len(faces) != p.Count {
+		return fmt.Sprintf("需要录入%d个点数，收到%d个", p.Count, len(faces))
+	}
+	physicalDicePendingMap.Store(key, &physicalDicePending{Msg: p.Msg, Count: p.Count, Faces: faces, Time: p.Time})
+	go ctx.Session.Execute(ctx.EndPoint, p.Msg, false)
+	return ""
+}
+
+// setRandSource 处理 .set rng，返回回复文本，以及只能私聊告知的种子信息。
+// 种子公开后之后的掷骰都可预测，因此不在群内展示
+func setRandSource(ctx *MsgContext, name, seed string) (string, string) {
+	group := ctx.Group
+	lock := randSeedLockFor(group.GroupID)
+	lock.Lock()
+	defer lock.Unlock()
+	if name == "" {
+		cur := group.RandSource
+		if cur == "" {
+			cur = "default"
+		}
+		text := fmt.Sprintf("当前随机源: %s", cur)
+		var secret string
+		if cur == "seeded" {
+			text += "\n种子已私聊发送给执行指令的管理员"
+			secret = fmt.Sprintf("群%s 的随机源种子: %s 已执行指令数: %d", group.GroupID, group.RandSeed, group.RandSeedCounter)
+		}
+		if group.FairRollOn {
+			text += "\n注意: 公平骰已开启，随机源设置暂不生效"
+		}
+		text += "\n可用的随机源:"
+		for _, item := range randSources {
+			text += fmt.Sprintf("\n%s - %s", item.Name(), item.Brief())
+		}
+		return text, secret
+	}
+
+	rs := RandSourceFind(name)
+	if rs == nil {
+		return fmt.Sprintf("未知的随机源: %s，可用 .set rng 查看列表", name), ""
+	}
+	group.RandSource = rs.Name()
+	if rs.Name() == "default" {
+		group.RandSource = ""
+	}
+	text := fmt.Sprintf("已切换随机源为: %s", rs.Name())
+	var secret string
+	if rs.Name() == "seeded" {
+		if seed == "" {
+			buf := make([]byte, 8)
+			_, _ = rand.Read(buf)
+			seed = hex.EncodeToString(buf)
+		}
+		group.RandSeed = seed
+		group.RandSeedCounter = 0
+		text += "\n种子已私聊发送给执行指令的管理员"
+		secret = fmt.Sprintf("群%s 的随机源种子: %s，使用相同种子重新设置即可重现之后的掷骰", group.GroupID, seed)
+	}
+	group.UpdatedAtTime = time.Now().Unix()
+	return text, secret
+}
@@ -0,0 +1,122 @@
+package dice
+
+import (
+	"sync"
+	"testing"
+)
+
+func randSourceSeededFirst(t *testing.T, ctx *MsgContext) uint64 {
+	src := RandSourceFind("seeded").Begin(ctx)
+	if src == nil {
+		t.Fatal("seeded source should give a stream")
+	}
+	return src.Uint64()
+}
+
+func TestRandSourceSeededReplay(t *testing.T) {
+	newCtx := func() *MsgContext {
+		return &MsgContext{Group: &GroupInfo{GroupID: "UI-Group:1"}}
+	}
+	a, b := newCtx(), newCtx()
+	setRandSource(a, "seeded", "table-seed")
+	setRandSource(b, "seeded", "table-seed")
+
+	var first []uint64
+	for i := 0; i < 5; i++ {
+		va, vb := randSourceSeededFirst(t, a), randSourceSeededFirst(t, b)
+		if va != vb {
+			t.Fatalf("command %d: same seed and counter gave %d and %d", i, va, vb)
+		}
+		first = append(first, va)
+	}
+	if first[0] == first[1] {
+		t.Fatal("each command should get its own stream")
+	}
+	if a.Group.RandSeedCounter != 5 || a.Group.UpdatedAtTime == 0 {
+		t.Fatalf("counter=%d, group should be marked dirty", a.Group.RandSeedCounter)
+	}
+
+	// 用相同种子重新设置后从头重现
+	setRandSource(a, "seeded", "table-seed")
+	if a.Group.RandSeedCounter != 0 {
+		t.Fatalf("counter should reset, got %d", a.Group.RandSeedCounter)
+	}
+	for i, want := range first {
+		if v := randSourceSeededFirst(t, a); v != want {
+			t.Fatalf("command %d after reset: %d, want %d", i, v, want)
+		}
+	}
+
+	setRandSource(b, "seeded", "other-seed")
+	if randSourceSeededFirst(t, b) == first[0] {
+		t.Fatal("a different seed should give a different stream")
+	}
+}
+
+func TestRandSourceSeededConcurrent(t *testing.T) {
+	group := &GroupInfo{GroupID: "UI-Group:2"}
+	setRandSource(&MsgContext{Group: group}, "seeded", "race")
+
+	const n = 64
+	values := make([]uint64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i] = randSourceSeededFirst(t, &MsgContext{Group: group})
+		}(i)
+	}
+	wg.Wait()
+
+	if group.RandSeedCounter != n {
+		t.Fatalf("counter=%d, want %d", group.RandSeedCounter, n)
+	}
+	seen := map[uint64]bool{}
+	for _, v := range values {
+		if seen[v] {
+			t.Fatal("two commands reused the same counter")
+		}
+		seen[v] = true
+	}
+}
+
+func TestPhysicalDiceRewrite(t *testing.T) {
+	ctx := newTestCtx(t)
+	pd := &physicalDiceState{Faces: []int64{3, 5, 1, 4, 6}}
+	ctx.randSource = &randSourceState{physical: pd}
+
+	r := ctx.Eval("2d6+3d6kh2+1", nil)
+	if r.vm.Error != nil {
+		t.Fatal(r.vm.Error)
+	}
+	// 2d6 取 3、5，3d6kh2 取 1、4、6 中的 4、6
+	if v, _ := r.ReadInt(); v != 19 {
+		t.Fatalf("result %s, want 19", r.ToString())
+	}
+	if pd.Waiting || len(pd.Used) != 2 || pd.Used[0] != "2d6" || pd.Used[1] != "3d6" {
+		t.Fatalf("unexpected state %+v", pd)
+	}
+	if r.vm.Matched != "2d6+3d6kh2+1" {
+		t.Fatalf("matched text should show the original expression, got %q", r.vm.Matched)
+	}
+
+	// 点数不足时等待录入，不使用随机数
+	pd = &physicalDiceState{Faces: []int64{2}}
+	ctx.randSource = &randSourceState{physical: pd}
+	r = ctx.Eval("d20+2d6", nil)
+	if r.vm.Error != errPhysicalDiceWaiting || !pd.Waiting || pd.count != 3 {
+		t.Fatalf("should wait for faces: %v %+v", r.vm.Error, pd)
+	}
+	if len(pd.Need) != 1 || pd.Need[0] != "2d6" {
+		t.Fatalf("need %v", pd.Need)
+	}
+
+	// 不合法的点数
+	pd = &physicalDiceState{Faces: []int64{7}}
+	ctx.randSource = &randSourceState{physical: pd}
+	_ = ctx.Eval("d6", nil)
+	if !pd.Waiting || pd.Invalid == "" {
+		t.Fatalf("7 is not a d6 face: %+v", pd)
+	}
+}
@@ -719,7 +719,10 @@ func (e *RollExpression) Evaluate(_ *Dice, ctx *MsgContext) (*VMStack, string, e
 			continue
 		case TypeDicePenalty, TypeDiceBonus:
 			t := stack[top-1]
-			diceResult := DiceRoll64x(ctx._v1Rand, 100)
+			diceResult, err := ctx.diceRoll64(100)
+			if err != nil {
+				return nil, "", err
+			}
 			diceTens := diceResult / 10
 			diceUnits := diceResult % 10
 
@@ -733,7 +736,10 @@ func (e *RollExpression) Evaluate(_ *Dice, ctx *MsgContext) (*VMStack, string, e
 			}
 
 			for i := int64(0); i < t.Value.(int64); i++ {
-				n := DiceRoll64x(ctx._v1Rand, 10)
+				n, err := ctx.diceRoll64(10)
+				if err != nil {
+					return nil, "", err
+				}
 
 				if n == 10 {
 					num10Exists = true
@@ -1042,7 +1048,11 @@ func (e *RollExpression) Evaluate(_ *Dice, ctx *MsgContext) (*VMStack, string, e
 		case TypeDiceUnary:
 			a := &stack[top-1]
 			// Dice XXX, 如 d100
-			a.Value = DiceRoll64x(ctx._v1Rand, a.Value.(int64))
+			v, err := ctx.diceRoll64(a.Value.(int64))
+			if err != nil {
+				return nil, "", err
+			}
+			a.Value = v
 			e.Calculated = true
 			continue
 		case TypeHalt:
@@ -1316,7 +1326,11 @@ func (e *RollExpression) Evaluate(_ *Dice, ctx *MsgContext) (*VMStack, string, e
 					if e.flags.BigFailDiceOn {
 						nums = append(nums, bInt)
 					} else {
-						nums = append(nums, DiceRoll64x(ctx._v1Rand, bInt))
+						v, err := ctx.diceRoll64(bInt)
+						if err != nil {
+							return nil, "", err
+						}
+						nums = append(nums, v)
 					}
 				}
 
@@ -1359,7 +1373,11 @@ func (e *RollExpression) Evaluate(_ *Dice, ctx *MsgContext) (*VMStack, string, e
 					if e.flags.BigFailDiceOn {
 						curNum = bInt
 					} else {
-						curNum = DiceRoll64x(ctx._v1Rand, bInt)
+						v, err := ctx.diceRoll64(bInt)
+						if err != nil {
+							return nil, "", err
+						}
+						curNum = v
 					}
 
 					num += curNum
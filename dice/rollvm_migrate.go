@@ -3,6 +3,7 @@ package dice
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
@@ -123,7 +124,7 @@ func (ctx *MsgContext) Eval(expr string, flags *ds.RollConfig) *VMResultV2 {
 	if flags != nil {
 		vm.Config = *flags
 	}
-	err := vm.Run(expr)
+	err := ctx.vmRun(vm, expr)
 
 	if err != nil {
 		return &VMResultV2{vm: vm}
@@ -131,6 +132,16 @@ func (ctx *MsgContext) Eval(expr string, flags *ds.RollConfig) *VMResultV2 {
 	return &VMResultV2{VMValue: *vm.Ret, vm: vm}
 }
 
+// vmRun 执行表达式，按需接入物理骰、公平骰记录和骰点统计
+func (ctx *MsgContext) vmRun(vm *ds.Context, expr string) error {
+	if ctx.randSource != nil && ctx.randSource.physical != nil && !ctx.randSource.physical.probing {
+		return ctx.physicalDiceRun(vm, expr)
+	}
+	err := ctx.fairRollTrace(vm, expr)
+	ctx.diceStatCollect(vm, expr)
+	return err
+}
+
 // EvalFString TODO: 这个名字得换一个
 func (ctx *MsgContext) EvalFString(expr string, flags *ds.RollConfig) *VMResultV2 {
 	expr = CompatibleReplace(ctx, expr)
@@ -222,9 +233,9 @@ func DiceExprEvalBase(ctx *MsgContext, s string, flags RollExtraFlags) (*VMResul
 
 	s = CompatibleReplace(ctx, s)
 
-	err := ctx.vm.Run(s)
+	err := ctx.vmRun(ctx.vm, s)
 	if err != nil || ctx.vm.Ret == nil {
-		if flags.V2Only {
+		if flags.V2Only || errors.Is(err, errPhysicalDiceWaiting) {
 			return nil, "", err
 		}
 		fmt.Println("脚本执行出错V2: ", strings.ReplaceAll(s, "\x1e", "`"), "->", err)
@@ -417,6 +428,10 @@ func (ctx *MsgContext) CreateVmIfNotExists() {
 	}
 	// 初始化骰子
 	ctx.vm = ds.NewVM()
+	if ctx._v1Rand != nil {
+		// 公平骰等场景下，v1与v2共用同一随机流
+		ctx.vm.RandSrc = ctx._v1Rand
+	}
 
 	// 根据当前规则开语法 - 暂时是都开
 	ctx.vm.Config.EnableDiceWoD = true
@@ -440,7 +455,7 @@ func (ctx *MsgContext) CreateVmIfNotExists() {
 		// 个人变量
 		if strings.HasPrefix(name, "$m") {
 			if ctx.Session != nil && ctx.Player != nil {
-				playerAttrs := lo.Must(am.LoadById(ctx.Player.UserID))
+				playerAttrs := lo.Must(am.LoadById(am.UIDConvert(ctx.Player.UserID)))
 				playerAttrs.Store(name, v)
 			}
 			return nil, true
@@ -470,7 +485,7 @@ func (ctx *MsgContext) CreateVmIfNotExists() {
 			// 个人变量
 			if strings.HasPrefix(name, "$m") {
 				if ctx.Session != nil && ctx.Player != nil {
-					playerAttrs := lo.Must(am.LoadById(ctx.Player.UserID))
+					playerAttrs := lo.Must(am.LoadById(am.UIDConvert(ctx.Player.UserID)))
 					v := playerAttrs.Load(name)
 					if v == nil {
 						return ds.NewIntVal(0)
@@ -504,7 +519,7 @@ func (ctx *MsgContext) CreateVmIfNotExists() {
 			name = ctx.SystemTemplate.GetAlias(name)
 			v, _ = ctx.SystemTemplate.GetRealValueBase(&ctx2, name)
 		} else {
-			playerAttrs := lo.Must(am.LoadById(ctx.Player.UserID))
+			playerAttrs := lo.Must(am.LoadById(am.UIDConvert(ctx.Player.UserID)))
 			v = playerAttrs.Load(name)
 		}
 
@@ -531,6 +531,10 @@ func UnpackGroupUserId(id string) (groupIdPart, userIdPart string, ok bool) {
 		"SEALCHAT-Group:":   "SEALCHAT:",
 		"SLACK-CH-Group":    "SLACK:",
 		"DINGTALK-Group":    "DINGTALK:",
+		"MATRIX-Group:":     "MATRIX:",
+		"IRC-Group:":        "IRC:",
+		"FEISHU-Group:":     "FEISHU:",
+		"HTTP-Group:":       "HTTP:",
 		"OpenQQ-Group-T:":   "OpenQQ-Member-T:",
 		"UI-Group:":         "UI:",
 	}
@@ -559,6 +563,10 @@ func UnpackGroupUserId(id string) (groupIdPart, userIdPart string, ok bool) {
 		"PG-SLACK:":    "SLACK:",
 		"PG-TG:":       "TG:",
 		"PG-DINGTALK:": "DINGTALK:",
+		"PG-MATRIX:":   "MATRIX:",
+		"PG-IRC:":      "IRC:",
+		"PG-FEISHU:":   "FEISHU:",
+		"PG-HTTP:":     "HTTP:",
 	}
 	for userPrivateGroupPrefix, userPrefix := range prefixMap2 {
 		if strings.HasPrefix(id, userPrivateGroupPrefix) {
@@ -571,5 +579,10 @@ func UnpackGroupUserId(id string) (groupIdPart, userIdPart string, ok bool) {
 		}
 	}
 
+	// 跨平台关联后的虚拟ID
+	if idx := strings.LastIndex(id, "-"+UniformIDPrefix); idx > 0 {
+		return id[:idx], id[idx+1:], true
+	}
+
 	return "", "", false
 }
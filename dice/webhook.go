@@ -0,0 +1,406 @@
+package dice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sealdice-core/dice/model"
+)
+
+// 可订阅的事件类型
+const (
+	WebhookEventRoll        = "roll"         // 指令产生的检定/骰点，附带 CommandInfo
+	WebhookEventLogStart    = "log.start"    // 开始或继续记录日志
+	WebhookEventLogEnd      = "log.end"      // 暂停或结束日志
+	WebhookEventSheetChange = "sheet.change" // 角色卡数据变动，随定期落库发出
+	WebhookEventBanChange   = "ban.change"   // 黑名单/信任名单变动
+	WebhookEventBanDelete   = "ban.delete"   // 黑名单条目删除
+	WebhookEventGroupJoin   = "group.join"   // 骰子进群
+	WebhookEventGroupLeave  = "group.leave"  // 骰子退群或被踢
+	WebhookEventConnState   = "conn.state"   // 帐号连接状态变化
+)
+
+var WebhookEventTypes = []string{
+	WebhookEventRoll,
+	WebhookEventLogStart,
+	WebhookEventLogEnd,
+	WebhookEventSheetChange,
+	WebhookEventBanChange,
+	WebhookEventBanDelete,
+	WebhookEventGroupJoin,
+	WebhookEventGroupLeave,
+	WebhookEventConnState,
+}
+
+// WebhookItem 一个订阅，Events 为空表示订阅全部事件
+type WebhookItem struct {
+	ID      string   `yaml:"id" json:"id"`
+	Name    string   `yaml:"name" json:"name"`
+	URL     string   `yaml:"url" json:"url"`
+	Secret  string   `yaml:"secret" json:"secret"`
+	Events  []string `yaml:"events" json:"events"`
+	Enable  bool     `yaml:"enable" json:"enable"`
+	Retries int      `yaml:"retries" json:"retries"` // 失败重试次数，0为默认值3，负数不重试，最多10次
+}
+
+const (
+	webhookMaxRetries = 10
+	webhookQueueMax   = 200 // 单个订阅最多排队的事件数，超出的直接记为死信
+)
+
+func (w *WebhookItem) subscribed(eventType string) bool {
+	if !w.Enable || w.URL == "" {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WebhookItem) retries() int {
+	switch {
+	case w.Retries == 0:
+		return 3
+	case w.Retries < 0:
+		return 0
+	case w.Retries > webhookMaxRetries:
+		return webhookMaxRetries
+	}
+	return w.Retries
+}
+
+// WebhookEvent 投递出去的事件
+type WebhookEvent struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Time int64       `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+type webhookJob struct {
+	hook      *WebhookItem
+	eventID   string
+	eventType string
+	payload   []byte
+}
+
+// webhookQueue 一个订阅的待投递事件，由一个协程按顺序投递，空闲时退出
+type webhookQueue struct {
+	jobs    []webhookJob
+	running bool
+}
+
+type WebhookManager struct {
+	parent *Dice
+	client *http.Client
+	sem    chan struct{} // 同时进行的请求数，重试等待期间不占用
+
+	// 保护 parent.Webhooks。修改时整体替换列表，读取方拿到的快照不会被改动
+	lock sync.RWMutex
+
+	queueLock sync.Mutex
+	queues    map[string]*webhookQueue // 订阅ID -> 投递队列
+}
+
+var ErrWebhookNotFound = errors.New("找不到该 webhook")
+
+func NewWebhookManager(d *Dice) *WebhookManager {
+	return &WebhookManager{
+		parent: d,
+		client: &http.Client{Timeout: 15 * time.Second},
+		sem:    make(chan struct{}, 8),
+		queues: map[string]*webhookQueue{},
+	}
+}
+
+// List 当前的订阅列表
+func (wm *WebhookManager) List() []*WebhookItem {
+	wm.lock.RLock()
+	defer wm.lock.RUnlock()
+	return wm.parent.Webhooks
+}
+
+// Upsert 新增或替换订阅，ID 为空时视为新增并分配 ID
+func (wm *WebhookManager) Upsert(item *WebhookItem) error {
+	wm.lock.Lock()
+	defer wm.lock.Unlock()
+	old := wm.parent.Webhooks
+	lst := make([]*WebhookItem, 0, len(old)+1)
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+		lst = append(append(lst, old...), item)
+	} else {
+		found := false
+		for _, w := range old {
+			if w.ID == item.ID {
+				w = item
+				found = true
+			}
+			lst = append(lst, w)
+		}
+		if !found {
+			return ErrWebhookNotFound
+		}
+	}
+	wm.parent.Webhooks = lst
+	return nil
+}
+
+// Delete 删除订阅
+func (wm *WebhookManager) Delete(id string) {
+	wm.lock.Lock()
+	defer wm.lock.Unlock()
+	lst := make([]*WebhookItem, 0, len(wm.parent.Webhooks))
+	for _, w := range wm.parent.Webhooks {
+		if w.ID != id {
+			lst = append(lst, w)
+		}
+	}
+	wm.parent.Webhooks = lst
+}
+
+// Subscribed 是否有启用的订阅关心该事件，用来省掉无人订阅时的序列化
+func (wm *WebhookManager) Subscribed(eventType string) bool {
+	if wm == nil {
+		return false
+	}
+	for _, w := range wm.List() {
+		if w.subscribed(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Emit 异步投递事件。data 会立即序列化，之后对其的修改不影响投递内容
+func (wm *WebhookManager) Emit(eventType string, data interface{}) {
+	if !wm.Subscribed(eventType) {
+		return
+	}
+	ev := WebhookEvent{
+		ID:   uuid.New().String(),
+		Type: eventType,
+		Time: time.Now().Unix(),
+		Data: data,
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		wm.parent.Logger.Errorf("webhook 事件序列化失败 %s: %v", eventType, err)
+		return
+	}
+	for _, w := range wm.List() {
+		if w.subscribed(eventType) {
+			wm.enqueue(webhookJob{hook: w, eventID: ev.ID, eventType: eventType, payload: payload})
+		}
+	}
+}
+
+// enqueue 事件放入订阅的投递队列，队列已满时直接记为死信
+func (wm *WebhookManager) enqueue(job webhookJob) {
+	wm.queueLock.Lock()
+	q := wm.queues[job.hook.ID]
+	if q == nil {
+		q = &webhookQueue{}
+		wm.queues[job.hook.ID] = q
+	}
+	if len(q.jobs) >= webhookQueueMax {
+		wm.queueLock.Unlock()
+		wm.parent.Logger.Errorf("webhook <%s> 投递队列已满，%s 事件记为死信", job.hook.Name, job.eventType)
+		wm.deadLetter(job, errors.New("投递队列已满"), 0)
+		return
+	}
+	q.jobs = append(q.jobs, job)
+	if !q.running {
+		q.running = true
+		go wm.work(job.hook.ID, q)
+	}
+	wm.queueLock.Unlock()
+}
+
+func (wm *WebhookManager) work(hookID string, q *webhookQueue) {
+	for {
+		wm.queueLock.Lock()
+		if len(q.jobs) == 0 {
+			q.running = false
+			delete(wm.queues, hookID)
+			wm.queueLock.Unlock()
+			return
+		}
+		job := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		wm.queueLock.Unlock()
+		wm.deliver(job.hook, job.eventID, job.eventType, job.payload)
+	}
+}
+
+func (wm *WebhookManager) deliver(w *WebhookItem, eventID, eventType string, payload []byte) {
+	defer ErrorLogAndContinue(wm.parent)
+
+	var err error
+	attempts := 0
+	for i := 0; i <= w.retries(); i++ {
+		if i > 0 {
+			delay := time.Duration(1<<(i-1)) * time.Second
+			if delay > time.Minute {
+				delay = time.Minute
+			}
+			time.Sleep(delay)
+		}
+		attempts++
+		var retry bool
+		wm.sem <- struct{}{}
+		retry, err = wm.post(context.Background(), w, eventID, eventType, payload)
+		<-wm.sem
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	wm.parent.Logger.Errorf("webhook <%s> 投递 %s 失败(%d次): %v", w.Name, eventType, attempts, err)
+	wm.deadLetter(webhookJob{hook: w, eventID: eventID, eventType: eventType, payload: payload}, err, attempts)
+}
+
+func (wm *WebhookManager) deadLetter(job webhookJob, err error, attempts int) {
+	_ = model.WebhookDeadLetterAdd(wm.parent.DBData, &model.WebhookDeadLetter{
+		HookID:    job.hook.ID,
+		EventID:   job.eventID,
+		EventType: job.eventType,
+		Payload:   string(job.payload),
+		Error:     err.Error(),
+		Attempts:  int64(attempts),
+	})
+}
+
+// post 发出一次请求，retry 表示失败后是否值得重试
+func (wm *WebhookManager) post(ctx context.Context, w *WebhookItem, eventID, eventType string, payload []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "SealDice-Webhook/"+VERSION.String())
+	req.Header.Set("X-Seal-Event", eventType)
+	req.Header.Set("X-Seal-Delivery", eventID)
+	req.Header.Set("X-Seal-Timestamp", ts)
+	if w.Secret != "" {
+		req.Header.Set("X-Seal-Signature", "sha256="+HTTPSign(w.Secret, ts, payload))
+	}
+	resp, err := wm.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("HTTP %d", resp.StatusCode)
+}
+
+func (wm *WebhookManager) getHook(id string) *WebhookItem {
+	for _, w := range wm.List() {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// Test 立即向指定订阅发送一个测试事件，不重试
+func (wm *WebhookManager) Test(id string) error {
+	w := wm.getHook(id)
+	if w == nil {
+		return ErrWebhookNotFound
+	}
+	ev := WebhookEvent{ID: uuid.New().String(), Type: "ping", Time: time.Now().Unix(), Data: map[string]string{"hookId": w.ID}}
+	payload, _ := json.Marshal(ev)
+	_, err := wm.post(context.Background(), w, ev.ID, ev.Type, payload)
+	return err
+}
+
+// RetryDeadLetter 重新投递一条死信，成功后删除
+func (wm *WebhookManager) RetryDeadLetter(id int64) error {
+	item, err := model.WebhookDeadLetterGet(wm.parent.DBData, id)
+	if err != nil {
+		return err
+	}
+	w := wm.getHook(item.HookID)
+	if w == nil {
+		return errors.New("对应的 webhook 已被删除")
+	}
+	if _, err = wm.post(context.Background(), w, item.EventID, item.EventType, []byte(item.Payload)); err != nil {
+		return err
+	}
+	return model.WebhookDeadLetterDelete(wm.parent.DBData, id)
+}
+
+// 以下为各事件的数据
+
+func webhookEmitRoll(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+	if ctx.CommandInfo == nil || !ctx.Dice.Webhook.Subscribed(WebhookEventRoll) {
+		return
+	}
+	data := map[string]interface{}{
+		"platform":    msg.Platform,
+		"messageType": msg.MessageType,
+		"groupId":     msg.GroupID,
+		"userId":      msg.Sender.UserID,
+		"command":     cmdArgs.Command,
+		"message":     msg.Message,
+		"commandInfo": ctx.CommandInfo,
+	}
+	if ctx.Player != nil {
+		data["nickname"] = ctx.Player.Name
+	}
+	if ctx.EndPoint != nil {
+		data["endpoint"] = ctx.EndPoint.UserID
+	}
+	ctx.Dice.Webhook.Emit(WebhookEventRoll, data)
+}
+
+func webhookEmitLog(ctx *MsgContext, eventType string, group *GroupInfo, logName string, lines int64) {
+	ctx.Dice.Webhook.Emit(eventType, map[string]interface{}{
+		"groupId":  group.GroupID,
+		"logName":  logName,
+		"lines":    lines,
+		"operator": ctx.Player.UserID,
+	})
+}
+
+func webhookEmitSheet(d *Dice, item *AttributesItem) {
+	if !d.Webhook.Subscribed(WebhookEventSheetChange) {
+		return
+	}
+	attrs, err := item.toJSON()
+	if err != nil {
+		return
+	}
+	d.Webhook.Emit(WebhookEventSheetChange, map[string]interface{}{
+		"id":        item.ID,
+		"name":      item.Name,
+		"sheetType": item.SheetType,
+		"attrs":     json.RawMessage(attrs),
+		"updatedAt": item.LastModifiedTime,
+	})
+}
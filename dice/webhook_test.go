@@ -0,0 +1,136 @@
+package dice
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"sealdice-core/dice/model"
+)
+
+func TestWebhookDeadLetter(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fail atomic.Bool
+	fail.Store(true)
+	var got WebhookEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Seal-Signature") != "sha256="+HTTPSign("key", r.Header.Get("X-Seal-Timestamp"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	d := &Dice{DBData: dataDB, Logger: zap.NewNop().Sugar()}
+	d.Webhook = NewWebhookManager(d)
+	hook := &WebhookItem{ID: "h1", URL: srv.URL, Secret: "key", Events: []string{WebhookEventBanDelete}, Enable: true, Retries: -1}
+	d.Webhooks = []*WebhookItem{hook}
+	if d.Webhook.Subscribed(WebhookEventRoll) || !d.Webhook.Subscribed(WebhookEventBanDelete) {
+		t.Fatal("订阅判断不对")
+	}
+
+	d.Webhook.deliver(hook, "e1", WebhookEventBanDelete, []byte(`{"id":"e1","type":"ban.delete","time":1,"data":{"id":"QQ:1"}}`))
+	total, lst, err := model.WebhookDeadLetterGetPage(dataDB, model.QueryWebhookDeadLetter{PageNum: 1, PageSize: 10})
+	if err != nil || total != 1 || lst[0].EventID != "e1" || lst[0].Error != "HTTP 502" || lst[0].Attempts != 1 {
+		t.Fatalf("死信记录不对: %v %+v", err, lst)
+	}
+
+	fail.Store(false)
+	if err = d.Webhook.RetryDeadLetter(lst[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "e1" || got.Type != WebhookEventBanDelete {
+		t.Errorf("重投内容不对: %+v", got)
+	}
+	total, _, _ = model.WebhookDeadLetterGetPage(dataDB, model.QueryWebhookDeadLetter{PageNum: 1, PageSize: 10})
+	if total != 0 {
+		t.Errorf("重投成功后应删除死信，剩余 %d", total)
+	}
+}
+
+func TestWebhookConcurrentEdit(t *testing.T) {
+	d := &Dice{Logger: zap.NewNop().Sugar()}
+	d.Webhook = NewWebhookManager(d)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			item := &WebhookItem{Name: "x", URL: "http://127.0.0.1:1", Events: []string{WebhookEventGroupJoin}}
+			_ = d.Webhook.Upsert(item)
+			d.Webhook.Delete(item.ID)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		d.Webhook.Subscribed(WebhookEventRoll)
+		_ = d.Webhook.getHook("none")
+	}
+	<-done
+
+	if err := d.Webhook.Upsert(&WebhookItem{ID: "missing"}); err != ErrWebhookNotFound {
+		t.Fatalf("updating a missing hook should fail: %v", err)
+	}
+	if len(d.Webhook.List()) != 0 {
+		t.Fatal("all hooks should be deleted")
+	}
+}
+
+func TestWebhookQueueBounded(t *testing.T) {
+	dataDB, _, err := model.SQLiteDBInit(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	var received atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received.Add(1)
+	}))
+	defer srv.Close()
+
+	d := &Dice{DBData: dataDB, Logger: zap.NewNop().Sugar()}
+	d.Webhook = NewWebhookManager(d)
+	d.Webhooks = []*WebhookItem{{ID: "h1", URL: srv.URL, Enable: true, Retries: 1000}}
+	if n := d.Webhooks[0].retries(); n != webhookMaxRetries {
+		t.Fatalf("retries should be capped, got %d", n)
+	}
+
+	// 第一个事件在投递中，其余排队；超出队列上限的记为死信，不另开协程
+	extra := 5
+	for i := 0; i < webhookQueueMax+1+extra; i++ {
+		d.Webhook.Emit(WebhookEventRoll, i)
+	}
+	total, _, err := model.WebhookDeadLetterGetPage(dataDB, model.QueryWebhookDeadLetter{PageNum: 1, PageSize: 10})
+	if err != nil || total < extra {
+		t.Fatalf("overflowed events should become dead letters: %v %d", err, total)
+	}
+	close(release)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		d.Webhook.queueLock.Lock()
+		idle := len(d.Webhook.queues) == 0
+		d.Webhook.queueLock.Unlock()
+		if idle {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := received.Load() + int64(total); got != int64(webhookQueueMax+1+extra) {
+		t.Fatalf("every event should be delivered or dead-lettered, got %d", got)
+	}
+}
@@ -0,0 +1,189 @@
+package dice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"sealdice-core/utils"
+)
+
+// WebUI 两步验证(TOTP)。主人与各用户分别设置，丢失验证器时可用恢复码登录，
+// 或以 --reset-2fa 启动参数关闭
+
+const (
+	totpIssuer         = "SealDice"
+	totpSetupTTL       = 10 * time.Minute
+	totpRecoveryCodeN  = 10
+	totpOwnerAccountID = "owner"
+	totpMaxFailures    = 5 // 连续错误次数上限，超出后锁定一段时间
+	totpLockDuration   = 10 * time.Minute
+)
+
+var (
+	ErrTOTPNotSetup     = errors.New("请先生成密钥")
+	ErrTOTPCodeInvalid  = errors.New("验证码错误")
+	ErrTOTPNotEnabled   = errors.New("未开启两步验证")
+	ErrTOTPAlreadyExist = errors.New("已开启两步验证，请先关闭")
+	ErrTOTPLocked       = errors.New("验证码错误次数过多，请稍后再试")
+)
+
+// TOTPConfig 两步验证设置
+type TOTPConfig struct {
+	Secret        string   `yaml:"secret"`
+	RecoveryCodes []string `yaml:"recoveryCodes"` // 恢复码的哈希，用过即删
+	LastStep      int64    `yaml:"lastStep"`      // 最近使用的验证码步数，防止重放
+}
+
+type totpPending struct {
+	secret  string
+	expires time.Time
+}
+
+type totpFailure struct {
+	count int
+	last  time.Time
+}
+
+func totpRecoveryHash(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// totpSlot 账号对应的设置位置，需持有锁。username 为空表示主人，用户不存在时返回nil
+func (dm *DiceManager) totpSlot(username string) **TOTPConfig {
+	if username == "" {
+		return &dm.UITOTP
+	}
+	if u := dm.webUIUserFind(username); u != nil {
+		return &u.TOTP
+	}
+	return nil
+}
+
+// TOTPEnabled 账号是否开启了两步验证，并返回剩余恢复码数量
+func (dm *DiceManager) TOTPEnabled(username string) (bool, int) {
+	dm.webUIUserLock.RLock()
+	defer dm.webUIUserLock.RUnlock()
+	slot := dm.totpSlot(username)
+	if slot == nil || *slot == nil {
+		return false, 0
+	}
+	return true, len((*slot).RecoveryCodes)
+}
+
+// TOTPSetupBegin 生成新密钥，返回密钥与扫码链接，需在有效期内用 TOTPSetupConfirm 确认
+func (dm *DiceManager) TOTPSetupBegin(username string) (string, string, error) {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	slot := dm.totpSlot(username)
+	if slot == nil {
+		return "", "", ErrWebUIUserNotFound
+	}
+	if *slot != nil {
+		return "", "", ErrTOTPAlreadyExist
+	}
+	secret := utils.TOTPSecretNew()
+	if dm.totpPending == nil {
+		dm.totpPending = map[string]*totpPending{}
+	}
+	dm.totpPending[username] = &totpPending{secret: secret, expires: time.Now().Add(totpSetupTTL)}
+
+	account := username
+	if account == "" {
+		account = totpOwnerAccountID
+	}
+	return secret, utils.TOTPURI(totpIssuer, account, secret), nil
+}
+
+// TOTPSetupConfirm 用验证码确认开启，返回一次性恢复码
+func (dm *DiceManager) TOTPSetupConfirm(username string, code string) ([]string, error) {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	slot := dm.totpSlot(username)
+	if slot == nil {
+		return nil, ErrWebUIUserNotFound
+	}
+	p := dm.totpPending[username]
+	if p == nil || time.Now().After(p.expires) {
+		return nil, ErrTOTPNotSetup
+	}
+	step := utils.TOTPValidate(p.secret, code, time.Now())
+	if step < 0 {
+		return nil, ErrTOTPCodeInvalid
+	}
+	delete(dm.totpPending, username)
+
+	cfg := &TOTPConfig{Secret: p.secret, LastStep: step}
+	codes := make([]string, 0, totpRecoveryCodeN)
+	for i := 0; i < totpRecoveryCodeN; i++ {
+		c := utils.RandStr(10)
+		c = c[:5] + "-" + c[5:]
+		codes = append(codes, c)
+		cfg.RecoveryCodes = append(cfg.RecoveryCodes, totpRecoveryHash(c))
+	}
+	*slot = cfg
+	return codes, nil
+}
+
+// TOTPVerify 校验验证码或恢复码，恢复码校验通过后作废
+func (dm *DiceManager) TOTPVerify(username string, code string) error {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	slot := dm.totpSlot(username)
+	if slot == nil || *slot == nil {
+		return ErrTOTPNotEnabled
+	}
+	now := time.Now()
+	if dm.totpFailures == nil {
+		dm.totpFailures = map[string]*totpFailure{}
+	}
+	f := dm.totpFailures[username]
+	if f != nil && now.Sub(f.last) > totpLockDuration {
+		f = nil
+	}
+	if f != nil && f.count >= totpMaxFailures {
+		return ErrTOTPLocked
+	}
+
+	cfg := *slot
+	if step := utils.TOTPValidate(cfg.Secret, code, now); step > cfg.LastStep {
+		cfg.LastStep = step
+		delete(dm.totpFailures, username)
+		return nil
+	}
+	h := totpRecoveryHash(code)
+	for idx, i := range cfg.RecoveryCodes {
+		if i == h {
+			cfg.RecoveryCodes = append(cfg.RecoveryCodes[:idx], cfg.RecoveryCodes[idx+1:]...)
+			delete(dm.totpFailures, username)
+			return nil
+		}
+	}
+	if f == nil {
+		f = &totpFailure{}
+		dm.totpFailures[username] = f
+	}
+	f.count++
+	f.last = now
+	return ErrTOTPCodeInvalid
+}
+
+// TOTPReset 关闭两步验证
+func (dm *DiceManager) TOTPReset(username string) error {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	slot := dm.totpSlot(username)
+	if slot == nil {
+		return ErrWebUIUserNotFound
+	}
+	if *slot == nil {
+		return ErrTOTPNotEnabled
+	}
+	*slot = nil
+	delete(dm.totpFailures, username)
+	return nil
+}
@@ -0,0 +1,64 @@
+package dice
+
+import (
+	"testing"
+	"time"
+
+	"sealdice-core/utils"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 附录B的测试向量，取后6位
+	code, err := utils.TOTPCode("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", utils.TOTPStep(time.Unix(59, 0)))
+	if err != nil || code != "287082" {
+		t.Fatalf("unexpected code %s %v", code, err)
+	}
+}
+
+func TestTOTPLogin(t *testing.T) {
+	dm := &DiceManager{AccessTokens: map[string]bool{}}
+	if _, err := dm.TOTPSetupConfirm("", "000000"); err != ErrTOTPNotSetup {
+		t.Fatalf("confirm without setup: %v", err)
+	}
+	secret, uri, err := dm.TOTPSetupBegin("")
+	if err != nil || uri == "" {
+		t.Fatal(err)
+	}
+	now := utils.TOTPStep(time.Now())
+	code, _ := utils.TOTPCode(secret, now)
+	recovery, err := dm.TOTPSetupConfirm("", code)
+	if err != nil || len(recovery) != totpRecoveryCodeN {
+		t.Fatal(err)
+	}
+
+	// 确认时用过的验证码不能再用于登录
+	if err = dm.TOTPVerify("", code); err != ErrTOTPCodeInvalid {
+		t.Fatalf("replay should fail: %v", err)
+	}
+	next, _ := utils.TOTPCode(secret, now+1)
+	if err = dm.TOTPVerify("", next); err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.TOTPVerify("", recovery[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.TOTPVerify("", recovery[0]); err != ErrTOTPCodeInvalid {
+		t.Fatal("recovery code should be one-time")
+	}
+	if _, left := dm.TOTPEnabled(""); left != totpRecoveryCodeN-1 {
+		t.Fatalf("recovery codes left: %d", left)
+	}
+
+	for i := 0; i < totpMaxFailures; i++ {
+		_ = dm.TOTPVerify("", "abcdef")
+	}
+	if err = dm.TOTPVerify("", recovery[1]); err != ErrTOTPLocked {
+		t.Fatalf("should be locked: %v", err)
+	}
+	if err = dm.TOTPReset(""); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := dm.TOTPEnabled(""); enabled {
+		t.Fatal("reset should disable 2fa")
+	}
+}
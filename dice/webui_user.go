@@ -0,0 +1,221 @@
+package dice
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// WebUI 多用户与权限。旧版的单密码登录视为主人(owner)，其余用户由主人在UI中添加
+
+type WebUIRole string
+
+const (
+	WebUIRoleOwner     WebUIRole = "owner"     // 主人，拥有全部权限
+	WebUIRoleAdmin     WebUIRole = "admin"     // 管理员，除用户管理与升级/关闭外的全部权限
+	WebUIRoleGM        WebUIRole = "gm"        // 主持人，只读日志与数据
+	WebUIRoleModerator WebUIRole = "moderator" // 协管，处理黑名单与敏感词
+)
+
+type WebUIPermission string
+
+const (
+	PermBase     WebUIPermission = "base"      // 基本信息
+	PermLogRead  WebUIPermission = "log:read"  // 查看跑团日志
+	PermLogWrite WebUIPermission = "log:write" // 上传、删除跑团日志
+	PermDataRead WebUIPermission = "data:read" // 查看群组、帐号关联、骰点统计等
+	PermBan      WebUIPermission = "ban"       // 黑名单
+	PermCensor   WebUIPermission = "censor"    // 敏感词
+	PermConfig   WebUIPermission = "config"    // 设置、帐号、牌堆、帮助文档等
+	PermScript   WebUIPermission = "script"    // JS扩展与指令测试
+	PermBackup   WebUIPermission = "backup"    // 备份
+	PermSystem   WebUIPermission = "system"    // 升级、关闭程序
+	PermUser     WebUIPermission = "user"      // 用户管理
+	PermAudit    WebUIPermission = "audit"     // 查看操作记录
+)
+
+// WebUIPermissionList 全部权限及其名称，顺序即界面展示顺序
+var WebUIPermissionList = []struct {
+	Key  WebUIPermission `json:"key"`
+	Name string          `json:"name"`
+}{
+	{PermBase, "基本信息"},
+	{PermLogRead, "查看日志"},
+	{PermLogWrite, "管理日志"},
+	{PermDataRead, "查看数据"},
+	{PermBan, "黑名单"},
+	{PermCensor, "敏感词"},
+	{PermConfig, "修改设置"},
+	{PermScript, "JS扩展与指令测试"},
+	{PermBackup, "备份"},
+	{PermSystem, "升级与关闭"},
+	{PermUser, "用户管理"},
+	{PermAudit, "操作记录"},
+}
+
+var WebUIRolePermissions = map[WebUIRole][]WebUIPermission{
+	WebUIRoleOwner:     {PermBase, PermLogRead, PermLogWrite, PermDataRead, PermBan, PermCensor, PermConfig, PermScript, PermBackup, PermSystem, PermUser, PermAudit},
+	WebUIRoleAdmin:     {PermBase, PermLogRead, PermLogWrite, PermDataRead, PermBan, PermCensor, PermConfig, PermScript, PermBackup, PermAudit},
+	WebUIRoleGM:        {PermBase, PermLogRead, PermDataRead},
+	WebUIRoleModerator: {PermBase, PermLogRead, PermDataRead, PermBan, PermCensor},
+}
+
+// WebUIRoleHas 角色是否拥有权限
+func WebUIRoleHas(role WebUIRole, p WebUIPermission) bool {
+	for _, i := range WebUIRolePermissions[role] {
+		if i == p {
+			return true
+		}
+	}
+	return false
+}
+
+type WebUIUser struct {
+	Username     string    `yaml:"username" json:"username"`
+	Role         WebUIRole `yaml:"role" json:"role"`
+	PasswordHash string    `yaml:"passwordHash" json:"-"` // 前端加盐哈希后的密码再经 bcrypt
+	Disabled     bool      `yaml:"disabled" json:"disabled"`
+	CreatedAt    int64     `yaml:"createdAt" json:"createdAt"`
+	LastLoginAt  int64     `yaml:"lastLoginAt" json:"lastLoginAt"`
+
+	TOTP        *TOTPConfig `yaml:"totp,omitempty" json:"-"` // 两步验证
+	TOTPEnabled bool        `yaml:"-" json:"totpEnabled"`
+}
+
+var (
+	ErrWebUIUserNameInvalid = errors.New("用户名只能包含字母、数字、下划线与横线，长度2-32")
+	ErrWebUIUserRoleInvalid = errors.New("无效的角色")
+	ErrWebUIUserNotFound    = errors.New("用户不存在")
+	ErrWebUIUserNoPassword  = errors.New("新用户必须设置密码")
+	ErrWebUISignInFailed    = errors.New("用户名或密码错误")
+)
+
+var webUIUserNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{2,32}$`)
+
+// WebUIUserList 全部用户的副本
+func (dm *DiceManager) WebUIUserList() []WebUIUser {
+	dm.webUIUserLock.RLock()
+	defer dm.webUIUserLock.RUnlock()
+	items := make([]WebUIUser, 0, len(dm.WebUIUsers))
+	for _, u := range dm.WebUIUsers {
+		item := *u
+		item.TOTPEnabled = u.TOTP != nil
+		items = append(items, item)
+	}
+	return items
+}
+
+func (dm *DiceManager) webUIUserFind(name string) *WebUIUser {
+	for _, u := range dm.WebUIUsers {
+		if u.Username == name {
+			return u
+		}
+	}
+	return nil
+}
+
+// WebUIUserSet 新建或修改用户，password 为空时不修改密码
+func (dm *DiceManager) WebUIUserSet(name string, role WebUIRole, password string, disabled bool) error {
+	if !webUIUserNameRe.MatchString(name) {
+		return ErrWebUIUserNameInvalid
+	}
+	if _, ok := WebUIRolePermissions[role]; !ok {
+		return ErrWebUIUserRoleInvalid
+	}
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+
+	u := dm.webUIUserFind(name)
+	if u == nil {
+		if password == "" {
+			return ErrWebUIUserNoPassword
+		}
+		u = &WebUIUser{Username: name, CreatedAt: time.Now().Unix()}
+		dm.WebUIUsers = append(dm.WebUIUsers, u)
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash = string(hash)
+	}
+	u.Role = role
+	u.Disabled = disabled
+	if disabled || password != "" {
+		dm.revokeUserTokens(name)
+	}
+	return nil
+}
+
+// WebUIUserDelete 删除用户，并使其登录失效
+func (dm *DiceManager) WebUIUserDelete(name string) error {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	for idx, u := range dm.WebUIUsers {
+		if u.Username == name {
+			dm.WebUIUsers = append(dm.WebUIUsers[:idx], dm.WebUIUsers[idx+1:]...)
+			dm.revokeUserTokens(name)
+			return nil
+		}
+	}
+	return ErrWebUIUserNotFound
+}
+
+// WebUIUserCheck 校验用户名密码，成功时记录登录时间
+func (dm *DiceManager) WebUIUserCheck(name string, password string) error {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	u := dm.webUIUserFind(name)
+	if u == nil || u.Disabled {
+		return ErrWebUISignInFailed
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return ErrWebUISignInFailed
+	}
+	u.LastLoginAt = time.Now().Unix()
+	return nil
+}
+
+// AccessTokenAdd 记录登录token，username 为空表示主人
+func (dm *DiceManager) AccessTokenAdd(token string, username string) {
+	dm.webUIUserLock.Lock()
+	defer dm.webUIUserLock.Unlock()
+	dm.AccessTokens[token] = true
+	if username != "" {
+		if dm.AccessTokenUsers == nil {
+			dm.AccessTokenUsers = map[string]string{}
+		}
+		dm.AccessTokenUsers[token] = username
+	}
+}
+
+// AccessTokenRole 登录token对应的用户名与角色，token无效或用户已停用时 ok 为 false
+func (dm *DiceManager) AccessTokenRole(token string) (username string, role WebUIRole, ok bool) {
+	dm.webUIUserLock.RLock()
+	defer dm.webUIUserLock.RUnlock()
+	if !dm.AccessTokens[token] {
+		return "", "", false
+	}
+	name, isUser := dm.AccessTokenUsers[token]
+	if !isUser {
+		return "", WebUIRoleOwner, true
+	}
+	u := dm.webUIUserFind(name)
+	if u == nil || u.Disabled {
+		return "", "", false
+	}
+	return u.Username, u.Role, true
+}
+
+// revokeUserTokens 需持有锁
+func (dm *DiceManager) revokeUserTokens(name string) {
+	for token, u := range dm.AccessTokenUsers {
+		if u == name {
+			delete(dm.AccessTokenUsers, token)
+			delete(dm.AccessTokens, token)
+		}
+	}
+}
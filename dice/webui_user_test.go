@@ -0,0 +1,37 @@
+package dice
+
+import "testing"
+
+func TestWebUIUserRole(t *testing.T) {
+	dm := &DiceManager{AccessTokens: map[string]bool{}}
+	dm.AccessTokenAdd("owner-token", "")
+	if _, role, ok := dm.AccessTokenRole("owner-token"); !ok || role != WebUIRoleOwner {
+		t.Fatal("legacy token should be owner")
+	}
+
+	if err := dm.WebUIUserSet("gm1", WebUIRoleGM, "", false); err != ErrWebUIUserNoPassword {
+		t.Fatalf("new user without password: %v", err)
+	}
+	if err := dm.WebUIUserSet("gm1", WebUIRoleGM, "hash", false); err != nil {
+		t.Fatal(err)
+	}
+	if dm.WebUIUserCheck("gm1", "wrong") == nil || dm.WebUIUserCheck("gm1", "hash") != nil {
+		t.Fatal("password check failed")
+	}
+	dm.AccessTokenAdd("gm-token", "gm1")
+	name, role, ok := dm.AccessTokenRole("gm-token")
+	if !ok || name != "gm1" || !WebUIRoleHas(role, PermLogRead) || WebUIRoleHas(role, PermScript) {
+		t.Fatalf("unexpected role %q for %q", role, name)
+	}
+
+	// 停用后登录立即失效
+	if err := dm.WebUIUserSet("gm1", WebUIRoleGM, "", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := dm.AccessTokenRole("gm-token"); ok {
+		t.Fatal("disabled user token should be revoked")
+	}
+	if dm.WebUIUserDelete("gm1") != nil || dm.WebUIUserDelete("gm1") != ErrWebUIUserNotFound {
+		t.Fatal("delete failed")
+	}
+}
@@ -191,6 +191,7 @@ func main() {
 		UpdateTest             bool   `long:"update-test" description:"更新测试"`
 		LogLevel               int8   `long:"log-level" description:"设置日志等级" default:"0" choice:"-1" choice:"0" choice:"1" choice:"2" choice:"3" choice:"4" choice:"5"`
 		ContainerMode          bool   `long:"container-mode" description:"容器模式，该模式下禁用内置客户端"`
+		Reset2FA               string `long:"reset-2fa" optional:"yes" optional-value:"-" description:"关闭WebUI的两步验证，用于丢失验证器时。不填用户名为主人帐号"`
 	}
 
 	_, err := flags.ParseArgs(&opts, os.Args)
@@ -248,6 +249,20 @@ func main() {
 		diceManager.ServeAddress = opts.Address
 	}
 
+	if opts.Reset2FA != "" {
+		username := opts.Reset2FA
+		if username == "-" {
+			username = ""
+		}
+		if err = diceManager.TOTPReset(username); err != nil {
+			fmt.Println("关闭两步验证失败:", err.Error())
+			return
+		}
+		diceManager.Save()
+		fmt.Println("已关闭两步验证，请登录后重新设置")
+		return
+	}
+
 	if opts.Install {
 		serviceName := opts.ServiceName
 		if serviceName == "" {
@@ -549,6 +564,14 @@ func diceServe(d *dice.Dice) {
 					dice.ServeDingTalk(d, conn)
 				case "SEALCHAT":
 					dice.ServeSealChat(d, conn)
+				case "MATRIX":
+					dice.ServeMatrix(d, conn)
+				case "IRC":
+					dice.ServeIRC(d, conn)
+				case "FEISHU":
+					dice.ServeFeishu(d, conn)
+				case "HTTP":
+					dice.ServeHTTPAdapter(d, conn)
 				}
 			}(_conn)
 		} else {
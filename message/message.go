@@ -54,6 +54,7 @@ const (
 	Reply                     // 回复
 	Record                    // 语音
 	Face                      // 表情
+	Button                    // 按钮
 )
 
 const maxFileSize = 1024 * 1024 * 50 // 50MB
@@ -129,6 +130,24 @@ func (f *FaceElement) Type() ElementType {
 	return Face
 }
 
+// ButtonElement 按钮，点击后由点击者执行 Command，不支持按钮的平台会转为提示文本
+type ButtonElement struct {
+	Label   string `jsbind:"label"`
+	Command string `jsbind:"command"`
+}
+
+func (b *ButtonElement) Type() ElementType {
+	return Button
+}
+
+var cqValueEscaper = strings.NewReplacer("&", "&amp;", "[", "&#91;", "]", "&#93;", ",", "&#44;")
+var cqValueUnescaper = strings.NewReplacer("&#44;", ",", "&#91;", "[", "&#93;", "]", "&amp;", "&")
+
+// ButtonCQ 生成按钮的CQ码，label 与 command 中的特殊字符会被转义
+func ButtonCQ(label, command string) string {
+	return fmt.Sprintf("[CQ:button,text=%s,cmd=%s]", cqValueEscaper.Replace(label), cqValueEscaper.Replace(command))
+}
+
 func newText(s string) *TextElement {
 	return &TextElement{Content: s}
 }
@@ -321,6 +340,16 @@ func toElement(t string, dMap map[string]string) (IMessageElement, error) {
 	case "reply":
 		target := dMap["id"]
 		return &ReplyElement{ReplySeq: target}, nil
+	case "button":
+		cmd := cqValueUnescaper.Replace(dMap["cmd"])
+		if cmd == "" {
+			return nil, errors.New("按钮缺少指令")
+		}
+		label := cqValueUnescaper.Replace(dMap["text"])
+		if label == "" {
+			label = cmd
+		}
+		return &ButtonElement{Label: label, Command: cmd}, nil
 	}
 	return CQToText(t, dMap), nil
 }
@@ -0,0 +1,281 @@
+// Package textimg 将长文本渲染为PNG图片，纯Go实现，使用内置的 Ark Pixel 点阵字库(许可见 FONT_LICENSE.txt)
+package textimg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 方舟像素字体 12px 等宽简体中文版，原样压缩，未作修改
+//
+//go:embed ark-pixel-12px-monospaced-zh_cn.bdf.gz
+var fontData []byte
+
+const (
+	glyphHeight = 12
+	glyphAscent = 10 // 基线以上的高度
+)
+
+// Options 渲染样式，尺寸均为缩放前的像素
+type Options struct {
+	Scale    int // 放大倍数，点阵字体整数倍放大不会模糊
+	MaxWidth int // 单行最大宽度，超出自动换行
+	Padding  int
+	LineGap  int
+	MaxLines int // 超出的部分截断
+
+	Background color.RGBA
+	Foreground color.RGBA
+	Accent     color.RGBA // 首行(标题)颜色
+	TitleBold  bool
+}
+
+var DefaultOptions = Options{
+	Scale:      2,
+	MaxWidth:   12 * 36,
+	Padding:    12,
+	LineGap:    4,
+	MaxLines:   400,
+	Background: color.RGBA{R: 0xfb, G: 0xf8, B: 0xf1, A: 0xff},
+	Foreground: color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff},
+	Accent:     color.RGBA{R: 0x8b, G: 0x45, B: 0x13, A: 0xff},
+	TitleBold:  true,
+}
+
+var ErrEmptyText = errors.New("文本为空")
+
+type bitmapFont struct {
+	widths  []byte   // 每个码位的宽度，0为无此字形
+	offsets []uint32 // 每个码位的点阵在 data 中的位置
+	data    []byte
+}
+
+var (
+	fontOnce   sync.Once
+	fontLoaded *bitmapFont
+	fontErr    error
+)
+
+func loadFont() (*bitmapFont, error) {
+	fontOnce.Do(func() {
+		zr, err := gzip.NewReader(bytes.NewReader(fontData))
+		if err != nil {
+			fontErr = err
+			return
+		}
+		fontLoaded, fontErr = parseBDF(bufio.NewScanner(zr))
+	})
+	return fontLoaded, fontErr
+}
+
+// parseBDF 读取BDF字库中基本多文种平面内的字形，每个字形按宽度铺满 glyphHeight 高的格子
+func parseBDF(sc *bufio.Scanner) (*bitmapFont, error) {
+	f := &bitmapFont{widths: make([]byte, 0x10000), offsets: make([]uint32, 0x10000)}
+	errBroken := errors.New("字库数据损坏")
+	atoi := func(s string) int {
+		v, _ := strconv.Atoi(s)
+		return v
+	}
+
+	code, width := -1, 0
+	var bbx [4]int // 宽、高、x偏移、y偏移
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "ENCODING":
+			if len(fields) < 2 {
+				return nil, errBroken
+			}
+			code = atoi(fields[1])
+		case "DWIDTH":
+			if len(fields) < 2 {
+				return nil, errBroken
+			}
+			width = atoi(fields[1])
+		case "BBX":
+			if len(fields) < 5 {
+				return nil, errBroken
+			}
+			for i := range bbx {
+				bbx[i] = atoi(fields[i+1])
+			}
+		case "BITMAP":
+			rowBytes := (width + 7) / 8
+			cell := make([]byte, rowBytes*glyphHeight)
+			top := glyphAscent - bbx[1] - bbx[3]
+			for y := 0; y < bbx[1]; y++ {
+				if !sc.Scan() {
+					return nil, errBroken
+				}
+				row, err := hex.DecodeString(strings.TrimSpace(sc.Text()))
+				if err != nil {
+					return nil, errBroken
+				}
+				cy := top + y
+				if cy < 0 || cy >= glyphHeight {
+					continue
+				}
+				for x := 0; x < bbx[0]; x++ {
+					cx := bbx[2] + x
+					if x/8 >= len(row) || row[x/8]&(0x80>>(x%8)) == 0 || cx < 0 || cx >= width {
+						continue
+					}
+					cell[cy*rowBytes+cx/8] |= 0x80 >> (cx % 8)
+				}
+			}
+			if code > 0 && code < 0x10000 && width > 0 && width < 256 {
+				f.widths[code] = byte(width)
+				f.offsets[code] = uint32(len(f.data))
+				f.data = append(f.data, cell...)
+			}
+			code, width = -1, 0
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if f.widths['?'] == 0 {
+		return nil, errBroken
+	}
+	return f, nil
+}
+
+// glyph 返回字形宽度与点阵，缺字时使用替代字符
+func (f *bitmapFont) glyph(r rune) (int, []byte) {
+	for _, c := range []rune{r, 0xFFFD, '?'} {
+		if c >= 0 && c < 0x10000 && f.widths[c] != 0 {
+			w := int(f.widths[c])
+			off := f.offsets[c]
+			return w, f.data[off : off+uint32((w+7)/8*glyphHeight)]
+		}
+	}
+	return 6, make([]byte, glyphHeight)
+}
+
+// wrap 按宽度折行
+func (f *bitmapFont) wrap(text string, maxWidth int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.ReplaceAll(strings.TrimRight(line, "\r"), "\t", "    ")
+		var cur []rune
+		width := 0
+		for _, r := range line {
+			w, _ := f.glyph(r)
+			if width+w > maxWidth && len(cur) > 0 {
+				lines = append(lines, string(cur))
+				cur, width = cur[:0], 0
+				if r == ' ' {
+					continue
+				}
+			}
+			cur = append(cur, r)
+			width += w
+		}
+		lines = append(lines, string(cur))
+	}
+	return lines
+}
+
+// Render 渲染文本，返回PNG数据
+func Render(text string, opt *Options) ([]byte, error) {
+	if opt == nil {
+		opt = &DefaultOptions
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptyText
+	}
+	f, err := loadFont()
+	if err != nil {
+		return nil, err
+	}
+	scale := opt.Scale
+	if scale < 1 {
+		scale = 1
+	}
+
+	lines := f.wrap(text, opt.MaxWidth)
+	if opt.MaxLines > 0 && len(lines) > opt.MaxLines {
+		lines = append(lines[:opt.MaxLines], "……(内容过长，已截断)")
+	}
+	contentWidth := 0
+	for _, line := range lines {
+		w := 0
+		for _, r := range line {
+			gw, _ := f.glyph(r)
+			w += gw
+		}
+		if w > contentWidth {
+			contentWidth = w
+		}
+	}
+	width := (contentWidth + opt.Padding*2 + 1) * scale
+	lineHeight := glyphHeight + opt.LineGap
+	height := (len(lines)*lineHeight - opt.LineGap + opt.Padding*2) * scale
+
+	// 调色板图片体积远小于RGBA
+	palette := color.Palette{opt.Background, opt.Foreground, opt.Accent}
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+
+	for i, line := range lines {
+		idx := uint8(1)
+		bold := false
+		if i == 0 && len(lines) > 1 {
+			idx, bold = 2, opt.TitleBold
+		}
+		x := opt.Padding
+		y := opt.Padding + i*lineHeight
+		for _, r := range line {
+			w, bits := f.glyph(r)
+			drawGlyph(img, bits, w, x, y, scale, idx, bold)
+			x += w
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err = enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawGlyph(img *image.Paletted, bits []byte, w, x0, y0, scale int, idx uint8, bold bool) {
+	bytesPerRow := (w + 7) / 8
+	boldOffset := scale / 2
+	if boldOffset < 1 {
+		boldOffset = 1
+	}
+	for gy := 0; gy < glyphHeight; gy++ {
+		for gx := 0; gx < w; gx++ {
+			if bits[gy*bytesPerRow+gx/8]&(0x80>>(gx%8)) == 0 {
+				continue
+			}
+			fillPixel(img, (x0+gx)*scale, (y0+gy)*scale, scale, idx)
+			if bold {
+				fillPixel(img, (x0+gx)*scale+boldOffset, (y0+gy)*scale, scale, idx)
+			}
+		}
+	}
+}
+
+func fillPixel(img *image.Paletted, x, y, size int, idx uint8) {
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			img.SetColorIndex(x+dx, y+dy, idx)
+		}
+	}
+}
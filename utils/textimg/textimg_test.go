@@ -0,0 +1,95 @@
+package textimg
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	f, err := loadFont()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		text     string
+		maxWidth int
+		want     []string
+	}{
+		{"abcdef", 6 * 4, []string{"abcd", "ef"}},
+		{"abcd", 6 * 4, []string{"abcd"}},
+		// 折行处的空格丢弃
+		{"abcd efgh", 6 * 4, []string{"abcd", "efgh"}},
+		// 半角宽度为6，汉字宽度为12
+		{"一二三四五", 12 * 2, []string{"一二", "三四", "五"}},
+		{"a一二", 12 * 2, []string{"a一", "二"}},
+		{"ab\r\n\ncd", 6 * 4, []string{"ab", "", "cd"}},
+		{"\tx", 6 * 4, []string{"    ", "x"}},
+		// 单个字符超宽时也至少放一个
+		{"一二", 6, []string{"一", "二"}},
+	}
+	for _, c := range cases {
+		got := f.wrap(c.text, c.maxWidth)
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("wrap(%q, %d) = %q, want %q", c.text, c.maxWidth, got, c.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	if _, err := Render(" \n ", nil); err != ErrEmptyText {
+		t.Errorf("empty text: err = %v", err)
+	}
+
+	opt := DefaultOptions
+	opt.MaxWidth = 12 * 10
+	text := "标题\n" + strings.Repeat("正文内容", 10)
+	data, err := Render(text, &opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := 1 + 40*12/opt.MaxWidth
+	b := img.Bounds()
+	wantW := (opt.MaxWidth + opt.Padding*2 + 1) * opt.Scale
+	wantH := (lines*(glyphHeight+opt.LineGap) - opt.LineGap + opt.Padding*2) * opt.Scale
+	if b.Dx() != wantW || b.Dy() != wantH {
+		t.Errorf("size %dx%d, want %dx%d", b.Dx(), b.Dy(), wantW, wantH)
+	}
+
+	// 应当画出了文字，而不是只有背景
+	bg := opt.Background
+	drawn := false
+	for y := b.Min.Y; y < b.Max.Y && !drawn; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if uint8(r>>8) != bg.R || uint8(g>>8) != bg.G || uint8(bl>>8) != bg.B {
+				drawn = true
+				break
+			}
+		}
+	}
+	if !drawn {
+		t.Error("rendered image has no text pixels")
+	}
+
+	opt.MaxLines = 2
+	data, err = Render(text, &opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantH = (3*(glyphHeight+opt.LineGap) - opt.LineGap + opt.Padding*2) * opt.Scale
+	if img.Bounds().Dy() != wantH {
+		t.Errorf("truncated height %d, want %d", img.Bounds().Dy(), wantH)
+	}
+}